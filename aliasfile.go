@@ -5,20 +5,22 @@ package cmd
 
 import (
 	"io/ioutil"
+	"os"
 	"strings"
 )
 
 // ParseAliasFile will read the specified file and convert
 // the content to a map of names to the command line arguments
-// they relate to.  The function will always return a valid map, even
-// if it is empty.
+// they relate to.  A leading "~" in the filename is replaced with the
+// user's real home directory, even when running under a snap.  The
+// function will always return a valid map, even if it is empty.
 func ParseAliasFile(aliasFilename string) map[string][]string {
 	result := map[string][]string{}
 	if aliasFilename == "" {
 		return result
 	}
 
-	content, err := ioutil.ReadFile(aliasFilename)
+	content, err := ioutil.ReadFile(expandHome(aliasFilename, os.Getenv))
 	if err != nil {
 		logger.Tracef("unable to read alias file %q: %s", aliasFilename, err)
 		return result
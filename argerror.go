@@ -0,0 +1,85 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ArgError is returned by the argument checks, such as CheckEmpty, when
+// an argument is not valid. It records the offending argument and its
+// position, so that the error can be reported precisely.
+type ArgError struct {
+	// Arg holds the offending argument.
+	Arg string `json:"argument" yaml:"argument"`
+
+	// Position holds the index of the argument in the positional
+	// arguments given to the command's Init.
+	Position int `json:"position" yaml:"position"`
+
+	// Message describes what is wrong with the argument.
+	Message string `json:"message" yaml:"message"`
+
+	// commandLine holds the words of the command line the argument was
+	// given on, and index the position of the argument in it, when the
+	// argument is to be pointed at.
+	commandLine []string
+	index       int
+}
+
+// NewArgError returns an ArgError for the argument arg at position,
+// described by message.
+func NewArgError(arg string, position int, message string) *ArgError {
+	return &ArgError{Arg: arg, Position: position, Message: message}
+}
+
+// Error implements error.
+func (e *ArgError) Error() string {
+	return e.Message
+}
+
+// pointAt arranges for the argument to be pointed at when the error is
+// written, by finding it in the command line made of prefix, the name of
+// the command, and args. The last matching argument is chosen, as
+// positional arguments usually follow any flags.
+func (e *ArgError) pointAt(prefix string, args []string) {
+	for i := len(args) - 1; i >= 0; i-- {
+		if args[i] == e.Arg {
+			e.commandLine = append(strings.Fields(prefix), args...)
+			e.index = len(e.commandLine) - len(args) + i
+			return
+		}
+	}
+}
+
+// writePointer writes the command line with the offending argument
+// underlined, if it is known.
+func (e *ArgError) writePointer(w io.Writer) {
+	if e.commandLine == nil {
+		return
+	}
+	line := strings.Join(e.commandLine, " ")
+	offset := len(strings.Join(e.commandLine[:e.index], " "))
+	if e.index > 0 {
+		offset++
+	}
+	width := len(e.Arg)
+	if width == 0 {
+		width = 1
+	}
+	fmt.Fprintf(w, "  %s\n  %s%s\n", Redact(line), strings.Repeat(" ", offset), strings.Repeat("^", width))
+}
+
+// asArgError returns the ArgError in err's chain, if there is one.
+func asArgError(err error) (*ArgError, bool) {
+	var argErr *ArgError
+	if errors.As(err, &argErr) {
+		return argErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"encoding/json"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ArgErrorSuite struct {
+	testing.LoggingCleanupSuite
+}
+
+var _ = gc.Suite(&ArgErrorSuite{})
+
+func (s *ArgErrorSuite) TestCheckEmpty(c *gc.C) {
+	err := cmd.CheckEmpty([]string{"extra", "more"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra" "more"\]`)
+	argErr, ok := err.(*cmd.ArgError)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(argErr.Arg, gc.Equals, "extra")
+	c.Assert(argErr.Position, gc.Equals, 0)
+}
+
+func (s *ArgErrorSuite) TestZeroOrOneArgs(c *gc.C) {
+	_, err := cmd.ZeroOrOneArgs([]string{"model", "extra"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra"\]`)
+	var argErr *cmd.ArgError
+	c.Assert(errors.As(errors.Annotate(err, "wrapped"), &argErr), jc.IsTrue)
+	c.Assert(argErr.Arg, gc.Equals, "extra")
+	c.Assert(argErr.Position, gc.Equals, 1)
+}
+
+func (s *ArgErrorSuite) TestJSON(c *gc.C) {
+	data, err := json.Marshal(cmd.NewArgError("extra", 2, "unexpected argument"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, `{"argument":"extra","position":2,"message":"unexpected argument"}`)
+}
+
+func (s *ArgErrorSuite) TestPointerWithDebug(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		stderr string
+	}{{
+		args:   []string{"blah", "extra"},
+		stderr: "ERROR unrecognized args: [\"extra\"]\n",
+	}, {
+		args: []string{"blah", "--debug", "--option", "extra", "extra"},
+		stderr: "" +
+			"ERROR unrecognized args: [\"extra\"]\n" +
+			"  jujutest blah --debug --option extra extra\n" +
+			"                                       ^^^^^\n",
+	}, {
+		args: []string{"--debug", "blah", "more", "extra"},
+		stderr: "" +
+			"ERROR unrecognized args: [\"more\" \"extra\"]\n" +
+			"  jujutest blah more extra\n" +
+			"                ^^^^\n",
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", Log: &cmd.Log{}})
+		sc.Register(&TestCommand{Name: "blah"})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(sc, ctx, test.args)
+		c.Check(code, gc.Equals, 2)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// expandArgFiles replaces each "@file" argument with the arguments read
+// from file, so that argument lists can exceed the limits of the shell.
+// An argument starting with "@@" stands for itself without the first "@",
+// and arguments following "--" are never expanded. Files are not expanded
+// recursively. Relative file names are resolved against ctx.Dir, as they
+// are when an invocation is saved, unless ctx is nil.
+//
+// Arguments in a file are separated by whitespace, including newlines.
+// Single quotes preserve everything they enclose; within double quotes and
+// unquoted text a backslash escapes the next character. Lines whose first
+// non-blank character is "#" are comments.
+func expandArgFiles(ctx *Context, args []string) ([]string, error) {
+	var result []string
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return append(result, args[i:]...), nil
+		case strings.HasPrefix(arg, "@@"):
+			result = append(result, arg[1:])
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			path := arg[1:]
+			if ctx != nil {
+				path = ctx.AbsPath(path)
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, errors.Annotate(err, "reading argument file")
+			}
+			fileArgs, err := splitArgFile(string(content))
+			if err != nil {
+				return nil, errors.Annotatef(err, "parsing argument file %q", arg[1:])
+			}
+			result = append(result, fileArgs...)
+		default:
+			result = append(result, arg)
+		}
+	}
+	return result, nil
+}
+
+// splitArgFile splits the content of an argument file into arguments,
+// following the quoting rules described for expandArgFiles.
+func splitArgFile(content string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   byte
+		escaped bool
+		comment bool
+	)
+	// The content is read byte by byte, rather than by rune, so that
+	// arguments that are not valid UTF-8 are passed through unchanged.
+	for i := 0; i < len(content); i++ {
+		b := content[i]
+		switch {
+		case comment:
+			comment = b != '\n'
+		case escaped:
+			current.WriteByte(b)
+			escaped = false
+		case quote == '\'':
+			if b == '\'' {
+				quote = 0
+			} else {
+				current.WriteByte(b)
+			}
+		case b == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if b == '"' {
+				quote = 0
+			} else {
+				current.WriteByte(b)
+			}
+		case b == '\'' || b == '"':
+			quote, inArg = b, true
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case b == '#' && !inArg:
+			comment = true
+		default:
+			current.WriteByte(b)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// saveInvocationFlag is the name of the flag that saves the invocation of
+// a command as an argument file.
+const saveInvocationFlag = "save-invocation"
+
+// quoteArgFileArg quotes arg so that splitArgFile reads it back
+// unchanged. Arguments starting with "@" need no escaping, as files are
+// not expanded recursively.
+func quoteArgFileArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\r\n'\"\\#") {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+// repeatedValue is implemented by flag values that collect an element
+// each time the flag is given.
+type repeatedValue interface {
+	elements() []string
+}
+
+// invocationValues returns the values with which the flag holding value
+// is given in a saved invocation: one for each element of a repeated
+// value, and otherwise its string form.
+func invocationValues(value gnuflag.Value) []string {
+	if v, ok := value.(*DynamicDefaultValue); ok {
+		value = v.Value
+	}
+	if v, ok := value.(repeatedValue); ok {
+		return v.elements()
+	}
+	return []string{value.String()}
+}
+
+// invocationArgs returns the arguments of the invocation of the selected
+// subcommand, once aliases have been expanded and defaults resolved: its
+// name, the flags given on the command line or set from the environment,
+// the user config file or a dynamic default, and its positional arguments.
+// The --save-invocation flag is left out.
+func (c *SuperCommand) invocationArgs(name string, args []string) []string {
+	// Flags that set the same value are aliases of each other, of which
+	// the longest name is used.
+	names := make(map[interface{}]string)
+	var values []gnuflag.Value
+	add := func(flag *gnuflag.Flag) {
+		if flag.Name == saveInvocationFlag {
+			return
+		}
+		current, ok := names[flag.Value]
+		if !ok {
+			values = append(values, flag.Value)
+		}
+		if len(flag.Name) > len(current) {
+			names[flag.Value] = flag.Name
+		}
+	}
+	if c.flags != nil {
+		c.flags.Visit(add)
+	}
+	c.commonflags.Visit(add)
+	c.commonflags.VisitAll(func(flag *gnuflag.Flag) {
+		if v, ok := flag.Value.(*DynamicDefaultValue); (ok && v.resolved) || c.defaulted[flag.Value] {
+			add(flag)
+		}
+	})
+	result := []string{name}
+	for _, value := range values {
+		flagName := names[value]
+		// Short flags take their value from the next argument, unless
+		// they are boolean, which can only be set to true.
+		if b, ok := value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && len(flagName) == 1 {
+			if value.String() == "true" {
+				result = append(result, "-"+flagName)
+			}
+			continue
+		}
+		for _, v := range invocationValues(value) {
+			if len(flagName) > 1 {
+				result = append(result, "--"+flagName+"="+v)
+			} else {
+				result = append(result, "-"+flagName, v)
+			}
+		}
+	}
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			result = append(result, "--")
+			return append(result, args[i:]...)
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// saveInvocation writes the invocation of the command to the file given
+// with --save-invocation, in the format read by "@file" arguments.
+func (c *SuperCommand) saveInvocation(ctx *Context) error {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# Run again with: %s @%s\n", c.commandPath(), c.invocationFile)
+	for _, arg := range c.invocation {
+		fmt.Fprintln(&buf, quoteArgFileArg(arg))
+	}
+	err := ioutil.WriteFile(ctx.AbsPath(c.invocationFile), []byte(buf.String()), 0644)
+	return errors.Annotate(err, "saving invocation")
+}
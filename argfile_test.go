@@ -0,0 +1,267 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ArgFileSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ArgFileSuite{})
+
+// argsCommand records the arguments it is initialised with.
+type argsCommand struct {
+	cmd.CommandBase
+	args []string
+}
+
+func (c *argsCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "remove-unit", Purpose: "remove units"}
+}
+
+func (c *argsCommand) Init(args []string) error {
+	c.args = args
+	return nil
+}
+
+func (c *argsCommand) Run(ctx *cmd.Context) error {
+	return nil
+}
+
+func (s *ArgFileSuite) TestSplitArgFile(c *gc.C) {
+	for i, test := range []struct {
+		content string
+		args    []string
+		err     string
+	}{{
+		content: "unit/0 unit/1\n\tunit/2\r\n",
+		args:    []string{"unit/0", "unit/1", "unit/2"},
+	}, {
+		content: "# targets\nunit/0 # first\n  # indented comment\nunit/1",
+		args:    []string{"unit/0", "unit/1"},
+	}, {
+		content: `'it''s' "a \"quoted\" arg" 'no \escape' back\ slash ""`,
+		args:    []string{"its", `a "quoted" arg`, `no \escape`, "back slash", ""},
+	}, {
+		content: `--config "key=value with spaces" a#b`,
+		args:    []string{"--config", "key=value with spaces", "a#b"},
+	}, {
+		content: `'unterminated`,
+		err:     "unterminated ' quote",
+	}, {
+		content: `trailing\`,
+		err:     "trailing backslash",
+	}} {
+		c.Logf("test %d: %q", i, test.content)
+		args, err := cmd.SplitArgFile(test.content)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(args, gc.DeepEquals, test.args)
+	}
+}
+
+func (s *ArgFileSuite) run(c *gc.C, expand bool, args ...string) (*argsCommand, *cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:           "juju",
+		ExpandArgFiles: expand,
+		TimeFlag:       true,
+	})
+	command := &argsCommand{}
+	sc.Register(command)
+	ctx := cmdtesting.Context(c)
+	return command, ctx, cmd.Main(sc, ctx, args)
+}
+
+func (s *ArgFileSuite) TestExpand(c *gc.C) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, "units.txt")
+	err := ioutil.WriteFile(path, []byte("unit/0\nunit/1\n"), 0644)
+	c.Assert(err, gc.IsNil)
+
+	command, _, code := s.run(c, true, "remove-unit", "@"+path, "@@literal", "unit/2", "--", "@"+path)
+	c.Assert(code, gc.Equals, 0)
+	// The "--" is consumed by the subcommand's flag parsing.
+	c.Assert(command.args, gc.DeepEquals, []string{"unit/0", "unit/1", "@literal", "unit/2", "@" + path})
+
+	// The subcommand itself can come from the file.
+	err = ioutil.WriteFile(path, []byte("remove-unit unit/0\n"), 0644)
+	c.Assert(err, gc.IsNil)
+	command, _, code = s.run(c, true, "@"+path)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.args, gc.DeepEquals, []string{"unit/0"})
+}
+
+func (s *ArgFileSuite) TestNotEnabled(c *gc.C) {
+	command, _, code := s.run(c, false, "remove-unit", "@units.txt")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.args, gc.DeepEquals, []string{"@units.txt"})
+}
+
+func (s *ArgFileSuite) TestMissingFile(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "missing.txt")
+	_, ctx, code := s.run(c, true, "remove-unit", "@"+path)
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Matches, "ERROR reading argument file: open .*missing.txt: .*\n")
+}
+
+func (s *ArgFileSuite) TestSaveInvocation(c *gc.C) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, "invocation.txt")
+	command, _, code := s.run(c, true,
+		"--time", "remove-unit", "--save-invocation", path,
+		"unit/0", "it's a #test", "@@unit", "", "--", "--force",
+	)
+	c.Assert(code, gc.Equals, 0)
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, "# Run again with: juju @"+path+`
+remove-unit
+--time=true
+unit/0
+'it'\''s a #test'
+@unit
+''
+--
+--force
+`)
+
+	// Running the saved invocation gives the same arguments.
+	expected := command.args
+	command, _, code = s.run(c, true, "@"+path)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.args, gc.DeepEquals, expected)
+}
+
+func (s *ArgFileSuite) TestSaveInvocationRelativePath(c *gc.C) {
+	// Relative paths are resolved against the context's directory, not
+	// the process working directory, both when saving and replaying.
+	dir := c.MkDir()
+	run := func(args ...string) (*argsCommand, int) {
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:           "juju",
+			ExpandArgFiles: true,
+		})
+		command := &argsCommand{}
+		sc.Register(command)
+		ctx := cmdtesting.Context(c)
+		ctx.Dir = dir
+		return command, cmd.Main(sc, ctx, args)
+	}
+	command, code := run("remove-unit", "--save-invocation", "invocation.txt", "unit/0")
+	c.Assert(code, gc.Equals, 0)
+	_, err := os.Stat(filepath.Join(dir, "invocation.txt"))
+	c.Assert(err, gc.IsNil)
+
+	replayed, code := run("@invocation.txt")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(replayed.args, gc.DeepEquals, command.args)
+}
+
+func (s *ArgFileSuite) TestSaveInvocationNotEnabled(c *gc.C) {
+	_, ctx, code := s.run(c, false, "remove-unit", "--save-invocation", "x")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR flag provided but not defined: --save-invocation\n")
+}
+
+func (s *ArgFileSuite) TestSaveInvocationWithDefaults(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "invocation.txt")
+	run := func(current string, args ...string) (*cmd.Context, int) {
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:           "juju",
+			ExpandArgFiles: true,
+		})
+		sc.Register(&modelCommand{current: current})
+		ctx := cmdtesting.Context(c)
+		return ctx, cmd.Main(sc, ctx, args)
+	}
+	_, code := run("prod", "status", "--save-invocation", path)
+	c.Assert(code, gc.Equals, 0)
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, "# Run again with: juju @"+path+"\nstatus\n-m\nprod\n")
+
+	ctx, code := run("dev", "@"+path)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "prod\n")
+}
+
+// listCommand records the output format, units and arguments it is
+// initialised with.
+type listCommand struct {
+	cmd.CommandBase
+	out   cmd.Output
+	units []string
+	args  []string
+}
+
+func (c *listCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "list", Purpose: "list storage"}
+}
+
+func (c *listCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFormatFlags(f, "yaml", cmd.DefaultFormatters)
+	f.Var(cmd.AppendStrings{Values: &c.units}, "unit", "a unit to list")
+}
+
+func (c *listCommand) Init(args []string) error {
+	c.args = args
+	return nil
+}
+
+func (c *listCommand) Run(ctx *cmd.Context) error {
+	return nil
+}
+
+func (s *ArgFileSuite) TestSaveInvocationNested(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "invocation.txt")
+	run := func(args ...string) (*listCommand, int) {
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:           "juju",
+			ExpandArgFiles: true,
+		})
+		storage := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:    "storage",
+			Purpose: "manage storage",
+		})
+		list := &listCommand{}
+		storage.Register(list)
+		sc.Register(storage)
+		return list, cmd.Main(sc, cmdtesting.Context(c), args)
+	}
+	list, code := run("--save-invocation", path,
+		"storage", "list", "--format", "json=pretty", "--unit", "a", "--unit", "b,c", "y")
+	c.Assert(code, gc.Equals, 0)
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, "# Run again with: juju @"+path+`
+storage
+list
+--format=json=pretty
+--unit=a
+--unit=b,c
+y
+`)
+
+	// Running the saved invocation gives the same flags and arguments.
+	replayed, code := run("@" + path)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(replayed.out.Name(), gc.Equals, "json")
+	c.Assert(replayed.units, gc.DeepEquals, list.units)
+	c.Assert(replayed.args, gc.DeepEquals, list.args)
+}
@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/juju/gnuflag"
@@ -57,3 +58,40 @@ func (v *AppendStringsValue) Set(s string) error {
 func (v *AppendStringsValue) String() string {
 	return strings.Join(*v, ",")
 }
+
+// elements implements repeatedValue.
+func (v *AppendStringsValue) elements() []string {
+	return *v
+}
+
+// AppendStrings is an AppendStringsValue for the slice pointed to by
+// Values. Like StringMap, it may be used without a constructor:
+//
+//	f.Var(cmd.AppendStrings{Values: &c.units}, "unit", "help")
+type AppendStrings struct {
+	Values *[]string
+}
+
+// Set implements gnuflag.Value's Set method.
+func (v AppendStrings) Set(s string) error {
+	if v.Values == nil {
+		return errors.New("no slice to append to")
+	}
+	return NewAppendStringsValue(v.Values).Set(s)
+}
+
+// String implements gnuflag.Value's String method.
+func (v AppendStrings) String() string {
+	if v.Values == nil {
+		return ""
+	}
+	return NewAppendStringsValue(v.Values).String()
+}
+
+// elements implements repeatedValue.
+func (v AppendStrings) elements() []string {
+	if v.Values == nil {
+		return nil
+	}
+	return *v.Values
+}
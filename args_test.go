@@ -172,3 +172,40 @@ func (*ArgsSuite) TestAppendStringsUsage(c *gc.C) {
 		c.Check(value, gc.DeepEquals, test.expectedValue)
 	}
 }
+
+func (*ArgsSuite) TestAppendStrings(c *gc.C) {
+	for i, test := range []struct {
+		message       string
+		initial       []string
+		args          []string
+		expectedValue []string
+		expectedUsage string
+	}{{
+		message: "no args",
+	}, {
+		message:       "value set by args",
+		args:          []string{"--unit", "mysql/0", "--unit=mysql/1,mysql/2"},
+		expectedValue: []string{"mysql/0", "mysql/1,mysql/2"},
+	}, {
+		message:       "appended to initial values",
+		initial:       []string{"wordpress/0"},
+		args:          []string{"--unit", "mysql/0"},
+		expectedValue: []string{"wordpress/0", "mysql/0"},
+		expectedUsage: "wordpress/0",
+	}} {
+		c.Log(fmt.Sprintf("%v: %s", i, test.message))
+		f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+		f.SetOutput(ioutil.Discard)
+		value := test.initial
+		f.Var(cmd.AppendStrings{Values: &value}, "unit", "help")
+		c.Check(f.Lookup("unit").DefValue, gc.Equals, test.expectedUsage)
+		err := f.Parse(false, test.args)
+		c.Check(err, gc.IsNil)
+		c.Check(value, gc.DeepEquals, test.expectedValue)
+	}
+}
+
+func (*ArgsSuite) TestAppendStringsZeroValue(c *gc.C) {
+	c.Assert(cmd.AppendStrings{}.String(), gc.Equals, "")
+	c.Assert(cmd.AppendStrings{}.Set("mysql/0"), gc.ErrorMatches, "no slice to append to")
+}
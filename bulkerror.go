@@ -0,0 +1,152 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+)
+
+// BulkErrorExitCode is the exit code used by Main when a command fails
+// with a BulkError.
+const BulkErrorExitCode = 3
+
+// PartialSuccessExitCode is the default exit code used by Main when a
+// command fails with a BulkError for some targets but succeeded for others,
+// so that automation can tell partial failures from total ones. A
+// SuperCommand may use a different code by setting
+// SuperCommandParams.PartialSuccessExitCode.
+const PartialSuccessExitCode = 64
+
+// TargetError records the failure of an operation on a single target.
+type TargetError struct {
+	Target string
+	Err    error
+}
+
+// BulkError collects the failures of an operation that is applied to many
+// targets (e.g. units or machines), so that one failure does not stop the
+// others and no detail is lost. When returned from a command run by a
+// SuperCommand, even if wrapped, the failures are rendered as a table on
+// stderr, and as structured entries on stdout when a machine readable
+// format is in use.
+type BulkError struct {
+	failures  []TargetError
+	succeeded []string
+}
+
+// Add records the failure of the operation on target. Nil errors are
+// ignored.
+func (e *BulkError) Add(target string, err error) {
+	if err == nil {
+		return
+	}
+	e.failures = append(e.failures, TargetError{Target: target, Err: err})
+}
+
+// Succeeded records that the operation succeeded on target. Recording
+// successes lets Main report partial success with a distinct exit code.
+func (e *BulkError) Succeeded(target string) {
+	e.succeeded = append(e.succeeded, target)
+}
+
+// Partial reports whether the operation succeeded on some targets and
+// failed on others.
+func (e *BulkError) Partial() bool {
+	return len(e.succeeded) > 0 && len(e.failures) > 0
+}
+
+// Successes returns the targets recorded as succeeded, in the order they
+// were recorded.
+func (e *BulkError) Successes() []string {
+	return e.succeeded
+}
+
+// Failures returns the recorded failures, in the order they were added.
+func (e *BulkError) Failures() []TargetError {
+	return e.failures
+}
+
+// ErrorOrNil returns e if any failures have been recorded, and nil
+// otherwise.
+func (e *BulkError) ErrorOrNil() error {
+	if e == nil || len(e.failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error implements error.
+func (e *BulkError) Error() string {
+	if len(e.failures) == 1 {
+		return fmt.Sprintf("%s: %v", e.failures[0].Target, e.failures[0].Err)
+	}
+	if len(e.succeeded) > 0 {
+		return fmt.Sprintf("%d of %d targets failed", len(e.failures), len(e.failures)+len(e.succeeded))
+	}
+	return fmt.Sprintf("%d targets failed", len(e.failures))
+}
+
+// exitCode returns the exit code for the error, using partialCode when
+// the operation only partially failed.
+func (e *BulkError) exitCode(partialCode int) int {
+	if e.Partial() {
+		return partialCode
+	}
+	return BulkErrorExitCode
+}
+
+// bulkErrorEntry is the structured form of a TargetError.
+type bulkErrorEntry struct {
+	Target string `json:"target" yaml:"target"`
+	Error  string `json:"error" yaml:"error"`
+}
+
+// structured returns the value written for the error when a machine
+// readable output format is in use.
+func (e *BulkError) structured() map[string]interface{} {
+	entries := make([]bulkErrorEntry, len(e.failures))
+	for i, failure := range e.failures {
+		entries[i] = bulkErrorEntry{
+			Target: failure.Target,
+			Error:  Redact(failure.Err.Error()),
+		}
+	}
+	return map[string]interface{}{"errors": entries}
+}
+
+// writeTable writes a table of failures to writer.
+func (e *BulkError) writeTable(writer io.Writer) {
+	tw := tabwriter.NewWriter(writer, 0, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tERROR")
+	for _, failure := range e.failures {
+		fmt.Fprintf(tw, "%s\t%s\n", failure.Target, Redact(failure.Err.Error()))
+	}
+	tw.Flush()
+}
+
+// writeError writes err to writer using renderer, or Print if
+// renderer is nil. A BulkError with several failures is followed by a
+// table of them.
+func writeError(ctx *Context, err error, renderer ErrorRenderer) {
+	var bulk *BulkError
+	table := errors.As(err, &bulk) && len(bulk.failures) > 1
+	switch {
+	case renderer != nil:
+		renderer.RenderError(ctx.Stderr, err)
+	case table:
+		Print(ctx.Stderr, SeverityError, err.Error()+":")
+	default:
+		Print(ctx.Stderr, SeverityError, err)
+	}
+	if table {
+		bulk.writeTable(ctx.Stderr)
+	}
+	if url := learnMoreURL(err); url != "" {
+		fmt.Fprintf(ctx.Stderr, "Learn more: %s\n", ctx.Link(ctx.Stderr, url, ""))
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type BulkErrorSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&BulkErrorSuite{})
+
+func (s *BulkErrorSuite) TestErrorOrNil(c *gc.C) {
+	var bulk cmd.BulkError
+	c.Assert(bulk.ErrorOrNil(), gc.IsNil)
+	bulk.Add("unit/0", nil)
+	c.Assert(bulk.ErrorOrNil(), gc.IsNil)
+	bulk.Add("unit/1", errors.New("boom"))
+	c.Assert(bulk.ErrorOrNil(), gc.Equals, &bulk)
+	c.Assert(bulk.Failures(), gc.DeepEquals, []cmd.TargetError{
+		{Target: "unit/1", Err: errors.New("boom")},
+	})
+}
+
+func (s *BulkErrorSuite) TestError(c *gc.C) {
+	var bulk cmd.BulkError
+	bulk.Add("unit/0", errors.New("boom"))
+	c.Assert(bulk.Error(), gc.Equals, "unit/0: boom")
+	bulk.Add("unit/1", errors.New("bang"))
+	c.Assert(bulk.Error(), gc.Equals, "2 targets failed")
+}
+
+func bulkFailure() error {
+	var bulk cmd.BulkError
+	bulk.Add("unit/0", errors.New("boom"))
+	bulk.Add("unit/12", errors.New("bang"))
+	return bulk.ErrorOrNil()
+}
+
+func (s *BulkErrorSuite) TestMain(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return bulkFailure() },
+	}, ctx, nil)
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `
+ERROR 2 targets failed:
+TARGET   ERROR
+unit/0   boom
+unit/12  bang
+`[1:])
+}
+
+func (s *BulkErrorSuite) TestMainSingleFailure(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&TestCommand{
+		Name: "blah",
+		CustomRun: func(*cmd.Context) error {
+			var bulk cmd.BulkError
+			bulk.Add("unit/0", errors.New("boom"))
+			return bulk.ErrorOrNil()
+		},
+	}, ctx, nil)
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR unit/0: boom\n")
+}
+
+func (s *BulkErrorSuite) TestSuperCommand(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+	})
+	sc.Register(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return bulkFailure() },
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `
+ERROR 2 targets failed:
+TARGET   ERROR
+unit/0   boom
+unit/12  bang
+`[1:])
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+}
+
+func (s *BulkErrorSuite) TestSuperCommandJSON(c *gc.C) {
+	output := cmd.Output{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+		GlobalFlags: flagAdderFunc(func(fset *gnuflag.FlagSet) {
+			output.AddFlags(fset, "json", map[string]cmd.Formatter{"json": cmd.FormatJson})
+		}),
+	})
+	sc.Register(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return bulkFailure() },
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah", "--format=json"})
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals,
+		`{"errors":[{"target":"unit/0","error":"boom"},{"target":"unit/12","error":"bang"}]}`+"\n")
+}
+
+func (s *BulkErrorSuite) TestSuperCommandWrapped(c *gc.C) {
+	output := cmd.Output{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+		GlobalFlags: flagAdderFunc(func(fset *gnuflag.FlagSet) {
+			output.AddFlags(fset, "smart", map[string]cmd.Formatter{"smart": cmd.FormatSmart, "json": cmd.FormatJson})
+		}),
+	})
+	sc.Register(&TestCommand{
+		Name: "blah",
+		CustomRun: func(*cmd.Context) error {
+			return fmt.Errorf("removing units: %w", bulkFailure())
+		},
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `
+ERROR removing units: 2 targets failed:
+TARGET   ERROR
+unit/0   boom
+unit/12  bang
+`[1:])
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(sc, ctx, []string{"blah", "--format=json"})
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals,
+		`{"errors":[{"target":"unit/0","error":"boom"},{"target":"unit/12","error":"bang"}]}`+"\n")
+}
+
+func (s *BulkErrorSuite) TestSuperCommandErrorRenderer(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:          "juju",
+		ErrorRenderer: cmd.PrefixErrorRenderer("error: "),
+	})
+	sc.Register(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return bulkFailure() },
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `
+error: 2 targets failed
+TARGET   ERROR
+unit/0   boom
+unit/12  bang
+`[1:])
+}
+
+func (s *BulkErrorSuite) TestSuperCommandJSONWarnings(c *gc.C) {
+	output := cmd.Output{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+		GlobalFlags: flagAdderFunc(func(fset *gnuflag.FlagSet) {
+			output.AddFlags(fset, "json", map[string]cmd.Formatter{"json": cmd.FormatJson})
+		}),
+	})
+	sc.Register(&TestCommand{
+		Name: "blah",
+		CustomRun: func(ctx *cmd.Context) error {
+			ctx.WarningWithCodef(cmd.WarningDeprecatedCommand, "old")
+			return bulkFailure()
+		},
+	})
+	sc.Register(&TestCommand{
+		Name: "bleh",
+		CustomRun: func(ctx *cmd.Context) error {
+			ctx.WarningWithCodef(cmd.WarningDeprecatedCommand, "old")
+			return errors.New("kaboom")
+		},
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah", "--format=json"})
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals,
+		`{"errors":[{"target":"unit/0","error":"boom"},{"target":"unit/12","error":"bang"}],"warnings":[{"code":"deprecated-command","message":"old"}]}`+"\n")
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(sc, ctx, []string{"bleh", "--format=json"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `{"warnings":[{"code":"deprecated-command","message":"old"}]}`+"\n")
+}
+
+func partialFailure() error {
+	var bulk cmd.BulkError
+	bulk.Succeeded("unit/0")
+	bulk.Add("unit/1", errors.New("boom"))
+	return bulk.ErrorOrNil()
+}
+
+func (s *BulkErrorSuite) TestPartial(c *gc.C) {
+	var bulk cmd.BulkError
+	bulk.Succeeded("unit/0")
+	c.Assert(bulk.Successes(), gc.DeepEquals, []string{"unit/0"})
+	c.Assert(bulk.Partial(), gc.Equals, false)
+	c.Assert(bulk.ErrorOrNil(), gc.IsNil)
+	bulk.Add("unit/1", errors.New("boom"))
+	bulk.Add("unit/2", errors.New("bang"))
+	c.Assert(bulk.Partial(), gc.Equals, true)
+	c.Assert(bulk.Error(), gc.Equals, "2 of 3 targets failed")
+	cmdtesting.AssertPartialSuccess(c, bulk.ErrorOrNil(), "unit/1", "unit/2")
+	cmdtesting.AssertTotalFailure(c, bulkFailure(), "unit/0", "unit/12")
+}
+
+func (s *BulkErrorSuite) TestMainPartialSuccess(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return partialFailure() },
+	}, ctx, nil)
+	c.Assert(code, gc.Equals, cmd.PartialSuccessExitCode)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR unit/1: boom\n")
+}
+
+func (s *BulkErrorSuite) TestSuperCommandPartialSuccess(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		Log:  &cmd.Log{},
+	})
+	sc.Register(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return partialFailure() },
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, cmd.PartialSuccessExitCode)
+}
+
+func (s *BulkErrorSuite) TestSuperCommandPartialSuccessExitCode(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:                   "juju",
+		Log:                    &cmd.Log{},
+		PartialSuccessExitCode: 5,
+	})
+	sc.Register(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return partialFailure() },
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, 5)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// CapabilitySource describes the server that commands talk to, so that a
+// SuperCommand can check the MinServerVersion and RequiredFeatures in the
+// Info of a subcommand before it is run.
+type CapabilitySource interface {
+	// ServerVersion returns the version of the server, e.g. "3.4.2".
+	ServerVersion(ctx *Context) (string, error)
+
+	// HasFeature reports whether the server supports the named feature.
+	HasFeature(ctx *Context, feature string) (bool, error)
+}
+
+// RequirementError is returned in place of running a command that needs a
+// newer server, or a feature the server does not support.
+type RequirementError struct {
+	// Server is what the server is known as, e.g. "controller".
+	Server string
+
+	// MinVersion and Version are set when the server is older than the
+	// command supports.
+	MinVersion string
+	Version    string
+
+	// Feature is set when the server does not support a feature the
+	// command needs.
+	Feature string
+}
+
+// Error implements error.
+func (e *RequirementError) Error() string {
+	if e.Feature != "" {
+		return fmt.Sprintf("this command requires the %q %s feature", e.Feature, e.Server)
+	}
+	return fmt.Sprintf("this command requires %s >= %s, but the %s is %s", e.Server, e.MinVersion, e.Server, e.Version)
+}
+
+// IsRequirementError reports whether err is a *RequirementError.
+func IsRequirementError(err error) bool {
+	_, ok := errors.Cause(err).(*RequirementError)
+	return ok
+}
+
+// checkRequirements returns a *RequirementError if the server described by
+// source does not meet the requirements in info.
+func checkRequirements(ctx *Context, source CapabilitySource, server string, info *Info) error {
+	if info.MinServerVersion != "" {
+		version, err := source.ServerVersion(ctx)
+		if err != nil {
+			return errors.Annotatef(err, "getting %s version", server)
+		}
+		if compareVersions(version, info.MinServerVersion) < 0 {
+			return &RequirementError{Server: server, MinVersion: info.MinServerVersion, Version: version}
+		}
+	}
+	for _, feature := range info.RequiredFeatures {
+		supported, err := source.HasFeature(ctx, feature)
+		if err != nil {
+			return errors.Annotatef(err, "checking %s feature %q", server, feature)
+		}
+		if !supported {
+			return &RequirementError{Server: server, Feature: feature}
+		}
+	}
+	return nil
+}
+
+// compareVersions compares the leading dot separated numbers of the
+// versions a and b, so that "3.1-beta1" is treated as "3.1" and "3.1" as
+// "3.1.0". It returns -1, 0 or 1 as a is older than, the same as, or
+// newer than b.
+func compareVersions(a, b string) int {
+	as, bs := versionNumbers(a), versionNumbers(b)
+	for len(as) < len(bs) {
+		as = append(as, 0)
+	}
+	for len(bs) < len(as) {
+		bs = append(bs, 0)
+	}
+	for i := range as {
+		if as[i] < bs[i] {
+			return -1
+		} else if as[i] > bs[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers returns the leading dot separated numbers of version.
+func versionNumbers(version string) []int {
+	var numbers []int
+	for _, part := range strings.Split(strings.TrimPrefix(version, "v"), ".") {
+		end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+		if end == 0 {
+			break
+		}
+		if end < 0 {
+			end = len(part)
+		}
+		n, err := strconv.Atoi(part[:end])
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+		if end < len(part) {
+			break
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"errors"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type CapabilitySuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&CapabilitySuite{})
+
+type fakeCapabilities struct {
+	version  string
+	features map[string]bool
+	err      error
+	calls    int
+}
+
+func (f *fakeCapabilities) ServerVersion(*cmd.Context) (string, error) {
+	f.calls++
+	return f.version, f.err
+}
+
+func (f *fakeCapabilities) HasFeature(_ *cmd.Context, feature string) (bool, error) {
+	f.calls++
+	return f.features[feature], f.err
+}
+
+// requiringCommand is a command with server requirements.
+type requiringCommand struct {
+	cmd.CommandBase
+	minVersion string
+	features   []string
+	ran        bool
+}
+
+func (c *requiringCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:             "add-secret",
+		Purpose:          "Add a secret.",
+		MinServerVersion: c.minVersion,
+		RequiredFeatures: c.features,
+	}
+}
+
+func (c *requiringCommand) Run(*cmd.Context) error {
+	c.ran = true
+	return nil
+}
+
+func (s *CapabilitySuite) run(c *gc.C, source cmd.CapabilitySource, command cmd.Command, args ...string) cmdtesting.SuperCommandResult {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:          "juju",
+		Capabilities:  source,
+		ServerKnownAs: "controller",
+		NoRemoteFlag:  true,
+	})
+	super.Register(command)
+	return cmdtesting.RunSuperCommand(c, super, args...)
+}
+
+func (s *CapabilitySuite) TestVersionMet(c *gc.C) {
+	for _, version := range []string{"3.1", "3.1.0", "3.1.1", "3.10", "4.0-beta1", "v3.2"} {
+		command := &requiringCommand{minVersion: "3.1"}
+		result := s.run(c, &fakeCapabilities{version: version}, command, "add-secret")
+		c.Check(result.Err, gc.IsNil, gc.Commentf("%s", version))
+		c.Check(command.ran, gc.Equals, true)
+	}
+}
+
+func (s *CapabilitySuite) TestVersionNotMet(c *gc.C) {
+	for _, version := range []string{"2.9.42", "3.0", "3", "3.0.9-rc1"} {
+		command := &requiringCommand{minVersion: "3.1"}
+		result := s.run(c, &fakeCapabilities{version: version}, command, "add-secret")
+		c.Check(result.Err, gc.ErrorMatches, `this command requires controller >= 3.1, but the controller is `+version)
+		c.Check(cmd.IsRequirementError(result.Err), gc.Equals, true)
+		c.Check(result.Code, gc.Equals, 1)
+		c.Check(result.Stderr, gc.Equals, "ERROR this command requires controller >= 3.1, but the controller is "+version+"\n")
+		c.Check(command.ran, gc.Equals, false)
+	}
+}
+
+func (s *CapabilitySuite) TestFeatures(c *gc.C) {
+	source := &fakeCapabilities{version: "3.1", features: map[string]bool{"secrets": true}}
+	command := &requiringCommand{features: []string{"secrets"}}
+	result := s.run(c, source, command, "add-secret")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(command.ran, gc.Equals, true)
+
+	command = &requiringCommand{features: []string{"secrets", "secret-backends"}}
+	result = s.run(c, source, command, "add-secret")
+	c.Assert(result.Err, gc.ErrorMatches, `this command requires the "secret-backends" controller feature`)
+	c.Assert(command.ran, gc.Equals, false)
+}
+
+func (s *CapabilitySuite) TestSourceError(c *gc.C) {
+	source := &fakeCapabilities{err: errors.New("connection refused")}
+	command := &requiringCommand{minVersion: "3.1"}
+	result := s.run(c, source, command, "add-secret")
+	c.Assert(result.Err, gc.ErrorMatches, "getting controller version: connection refused")
+	c.Assert(cmd.IsRequirementError(result.Err), gc.Equals, false)
+	c.Assert(command.ran, gc.Equals, false)
+}
+
+func (s *CapabilitySuite) TestNoRequirements(c *gc.C) {
+	source := &fakeCapabilities{err: errors.New("connection refused")}
+	command := &requiringCommand{}
+	result := s.run(c, source, command, "add-secret")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(command.ran, gc.Equals, true)
+	c.Assert(source.calls, gc.Equals, 0)
+}
+
+func (s *CapabilitySuite) TestNoRemote(c *gc.C) {
+	source := &fakeCapabilities{version: "2.9"}
+	command := &requiringCommand{minVersion: "3.1"}
+	result := s.run(c, source, command, "--no-remote", "add-secret")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(command.ran, gc.Equals, true)
+	c.Assert(source.calls, gc.Equals, 0)
+}
+
+func (s *CapabilitySuite) TestNested(c *gc.C) {
+	inner := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "secrets"})
+	command := &requiringCommand{minVersion: "3.1"}
+	inner.Register(command)
+	result := s.run(c, &fakeCapabilities{version: "2.9"}, inner, "secrets", "add-secret")
+	c.Assert(result.Err, gc.ErrorMatches, `this command requires controller >= 3.1, but the controller is 2.9`)
+	c.Assert(command.ran, gc.Equals, false)
+}
+
+func (s *CapabilitySuite) TestDefaultServerName(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:         "juju",
+		Capabilities: &fakeCapabilities{version: "2.9"},
+	})
+	super.Register(&requiringCommand{minVersion: "3.1"})
+	result := cmdtesting.RunSuperCommand(c, super, "add-secret")
+	c.Assert(result.Err, gc.ErrorMatches, `this command requires server >= 3.1, but the server is 2.9`)
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io"
+	"sync"
+)
+
+// CaptureOutput keeps a copy of the last size bytes written to the
+// context's Stdout and Stderr, interleaved as they were written, so that
+// what the user saw can be included in support bundles and crash reports
+// when a command fails. The copy is returned by CapturedOutput, and is
+// logged at DEBUG level when a subcommand of a SuperCommand fails. Stdout
+// and Stderr are still reported as terminals when they are.
+func (ctx *Context) CaptureOutput(size int) {
+	if size <= 0 {
+		return
+	}
+	ring := &ringBuffer{size: size}
+	ctx.captured = ring
+	ctx.Stdout = newCaptureWriter(ctx.Stdout, ring)
+	ctx.Stderr = newCaptureWriter(ctx.Stderr, ring)
+}
+
+// CapturedOutput returns the output kept since CaptureOutput was called,
+// or nil if it was not.
+func (ctx *Context) CapturedOutput() []byte {
+	if ctx.captured == nil {
+		return nil
+	}
+	return ctx.captured.bytes()
+}
+
+// ringBuffer holds the last size bytes written to it.
+type ringBuffer struct {
+	mu   sync.Mutex
+	size int
+	data []byte
+}
+
+// Write implements io.Writer.
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data = append(r.data, p...)
+	if excess := len(r.data) - r.size; excess > 0 {
+		r.data = append(r.data[:0], r.data[excess:]...)
+	}
+	return len(p), nil
+}
+
+// bytes returns a copy of the content of the buffer.
+func (r *ringBuffer) bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.data...)
+}
+
+// captureWriter copies what is written to it to another writer, such as
+// a ring buffer.
+type captureWriter struct {
+	w    io.Writer
+	ring io.Writer
+}
+
+// Write implements io.Writer.
+func (w captureWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	_, _ = w.ring.Write(p[:n])
+	return n, err
+}
+
+// captureFile is a captureWriter for a file, which keeps its descriptor
+// so that terminals are still recognised.
+type captureFile struct {
+	captureWriter
+	fd uintptr
+}
+
+// Fd returns the file descriptor of the file written to.
+func (w captureFile) Fd() uintptr {
+	return w.fd
+}
+
+// ColorCapable reports whether the file is a terminal that displays
+// colours, as the file itself is not seen by ansiterm.
+func (w captureFile) ColorCapable() bool {
+	return terminalColorCapable(int(w.fd))
+}
+
+// captureTerminal is a captureWriter for a TerminalWriter.
+type captureTerminal struct {
+	TerminalWriter
+	capture captureWriter
+}
+
+// Write implements io.Writer.
+func (w captureTerminal) Write(p []byte) (int, error) {
+	return w.capture.Write(p)
+}
+
+// newCaptureWriter returns a writer that writes to w and ring.
+func newCaptureWriter(w io.Writer, ring io.Writer) io.Writer {
+	capture := captureWriter{w: w, ring: ring}
+	if t, ok := w.(TerminalWriter); ok {
+		return captureTerminal{TerminalWriter: t, capture: capture}
+	}
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		return captureFile{captureWriter: capture, fd: f.Fd()}
+	}
+	return capture
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type CaptureSuite struct {
+	testing.LoggingCleanupSuite
+}
+
+var _ = gc.Suite(&CaptureSuite{})
+
+func (s *CaptureSuite) TestCaptureOutput(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	stdout, stderr := ctx.Stdout.(*bytes.Buffer), ctx.Stderr.(*bytes.Buffer)
+	c.Assert(ctx.CapturedOutput(), gc.IsNil)
+
+	ctx.CaptureOutput(16)
+	fmt.Fprint(ctx.Stdout, "one\n")
+	fmt.Fprint(ctx.Stderr, "two\n")
+	c.Assert(string(ctx.CapturedOutput()), gc.Equals, "one\ntwo\n")
+
+	fmt.Fprint(ctx.Stdout, "three\nfour\n")
+	c.Assert(string(ctx.CapturedOutput()), gc.Equals, "\ntwo\nthree\nfour\n")
+	c.Assert(stdout.String(), gc.Equals, "one\nthree\nfour\n")
+	c.Assert(stderr.String(), gc.Equals, "two\n")
+
+	fmt.Fprint(ctx.Stderr, "a line longer than the buffer\n")
+	c.Assert(string(ctx.CapturedOutput()), gc.Equals, "than the buffer\n")
+}
+
+func (s *CaptureSuite) TestCaptureOutputTerminal(c *gc.C) {
+	ctx := cmdtesting.TerminalContext(c, 80, 24, true)
+	stdout := ctx.Stdout
+	ctx.CaptureOutput(1024)
+	c.Assert(ctx.IsTerminal(), gc.Equals, true)
+	size, err := ctx.WindowSize()
+	c.Assert(err, gc.IsNil)
+	c.Assert(size, gc.Equals, cmd.WindowSize{Width: 80, Height: 24})
+
+	fmt.Fprint(ctx.Stdout, "hello\n")
+	c.Assert(stdout.(fmt.Stringer).String(), gc.Equals, "hello\n")
+	c.Assert(string(ctx.CapturedOutput()), gc.Equals, "hello\n")
+}
+
+func (s *CaptureSuite) TestCapturedOutputLoggedOnError(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", Log: &cmd.Log{}})
+	sc.Register(&TestCommand{Name: "blah", CustomRun: func(ctx *cmd.Context) error {
+		fmt.Fprintln(ctx.Stdout, "deploying mysql")
+		return fmt.Errorf("cannot deploy")
+	}})
+	ctx := cmdtesting.Context(c)
+	stderr := ctx.Stderr.(*bytes.Buffer)
+	ctx.CaptureOutput(1024)
+	code := cmd.Main(sc, ctx, []string{"blah", "--debug"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(stderr.String(), gc.Matches, `(?s).*DEBUG cmd .* output before the error: \ndeploying mysql\n(.*\n)?ERROR cannot deploy\n.*`)
+}
@@ -10,13 +10,15 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
-	"github.com/juju/ansiterm"
+	"github.com/juju/clock"
 	"github.com/juju/gnuflag"
 	"github.com/juju/loggo/v2"
 	"github.com/juju/utils/v4"
@@ -31,12 +33,55 @@ func IsErrSilent(err error) bool {
 	if err == ErrSilent {
 		return true
 	}
+	if _, ok := err.(*silentError); ok {
+		return true
+	}
 	if utils.IsRcPassthroughError(err) {
 		return true
 	}
+	var withCode *codeError
+	if errors.As(err, &withCode) {
+		return IsErrSilent(withCode.err)
+	}
 	return false
 }
 
+// silentError is an error that Main exits with silently, while keeping
+// the original error for the embedding application.
+type silentError struct {
+	err error
+}
+
+// Error implements error.
+func (e *silentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *silentError) Unwrap() error {
+	return e.err
+}
+
+// SilenceError returns an error that Main treats like ErrSilent, exiting
+// with a non-zero code without printing anything, but from which the
+// original error can be retrieved with UnwrapSilent or ctx.LastError.
+// It returns nil if err is nil.
+func SilenceError(err error) error {
+	if err == nil || IsErrSilent(err) {
+		return err
+	}
+	return &silentError{err: err}
+}
+
+// UnwrapSilent returns the original error of an error created with
+// SilenceError, or err itself otherwise.
+func UnwrapSilent(err error) error {
+	if silent, ok := err.(*silentError); ok {
+		return silent.err
+	}
+	return err
+}
+
 // Command is implemented by types that interpret command-line arguments.
 type Command interface {
 	// IsSuperCommand returns true if the command is a super command.
@@ -60,6 +105,30 @@ type Command interface {
 	AllowInterspersedFlags() bool
 }
 
+// Validator may be implemented by a Command that needs to validate its
+// arguments against the Context (e.g. the environment, the current
+// directory or whether stdin is a terminal) before it is run. Validate is
+// called after Init and before Run. Unlike errors returned from Init,
+// validation errors are not treated as usage errors.
+type Validator interface {
+	// Validate checks that the Command can be run in the given Context.
+	Validate(ctx *Context) error
+}
+
+// validate calls Validate on c if it implements Validator.
+func validate(c Command, ctx *Context) error {
+	if !c.IsSuperCommand() {
+		// SuperCommands validate the subcommand they run.
+		if err := checkInteraction(ctx, c.Info()); err != nil {
+			return err
+		}
+	}
+	if v, ok := c.(Validator); ok {
+		return v.Validate(ctx)
+	}
+	return nil
+}
+
 // CommandBase provides the default implementation for SetFlags, Init, and Help.
 type CommandBase struct{}
 
@@ -87,15 +156,43 @@ func (c *CommandBase) AllowInterspersedFlags() bool {
 // output and errors to Stdout and Stderr respectively.
 type Context struct {
 	context.Context
-	Dir              string
-	Env              map[string]string
-	Stdin            io.Reader
-	Stdout           io.Writer
-	Stderr           io.Writer
+	Dir    string
+	Env    map[string]string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Clock is used by commands for anything time related. If it is nil,
+	// the wall clock is used.
+	Clock clock.Clock
+
+	// HTTPClient is supplied to commands registered with RegisterFunc for
+	// HTTP requests. If it is nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// FileSystem is supplied to commands registered with RegisterFunc for
+	// file access. If it is nil, files are accessed with the os package,
+	// with relative paths resolved against Dir.
+	FileSystem FileSystem
+
+	// Observer, if not nil, receives a structured Event for each warning,
+	// progress update and prompt emitted through the context.
+	Observer EventObserver
+
 	outputFormatUsed bool
+	warnings         *warningState
+	noRemote         bool
+	lastError        error
 	quiet            bool
 	verbose          bool
 	serialisable     bool
+	colorMode        ColorMode
+	accessible       bool
+	commandPath      []string
+	viaAlias         bool
+	captured         *ringBuffer
+	serviceNotify    func(state string) error
+	printfWriters    *printfWriters
 }
 
 // With returns a command context with the specified context.Context.
@@ -105,6 +202,129 @@ func (ctx *Context) With(c context.Context) *Context {
 	return &newCtx
 }
 
+// WithCancel returns a copy of ctx whose context.Context is cancelled
+// when the returned function is called, or when ctx is cancelled.
+func (ctx *Context) WithCancel() (*Context, func()) {
+	c, cancel := context.WithCancel(ctx.background())
+	return ctx.With(c), cancel
+}
+
+// Done returns a channel that is closed when the command is cancelled,
+// e.g. because Main received an interrupt. It returns nil, which is never
+// closed, if the context has no context.Context.
+func (ctx *Context) Done() <-chan struct{} {
+	if ctx.Context == nil {
+		return nil
+	}
+	return ctx.Context.Done()
+}
+
+// background returns the context's context.Context, defaulting to
+// context.Background().
+func (ctx *Context) background() context.Context {
+	if ctx.Context == nil {
+		return context.Background()
+	}
+	return ctx.Context
+}
+
+// cancelOnShutdownSignals replaces the context's context.Context with one
+// that is cancelled when one of the given signals is received, or one of
+// ShutdownSignals if none are given. Only the first signal is caught, so
+// that another one terminates a command that does not stop. The returned
+// function stops listening for the signals and restores the original
+// context.Context.
+func (ctx *Context) cancelOnShutdownSignals(shutdownSignals ...os.Signal) func() {
+	if len(shutdownSignals) == 0 {
+		shutdownSignals = ShutdownSignals
+	}
+	original := ctx.Context
+	c, cancel := context.WithCancel(ctx.background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			cancel()
+		case <-done:
+		}
+	}()
+	ctx.Context = c
+	return func() {
+		close(done)
+		signal.Stop(signals)
+		cancel()
+		ctx.Context = original
+	}
+}
+
+// LastError returns the error that caused the command to fail, after
+// Main returns. It is the original error even when nothing was printed
+// because the command returned an error from SilenceError, or because the
+// error was already reported by a SuperCommand.
+func (ctx *Context) LastError() error {
+	return ctx.lastError
+}
+
+// recordError records err as the reason the command failed. An error that
+// exits silently does not replace one already recorded, as it is typically
+// what remains after the original error has been reported.
+func (ctx *Context) recordError(err error) {
+	if IsErrSilent(err) && ctx.lastError != nil {
+		return
+	}
+	ctx.lastError = UnwrapSilent(err)
+}
+
+// clock returns the context's clock, defaulting to the wall clock.
+func (ctx *Context) clock() clock.Clock {
+	if ctx.Clock == nil {
+		return clock.WallClock
+	}
+	return ctx.Clock
+}
+
+// httpClient returns the HTTP client to be used for requests made on
+// behalf of the command. If remote access has been disabled, the client
+// refuses all requests.
+func (ctx *Context) httpClient() *http.Client {
+	if ctx.noRemote {
+		return &http.Client{Transport: noRemoteTransport{}}
+	}
+	if ctx.HTTPClient != nil {
+		return ctx.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// fileSystem returns the FileSystem to be used by the command.
+func (ctx *Context) fileSystem() FileSystem {
+	if ctx.FileSystem != nil {
+		return ctx.FileSystem
+	}
+	return osFileSystem{ctx: ctx}
+}
+
+// NoRemote reports whether the --no-remote flag was given, in which case
+// the framework performs no network activity on the command's behalf.
+func (ctx *Context) NoRemote() bool {
+	return ctx.noRemote
+}
+
+// ErrNoRemote is returned when network access is attempted through the
+// framework while the --no-remote flag is in effect.
+var ErrNoRemote = errors.New("network access disabled by --no-remote")
+
+// noRemoteTransport is an http.RoundTripper that refuses all requests.
+type noRemoteTransport struct{}
+
+// RoundTrip implements http.RoundTripper.
+func (noRemoteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, ErrNoRemote
+}
+
 // Quiet reports whether the command is in "quiet" mode. When
 // this is true, informational output should be suppressed (logger
 // messages can be used instead).
@@ -146,12 +366,20 @@ func (ctx *Context) Infof(format string, params ...interface{}) {
 // command's context. This is useful for logging errors which do not cause a
 // command to fail (e.g. an error message used as a deprecation warning that
 // will be upgraded to a real error message at some point in the future.)
+// A warning emitted more than once while a command runs is only written the
+// first time, and once more with the number of repeats when it finishes.
 func (ctx *Context) Warningf(format string, params ...interface{}) {
+	message := fmt.Sprintf(format, params...)
+	if ctx.repeatWarning(message) {
+		ctx.emit(Event{Kind: EventWarning, Message: message})
+		return
+	}
 	// Here we use the Loggo.logger method `Logf` as opposed to
 	// `logger.Warningf` to avoid introducing an additional call stack level
 	// (since `Warningf` calls Logf internally). This is done so that this
 	// function can produce more accurate source location debug information.
 	logger.Logf(loggo.WARNING, format, params...)
+	ctx.emit(Event{Kind: EventWarning, Message: message})
 }
 
 // Verbosef will write the formatted string to Stderr if the verbose is true,
@@ -183,14 +411,150 @@ func (ctx *Context) Errorf(format string, params ...interface{}) {
 	logger.Logf(loggo.ERROR, format, params...)
 }
 
+// InfoWriter returns a writer that passes each line written to it to
+// Infof, for libraries that only accept an io.Writer. A final line without
+// a newline is passed on when the writer is closed, or when the command
+// finishes running under Main.
+func (ctx *Context) InfoWriter() io.WriteCloser {
+	return ctx.newPrintfWriter(ctx.Infof)
+}
+
+// VerboseWriter returns a writer that passes each line written to it to
+// Verbosef, as for InfoWriter.
+func (ctx *Context) VerboseWriter() io.WriteCloser {
+	return ctx.newPrintfWriter(ctx.Verbosef)
+}
+
+// ErrorWriter returns a writer that passes each line written to it to
+// Errorf, as for InfoWriter.
+func (ctx *Context) ErrorWriter() io.WriteCloser {
+	return ctx.newPrintfWriter(ctx.Errorf)
+}
+
+// printfWriters holds the writers returned by InfoWriter, VerboseWriter
+// and ErrorWriter, so that their partial lines can be flushed once the
+// command finishes. It is shared by the copies of the context made once
+// it exists.
+type printfWriters struct {
+	mu      sync.Mutex
+	writers []*printfWriter
+}
+
+// printfWritersMu guards the creation of the printfWriters of each
+// context.
+var printfWritersMu sync.Mutex
+
+// newPrintfWriter returns a printfWriter for printf that is flushed by
+// flushPrintfWriters.
+func (ctx *Context) newPrintfWriter(printf func(format string, params ...interface{})) *printfWriter {
+	printfWritersMu.Lock()
+	if ctx.printfWriters == nil {
+		ctx.printfWriters = &printfWriters{}
+	}
+	state := ctx.printfWriters
+	printfWritersMu.Unlock()
+
+	w := &printfWriter{printf: printf}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.writers = append(state.writers, w)
+	return w
+}
+
+// flushPrintfWriters passes on the partial lines left in the writers
+// returned by InfoWriter, VerboseWriter and ErrorWriter, and forgets the
+// writers.
+func (ctx *Context) flushPrintfWriters() {
+	printfWritersMu.Lock()
+	state := ctx.printfWriters
+	printfWritersMu.Unlock()
+	if state == nil {
+		return
+	}
+	state.mu.Lock()
+	writers := state.writers
+	state.writers = nil
+	state.mu.Unlock()
+	for _, w := range writers {
+		_ = w.Close()
+	}
+}
+
+// printfWriter is an io.Writer that calls a printf style function with
+// each line written to it. Writes are buffered until a line is complete,
+// so that libraries writing a line in several pieces produce one message.
+type printfWriter struct {
+	mu     sync.Mutex
+	printf func(format string, params ...interface{})
+	buf    []byte
+}
+
+// Write implements io.Writer.
+func (w *printfWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	for _, line := range strings.Split(string(w.buf[:i]), "\n") {
+		w.printf("%s", line)
+	}
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	return len(p), nil
+}
+
+// Close implements io.Closer, passing on any partial line that remains.
+func (w *printfWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.printf("%s", w.buf)
+		w.buf = w.buf[:0]
+	}
+	return nil
+}
+
 // WriteError will output the formatted text to the writer with
-// a colored ERROR like the logging would.
+// a colored ERROR like the logging would. Any registered secrets
+// are redacted from the message (see Redact).
 //
-// DEPRECATED: Use ctx.Errorf instead
+// DEPRECATED: Use Print with SeverityError instead
 func WriteError(writer io.Writer, err error) {
-	w := ansiterm.NewWriter(writer)
-	ansiterm.Foreground(ansiterm.BrightRed).Fprintf(w, "ERROR")
-	fmt.Fprintf(w, " %s\n", err.Error())
+	Print(writer, SeverityError, err)
+}
+
+// ErrorRenderer writes an error that stopped a command to the user. It
+// allows applications to use their own style in place of WriteError.
+// Renderers should pass messages through Redact.
+type ErrorRenderer interface {
+	RenderError(writer io.Writer, err error)
+}
+
+// ErrorRendererFunc is a function that implements ErrorRenderer.
+type ErrorRendererFunc func(writer io.Writer, err error)
+
+// RenderError implements ErrorRenderer.
+func (f ErrorRendererFunc) RenderError(writer io.Writer, err error) {
+	f(writer, err)
+}
+
+// PrefixErrorRenderer returns an ErrorRenderer that writes the message of
+// the error after prefix, e.g. "error: ", without colour.
+func PrefixErrorRenderer(prefix string) ErrorRenderer {
+	return ErrorRendererFunc(func(writer io.Writer, err error) {
+		fmt.Fprintf(writer, "%s%s\n", prefix, Redact(err.Error()))
+	})
+}
+
+// errorRenderer returns the ErrorRenderer used for errors from c, which is
+// nil unless c is a SuperCommand with its own renderer.
+func errorRenderer(c Command) ErrorRenderer {
+	if r, ok := c.(interface{ errorRenderer() ErrorRenderer }); ok {
+		return r.errorRenderer()
+	}
+	return nil
 }
 
 // Getenv looks up an environment variable in the context. It mirrors
@@ -211,8 +575,9 @@ func (ctx *Context) Setenv(key, value string) error {
 
 // AbsPath returns an absolute representation of path, with relative paths
 // interpreted as relative to ctx.Dir and with "~/" replaced with users
-// home dir.
+// home dir (see HomeDir).
 func (ctx *Context) AbsPath(path string) string {
+	path = expandHome(path, ctx.lookupEnv)
 	if normalizedPath, err := utils.NormalizePath(path); err == nil {
 		path = normalizedPath
 	}
@@ -280,9 +645,48 @@ type Info struct {
 	// will become 'value for option'.
 	FlagKnownAs string
 
+	// Category groups the command with related commands, e.g. "storage",
+	// in the output of the built-in "commands" subcommand.
+	Category string
+
 	// ShowSuperFlags contains the names of the 'super' command flags
 	// that are desired to be shown in the sub-command help output.
 	ShowSuperFlags []string
+
+	// MinServerVersion is the oldest version of the server the command
+	// works with, e.g. "3.1". RequiredFeatures names the server features
+	// the command needs. They are checked before the command is run when
+	// the SuperCommand has a CapabilitySource.
+	MinServerVersion string
+	RequiredFeatures []string
+
+	// RequiresTTY is set by commands that must interact with the user
+	// through a terminal. They fail early, rather than waiting for input
+	// that cannot come, when Stdin is not a terminal, and are warned about
+	// when run in CI.
+	RequiresTTY bool
+
+	// NonInteractiveSafe is set by commands that never prompt for input,
+	// such as the built-in commands. They are never checked for a
+	// terminal.
+	NonInteractiveSafe bool
+
+	// Hidden commands can be run, but are left out of help, completion
+	// and generated documentation.
+	Hidden bool
+
+	// Experimental commands may change or be removed without notice. A
+	// warning saying so is shown when they are run.
+	Experimental bool
+
+	// LearnMoreURL is the address of further documentation for the
+	// command, shown at the end of its help.
+	LearnMoreURL string
+
+	// nestedSubcommands holds the name and description of the
+	// subcommands of each subcommand that is itself a super command, for
+	// help that lists them below their parent.
+	nestedSubcommands map[string]map[string]string
 }
 
 // Help renders i's content, along with documentation for any
@@ -295,6 +699,19 @@ func (i *Info) Help(f *gnuflag.FlagSet) []byte {
 // flags defined in both command and its super command flag sets.
 // Only super command flags defined in i.ShowSuperFlags are displayed, if found.
 func (i *Info) HelpWithSuperFlags(superF *gnuflag.FlagSet, f *gnuflag.FlagSet) []byte {
+	return i.helpWithSuperFlags(superF, f, nil)
+}
+
+// helpWithSuperFlags renders help like HelpWithSuperFlags, writing URLs
+// with link if it is not nil.
+func (i *Info) helpWithSuperFlags(superF *gnuflag.FlagSet, f *gnuflag.FlagSet, link func(url string) string) []byte {
+	if link == nil {
+		link = func(url string) string { return url }
+	}
+	describeChoices(f)
+	if superF != nil {
+		describeChoices(superF)
+	}
 	buf := &bytes.Buffer{}
 	fmt.Fprintf(buf, "Usage: %s", i.Name)
 	hasOptions := false
@@ -360,9 +777,15 @@ func (i *Info) HelpWithSuperFlags(superF *gnuflag.FlagSet, f *gnuflag.FlagSet) [
 	if len(i.SeeAlso) > 0 {
 		fmt.Fprintf(buf, "\nSee also:\n")
 		for _, entry := range i.SeeAlso {
+			if isURL(entry) {
+				entry = link(entry)
+			}
 			fmt.Fprintf(buf, " - %s\n", entry)
 		}
 	}
+	if i.LearnMoreURL != "" {
+		fmt.Fprintf(buf, "\nLearn more: %s\n", link(i.LearnMoreURL))
+	}
 
 	return buf.Bytes()
 }
@@ -376,11 +799,34 @@ func isDefaultCommand(cmd string) bool {
 	return false
 }
 
+// isHiddenCommand reports whether the named command is used by the
+// framework itself, such as by completion scripts, and should not be shown
+// to users at all.
+func isHiddenCommand(cmd string) bool {
+	return strings.HasPrefix(cmd, "__")
+}
+
 func (i *Info) describeCommands() string {
-	// Sort command names, and work out length of the longest one
-	cmdNames := make([]string, 0, len(i.Subcommands))
+	descr := "Subcommands:\n"
+	cmdNames, longest := sortedCommandNames(i.Subcommands)
+	for _, name := range cmdNames {
+		purpose := i.Subcommands[name]
+		descr += fmt.Sprintf("    %-*s - %s\n", longest, name, purpose)
+		nested := i.nestedSubcommands[name]
+		nestedNames, nestedLongest := sortedCommandNames(nested)
+		for _, nestedName := range nestedNames {
+			descr += fmt.Sprintf("        %-*s - %s\n", nestedLongest, nestedName, nested[nestedName])
+		}
+	}
+	return descr
+}
+
+// sortedCommandNames returns the sorted names of the given commands,
+// leaving out the default commands, and the length of the longest one.
+func sortedCommandNames(commands map[string]string) ([]string, int) {
+	cmdNames := make([]string, 0, len(commands))
 	longest := 0
-	for name := range i.Subcommands {
+	for name := range commands {
 		if isDefaultCommand(name) {
 			continue
 		}
@@ -390,13 +836,7 @@ func (i *Info) describeCommands() string {
 		cmdNames = append(cmdNames, name)
 	}
 	sort.Strings(cmdNames)
-
-	descr := "Subcommands:\n"
-	for _, name := range cmdNames {
-		purpose := i.Subcommands[name]
-		descr += fmt.Sprintf("    %-*s - %s\n", longest, name, purpose)
-	}
-	return descr
+	return cmdNames, longest
 }
 
 // Errors from commands can be ErrSilent (don't print an error message),
@@ -411,13 +851,35 @@ func handleCommandError(c Command, ctx *Context, err error, f *gnuflag.FlagSet)
 		ctx.Stdout.Write(c.Info().Help(f))
 		return 0, true
 	case ErrSilent:
-		return 2, true
+		return usageExitCode(c, err), true
 	default:
-		WriteError(ctx.Stderr, err)
-		return 2, true
+		ctx.recordError(err)
+		if IsErrSilent(err) {
+			return usageExitCode(c, err), true
+		}
+		writeError(ctx, err, errorRenderer(c))
+		if argErr, ok := asArgError(err); ok {
+			argErr.writePointer(ctx.Stderr)
+		}
+		return usageExitCode(c, err), true
 	}
 }
 
+// usageExitCode returns the exit code for err, which stopped c before it
+// ran. Unless err has a code of its own, it is the code that c maps usage
+// errors to, or 2.
+func usageExitCode(c Command, err error) int {
+	var codes map[string]int
+	if sc, ok := c.(*SuperCommand); ok {
+		codes = sc.exitCodes
+	}
+	code := 2
+	if usage, ok := codes[UsageErrorCategory]; ok {
+		code = usage
+	}
+	return errorExitCode(err, nil, PartialSuccessExitCode, code)
+}
+
 func FlagAlias(c Command, akaDefault string) string {
 	flagsAKA := c.Info().FlagKnownAs
 	if flagsAKA == "" {
@@ -428,12 +890,44 @@ func FlagAlias(c Command, akaDefault string) string {
 
 // Main runs the given Command in the supplied Context with the given
 // arguments, which should not include the command name. It returns a code
-// suitable for passing to os.Exit.
+// suitable for passing to os.Exit. When c is a SuperCommand with
+// CancelOnShutdownSignals, ctx is cancelled while the command runs if the
+// process receives SIGINT or SIGTERM. When c is a SuperCommand with a
+// ChainSeparator, each of the chained subcommands is run in turn, until
+// one fails.
 func Main(c Command, ctx *Context, args []string) int {
+	if chain := chainedArgs(c, args); chain != nil {
+		for _, args := range chain {
+			if rc := Main(c, ctx, args); rc != 0 {
+				return rc
+			}
+		}
+		return 0
+	}
+	timer := newPhaseTimer(ctx)
+	defer timer.report(c)
+	defer restoreTerminals()
+	defer setUpTerminals(ctx)()
+	if sc, ok := c.(*SuperCommand); ok && sc.cancelOnSignals {
+		defer ctx.cancelOnShutdownSignals()()
+	}
+
 	f := gnuflag.NewFlagSetWithFlagKnownAs(c.Info().Name, gnuflag.ContinueOnError, FlagAlias(c, "flag"))
 	f.SetOutput(ioutil.Discard)
+	if sc, ok := c.(*SuperCommand); ok {
+		sc.loadDynamicCommands(ctx)
+		// The Info of a SuperCommand names its selected subcommand.
+		ctx.startInvocation(sc.Name)
+	} else {
+		ctx.startInvocation(c.Info().Name)
+	}
 	c.SetFlags(f)
-	if rc, done := handleCommandError(c, ctx, f.Parse(c.AllowInterspersedFlags(), args), f); done {
+	err := f.Parse(c.AllowInterspersedFlags(), args)
+	if err == nil && !c.IsSuperCommand() {
+		// SuperCommands resolve defaults once the subcommand's flags are known.
+		err = ResolveDefaults(f)
+	}
+	if rc, done := handleCommandError(c, ctx, err, f); done {
 		return rc
 	}
 	// Since SuperCommands can also return gnuflag.ErrHelp errors, we need to
@@ -441,14 +935,20 @@ func Main(c Command, ctx *Context, args []string) int {
 	if rc, done := handleCommandError(c, ctx, c.Init(f.Args()), f); done {
 		return rc
 	}
-	if err := c.Run(ctx); err != nil {
-		if utils.IsRcPassthroughError(err) {
-			return err.(*utils.RcPassthroughError).Code
-		}
-		if err != ErrSilent {
-			WriteError(ctx.Stderr, err)
+	timer.done("init")
+	err = validate(c, ctx)
+	if err == nil {
+		err = c.Run(ctx)
+	}
+	ctx.flushPrintfWriters()
+	ctx.reportRepeatedWarnings()
+	timer.done("run")
+	if err != nil {
+		ctx.recordError(err)
+		if !IsErrSilent(err) {
+			writeError(ctx, err, errorRenderer(c))
 		}
-		return 1
+		return ExitCode(err)
 	}
 	return 0
 }
@@ -468,6 +968,7 @@ func DefaultContext() (*Context, error) {
 		Stdin:  os.Stdin,
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
+		Clock:  clock.WallClock,
 	}
 	ctx.Context = context.Background()
 	return ctx, nil
@@ -475,8 +976,14 @@ func DefaultContext() (*Context, error) {
 
 // CheckEmpty is a utility function that returns an error if args is not empty.
 func CheckEmpty(args []string) error {
+	return checkEmptyFrom(args, 0)
+}
+
+// checkEmptyFrom works like CheckEmpty, for args that follow the given
+// number of accepted positional arguments.
+func checkEmptyFrom(args []string, position int) error {
 	if len(args) != 0 {
-		return fmt.Errorf("unrecognized args: %q", args)
+		return NewArgError(args[0], position, fmt.Sprintf("unrecognized args: %q", args))
 	}
 	return nil
 }
@@ -488,7 +995,7 @@ func ZeroOrOneArgs(args []string) (string, error) {
 	if len(args) > 0 {
 		result, args = args[0], args[1:]
 	}
-	if err := CheckEmpty(args); err != nil {
+	if err := checkEmptyFrom(args, 1); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -9,7 +9,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/juju/loggo/v2"
 	jc "github.com/juju/testing/checkers"
@@ -55,6 +58,63 @@ func (s *CmdSuite) TestWith(c *gc.C) {
 	c.Assert(ctx.Context, jc.DeepEquals, cancelCtx)
 }
 
+func (s *CmdSuite) TestWithCancel(c *gc.C) {
+	ctx, cancel := s.ctx.WithCancel()
+	c.Assert(ctx.Err(), gc.IsNil)
+	cancel()
+	<-ctx.Done()
+	c.Assert(ctx.Err(), gc.Equals, context.Canceled)
+	c.Assert(s.ctx.Err(), gc.IsNil)
+}
+
+func (s *CmdSuite) TestDoneWithoutContext(c *gc.C) {
+	ctx := &cmd.Context{}
+	c.Assert(ctx.Done(), gc.IsNil)
+	ctx, cancel := ctx.WithCancel()
+	cancel()
+	<-ctx.Done()
+}
+
+func (s *CmdSuite) TestMainCancelledBySignal(c *gc.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("sending signals is not supported on windows")
+	}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:                    "jujutest",
+		CancelOnShutdownSignals: true,
+	})
+	sc.Register(&signalledCommand{})
+	code := cmd.Main(sc, s.ctx, []string{"signalled"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(bufferString(s.ctx.Stderr), gc.Equals, "ERROR context canceled\n")
+	c.Assert(s.ctx.Context, jc.DeepEquals, context.Background())
+}
+
+// signalledCommand interrupts its own process and waits to be cancelled.
+type signalledCommand struct {
+	cmd.CommandBase
+}
+
+func (c *signalledCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "signalled"}
+}
+
+func (c *signalledCommand) Run(ctx *cmd.Context) error {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(testing.LongWait):
+		return fmt.Errorf("context not cancelled")
+	}
+}
+
 func (s *CmdSuite) TestContextGetenv(c *gc.C) {
 	s.ctx.Env = make(map[string]string)
 	before := s.ctx.Getenv("foo")
@@ -144,6 +204,45 @@ func (s *CmdSuite) TestMainSuccess(c *gc.C) {
 	c.Assert(bufferString(s.ctx.Stderr), gc.Equals, "")
 }
 
+type validatingCommand struct {
+	TestCommand
+	validateErr error
+}
+
+func (c *validatingCommand) Validate(ctx *cmd.Context) error {
+	return c.validateErr
+}
+
+func (s *CmdSuite) TestMainValidateError(c *gc.C) {
+	command := &validatingCommand{
+		TestCommand: TestCommand{Name: "verb"},
+		validateErr: fmt.Errorf("not in a terminal"),
+	}
+	result := cmd.Main(command, s.ctx, []string{"--option", "success!"})
+	c.Assert(result, gc.Equals, 1)
+	c.Assert(bufferString(s.ctx.Stdout), gc.Equals, "")
+	c.Assert(bufferString(s.ctx.Stderr), gc.Equals, "ERROR not in a terminal\n")
+}
+
+func (s *CmdSuite) TestMainValidateSuccess(c *gc.C) {
+	command := &validatingCommand{TestCommand: TestCommand{Name: "verb"}}
+	result := cmd.Main(command, s.ctx, []string{"--option", "success!"})
+	c.Assert(result, gc.Equals, 0)
+	c.Assert(bufferString(s.ctx.Stdout), gc.Equals, "success!\n")
+}
+
+func (s *CmdSuite) TestSuperCommandValidate(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.Register(&validatingCommand{
+		TestCommand: TestCommand{Name: "verb"},
+		validateErr: fmt.Errorf("not in a terminal"),
+	})
+	result := cmd.Main(sc, s.ctx, []string{"verb", "--option", "success!"})
+	c.Assert(result, gc.Equals, 1)
+	c.Assert(bufferString(s.ctx.Stdout), gc.Equals, "")
+	c.Assert(bufferString(s.ctx.Stderr), gc.Equals, "ERROR not in a terminal\n")
+}
+
 func (s *CmdSuite) TestStdin(c *gc.C) {
 	const phrase = "Do you, Juju?"
 	s.ctx.Stdin = bytes.NewBuffer([]byte(phrase))
@@ -217,6 +316,9 @@ func (s *CmdSuite) TestIsErrSilent(c *gc.C) {
 	c.Assert(cmd.IsErrSilent(cmd.ErrSilent), gc.Equals, true)
 	c.Assert(cmd.IsErrSilent(utils.NewRcPassthroughError(99)), gc.Equals, true)
 	c.Assert(cmd.IsErrSilent(fmt.Errorf("noisy")), gc.Equals, false)
+	c.Assert(cmd.IsErrSilent(cmd.ErrWithCode(cmd.ErrSilent, 7)), gc.Equals, true)
+	c.Assert(cmd.IsErrSilent(fmt.Errorf("hook: %w", cmd.ErrWithCode(cmd.ErrSilent, 7))), gc.Equals, true)
+	c.Assert(cmd.IsErrSilent(fmt.Errorf("hook: %w", cmd.ErrWithCode(fmt.Errorf("noisy"), 7))), gc.Equals, false)
 }
 
 func (s *CmdSuite) TestInfoHelp(c *gc.C) {
@@ -6,7 +6,9 @@ package cmdtesting
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
+	"sync"
 
 	"github.com/juju/gnuflag"
 	gc "gopkg.in/check.v1"
@@ -31,17 +33,22 @@ func InitCommand(c cmd.Command, args []string) error {
 	if err := f.Parse(c.AllowInterspersedFlags(), args); err != nil {
 		return err
 	}
+	if err := cmd.ResolveDefaults(f); err != nil {
+		return err
+	}
 	return c.Init(f.Args())
 }
 
 // Context creates a simple command execution context with the current
-// dir set to a newly created directory within the test directory.
+// dir set to a newly created directory within the test directory. The
+// events emitted through the context are recorded; see Events.
 func Context(c *gc.C) *cmd.Context {
 	ctx := &cmd.Context{
-		Dir:    c.MkDir(),
-		Stdin:  &bytes.Buffer{},
-		Stdout: &bytes.Buffer{},
-		Stderr: &bytes.Buffer{},
+		Dir:      c.MkDir(),
+		Stdin:    &bytes.Buffer{},
+		Stdout:   &bytes.Buffer{},
+		Stderr:   &bytes.Buffer{},
+		Observer: &eventLog{},
 	}
 	ctx.Context = context.Background()
 	return ctx
@@ -51,10 +58,11 @@ func Context(c *gc.C) *cmd.Context {
 // dir set to the specified directory.
 func ContextForDir(c *gc.C, dir string) *cmd.Context {
 	ctx := &cmd.Context{
-		Dir:    dir,
-		Stdin:  &bytes.Buffer{},
-		Stdout: &bytes.Buffer{},
-		Stderr: &bytes.Buffer{},
+		Dir:      dir,
+		Stdin:    &bytes.Buffer{},
+		Stdout:   &bytes.Buffer{},
+		Stderr:   &bytes.Buffer{},
+		Observer: &eventLog{},
 	}
 	ctx.Context = context.Background()
 	return ctx
@@ -63,13 +71,53 @@ func ContextForDir(c *gc.C, dir string) *cmd.Context {
 // Stdout takes a command Context that we assume has been created in this
 // package, and gets the content of the Stdout buffer as a string.
 func Stdout(ctx *cmd.Context) string {
-	return ctx.Stdout.(*bytes.Buffer).String()
+	return ctx.Stdout.(fmt.Stringer).String()
 }
 
 // Stderr takes a command Context that we assume has been created in this
 // package, and gets the content of the Stderr buffer as a string.
 func Stderr(ctx *cmd.Context) string {
-	return ctx.Stderr.(*bytes.Buffer).String()
+	return ctx.Stderr.(fmt.Stringer).String()
+}
+
+// Events takes a command Context that we assume has been created in this
+// package, and returns the events of the given kinds, or of every kind if
+// none are given, that were emitted through it.
+func Events(ctx *cmd.Context, kinds ...cmd.EventKind) []cmd.Event {
+	return ctx.Observer.(*eventLog).filter(kinds)
+}
+
+// eventLog is a cmd.EventObserver that records events.
+type eventLog struct {
+	mu     sync.Mutex
+	events []cmd.Event
+}
+
+// ObserveEvent implements cmd.EventObserver.
+func (l *eventLog) ObserveEvent(event cmd.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+// filter returns the recorded events of the given kinds.
+func (l *eventLog) filter(kinds []cmd.EventKind) []cmd.Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var events []cmd.Event
+	for _, event := range l.events {
+		if len(kinds) == 0 {
+			events = append(events, event)
+			continue
+		}
+		for _, kind := range kinds {
+			if event.Kind == kind {
+				events = append(events, event)
+				break
+			}
+		}
+	}
+	return events
 }
 
 // RunCommand runs a command with the specified args.  The returned error
@@ -92,9 +140,20 @@ func runCommand(ctx *cmd.Context, com cmd.Command, args []string) (*cmd.Context,
 		cmd.WriteError(ctx.Stderr, err)
 		return ctx, err
 	}
+	if err := validateCommand(ctx, com); err != nil {
+		return ctx, err
+	}
 	return ctx, com.Run(ctx)
 }
 
+// validateCommand calls the command's Validate method, if it has one.
+func validateCommand(ctx *cmd.Context, com cmd.Command) error {
+	if v, ok := com.(cmd.Validator); ok {
+		return v.Validate(ctx)
+	}
+	return nil
+}
+
 // RunCommandWithContext runs the command asynchronously with
 // the specified context and returns a channel which providers
 // the command's errors.
@@ -108,6 +167,10 @@ func RunCommandWithContext(ctx *cmd.Context, com cmd.Command, args ...string) ch
 			errc <- err
 			return
 		}
+		if err := validateCommand(ctx, com); err != nil {
+			errc <- err
+			return
+		}
 		errc <- com.Run(ctx)
 	}()
 	return errc
@@ -134,3 +197,67 @@ func HelpText(command cmd.Command, name string) string {
 	buff.Write(info.Help(f))
 	return buff.String()
 }
+
+// AssertPartialSuccess checks that err is a *cmd.BulkError for an operation
+// that succeeded on some targets and failed on exactly failedTargets.
+func AssertPartialSuccess(c *gc.C, err error, failedTargets ...string) {
+	bulk := assertBulkError(c, err, failedTargets)
+	c.Assert(bulk.Partial(), gc.Equals, true, gc.Commentf("expected some targets to succeed"))
+}
+
+// AssertTotalFailure checks that err is a *cmd.BulkError for an operation
+// that failed on every target, and that the targets are failedTargets.
+func AssertTotalFailure(c *gc.C, err error, failedTargets ...string) {
+	bulk := assertBulkError(c, err, failedTargets)
+	c.Assert(bulk.Partial(), gc.Equals, false, gc.Commentf("expected no targets to succeed"))
+}
+
+func assertBulkError(c *gc.C, err error, failedTargets []string) *cmd.BulkError {
+	bulk, ok := err.(*cmd.BulkError)
+	c.Assert(ok, gc.Equals, true, gc.Commentf("expected *cmd.BulkError, got %#v", err))
+	targets := make([]string, len(bulk.Failures()))
+	for i, failure := range bulk.Failures() {
+		targets[i] = failure.Target
+	}
+	c.Assert(targets, gc.DeepEquals, failedTargets)
+	return bulk
+}
+
+// SuperCommandResult holds the outcome of running a SuperCommand with
+// RunSuperCommand.
+type SuperCommandResult struct {
+	// Context is the context the command was run with.
+	Context *cmd.Context
+
+	// Code is the exit code returned by cmd.Main.
+	Code int
+
+	// Err is the error that caused the command to fail, or nil if it
+	// succeeded. See cmd.Context.LastError.
+	Err error
+
+	// Stdout and Stderr hold the output written by the command.
+	Stdout string
+	Stderr string
+}
+
+// RunSuperCommand runs super with the specified args in the same way as
+// cmd.Main, so that the subcommand named by args is found however deeply
+// it is nested, its flags and those of every command above it are parsed,
+// and it is initialised, validated and run.
+func RunSuperCommand(c *gc.C, super *cmd.SuperCommand, args ...string) SuperCommandResult {
+	return RunSuperCommandWithContext(Context(c), super, args...)
+}
+
+// RunSuperCommandWithContext works like RunSuperCommand, but runs with
+// ctx, which must have been created in this package.
+func RunSuperCommandWithContext(ctx *cmd.Context, super *cmd.SuperCommand, args ...string) SuperCommandResult {
+	code := cmd.Main(super, ctx, args)
+	return SuperCommandResult{
+		Context: ctx,
+		Code:    code,
+		Err:     ctx.LastError(),
+		Stdout:  Stdout(ctx),
+		Stderr:  Stderr(ctx),
+	}
+}
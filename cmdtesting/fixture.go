@@ -0,0 +1,93 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmdtesting
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+// FixtureTime is the time at which the clock of a Fixture starts.
+var FixtureTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Fixture isolates commands under test from the state of the host: their
+// contexts use a fake clock, and an environment holding only the seeded
+// variables and a home directory, XDG base directories and data directory
+// that are created afresh for the test.
+type Fixture struct {
+	// Clock is the clock used by the fixture's contexts. It starts at
+	// FixtureTime.
+	Clock *testclock.Clock
+
+	// Env holds the environment given to the fixture's contexts. Changes
+	// made to it affect contexts created afterwards.
+	Env map[string]string
+
+	// HomeDir is the home directory, set as HOME in Env.
+	HomeDir string
+
+	// DataDir is a directory for the data of the command under test,
+	// suitable for SuperCommandParams.DataDir. It is also set as
+	// XDG_DATA_HOME in Env.
+	DataDir string
+}
+
+// NewFixture returns a Fixture whose environment holds env, together with
+// HOME and the XDG_CONFIG_HOME, XDG_DATA_HOME, XDG_CACHE_HOME,
+// XDG_STATE_HOME and XDG_RUNTIME_DIR directories, unless they are set in
+// env.
+func NewFixture(c *gc.C, env map[string]string) *Fixture {
+	home := c.MkDir()
+	f := &Fixture{
+		Clock:   testclock.NewClock(FixtureTime),
+		Env:     make(map[string]string),
+		HomeDir: home,
+		DataDir: filepath.Join(home, ".local", "share"),
+	}
+	dirs := map[string]string{
+		"HOME":            home,
+		"XDG_CONFIG_HOME": filepath.Join(home, ".config"),
+		"XDG_DATA_HOME":   f.DataDir,
+		"XDG_CACHE_HOME":  filepath.Join(home, ".cache"),
+		"XDG_STATE_HOME":  filepath.Join(home, ".local", "state"),
+		"XDG_RUNTIME_DIR": c.MkDir(),
+	}
+	for key, dir := range dirs {
+		c.Assert(os.MkdirAll(dir, 0700), gc.IsNil)
+		f.Env[key] = dir
+	}
+	for key, value := range env {
+		f.Env[key] = value
+	}
+	return f
+}
+
+// Context returns a command execution context that uses the fixture's
+// clock and a copy of its environment, with the current dir set to a newly
+// created directory within the test directory.
+func (f *Fixture) Context(c *gc.C) *cmd.Context {
+	env := make(map[string]string, len(f.Env))
+	for key, value := range f.Env {
+		env[key] = value
+	}
+	ctx := &cmd.Context{
+		Dir:      c.MkDir(),
+		Env:      env,
+		Stdin:    &bytes.Buffer{},
+		Stdout:   &bytes.Buffer{},
+		Stderr:   &bytes.Buffer{},
+		Clock:    f.Clock,
+		Observer: &eventLog{},
+	}
+	ctx.Context = context.Background()
+	return ctx
+}
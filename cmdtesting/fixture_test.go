@@ -0,0 +1,45 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmdtesting_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type fixtureSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&fixtureSuite{})
+
+func (*fixtureSuite) TestContext(c *gc.C) {
+	f := cmdtesting.NewFixture(c, map[string]string{"JUJU_MODEL": "foo"})
+	ctx := f.Context(c)
+	c.Assert(ctx.Clock.Now(), gc.Equals, cmdtesting.FixtureTime)
+	c.Assert(ctx.Getenv("JUJU_MODEL"), gc.Equals, "foo")
+	c.Assert(ctx.Getenv("PATH"), gc.Equals, "")
+	c.Assert(ctx.HomeDir(), gc.Equals, f.HomeDir)
+	c.Assert(ctx.Getenv("XDG_DATA_HOME"), gc.Equals, f.DataDir)
+	c.Assert(ctx.Getenv("XDG_CONFIG_HOME"), gc.Equals, filepath.Join(f.HomeDir, ".config"))
+	for _, key := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME", "XDG_RUNTIME_DIR"} {
+		info, err := os.Stat(ctx.Getenv(key))
+		c.Assert(err, gc.IsNil)
+		c.Assert(info.IsDir(), gc.Equals, true)
+	}
+
+	// Each context has its own copy of the environment.
+	c.Assert(ctx.Setenv("JUJU_MODEL", "bar"), gc.IsNil)
+	c.Assert(f.Context(c).Getenv("JUJU_MODEL"), gc.Equals, "foo")
+}
+
+func (*fixtureSuite) TestSeededEnvOverridesDirs(c *gc.C) {
+	f := cmdtesting.NewFixture(c, map[string]string{"XDG_CONFIG_HOME": "/etc/xdg"})
+	c.Assert(f.Context(c).Getenv("XDG_CONFIG_HOME"), gc.Equals, "/etc/xdg")
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmdtesting
+
+import (
+	"bytes"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+// Terminal is a fake terminal that records what is written to it. It
+// implements cmd.TerminalWriter, so the framework treats it as a terminal
+// of the given size and colour capability, allowing width aware rendering
+// and colour output to be tested without a real pseudo-terminal.
+type Terminal struct {
+	bytes.Buffer
+
+	// Size is the size reported for the terminal.
+	Size cmd.WindowSize
+
+	// Color reports whether the terminal displays colours.
+	Color bool
+}
+
+// NewTerminal returns a fake terminal of the given size.
+func NewTerminal(width, height int, color bool) *Terminal {
+	return &Terminal{
+		Size:  cmd.WindowSize{Width: width, Height: height},
+		Color: color,
+	}
+}
+
+// WindowSize implements cmd.TerminalWriter.
+func (t *Terminal) WindowSize() (cmd.WindowSize, error) {
+	return t.Size, nil
+}
+
+// ColorCapable implements cmd.TerminalWriter.
+func (t *Terminal) ColorCapable() bool {
+	return t.Color
+}
+
+// TerminalContext works like Context, but the Stdout and Stderr of the
+// returned context are fake terminals of the given size, e.g.
+//
+//	ctx := cmdtesting.TerminalContext(c, 80, 24, true)
+//
+// declares that stdout is an 80x24 colour terminal. Stdout and Stderr may
+// be used to read what was written.
+func TerminalContext(c *gc.C, width, height int, color bool) *cmd.Context {
+	ctx := Context(c)
+	ctx.Stdout = NewTerminal(width, height, color)
+	ctx.Stderr = NewTerminal(width, height, color)
+	return ctx
+}
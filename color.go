@@ -0,0 +1,161 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/juju/ansiterm"
+)
+
+// ColorMode controls whether colours and styles are written to the output
+// of a command.
+type ColorMode string
+
+const (
+	// ColorAuto writes colours to terminals capable of displaying them,
+	// unless the NO_COLOR environment variable is set.
+	ColorAuto ColorMode = "auto"
+
+	// ColorAlways writes colours, even when the output is not a terminal.
+	ColorAlways ColorMode = "always"
+
+	// ColorNever never writes colours.
+	ColorNever ColorMode = "never"
+)
+
+// Set implements gnuflag.Value.
+func (m *ColorMode) Set(value string) error {
+	switch mode := ColorMode(value); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		*m = mode
+		return nil
+	}
+	return fmt.Errorf("unknown color mode %q, expected one of auto, always, never", value)
+}
+
+// String implements gnuflag.Value.
+func (m *ColorMode) String() string {
+	if *m == "" {
+		return string(ColorAuto)
+	}
+	return string(*m)
+}
+
+// SetColorMode sets whether colours are written to the output of the
+// command. Output.Write sets it from the --color flag.
+func (ctx *Context) SetColorMode(mode ColorMode) {
+	ctx.colorMode = mode
+}
+
+// IsStderrTerminal reports whether Stderr is a terminal.
+func (ctx *Context) IsStderrTerminal() bool {
+	return isTerminal(ctx.Stderr)
+}
+
+// ColorEnabled reports whether colours should be written to w, which is
+// typically Stdout or Stderr, according to the colour mode, the NO_COLOR
+// environment variable and whether w is a terminal capable of colour.
+func (ctx *Context) ColorEnabled(w io.Writer) bool {
+	switch ctx.colorMode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if ctx.lookupEnv("NO_COLOR") != "" || !isTerminal(w) || !supportsANSI(w) {
+		return false
+	}
+	if t, ok := w.(colorCapable); ok {
+		return t.ColorCapable()
+	}
+	return ctx.lookupEnv("TERM") != "dumb"
+}
+
+// ColorWriter returns an ansiterm.Writer that writes colours and styles to
+// w only if ColorEnabled(w).
+func (ctx *Context) ColorWriter(w io.Writer) *ansiterm.Writer {
+	writer := ansiterm.NewWriter(w)
+	writer.SetColorCapable(ctx.ColorEnabled(w))
+	return writer
+}
+
+// Colorize returns text in the given style if colours are enabled for
+// Stdout, and text unchanged otherwise, e.g.
+//
+//	fmt.Fprintln(ctx.Stdout, ctx.Colorize(ansiterm.Foreground(ansiterm.Red), "failed"))
+func (ctx *Context) Colorize(style *ansiterm.Context, text string) string {
+	var buf bytes.Buffer
+	writer := ansiterm.NewWriter(&buf)
+	writer.SetColorCapable(ctx.ColorEnabled(ctx.Stdout))
+	style.Fprint(writer, text)
+	return buf.String()
+}
+
+// colorCapable is implemented by writers that know whether they display
+// colours.
+type colorCapable interface {
+	ColorCapable() bool
+}
+
+// colorWriter is the writer given to formatters, which reports whether
+// colours are enabled for the output so that formatters writing through
+// NewColorWriter honour the colour mode.
+type colorWriter struct {
+	io.Writer
+	color bool
+}
+
+// ColorCapable implements colorCapable.
+func (w colorWriter) ColorCapable() bool {
+	return w.color
+}
+
+// colorFile is a colorWriter for a file, which keeps its descriptor so
+// that formatters can still recognise terminals.
+type colorFile struct {
+	colorWriter
+	fd uintptr
+}
+
+// Fd returns the file descriptor of the file written to.
+func (w colorFile) Fd() uintptr {
+	return w.fd
+}
+
+// colorTerminal is a colorWriter for a TerminalWriter.
+type colorTerminal struct {
+	colorWriter
+	terminal TerminalWriter
+}
+
+// WindowSize implements TerminalWriter.
+func (w colorTerminal) WindowSize() (WindowSize, error) {
+	return w.terminal.WindowSize()
+}
+
+// newColorWriter returns a writer that writes to w, and reports to
+// formatters whether colours are enabled for it.
+func newColorWriter(w io.Writer, color bool) io.Writer {
+	writer := colorWriter{Writer: w, color: color}
+	if t, ok := w.(TerminalWriter); ok {
+		return colorTerminal{colorWriter: writer, terminal: t}
+	}
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		return colorFile{colorWriter: writer, fd: f.Fd()}
+	}
+	return writer
+}
+
+// terminalColorCapable reports whether the terminal with the given file
+// descriptor displays colours, in the same way ansiterm decides for files.
+func terminalColorCapable(fd int) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminalFd(fd) && supportsANSIFd(fd)
+}
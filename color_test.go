@@ -0,0 +1,181 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/ansiterm"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ColorSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ColorSuite{})
+
+var red = ansiterm.Foreground(ansiterm.Red)
+
+func (s *ColorSuite) TestColorEnabled(c *gc.C) {
+	for i, test := range []struct {
+		mode     cmd.ColorMode
+		terminal bool
+		color    bool
+		env      map[string]string
+		expected bool
+	}{
+		{terminal: true, color: true, expected: true},
+		{terminal: true, color: false, expected: false},
+		{terminal: false, expected: false},
+		{terminal: true, color: true, env: map[string]string{"NO_COLOR": "1"}, expected: false},
+		{mode: cmd.ColorAuto, terminal: true, color: true, expected: true},
+		{mode: cmd.ColorAlways, terminal: false, expected: true},
+		{mode: cmd.ColorAlways, terminal: true, color: true, env: map[string]string{"NO_COLOR": "1"}, expected: true},
+		{mode: cmd.ColorNever, terminal: true, color: true, expected: false},
+	} {
+		ctx := cmdtesting.Context(c)
+		if test.terminal {
+			ctx = cmdtesting.TerminalContext(c, 80, 24, test.color)
+		}
+		ctx.Env = test.env
+		ctx.SetColorMode(test.mode)
+		c.Check(ctx.ColorEnabled(ctx.Stdout), gc.Equals, test.expected, gc.Commentf("test %d", i))
+	}
+}
+
+func (s *ColorSuite) TestIsStderrTerminal(c *gc.C) {
+	c.Assert(cmdtesting.Context(c).IsStderrTerminal(), gc.Equals, false)
+	c.Assert(cmdtesting.TerminalContext(c, 80, 24, false).IsStderrTerminal(), gc.Equals, true)
+}
+
+func (s *ColorSuite) TestColorize(c *gc.C) {
+	ctx := cmdtesting.TerminalContext(c, 80, 24, true)
+	c.Assert(ctx.Colorize(red, "failed"), gc.Equals, "\x1b[31mfailed\x1b[0m")
+	ctx.SetColorMode(cmd.ColorNever)
+	c.Assert(ctx.Colorize(red, "failed"), gc.Equals, "failed")
+}
+
+func (s *ColorSuite) TestColorWriter(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.SetColorMode(cmd.ColorAlways)
+	red.Fprint(ctx.ColorWriter(ctx.Stdout), "failed")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "\x1b[31mfailed\x1b[0m")
+}
+
+// colorCommand writes its output with a formatter that uses colours.
+type colorCommand struct {
+	cmd.CommandBase
+	out cmd.Output
+}
+
+func (c *colorCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "color"}
+}
+
+func (c *colorCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "colored", map[string]cmd.Formatter{
+		"colored": func(w io.Writer, value interface{}) error {
+			red.Fprint(cmd.NewColorWriter(w), value)
+			_, err := fmt.Fprintln(w)
+			return err
+		},
+	})
+	c.out.AddColorFlag(f)
+}
+
+func (c *colorCommand) Run(ctx *cmd.Context) error {
+	return c.out.Write(ctx, "failed")
+}
+
+func (s *ColorSuite) TestColorFlag(c *gc.C) {
+	for i, test := range []struct {
+		args     []string
+		color    bool
+		expected string
+	}{
+		{color: true, expected: "\x1b[31mfailed\x1b[0m\n"},
+		{color: false, expected: "failed\n"},
+		{args: []string{"--color=never"}, color: true, expected: "failed\n"},
+		{args: []string{"--color", "always"}, color: false, expected: "\x1b[31mfailed\x1b[0m\n"},
+	} {
+		ctx := cmdtesting.TerminalContext(c, 80, 24, test.color)
+		code := cmd.Main(&colorCommand{}, ctx, test.args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(cmdtesting.Stdout(ctx), gc.Equals, test.expected, gc.Commentf("test %d", i))
+	}
+}
+
+func (s *ColorSuite) TestColorFlagInvalid(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&colorCommand{}, ctx, []string{"--color", "sometimes"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `ERROR invalid value "sometimes" for flag --color: unknown color mode "sometimes", expected one of auto, always, never`+"\n")
+}
+
+// ownColorCommand has output flags and a --color flag of its own.
+type ownColorCommand struct {
+	cmd.CommandBase
+	out   cmd.Output
+	color bool
+}
+
+func (c *ownColorCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "status"}
+}
+
+func (c *ownColorCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "smart", map[string]cmd.Formatter{"smart": cmd.FormatSmart})
+	f.BoolVar(&c.color, "color", false, "Use colors")
+}
+
+func (c *ownColorCommand) Run(ctx *cmd.Context) error {
+	return c.out.Write(ctx, fmt.Sprint(c.color))
+}
+
+func (s *ColorSuite) TestColorFlagNotAddedByDefault(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&ownColorCommand{}, ctx, []string{"--color"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "true\n")
+}
+
+// fileCommand checks that its formatter can see the file it writes to.
+type fileCommand struct {
+	colorCommand
+	writers []io.Writer
+}
+
+func (c *fileCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "file", map[string]cmd.Formatter{
+		"file": func(w io.Writer, value interface{}) error {
+			c.writers = append(c.writers, w)
+			_, err := fmt.Fprintln(w, value)
+			return err
+		},
+	})
+}
+
+func (s *ColorSuite) TestFormatterGivenFile(c *gc.C) {
+	f, err := os.Create(filepath.Join(c.MkDir(), "stdout"))
+	c.Assert(err, gc.IsNil)
+	defer f.Close()
+	ctx := cmdtesting.Context(c)
+	ctx.Stdout = f
+	command := &fileCommand{}
+	code := cmd.Main(command, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.writers, gc.HasLen, 1)
+	file, ok := command.writers[0].(interface{ Fd() uintptr })
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(file.Fd(), gc.Equals, f.Fd())
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// commandSummary describes a registered command in the output of the
+// built-in "commands" subcommand.
+type commandSummary struct {
+	Name        string `json:"name" yaml:"name"`
+	Purpose     string `json:"purpose" yaml:"purpose"`
+	Category    string `json:"category,omitempty" yaml:"category,omitempty"`
+	AliasFor    string `json:"alias-for,omitempty" yaml:"alias-for,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+// commandsCommand lists the commands registered with a SuperCommand and
+// the SuperCommands below it, for use by scripts and tooling.
+type commandsCommand struct {
+	CommandBase
+	super      *SuperCommand
+	out        Output
+	all        bool
+	deprecated bool
+	category   string
+}
+
+func (c *commandsCommand) Info() *Info {
+	return &Info{
+		Name:               "commands",
+		Purpose:            "List the available commands.",
+		NonInteractiveSafe: true,
+		Doc: `
+List every command that can be run, along with its purpose and category.
+Commands below other commands are listed by their full path. Hidden and
+deprecated commands are only listed with --all, and --deprecated lists
+only the deprecated commands.`[1:],
+	}
+}
+
+func (c *commandsCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "tabular", map[string]Formatter{
+		"tabular": formatCommandSummaries,
+		"json":    FormatJson,
+		"yaml":    FormatYaml,
+	})
+	f.BoolVar(&c.all, "all", false, "Include hidden and deprecated commands")
+	f.BoolVar(&c.deprecated, "deprecated", false, "Only list deprecated commands")
+	f.StringVar(&c.category, "category", "", "Only list commands in the given category")
+}
+
+func (c *commandsCommand) Init(args []string) error {
+	return CheckEmpty(args)
+}
+
+func (c *commandsCommand) Run(ctx *Context) error {
+	c.super.loadAllDynamicCommands(ctx)
+	var summaries []commandSummary
+	for _, summary := range summariseCommands(c.super, nil) {
+		if c.deprecated && !summary.Deprecated {
+			continue
+		}
+		if !c.all && !c.deprecated && (summary.Hidden || summary.Deprecated) {
+			continue
+		}
+		if c.category != "" && summary.Category != c.category {
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return errors.Trace(c.out.Write(ctx, summaries))
+}
+
+// summariseCommands returns a summary of each command registered with
+// super, and of the commands below each SuperCommand registered with it.
+// The names are prefixed by parents.
+func summariseCommands(super *SuperCommand, parents []string) []commandSummary {
+	var summaries []commandSummary
+	for name, ref := range super.subcmds {
+		path := append(append([]string{}, parents...), name)
+		info := ref.command.Info()
+		summary := commandSummary{
+			Name:     strings.Join(path, " "),
+			Purpose:  info.Purpose,
+			Category: info.Category,
+			Hidden:   ref.hidden(name),
+		}
+		summary.Deprecated, summary.Replacement = ref.Deprecated()
+		if ref.alias != "" {
+			summary.AliasFor = strings.Join(append(append([]string{}, parents...), ref.alias), " ")
+		}
+		summaries = append(summaries, summary)
+		if sc, ok := ref.command.(*SuperCommand); ok && ref.alias == "" {
+			summaries = append(summaries, summariseCommands(sc, path)...)
+		}
+	}
+	return summaries
+}
+
+// formatCommandSummaries writes command summaries as a table.
+func formatCommandSummaries(writer io.Writer, value interface{}) error {
+	summaries, ok := value.([]commandSummary)
+	if !ok {
+		return errors.Errorf("expected value of type %T, got %T", summaries, value)
+	}
+	tw := tabwriter.NewWriter(writer, 0, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tCATEGORY\tPURPOSE")
+	for _, summary := range summaries {
+		purpose := summary.Purpose
+		if summary.AliasFor != "" {
+			purpose = "Alias for '" + summary.AliasFor + "'."
+		}
+		switch {
+		case summary.Deprecated && summary.Replacement != "":
+			purpose += fmt.Sprintf(" (deprecated, use %q)", summary.Replacement)
+		case summary.Deprecated:
+			purpose += " (deprecated)"
+		case summary.Hidden:
+			purpose += " (hidden)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", summary.Name, summary.Category, purpose)
+	}
+	return tw.Flush()
+}
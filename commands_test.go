@@ -0,0 +1,96 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type CommandsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&CommandsSuite{})
+
+// categorisedCommand is a command with a category.
+type categorisedCommand struct {
+	TestCommand
+	category string
+}
+
+func (c *categorisedCommand) Info() *cmd.Info {
+	info := c.TestCommand.Info()
+	info.Category = c.category
+	return info
+}
+
+func (s *CommandsSuite) run(c *gc.C, args ...string) (*cmd.Context, int) {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", UsagePrefix: "juju", Purpose: "Manage pools."})
+	pools.Register(&categorisedCommand{TestCommand: TestCommand{Name: "list"}, category: "storage"})
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju", ListCommands: true})
+	super.Register(pools)
+	super.Register(&categorisedCommand{TestCommand: TestCommand{Name: "deploy"}, category: "applications"})
+	super.RegisterDeprecated(&TestCommand{Name: "destroy"}, deprecate{replacement: "remove"})
+	super.RegisterAlias("ls", "pools", nil)
+	ctx := cmdtesting.Context(c)
+	return ctx, cmd.Main(super, ctx, append([]string{"commands"}, args...))
+}
+
+func (s *CommandsSuite) TestNotRegisteredByDefault(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	err := cmdtesting.InitCommand(super, []string{"commands"})
+	c.Assert(err, gc.ErrorMatches, "unrecognized command: juju commands")
+}
+
+func (s *CommandsSuite) TestList(c *gc.C) {
+	ctx, code := s.run(c)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+COMMAND              CATEGORY      PURPOSE
+commands                           List the available commands.
+deploy               applications  deploy the juju
+documentation                      Generate the documentation for all commands
+help                               Show help on a command or other topic.
+ls                                 Alias for 'pools'.
+pools                              Manage pools.
+pools documentation                Generate the documentation for all commands
+pools help                         Show help on a command or other topic.
+pools list           storage       list the juju
+`[1:])
+}
+
+func (s *CommandsSuite) TestDeprecated(c *gc.C) {
+	ctx, code := s.run(c, "--deprecated")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+COMMAND  CATEGORY  PURPOSE
+destroy            destroy the juju (deprecated, use "remove")
+`[1:])
+}
+
+func (s *CommandsSuite) TestCategory(c *gc.C) {
+	ctx, code := s.run(c, "--category", "storage", "--format", "yaml")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+- name: pools list
+  purpose: list the juju
+  category: storage
+`[1:])
+}
+
+func (s *CommandsSuite) TestAllJSON(c *gc.C) {
+	ctx, code := s.run(c, "--all", "--category", "", "--format", "json")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `\[.*\{"name":"destroy","purpose":"destroy the juju","deprecated":true,"replacement":"remove"\}.*\{"name":"ls","purpose":"Manage pools.","alias-for":"pools"\}.*\]\n`)
+}
+
+func (s *CommandsSuite) TestInitErrors(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju", ListCommands: true})
+	err := cmdtesting.InitCommand(super, []string{"commands", "extra"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra"\]`)
+}
@@ -0,0 +1,563 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// completionShells holds the shells for which completion scripts can be
+// generated, in the order they are listed in help output.
+var completionShells = []string{"bash", "fish", "powershell", "zsh"}
+
+// Completer is implemented by commands that complete their positional
+// arguments at run time, for example with the names of models or units.
+type Completer interface {
+	// CompleteArgs returns the candidates for the last of args, the
+	// positional arguments of the command line being completed. Candidates
+	// that do not start with the last argument are ignored. The command's
+	// flags are set from the command line, but Init is not called.
+	CompleteArgs(ctx *Context, args []string) []string
+}
+
+// ValueCompleter is implemented by flag values that offer candidates when
+// the value of their flag is completed, such as EnumValue.
+type ValueCompleter interface {
+	// CompleteValue returns the values the flag may be given.
+	CompleteValue() []string
+}
+
+// completionCommand prints a script that completes the subcommands and
+// flags of a SuperCommand in the user's shell.
+type completionCommand struct {
+	CommandBase
+	super *SuperCommand
+	shell string
+}
+
+func (c *completionCommand) Info() *Info {
+	return &Info{
+		Name:               "completion",
+		Args:               "<" + strings.Join(completionShells, "|") + ">",
+		Purpose:            "Print a shell completion script.",
+		NonInteractiveSafe: true,
+		Doc: fmt.Sprintf(`
+Print a script that completes the subcommands and flags of %[1]s in the
+given shell. Arguments of commands that support it are completed by
+running "%[1]s __complete". To enable completion in the current shell, run
+
+    source <(%[1]s completion bash)
+    source <(%[1]s completion zsh)
+    %[1]s completion fish | source
+    %[1]s completion powershell | Out-String | Invoke-Expression
+
+Add the same line to the shell's start up file to enable completion in
+every new shell.`[1:], c.super.Name),
+	}
+}
+
+func (c *completionCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no shell specified")
+	}
+	c.shell, args = args[0], args[1:]
+	for _, shell := range completionShells {
+		if c.shell == shell {
+			return CheckEmpty(args)
+		}
+	}
+	return errors.Errorf("unsupported shell %q, expected one of %s", c.shell, strings.Join(completionShells, ", "))
+}
+
+func (c *completionCommand) Run(ctx *Context) error {
+	c.super.loadAllDynamicCommands(ctx)
+	tree := newCompletionTree(c.super)
+	var script bytes.Buffer
+	switch c.shell {
+	case "bash":
+		writeBashCompletion(&script, c.super.Name, tree)
+	case "fish":
+		writeFishCompletion(&script, c.super.Name, tree)
+	case "powershell":
+		writePowerShellCompletion(&script, c.super.Name, tree)
+	case "zsh":
+		writeZshCompletion(&script, c.super.Name, tree)
+	}
+	_, err := io.Copy(ctx.Stdout, &script)
+	return errors.Trace(err)
+}
+
+// completeCommand implements the run-time completion protocol used by the
+// completion scripts: given the words of a command line, starting with the
+// name of the top-level command, and the index of the word being completed,
+// it prints the candidates for that word, one per line.
+type completeCommand struct {
+	CommandBase
+	super *SuperCommand
+	words []string
+	index int
+}
+
+func (c *completeCommand) Info() *Info {
+	return &Info{
+		Name:               "__complete",
+		Args:               "<index> <word>...",
+		Purpose:            "Print the candidates for a word of a command line.",
+		NonInteractiveSafe: true,
+	}
+}
+
+// AllowInterspersedFlags returns false, so that the flags of the command
+// line being completed are not parsed as flags of the command.
+func (c *completeCommand) AllowInterspersedFlags() bool {
+	return false
+}
+
+func (c *completeCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no word index specified")
+	}
+	index, err := strconv.Atoi(args[0])
+	c.words = args[1:]
+	if err != nil || index < 1 || index > len(c.words) {
+		return errors.Errorf("invalid word index %q", args[0])
+	}
+	c.index = index
+	return nil
+}
+
+func (c *completeCommand) Run(ctx *Context) error {
+	c.super.loadAllDynamicCommands(ctx)
+	var current string
+	if c.index < len(c.words) {
+		current = c.words[c.index]
+	}
+	for _, candidate := range completeWord(ctx, c.super, c.words[1:c.index], current) {
+		if _, err := fmt.Fprintln(ctx.Stdout, candidate); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// completeWord returns the candidates for current, the word following words
+// in a command line of super: the flags of the selected command if current
+// starts with "-", the values offered by a ValueCompleter if current is the
+// value of its flag, otherwise the subcommands of a super command or the
+// arguments returned by a Completer.
+func completeWord(ctx *Context, super *SuperCommand, words []string, current string) []string {
+	tree := newCompletionTree(super)
+	var (
+		command Command = super
+		flags   *gnuflag.FlagSet
+		path    string
+		args    []string
+	)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if sc, ok := command.(*SuperCommand); ok {
+			if strings.HasPrefix(word, "-") {
+				continue
+			}
+			ref, found := sc.subcmds[word]
+			if !found || isHiddenCommand(word) {
+				return nil
+			}
+			command = ref.command
+			path = strings.TrimSpace(path + " " + word)
+			flags = gnuflag.NewFlagSetWithFlagKnownAs(word, gnuflag.ContinueOnError, FlagAlias(command, "flag"))
+			command.SetFlags(flags)
+			continue
+		}
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			args = append(args, word)
+			continue
+		}
+		// Set the command's flags, so that its completer may use them.
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		flag := flags.Lookup(name)
+		if flag == nil {
+			continue
+		}
+		if b, ok := flag.Value.(interface{ IsBoolFlag() bool }); !hasValue && ok && b.IsBoolFlag() {
+			value, hasValue = "true", true
+		}
+		if !hasValue {
+			if i+1 == len(words) {
+				// The word being completed is the flag's value.
+				if completer, ok := flag.Value.(ValueCompleter); ok {
+					return matchingCandidates(completer.CompleteValue(), current)
+				}
+				return nil
+			}
+			i++
+			value = words[i]
+		}
+		_ = flag.Value.Set(value)
+	}
+
+	var candidates []string
+	if strings.HasPrefix(current, "-") {
+		candidates = tree[path].flags
+	} else if _, ok := command.(*SuperCommand); ok {
+		candidates = tree[path].subcommands
+	} else if completer, ok := command.(Completer); ok {
+		candidates = completer.CompleteArgs(ctx, append(args, current))
+	}
+	return matchingCandidates(candidates, current)
+}
+
+// matchingCandidates returns the candidates that start with current.
+func matchingCandidates(candidates []string, current string) []string {
+	var matching []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matching = append(matching, candidate)
+		}
+	}
+	return matching
+}
+
+// completionNode holds the words that may follow a command in a command
+// line, keyed in a completionTree by the space separated path of the
+// command below the top-level command. Dynamic is set for commands that
+// implement Completer or have flags whose values implement ValueCompleter.
+type completionNode struct {
+	subcommands []string
+	flags       []string
+	dynamic     bool
+}
+
+type completionTree map[string]completionNode
+
+// newCompletionTree returns the completion nodes of super and every command
+// below it. The flags of the top-level command are read from the flag set
+// it was given when the command line was parsed, so that it is not reset.
+func newCompletionTree(super *SuperCommand) completionTree {
+	tree := make(completionTree)
+	var common []string
+	if super.commonflags != nil {
+		common = flagNames(super.commonflags)
+	}
+	var flags []string
+	if super.flags != nil {
+		flags = flagNames(super.flags)
+	}
+	tree.add(super, "", flags, common)
+	return tree
+}
+
+func (t completionTree) add(super *SuperCommand, path string, flags, common []string) {
+	node := completionNode{flags: flags}
+	for name, ref := range super.subcmds {
+		if ref.hidden(name) {
+			continue
+		}
+		node.subcommands = append(node.subcommands, name)
+		subpath := strings.TrimSpace(path + " " + name)
+
+		f := gnuflag.NewFlagSetWithFlagKnownAs(name, gnuflag.ContinueOnError, FlagAlias(ref.command, "flag"))
+		ref.command.SetFlags(f)
+		subflags := mergeNames(flagNames(f), common)
+		if sc, ok := ref.command.(*SuperCommand); ok {
+			t.add(sc, subpath, subflags, common)
+			continue
+		}
+		_, dynamic := ref.command.(Completer)
+		f.VisitAll(func(flag *gnuflag.Flag) {
+			if _, ok := flag.Value.(ValueCompleter); ok {
+				dynamic = true
+			}
+		})
+		t[subpath] = completionNode{flags: subflags, dynamic: dynamic}
+	}
+	sort.Strings(node.subcommands)
+	t[path] = node
+}
+
+// paths returns the paths of the nodes of the tree, sorted.
+func (t completionTree) paths() []string {
+	paths := make([]string, 0, len(t))
+	for path := range t {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// dynamicPaths returns the sorted paths of the commands whose arguments or
+// flag values are completed at run time.
+func (t completionTree) dynamicPaths() []string {
+	var paths []string
+	for _, path := range t.paths() {
+		if t[path].dynamic {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// flagNames returns the names of the flags in f, as they are given on the
+// command line, sorted.
+func flagNames(f *gnuflag.FlagSet) []string {
+	var names []string
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		if len(flag.Name) == 1 {
+			names = append(names, "-"+flag.Name)
+		} else {
+			names = append(names, "--"+flag.Name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// mergeNames returns the sorted union of a and b.
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(append([]string(nil), a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionFuncName returns a shell function name derived from the name
+// of the command and suffix.
+func completionFuncName(name, suffix string) string {
+	ident := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	return "_" + ident + suffix
+}
+
+// writeShellTables writes the POSIX shell functions, shared by bash and
+// zsh, that print the subcommands and flags that may follow the command
+// path given as their argument.
+func writeShellTables(w io.Writer, name string, tree completionTree) {
+	for _, table := range []struct {
+		suffix string
+		words  func(completionNode) []string
+	}{
+		{"_subcommands", func(n completionNode) []string { return n.subcommands }},
+		{"_flags", func(n completionNode) []string { return n.flags }},
+	} {
+		fmt.Fprintf(w, "%s()\n{\n    case \"$1\" in\n", completionFuncName(name, table.suffix))
+		for _, path := range tree.paths() {
+			if words := table.words(tree[path]); len(words) > 0 {
+				fmt.Fprintf(w, "    %q) echo %q ;;\n", path, strings.Join(words, " "))
+			}
+		}
+		fmt.Fprintf(w, "    esac\n}\n\n")
+	}
+	fmt.Fprintf(w, "%s()\n{\n", completionFuncName(name, "_dynamic"))
+	if paths := tree.dynamicPaths(); len(paths) > 0 {
+		var quoted []string
+		for _, path := range paths {
+			quoted = append(quoted, strconv.Quote(path))
+		}
+		fmt.Fprintf(w, "    case \"$1\" in\n    %s) return 0 ;;\n    esac\n", strings.Join(quoted, "|"))
+	}
+	fmt.Fprintf(w, "    return 1\n}\n\n")
+}
+
+func writeBashCompletion(w io.Writer, name string, tree completionTree) {
+	fn := completionFuncName(name, "")
+	fmt.Fprintf(w, "# bash completion for %s\n\n", name)
+	writeShellTables(w, name, tree)
+	fmt.Fprintf(w, `%[1]s()
+{
+    local cur="${COMP_WORDS[COMP_CWORD]}" cmdpath="" word i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        case " $(%[1]s_subcommands "$cmdpath") " in
+        *" $word "*) cmdpath="${cmdpath:+$cmdpath }$word" ;;
+        esac
+    done
+    case "$cur" in
+    -*) COMPREPLY=($(compgen -W "$(%[1]s_flags "$cmdpath")" -- "$cur")) ;;
+    *)
+        if %[1]s_dynamic "$cmdpath"; then
+            COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" __complete "$COMP_CWORD" "${COMP_WORDS[@]}" 2>/dev/null)" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "$(%[1]s_subcommands "$cmdpath")" -- "$cur"))
+        fi
+        ;;
+    esac
+}
+
+complete -o default -F %[1]s %[2]s
+`, fn, name)
+}
+
+func writeZshCompletion(w io.Writer, name string, tree completionTree) {
+	fn := completionFuncName(name, "")
+	fmt.Fprintf(w, "#compdef %s\n\n# zsh completion for %s\n\n", name, name)
+	writeShellTables(w, name, tree)
+	fmt.Fprintf(w, `%[1]s()
+{
+    local cmdpath="" word i subcommands
+    for ((i = 2; i < CURRENT; i++)); do
+        word="${words[i]}"
+        if [[ " $(%[1]s_subcommands "$cmdpath") " == *" $word "* ]]; then
+            cmdpath="${cmdpath:+$cmdpath }$word"
+        fi
+    done
+    if [[ "${words[CURRENT]}" == -* ]]; then
+        compadd -- ${=$(%[1]s_flags "$cmdpath")}
+        return
+    fi
+    if %[1]s_dynamic "$cmdpath"; then
+        compadd -- ${(f)"$("${words[1]}" __complete $((CURRENT - 1)) "${words[@]}" 2>/dev/null)"}
+        return
+    fi
+    subcommands="$(%[1]s_subcommands "$cmdpath")"
+    if [[ -n "$subcommands" ]]; then
+        compadd -- ${=subcommands}
+    else
+        _files
+    fi
+}
+
+if [[ "${funcstack[1]}" == "%[1]s" ]]; then
+    %[1]s "$@"
+else
+    compdef %[1]s %[2]s
+fi
+`, fn, name)
+}
+
+func writeFishCompletion(w io.Writer, name string, tree completionTree) {
+	fn := completionFuncName(name, "")
+	fmt.Fprintf(w, "# fish completion for %s\n\n", name)
+	for _, table := range []struct {
+		suffix string
+		words  func(completionNode) []string
+	}{
+		{"_subcommands", func(n completionNode) []string { return n.subcommands }},
+		{"_flags", func(n completionNode) []string { return n.flags }},
+	} {
+		fmt.Fprintf(w, "function %s\n    switch \"$argv[1]\"\n", completionFuncName(name, table.suffix))
+		for _, path := range tree.paths() {
+			if words := table.words(tree[path]); len(words) > 0 {
+				fmt.Fprintf(w, "    case '%s'\n        printf '%%s\\n' %s\n", path, strings.Join(words, " "))
+			}
+		}
+		fmt.Fprintf(w, "    end\nend\n\n")
+	}
+	fmt.Fprintf(w, "function %s\n", completionFuncName(name, "_dynamic"))
+	if paths := tree.dynamicPaths(); len(paths) > 0 {
+		fmt.Fprintf(w, "    switch \"$argv[1]\"\n    case '%s'\n        return 0\n    end\n", strings.Join(paths, "' '"))
+	}
+	fmt.Fprintf(w, "    return 1\nend\n\n")
+	fmt.Fprintf(w, `function %[1]s_path
+    set -l cmdpath ''
+    for word in (commandline -opc)[2..-1]
+        if contains -- $word (%[1]s_subcommands "$cmdpath")
+            set cmdpath (string trim -- "$cmdpath $word")
+        end
+    end
+    echo $cmdpath
+end
+
+function %[1]s_complete
+    set -l cmdpath (%[1]s_path)
+    switch (commandline -ct)
+    case '-*'
+        %[1]s_flags "$cmdpath"
+    case '*'
+        if %[1]s_dynamic "$cmdpath"
+            set -l tokens (commandline -opc) (commandline -ct)
+            $tokens[1] __complete (math (count $tokens) - 1) $tokens 2>/dev/null
+        else
+            %[1]s_subcommands "$cmdpath"
+        end
+    end
+end
+
+function %[1]s_has_subcommands
+    count (%[1]s_subcommands (%[1]s_path)) >/dev/null
+end
+
+complete -c %[2]s -f -n '%[1]s_has_subcommands' -a '(%[1]s_complete)'
+complete -c %[2]s -n 'not %[1]s_has_subcommands' -a '(%[1]s_complete)'
+`, fn, name)
+}
+
+// powerShellQuote returns s as a PowerShell single quoted string.
+func powerShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// powerShellArray returns words as a PowerShell array expression.
+func powerShellArray(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = powerShellQuote(word)
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+func writePowerShellCompletion(w io.Writer, name string, tree completionTree) {
+	fmt.Fprintf(w, "# powershell completion for %s\n\n", name)
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", powerShellQuote(name))
+	fmt.Fprintf(w, "    param($wordToComplete, $commandAst, $cursorPosition)\n\n")
+	for _, table := range []struct {
+		variable string
+		words    func(completionNode) []string
+	}{
+		{"subcommands", func(n completionNode) []string { return n.subcommands }},
+		{"flags", func(n completionNode) []string { return n.flags }},
+	} {
+		fmt.Fprintf(w, "    $%s = @{\n", table.variable)
+		for _, path := range tree.paths() {
+			if words := table.words(tree[path]); len(words) > 0 {
+				fmt.Fprintf(w, "        %s = %s\n", powerShellQuote(path), powerShellArray(words))
+			}
+		}
+		fmt.Fprintf(w, "    }\n")
+	}
+	fmt.Fprintf(w, "    $dynamic = %s\n", powerShellArray(tree.dynamicPaths()))
+	fmt.Fprint(w, `
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.EndOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    $cmdpath = ''
+    foreach ($word in ($words | Select-Object -Skip 1)) {
+        if ($subcommands[$cmdpath] -contains $word) {
+            $cmdpath = "$cmdpath $word".Trim()
+        }
+    }
+    if ($wordToComplete.StartsWith('-')) {
+        $candidates = $flags[$cmdpath]
+    } elseif ($dynamic -contains $cmdpath) {
+        $completeArgs = @('__complete', $words.Count) + $words
+        if ($wordToComplete -ne '') {
+            $completeArgs += $wordToComplete
+        }
+        $candidates = & $words[0] @completeArgs 2>$null
+    } else {
+        $candidates = $subcommands[$cmdpath]
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)
+}
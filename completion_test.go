@@ -0,0 +1,340 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type CompletionSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&CompletionSuite{})
+
+func (s *CompletionSuite) newSuper() *cmd.SuperCommand {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", UsagePrefix: "jujutest"})
+	pools.Register(&TestCommand{Name: "list"})
+	pools.Register(&TestCommand{Name: "create", Minimal: true})
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:            "jujutest",
+		Version:         "1.2.3",
+		ShellCompletion: true,
+		NoRemoteFlag:    true,
+	})
+	super.Register(pools)
+	super.Register(&TestCommand{Name: "blah", Aliases: []string{"bleh"}})
+	super.Register(&unitsCommand{})
+	return super
+}
+
+// unitsCommand completes its arguments with the names of the units of the
+// application given by its --application flag.
+type unitsCommand struct {
+	cmd.CommandBase
+	application string
+	force       bool
+	args        [][]string
+}
+
+func (c *unitsCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "remove-unit", Args: "<unit>...", Purpose: "Remove units."}
+}
+
+func (c *unitsCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.application, "application", "mysql", "The application")
+	f.BoolVar(&c.force, "force", false, "Remove units even if they are busy")
+}
+
+func (c *unitsCommand) CompleteArgs(ctx *cmd.Context, args []string) []string {
+	c.args = append(c.args, args)
+	if c.force {
+		return []string{c.application + "/0"}
+	}
+	return []string{c.application + "/0", c.application + "/1", "other/0"}
+}
+
+func (c *unitsCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (s *CompletionSuite) script(c *gc.C, shell string) string {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newSuper(), ctx, []string{"completion", shell})
+	c.Assert(code, gc.Equals, 0)
+	return cmdtesting.Stdout(ctx)
+}
+
+func (s *CompletionSuite) TestNotRegisteredByDefault(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	c.Assert(super.Info().Subcommands, gc.Not(gc.HasLen), 0)
+	_, found := super.Info().Subcommands["completion"]
+	c.Assert(found, gc.Equals, false)
+	err := cmdtesting.InitCommand(super, []string{"__complete", "1", "jujutest"})
+	c.Assert(err, gc.ErrorMatches, "unrecognized command: jujutest __complete")
+}
+
+func (s *CompletionSuite) TestCompleteIsHidden(c *gc.C) {
+	super := s.newSuper()
+	_, found := super.Info().Subcommands["completion"]
+	c.Assert(found, gc.Equals, true)
+	_, found = super.Info().Subcommands["__complete"]
+	c.Assert(found, gc.Equals, false)
+	c.Assert(s.script(c, "bash"), gc.Matches, `(?s).*\n    ""\) echo "blah bleh completion documentation help pools remove-unit version" ;;\n.*`)
+}
+
+func (s *CompletionSuite) complete(c *gc.C, super *cmd.SuperCommand, index string, words ...string) []string {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(super, ctx, append([]string{"__complete", index}, words...))
+	c.Assert(code, gc.Equals, 0, gc.Commentf("%s", cmdtesting.Stderr(ctx)))
+	if cmdtesting.Stdout(ctx) == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(cmdtesting.Stdout(ctx), "\n"), "\n")
+}
+
+func (s *CompletionSuite) TestComplete(c *gc.C) {
+	for _, test := range []struct {
+		index    string
+		words    []string
+		expected []string
+	}{{
+		index:    "1",
+		words:    []string{"jujutest", "b"},
+		expected: []string{"blah", "bleh"},
+	}, {
+		index:    "1",
+		words:    []string{"jujutest"},
+		expected: []string{"blah", "bleh", "completion", "documentation", "help", "pools", "remove-unit", "version"},
+	}, {
+		index:    "3",
+		words:    []string{"jujutest", "--debug", "pools", "l"},
+		expected: []string{"list"},
+	}, {
+		index:    "2",
+		words:    []string{"jujutest", "pools", "l"},
+		expected: []string{"list"},
+	}, {
+		index:    "3",
+		words:    []string{"jujutest", "pools", "list", "--o"},
+		expected: []string{"--option"},
+	}, {
+		index:    "2",
+		words:    []string{"jujutest", "remove-unit"},
+		expected: []string{"mysql/0", "mysql/1", "other/0"},
+	}, {
+		index:    "4",
+		words:    []string{"jujutest", "remove-unit", "--application", "wordpress", "word"},
+		expected: []string{"wordpress/0", "wordpress/1"},
+	}, {
+		index:    "4",
+		words:    []string{"jujutest", "remove-unit", "--application=wordpress", "--force", "word"},
+		expected: []string{"wordpress/0"},
+	}, {
+		index:    "3",
+		words:    []string{"jujutest", "remove-unit", "--application", "w"},
+		expected: nil,
+	}, {
+		index:    "2",
+		words:    []string{"jujutest", "unknown", "x"},
+		expected: nil,
+	}} {
+		c.Check(s.complete(c, s.newSuper(), test.index, test.words...), gc.DeepEquals, test.expected, gc.Commentf("%s %q", test.index, test.words))
+	}
+}
+
+func (s *CompletionSuite) TestCompleteArgs(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", ShellCompletion: true})
+	units := &unitsCommand{}
+	super.Register(units)
+	s.complete(c, super, "4", "jujutest", "remove-unit", "mysql/0", "--force", "my")
+	c.Assert(units.args, gc.DeepEquals, [][]string{{"mysql/0", "my"}})
+}
+
+func (s *CompletionSuite) TestCompleteInitErrors(c *gc.C) {
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{{
+		args: []string{"__complete"},
+		err:  "no word index specified",
+	}, {
+		args: []string{"__complete", "x", "jujutest"},
+		err:  `invalid word index "x"`,
+	}, {
+		args: []string{"__complete", "0", "jujutest"},
+		err:  `invalid word index "0"`,
+	}, {
+		args: []string{"__complete", "2", "jujutest"},
+		err:  `invalid word index "2"`,
+	}} {
+		err := cmdtesting.InitCommand(s.newSuper(), test.args)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *CompletionSuite) TestInitErrors(c *gc.C) {
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{{
+		args: []string{"completion"},
+		err:  "no shell specified",
+	}, {
+		args: []string{"completion", "tcsh"},
+		err:  `unsupported shell "tcsh", expected one of bash, fish, powershell, zsh`,
+	}, {
+		args: []string{"completion", "bash", "zsh"},
+		err:  `unrecognized args: \["zsh"\]`,
+	}} {
+		err := cmdtesting.InitCommand(s.newSuper(), test.args)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+// completeBash runs the bash completion function in the script given as
+// its first argument for the command line given by the rest. The command
+// run to complete arguments is replaced by a function that records its
+// arguments in $ARGS_FILE.
+const completeBash = `
+jujutest() { echo "$@" >"$ARGS_FILE"; printf '%s\n' mysql/0 mysql/1 other/0; }
+source "$1"; shift
+COMP_WORDS=("$@")
+COMP_CWORD=$(($# - 1))
+_jujutest
+printf '%s\n' "${COMPREPLY[@]}"
+`
+
+func (s *CompletionSuite) TestBash(c *gc.C) {
+	script := s.script(c, "bash")
+	c.Assert(script, gc.Matches, `(?s)# bash completion for jujutest\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\n    "pools"\) echo "create documentation help list" ;;\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\ncomplete -o default -F _jujutest jujutest\n`)
+
+	// The isolation suite clears PATH.
+	s.PatchEnvironment("PATH", "/usr/local/bin:/usr/bin:/bin")
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		c.Skip("bash not found")
+	}
+	dir := c.MkDir()
+	path := filepath.Join(dir, "completion.bash")
+	argsPath := filepath.Join(dir, "args")
+	c.Assert(os.WriteFile(path, []byte(script), 0644), gc.IsNil)
+
+	for _, test := range []struct {
+		words    []string
+		expected []string
+	}{{
+		words:    []string{"jujutest", "b"},
+		expected: []string{"blah", "bleh"},
+	}, {
+		words:    []string{"jujutest", ""},
+		expected: []string{"blah", "bleh", "completion", "documentation", "help", "pools", "remove-unit", "version"},
+	}, {
+		words:    []string{"jujutest", "remove-unit", "mysql/0", "my"},
+		expected: []string{"mysql/0", "mysql/1"},
+	}, {
+		words:    []string{"jujutest", "pools", "l"},
+		expected: []string{"list"},
+	}, {
+		words:    []string{"jujutest", "pools", "list", "--o"},
+		expected: []string{"--option"},
+	}, {
+		words:    []string{"jujutest", "pools", "create", "--o"},
+		expected: []string{},
+	}, {
+		words:    []string{"jujutest", "--ver"},
+		expected: []string{"--version"},
+	}, {
+		words:    []string{"jujutest", "blah", "--h"},
+		expected: []string{"--help"},
+	}} {
+		command := exec.Command(bash, append([]string{"-c", completeBash, "bash", path}, test.words...)...)
+		command.Env = []string{"ARGS_FILE=" + argsPath}
+		out, err := command.CombinedOutput()
+		c.Assert(err, gc.IsNil, gc.Commentf("%s", out))
+		c.Check(strings.Fields(string(out)), gc.DeepEquals, test.expected, gc.Commentf("%q", test.words))
+	}
+	args, err := os.ReadFile(argsPath)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(args), gc.Equals, "__complete 3 jujutest remove-unit mysql/0 my\n")
+}
+
+func (s *CompletionSuite) TestZsh(c *gc.C) {
+	script := s.script(c, "zsh")
+	c.Assert(script, gc.Matches, `#compdef jujutest\n(.|\n)*`)
+	c.Assert(script, gc.Matches, `(?s).*\n    "pools list"\) echo "--description --help --no-remote --option -h" ;;\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\n    compdef _jujutest jujutest\n.*`)
+}
+
+func (s *CompletionSuite) TestFish(c *gc.C) {
+	script := s.script(c, "fish")
+	c.Assert(script, gc.Matches, `(?s)# fish completion for jujutest\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\n    case 'pools'\n        printf '%s\\n' create documentation help list\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\ncomplete -c jujutest -f -n '_jujutest_has_subcommands' -a '\(_jujutest_complete\)'\n.*`)
+}
+
+func (s *CompletionSuite) TestPowerShell(c *gc.C) {
+	script := s.script(c, "powershell")
+	c.Assert(script, gc.Matches, `(?s)# powershell completion for jujutest\n\nRegister-ArgumentCompleter -Native -CommandName 'jujutest' -ScriptBlock \{\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\n        'pools' = @\('create', 'documentation', 'help', 'list'\)\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\n        'pools list' = @\('--description', '--help', '--no-remote', '--option', '-h'\)\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\n    \$dynamic = @\('remove-unit'\)\n.*`)
+	c.Assert(script, gc.Matches, `(?s).*\$completeArgs = @\('__complete', \$words.Count\) \+ \$words\n.*`)
+}
+
+func (s *CompletionSuite) TestDynamicCommands(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:            "jujutest",
+		ShellCompletion: true,
+		DynamicCommands: func(*cmd.Context) []cmd.Command {
+			return []cmd.Command{&unitsCommand{}}
+		},
+	})
+	c.Assert(s.complete(c, super, "1", "jujutest", "rem"), gc.DeepEquals, []string{"remove-unit"})
+	c.Assert(s.complete(c, super, "2", "jujutest", "remove-unit", "o"), gc.DeepEquals, []string{"other/0"})
+}
+
+// upgradeCommand has a flag with a fixed set of values.
+type upgradeCommand struct {
+	cmd.CommandBase
+}
+
+func (c *upgradeCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "upgrade", Purpose: "Upgrade the model."}
+}
+
+func (c *upgradeCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.Var(cmd.NewEnumValue([]string{"fast", "safe", "staged"}, "safe"), "mode", "The upgrade mode")
+}
+
+func (c *upgradeCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (s *CompletionSuite) TestCompleteFlagValue(c *gc.C) {
+	super := s.newSuper()
+	super.Register(&upgradeCommand{})
+	c.Check(s.complete(c, super, "3", "jujutest", "upgrade", "--mode", "s"), gc.DeepEquals, []string{"safe", "staged"})
+	c.Check(s.complete(c, super, "3", "jujutest", "upgrade", "--mode", ""), gc.DeepEquals, []string{"fast", "safe", "staged"})
+	c.Check(s.complete(c, super, "2", "jujutest", "upgrade", ""), gc.HasLen, 0)
+
+	// The command's arguments are completed at run time, so that the
+	// values of the flag are offered.
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"completion", "bash"})
+	c.Assert(code, gc.Equals, 0)
+	c.Check(cmdtesting.Stdout(ctx), gc.Matches, `(?s).*    case "\$1" in\n    "remove-unit"\|"upgrade"\) return 0 ;;.*`)
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/juju/gnuflag"
+)
+
+const configDoc = `
+Default flag values are stored in the user config file and are used for
+flags that are not given on the command line.
+
+The "show" action displays the defaults for every command, or for the named
+command only. The "set" action validates the given flags against the command
+before storing them, and the "unset" action removes them. Commands below
+others are named by their path, e.g. "storage list".
+`
+
+const configExamples = `
+    {{.Prefix}} config set status --format json
+    {{.Prefix}} config show status
+    {{.Prefix}} config unset status format
+    {{.Prefix}} config set storage list --format yaml
+`
+
+// configCommand views and modifies the default flag values persisted in the
+// user config file.
+type configCommand struct {
+	CommandBase
+	super *SuperCommand
+
+	action  string
+	command string
+	values  map[string]string
+	names   []string
+}
+
+func (c *configCommand) Info() *Info {
+	prefix := c.super.Name
+	if c.super.usagePrefix != "" {
+		prefix = c.super.usagePrefix + " " + prefix
+	}
+	return &Info{
+		Name:               "config",
+		Args:               "[show [<command>] | set <command> --<flag> <value>... | unset <command> <flag>...]",
+		Purpose:            "View and change the default flag values of commands.",
+		NonInteractiveSafe: true,
+		Doc:                strings.TrimSpace(configDoc),
+		Examples:           strings.Replace(configExamples, "{{.Prefix}}", prefix, -1),
+	}
+}
+
+func (c *configCommand) AllowInterspersedFlags() bool {
+	return false
+}
+
+func (c *configCommand) Init(args []string) error {
+	if len(args) == 0 {
+		c.action = "show"
+		return nil
+	}
+	c.action, args = args[0], args[1:]
+	switch c.action {
+	case "show":
+		if len(args) > 0 {
+			var err error
+			if _, c.command, args, err = c.lookup(args); err != nil {
+				return err
+			}
+		}
+		return CheckEmpty(args)
+	case "set":
+		if len(args) == 0 {
+			return fmt.Errorf("no command specified")
+		}
+		command, path, args, err := c.lookup(args)
+		if err != nil {
+			return err
+		}
+		c.command = path
+		if c.values, err = c.parseValues(command, args); err != nil {
+			return err
+		}
+		if len(c.values) == 0 {
+			return fmt.Errorf("no %ss specified", c.super.FlagKnownAs)
+		}
+		return nil
+	case "unset":
+		if len(args) == 0 {
+			return fmt.Errorf("no command specified")
+		}
+		// Defaults of commands that no longer exist can be removed too.
+		c.command, c.names = args[0], args[1:]
+		if _, path, rest, err := c.lookup(args); err == nil {
+			c.command, c.names = path, rest
+		}
+		if len(c.names) == 0 {
+			return fmt.Errorf("no %ss specified", c.super.FlagKnownAs)
+		}
+		return nil
+	}
+	return fmt.Errorf("unrecognized action %q", c.action)
+}
+
+// lookup returns the subcommand named at the start of args, which names
+// a command below nested SuperCommands by its path, e.g. "storage list",
+// along with its path and the remaining arguments.
+func (c *configCommand) lookup(args []string) (Command, string, []string, error) {
+	super := c.super
+	var path []string
+	for {
+		name := args[0]
+		args = args[1:]
+		ref, ok := super.subcmds[name]
+		if !ok || ref.alias != "" {
+			return nil, "", nil, fmt.Errorf("unrecognized command: %s %s", super.Name, name)
+		}
+		path = append(path, name)
+		sc, ok := ref.command.(*SuperCommand)
+		if !ok {
+			return ref.command, strings.Join(path, " "), args, nil
+		}
+		if len(args) == 0 {
+			return nil, "", nil, fmt.Errorf("no command specified below %q", strings.Join(path, " "))
+		}
+		super = sc
+	}
+}
+
+// parseValues validates args against the flags of command, returning the
+// values of the flags given.
+func (c *configCommand) parseValues(command Command, args []string) (map[string]string, error) {
+	f := gnuflag.NewFlagSetWithFlagKnownAs(command.Info().Name, gnuflag.ContinueOnError, FlagAlias(command, "flag"))
+	f.SetOutput(ioutil.Discard)
+	command.SetFlags(f)
+	values, rest, err := parseCommonFlags(f, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range rest {
+		if strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("%s provided but not defined: %s", f.FlagKnownAs, arg)
+		}
+		return nil, fmt.Errorf("unrecognized args: %q", rest)
+	}
+	return values, nil
+}
+
+func (c *configCommand) Run(ctx *Context) error {
+	filename := ctx.expandHome(c.super.userConfigFilename)
+	cfg, err := readUserConfig(filename)
+	if err != nil {
+		return err
+	}
+	switch c.action {
+	case "set":
+		for name, value := range c.values {
+			cfg.set(c.command, name, value)
+		}
+		return cfg.write(filename)
+	case "unset":
+		for _, name := range c.names {
+			cfg.unset(c.command, name)
+		}
+		return cfg.write(filename)
+	}
+	commands := make([]string, 0, len(cfg))
+	for command := range cfg {
+		if c.command == "" || command == c.command {
+			commands = append(commands, command)
+		}
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		names := make([]string, 0, len(cfg[command]))
+		for name := range cfg[command] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			dash := "--"
+			if len(name) == 1 {
+				dash = "-"
+			}
+			fmt.Fprintf(ctx.Stdout, "%s %s%s=%s\n", command, dash, name, cfg[command][name])
+		}
+	}
+	return nil
+}
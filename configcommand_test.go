@@ -0,0 +1,241 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ConfigCommandSuite struct {
+	testing.IsolationSuite
+
+	filename string
+}
+
+var _ = gc.Suite(&ConfigCommandSuite{})
+
+func (s *ConfigCommandSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.filename = filepath.Join(c.MkDir(), "juju", "config.yaml")
+}
+
+func (s *ConfigCommandSuite) run(c *gc.C, args ...string) (*cmd.Context, int) {
+	return s.runWithEnv(c, nil, args...)
+}
+
+func (s *ConfigCommandSuite) runWithEnv(c *gc.C, env map[string]string, args ...string) (*cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:               "juju",
+		UserConfigFilename: s.filename,
+		FlagEnvPrefix:      "JUJU_",
+		TimeFlag:           true,
+	})
+	sc.Register(&TestCommand{Name: "blah"})
+	sc.Register(&modelCommand{})
+	sc.Register(&countCommand{})
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:        "storage",
+		UsagePrefix: "juju",
+		Purpose:     "manage storage",
+	})
+	storage.Register(&countCommand{})
+	sc.Register(storage)
+	ctx := cmdtesting.Context(c)
+	for key, value := range env {
+		ctx.Setenv(key, value)
+	}
+	return ctx, cmd.Main(sc, ctx, args)
+}
+
+func (s *ConfigCommandSuite) TestNotRegisteredWithoutFilename(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"config"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR unrecognized command: juju config\n")
+}
+
+func (s *ConfigCommandSuite) TestSetAndShow(c *gc.C) {
+	_, code := s.run(c, "config", "set", "blah", "--option", "foo")
+	c.Assert(code, gc.Equals, 0)
+	_, code = s.run(c, "config", "set", "status", "-m", "prod")
+	c.Assert(code, gc.Equals, 0)
+
+	content, err := ioutil.ReadFile(s.filename)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, `
+blah:
+  option: foo
+status:
+  m: prod
+`[1:])
+
+	ctx, code := s.run(c, "config")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "blah --option=foo\nstatus -m=prod\n")
+
+	ctx, code = s.run(c, "config", "show", "status")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "status -m=prod\n")
+}
+
+func (s *ConfigCommandSuite) TestUnset(c *gc.C) {
+	_, code := s.run(c, "config", "set", "blah", "--option", "foo")
+	c.Assert(code, gc.Equals, 0)
+	_, code = s.run(c, "config", "unset", "blah", "option")
+	c.Assert(code, gc.Equals, 0)
+
+	ctx, code := s.run(c, "config", "show")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+}
+
+func (s *ConfigCommandSuite) TestInitErrors(c *gc.C) {
+	for i, test := range []struct {
+		args []string
+		err  string
+	}{{
+		args: []string{"config", "set", "blah", "--unknown", "x"},
+		err:  "flag provided but not defined: --unknown",
+	}, {
+		args: []string{"config", "set", "blah", "--option"},
+		err:  "flag needs an argument: --option",
+	}, {
+		args: []string{"config", "set", "blah", "extra"},
+		err:  `unrecognized args: \["extra"\]`,
+	}, {
+		args: []string{"config", "set", "blah"},
+		err:  "no flags specified",
+	}, {
+		args: []string{"config", "set", "missing", "--option", "x"},
+		err:  "unrecognized command: juju missing",
+	}, {
+		args: []string{"config", "show", "missing"},
+		err:  "unrecognized command: juju missing",
+	}, {
+		args: []string{"config", "unset", "blah"},
+		err:  "no flags specified",
+	}, {
+		args: []string{"config", "frob"},
+		err:  `unrecognized action "frob"`,
+	}} {
+		c.Logf("test %d: %q", i, test.args)
+		ctx, code := s.run(c, test.args...)
+		c.Check(code, gc.Equals, 2)
+		c.Check(cmdtesting.Stderr(ctx), gc.Matches, "ERROR "+test.err+"\n")
+	}
+}
+
+func (s *ConfigCommandSuite) TestDefaultsApplied(c *gc.C) {
+	_, code := s.run(c, "config", "set", "blah", "--option", "foo")
+	c.Assert(code, gc.Equals, 0)
+
+	ctx, code := s.run(c, "blah")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "foo\n")
+}
+
+func (s *ConfigCommandSuite) TestPrecedence(c *gc.C) {
+	_, code := s.run(c, "config", "set", "blah", "--option", "config")
+	c.Assert(code, gc.Equals, 0)
+	env := map[string]string{"JUJU_OPTION": "env"}
+
+	ctx, code := s.runWithEnv(c, env, "blah")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "env\n")
+
+	ctx, code = s.runWithEnv(c, env, "blah", "--option", "cli")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "cli\n")
+}
+
+func (s *ConfigCommandSuite) TestDefaultOverridesDynamicDefault(c *gc.C) {
+	_, code := s.run(c, "config", "set", "status", "-m", "prod")
+	c.Assert(code, gc.Equals, 0)
+
+	ctx, code := s.run(c, "status")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "prod\n")
+}
+
+func (s *ConfigCommandSuite) TestInvalidDefault(c *gc.C) {
+	ctx, code := s.runWithEnv(c, map[string]string{"JUJU_COUNT": "many"}, "count")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Matches, `ERROR invalid value "many" for flag --count from \$JUJU_COUNT: .*\n`)
+}
+
+func (s *ConfigCommandSuite) TestCommonFlagsNotDefaulted(c *gc.C) {
+	env := map[string]string{
+		"JUJU_HELP": "true",
+		"JUJU_TIME": "maybe",
+	}
+	ctx, code := s.runWithEnv(c, env, "count")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "count: 1\n")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+type countCommand struct {
+	cmd.CommandBase
+	count int
+}
+
+func (c *countCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "count", Purpose: "count something"}
+}
+
+func (c *countCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.IntVar(&c.count, "count", 1, "how many")
+}
+
+func (c *countCommand) Run(ctx *cmd.Context) error {
+	fmt.Fprintf(ctx.Stdout, "count: %d\n", c.count)
+	return nil
+}
+
+func (s *ConfigCommandSuite) TestNestedCommand(c *gc.C) {
+	_, code := s.run(c, "config", "set", "storage", "count", "--count", "3")
+	c.Assert(code, gc.Equals, 0)
+	ctx, code := s.run(c, "config", "show", "storage", "count")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "storage count --count=3\n")
+
+	// The default only applies to the nested command.
+	ctx, code = s.run(c, "storage", "count")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "count: 3\n")
+	ctx, code = s.run(c, "count")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "count: 1\n")
+
+	// The environment applies to nested commands too.
+	ctx, code = s.runWithEnv(c, map[string]string{"JUJU_COUNT": "5"}, "storage", "count")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "count: 5\n")
+
+	_, code = s.run(c, "config", "unset", "storage", "count", "count")
+	c.Assert(code, gc.Equals, 0)
+	ctx, code = s.run(c, "storage", "count")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "count: 1\n")
+}
+
+func (s *ConfigCommandSuite) TestNestedCommandNotFound(c *gc.C) {
+	ctx, code := s.run(c, "config", "set", "storage", "list", "--count", "3")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR unrecognized command: storage list\n")
+
+	ctx, code = s.run(c, "config", "set", "storage")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR no command specified below \"storage\"\n")
+}
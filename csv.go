@@ -0,0 +1,234 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// FormatCSV writes out value as RFC 4180 comma separated values with a
+// header row. See FormatCSVColumns for the values accepted.
+func FormatCSV(writer io.Writer, value interface{}) error {
+	return formatDelimited(writer, value, ',', nil)
+}
+
+// FormatTSV writes out value as tab separated values with a header row.
+// See FormatCSVColumns for the values accepted.
+func FormatTSV(writer io.Writer, value interface{}) error {
+	return formatDelimited(writer, value, '\t', nil)
+}
+
+// FormatCSVColumns returns a Formatter writing comma separated values
+// with only the comma separated columns given, in that order, as in
+// "--format csv=name,status".
+//
+// The value is converted as it would be by FormatJson. A slice gives a row
+// per element, and a map a row per key, in a "key" column. The fields of
+// objects become columns, in the order they first appear; other values are
+// written in a "value" column. Nested objects and lists are written as
+// JSON.
+func FormatCSVColumns(columns string) (Formatter, error) {
+	return delimitedColumns(',', columns)
+}
+
+// FormatTSVColumns is like FormatCSVColumns, but returns a Formatter
+// writing tab separated values.
+func FormatTSVColumns(columns string) (Formatter, error) {
+	return delimitedColumns('\t', columns)
+}
+
+func delimitedColumns(comma rune, columns string) (Formatter, error) {
+	selected, err := parseColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	return func(writer io.Writer, value interface{}) error {
+		return formatDelimited(writer, value, comma, selected)
+	}, nil
+}
+
+// parseColumns returns the columns in the comma separated list given as
+// the argument of a format.
+func parseColumns(columns string) ([]string, error) {
+	var selected []string
+	for _, column := range strings.Split(columns, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			selected = append(selected, column)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no columns specified")
+	}
+	return selected, nil
+}
+
+// formatDelimited writes value as a table of delimited values, with the
+// given columns if not empty.
+func formatDelimited(writer io.Writer, value interface{}, comma rune, columns []string) error {
+	if value == nil {
+		return nil
+	}
+	columns, records, err := projectColumns(value, columns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w := csv.NewWriter(writer)
+	w.Comma = comma
+	if err := w.Write(columns); err != nil {
+		return errors.Trace(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	w.Flush()
+	return errors.Trace(w.Error())
+}
+
+// projectColumns converts value to a table, returning its columns and a
+// record of cells for each row. If columns is not empty, the table has
+// only those columns, in that order.
+func projectColumns(value interface{}, columns []string) ([]string, [][]string, error) {
+	found, rows, err := tableRows(value)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if columns == nil {
+		columns = found
+	} else if len(rows) > 0 {
+		known := make(map[string]bool, len(found))
+		for _, column := range found {
+			known[column] = true
+		}
+		for _, column := range columns {
+			if !known[column] {
+				return nil, nil, errors.Errorf("unknown column %q, expected one of %s", column, strings.Join(found, ", "))
+			}
+		}
+	}
+	records := make([][]string, len(rows))
+	for i, row := range rows {
+		records[i] = make([]string, len(columns))
+		for j, column := range columns {
+			records[i][j] = row[column]
+		}
+	}
+	return columns, records, nil
+}
+
+// tableRows converts value to rows of cells, keyed by column, along with
+// the columns in the order they first appear.
+func tableRows(value interface{}) ([]string, []map[string]string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	var (
+		keys     []string
+		elements []json.RawMessage
+	)
+	switch firstByte(data) {
+	case '[':
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+	case '{':
+		if keys, elements, err = orderedFields(data); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+	default:
+		elements = []json.RawMessage{data}
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	addColumn := func(column string) {
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	if keys != nil {
+		addColumn("key")
+	}
+	rows := make([]map[string]string, len(elements))
+	for i, element := range elements {
+		row := make(map[string]string)
+		if keys != nil {
+			row["key"] = keys[i]
+		}
+		if firstByte(element) == '{' {
+			fields, values, err := orderedFields(element)
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			for j, field := range fields {
+				addColumn(field)
+				row[field] = cellValue(values[j])
+			}
+		} else {
+			addColumn("value")
+			row["value"] = cellValue(element)
+		}
+		rows[i] = row
+	}
+	return columns, rows, nil
+}
+
+// orderedFields returns the fields of the JSON object in data, and their
+// values, in the order they appear.
+func orderedFields(data []byte) ([]string, []json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	var (
+		fields []string
+		values []json.RawMessage
+	)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		fields = append(fields, token.(string))
+		values = append(values, value)
+	}
+	return fields, values, nil
+}
+
+// cellValue returns the text of a cell holding the JSON value in data.
+// Strings are unquoted and null is empty; other values are left as JSON.
+func cellValue(data json.RawMessage) string {
+	switch firstByte(data) {
+	case 'n':
+		return ""
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err == nil {
+			return s
+		}
+	}
+	return string(data)
+}
+
+// firstByte returns the first byte of the JSON value in data.
+func firstByte(data []byte) byte {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
+}
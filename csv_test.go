@@ -0,0 +1,133 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type CSVSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&CSVSuite{})
+
+type unitStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Age    int    `json:"age,omitempty"`
+	Ports  []int  `json:"ports,omitempty"`
+}
+
+var units = []unitStatus{
+	{Name: "mysql/0", Status: "active", Age: 3, Ports: []int{3306}},
+	{Name: "wordpress/0", Status: "blocked, waiting for \"db\""},
+}
+
+func (s *CSVSuite) TestFormatCSV(c *gc.C) {
+	for i, test := range []struct {
+		value  interface{}
+		output string
+	}{{
+		value: units,
+		output: `
+name,status,age,ports
+mysql/0,active,3,[3306]
+wordpress/0,"blocked, waiting for ""db""",,
+`[1:],
+	}, {
+		value: map[string]unitStatus{"b": {Name: "x"}, "a": {Status: "idle"}},
+		output: `
+key,name,status
+a,,idle
+b,x,
+`[1:],
+	}, {
+		value:  map[string]int{"mysql": 1, "wordpress": 2},
+		output: "key,value\nmysql,1\nwordpress,2\n",
+	}, {
+		value:  []string{"a", "b"},
+		output: "value\na\nb\n",
+	}, {
+		value:  []string{},
+		output: "\n",
+	}, {
+		value:  "hello",
+		output: "value\nhello\n",
+	}, {
+		value:  nil,
+		output: "",
+	}} {
+		c.Logf("test %d", i)
+		var buf bytes.Buffer
+		c.Check(cmd.FormatCSV(&buf, test.value), gc.IsNil)
+		c.Check(buf.String(), gc.Equals, test.output)
+	}
+}
+
+func (s *CSVSuite) TestFormatTSV(c *gc.C) {
+	var buf bytes.Buffer
+	c.Assert(cmd.FormatTSV(&buf, units), gc.IsNil)
+	c.Assert(buf.String(), gc.Equals, ""+
+		"name\tstatus\tage\tports\n"+
+		"mysql/0\tactive\t3\t[3306]\n"+
+		"wordpress/0\t\"blocked, waiting for \"\"db\"\"\"\t\t\n")
+}
+
+func (s *CSVSuite) TestFormatCSVColumns(c *gc.C) {
+	formatter, err := cmd.FormatCSVColumns("status, name")
+	c.Assert(err, gc.IsNil)
+	var buf bytes.Buffer
+	c.Assert(formatter(&buf, units[:1]), gc.IsNil)
+	c.Assert(buf.String(), gc.Equals, "status,name\nactive,mysql/0\n")
+
+	err = formatter(&buf, []string{"a"})
+	c.Assert(err, gc.ErrorMatches, `unknown column "status", expected one of value`)
+
+	_, err = cmd.FormatCSVColumns(",")
+	c.Assert(err, gc.ErrorMatches, "no columns specified")
+}
+
+func (s *CSVSuite) TestFormatFlag(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		code   int
+		output string
+		err    string
+	}{{
+		args:   []string{"--format", "csv"},
+		output: "name,status,age,ports\nmysql/0,active,3,[3306]\n",
+	}, {
+		args:   []string{"--format", "csv=name,age"},
+		output: "name,age\nmysql/0,3\n",
+	}, {
+		args:   []string{"--format=tsv=age,name"},
+		output: "age\tname\n3\tmysql/0\n",
+	}, {
+		args: []string{"--format", "yaml=name"},
+		code: 2,
+		err:  `ERROR invalid value "yaml=name" for flag --format: format "yaml" does not take an argument` + "\n",
+	}, {
+		args: []string{"--format", "csv="},
+		code: 2,
+		err:  `ERROR invalid value "csv=" for flag --format: invalid csv format: no columns specified` + "\n",
+	}, {
+		args: []string{"--format", "csv=name,size"},
+		code: 1,
+		err:  `ERROR unknown column "size", expected one of name, status, age, ports` + "\n",
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(&OutputCommand{value: units[:1]}, ctx, test.args)
+		c.Check(code, gc.Equals, test.code)
+		c.Check(cmdtesting.Stdout(ctx), gc.Equals, test.output)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.err)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/juju/gnuflag"
+)
+
+// DynamicDefault is shown in place of the default value in help output for
+// flags whose default is provided when the command line is parsed.
+const DynamicDefault = "<dynamic>"
+
+// DefaultProvider returns the default value of a flag, in the form accepted
+// by the flag value's Set method.
+type DefaultProvider func() (string, error)
+
+// DynamicDefaultValue wraps a gnuflag.Value so that its default is provided
+// by a function evaluated when the command line is parsed, rather than when
+// the flag is added to the FlagSet. This avoids baking stale values such as
+// the current model or today's date into the FlagSet.
+type DynamicDefaultValue struct {
+	gnuflag.Value
+
+	// Source describes where the default comes from, e.g. "current model".
+	// It is shown in generated documentation.
+	Source string
+
+	provide  DefaultProvider
+	set      bool
+	resolved bool
+}
+
+// NewDynamicDefault returns a value that is passed to the gnuflag.FlagSet
+// Var function in place of value.
+//
+//	f.Var(cmd.NewDynamicDefault(&someValue, "current model", currentModel), "model", "help")
+func NewDynamicDefault(value gnuflag.Value, source string, provide DefaultProvider) *DynamicDefaultValue {
+	return &DynamicDefaultValue{
+		Value:   value,
+		Source:  source,
+		provide: provide,
+	}
+}
+
+// Set implements gnuflag.Value.
+func (v *DynamicDefaultValue) Set(s string) error {
+	v.set = true
+	return v.Value.Set(s)
+}
+
+// String implements gnuflag.Value. Before the default has been provided it
+// returns DynamicDefault.
+func (v *DynamicDefaultValue) String() string {
+	if !v.set && !v.resolved {
+		return DynamicDefault
+	}
+	return v.Value.String()
+}
+
+// IsBoolFlag reports whether the wrapped value is a boolean flag, so that
+// gnuflag does not require an argument for it.
+func (v *DynamicDefaultValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// ResolveDefaults sets every flag in f that has a dynamic default and was
+// not given on the command line to the value returned by its provider.
+// Main and SuperCommand call it after parsing flags; other code that parses
+// a command's flags itself should call it before the command's Init.
+func ResolveDefaults(f *gnuflag.FlagSet) error {
+	var err error
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		v, ok := flag.Value.(*DynamicDefaultValue)
+		if !ok || v.set || v.resolved || err != nil {
+			return
+		}
+		var value string
+		if value, err = v.provide(); err != nil {
+			err = fmt.Errorf("cannot determine default for %s %s: %v", f.FlagKnownAs, flag.Name, err)
+			return
+		}
+		if err = v.Value.Set(value); err != nil {
+			err = fmt.Errorf("invalid default %q for %s %s: %v", value, f.FlagKnownAs, flag.Name, err)
+			return
+		}
+		v.resolved = true
+	})
+	return err
+}
+
+// defaultSource returns the description of where the flag's default comes
+// from, if it is provided dynamically.
+func defaultSource(flag *gnuflag.Flag) string {
+	if v, ok := flag.Value.(*DynamicDefaultValue); ok {
+		return v.Source
+	}
+	return ""
+}
+
+// Placeholders shown in generated documentation in place of flag defaults
+// that depend on the environment the command runs in.
+const (
+	UserHomeDefault = "<user home>"
+	UserNameDefault = "<user name>"
+)
+
+// EnvironmentDefaultValue wraps a gnuflag.Value whose default is derived
+// from the environment, such as a path under the user's home directory or
+// the user's name. Help output shows the actual default, but generated
+// documentation shows Placeholder instead, so that details of the machine
+// the documentation was generated on are not published.
+type EnvironmentDefaultValue struct {
+	gnuflag.Value
+
+	// Placeholder is shown in generated documentation in place of the
+	// default, e.g. UserHomeDefault.
+	Placeholder string
+}
+
+// NewEnvironmentDefault returns a value that is passed to the
+// gnuflag.FlagSet Var function in place of value.
+//
+//	f.Var(cmd.NewEnvironmentDefault(&dataDir, cmd.UserHomeDefault+"/.local/share/juju"), "data-dir", "help")
+func NewEnvironmentDefault(value gnuflag.Value, placeholder string) *EnvironmentDefaultValue {
+	return &EnvironmentDefaultValue{
+		Value:       value,
+		Placeholder: placeholder,
+	}
+}
+
+// IsBoolFlag reports whether the wrapped value is a boolean flag, so that
+// gnuflag does not require an argument for it.
+func (v *EnvironmentDefaultValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// defaultPlaceholder returns the placeholder to document in place of the
+// flag's default, if the default is derived from the environment.
+func defaultPlaceholder(flag *gnuflag.Flag) string {
+	if v, ok := flag.Value.(*EnvironmentDefaultValue); ok {
+		return v.Placeholder
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type DefaultsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&DefaultsSuite{})
+
+type modelCommand struct {
+	cmd.CommandBase
+	model   string
+	calls   int
+	current string
+	err     error
+}
+
+func (c *modelCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "status", Purpose: "show status"}
+}
+
+func (c *modelCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.Var(cmd.NewDynamicDefault(newStringValue(&c.model), "current model", func() (string, error) {
+		c.calls++
+		return c.current, c.err
+	}), "m", "the model to use")
+}
+
+func (c *modelCommand) Run(ctx *cmd.Context) error {
+	_, err := ctx.Stdout.Write([]byte(c.model + "\n"))
+	return err
+}
+
+type stringValue string
+
+func newStringValue(target *string) *stringValue {
+	return (*stringValue)(target)
+}
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string {
+	return string(*v)
+}
+
+func (s *DefaultsSuite) TestResolvedAtParseTime(c *gc.C) {
+	command := &modelCommand{current: "prod"}
+	f := cmdtesting.NewFlagSet()
+	command.SetFlags(f)
+	c.Assert(command.calls, gc.Equals, 0)
+	command.current = "staging"
+	c.Assert(f.Parse(true, nil), gc.IsNil)
+	c.Assert(cmd.ResolveDefaults(f), gc.IsNil)
+	c.Assert(command.model, gc.Equals, "staging")
+	c.Assert(command.calls, gc.Equals, 1)
+
+	// Resolving again does not call the provider a second time.
+	c.Assert(cmd.ResolveDefaults(f), gc.IsNil)
+	c.Assert(command.calls, gc.Equals, 1)
+}
+
+func (s *DefaultsSuite) TestNotResolvedWhenSet(c *gc.C) {
+	command := &modelCommand{current: "prod"}
+	err := cmdtesting.InitCommand(command, []string{"-m", "dev"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(command.model, gc.Equals, "dev")
+	c.Assert(command.calls, gc.Equals, 0)
+}
+
+func (s *DefaultsSuite) TestProviderError(c *gc.C) {
+	command := &modelCommand{err: errors.New("no current model")}
+	err := cmdtesting.InitCommand(command, nil)
+	c.Assert(err, gc.ErrorMatches, "cannot determine default for flag m: no current model")
+}
+
+func (s *DefaultsSuite) TestMain(c *gc.C) {
+	command := &modelCommand{current: "prod"}
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(command, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "prod\n")
+}
+
+func (s *DefaultsSuite) TestSuperCommand(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	command := &modelCommand{current: "prod"}
+	sc.Register(command)
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"status"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "prod\n")
+
+	// Asking for help does not evaluate the default.
+	command = &modelCommand{current: "prod"}
+	sc = cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	sc.Register(command)
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(sc, ctx, []string{"status", "--help"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.calls, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `(?s).*-m  \(= <dynamic>\)\n    the model to use.*`)
+}
+
+func (s *DefaultsSuite) TestMarkdownShowsSource(c *gc.C) {
+	var buf bytes.Buffer
+	err := cmd.PrintMarkdown(&buf, &modelCommand{}, cmd.MarkdownOptions{})
+	c.Assert(err, gc.IsNil)
+	c.Assert(buf.String(), gc.Matches, "(?s).*\\| `-m` \\| &lt;dynamic&gt; \\(current model\\) \\| the model to use \\|.*")
+}
+
+func (s *DefaultsSuite) TestBoolFlag(c *gc.C) {
+	var b bool
+	f := cmdtesting.NewFlagSet()
+	f.Var(cmd.NewDynamicDefault(newBoolValue(&b), "today", func() (string, error) {
+		return "false", nil
+	}), "force", "")
+	c.Assert(f.Parse(true, []string{"--force"}), gc.IsNil)
+	c.Assert(cmd.ResolveDefaults(f), gc.IsNil)
+	c.Assert(b, gc.Equals, true)
+}
+
+type boolValue bool
+
+func newBoolValue(target *bool) *boolValue {
+	return (*boolValue)(target)
+}
+
+func (v *boolValue) Set(s string) error {
+	b, err := cmd.ParseBool(s, cmd.Strict)
+	*v = boolValue(b)
+	return err
+}
+
+func (v *boolValue) String() string {
+	if *v {
+		return "true"
+	}
+	return "false"
+}
+
+func (v *boolValue) IsBoolFlag() bool {
+	return true
+}
+
+type homeCommand struct {
+	cmd.CommandBase
+	dataDir string
+	force   bool
+}
+
+func (c *homeCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "backup", Purpose: "back up client data"}
+}
+
+func (c *homeCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.dataDir = "/home/alice/.local/share/juju"
+	f.Var(cmd.NewEnvironmentDefault(newStringValue(&c.dataDir), cmd.UserHomeDefault+"/.local/share/juju"), "data-dir", "the data directory")
+	f.Var(cmd.NewEnvironmentDefault(newBoolValue(&c.force), "<depends>"), "force", "")
+}
+
+func (c *homeCommand) Run(ctx *cmd.Context) error {
+	_, err := ctx.Stdout.Write([]byte(c.dataDir + "\n"))
+	return err
+}
+
+func (s *DefaultsSuite) TestMarkdownShowsPlaceholder(c *gc.C) {
+	var buf bytes.Buffer
+	err := cmd.PrintMarkdown(&buf, &homeCommand{}, cmd.MarkdownOptions{})
+	c.Assert(err, gc.IsNil)
+	c.Assert(buf.String(), gc.Matches, "(?s).*\\| `--data-dir` \\| &lt;user home&gt;/.local/share/juju \\| the data directory \\|.*")
+	c.Assert(buf.String(), gc.Not(gc.Matches), "(?s).*/home/alice.*")
+}
+
+func (s *DefaultsSuite) TestHelpShowsEnvironmentDefault(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&homeCommand{}, ctx, []string{"--help"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `(?s).*--data-dir  \(= /home/alice/.local/share/juju\).*`)
+}
+
+func (s *DefaultsSuite) TestEnvironmentDefaultSet(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&homeCommand{}, ctx, []string{"--force", "--data-dir", "/srv/juju"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "/srv/juju\n")
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/juju/clock"
+)
+
+// Dependencies holds the services that the framework supplies to commands
+// registered with RegisterFunc. Commands should use these rather than
+// package level globals, so that tests can substitute them by setting the
+// Context's Clock, HTTPClient and FileSystem fields.
+type Dependencies struct {
+	// Clock is used for anything time related.
+	Clock clock.Clock
+
+	// HTTPClient is used for any HTTP requests.
+	HTTPClient *http.Client
+
+	// FileSystem provides access to files, with relative paths resolved
+	// against the Context's Dir.
+	FileSystem FileSystem
+}
+
+// NewCommandFunc creates a command that uses the given dependencies.
+// It is called with the dependencies derived from the Context each time
+// the command is dispatched to, and with the default dependencies for the
+// command's Info, help and documentation.
+type NewCommandFunc func(deps *Dependencies) Command
+
+// FileSystem is the subset of filesystem operations supplied to commands
+// through Dependencies.
+type FileSystem interface {
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+
+	// Create creates or truncates the named file for writing.
+	Create(name string) (io.WriteCloser, error)
+
+	// Stat returns information about the named file.
+	Stat(name string) (os.FileInfo, error)
+
+	// MkdirAll creates the named directory along with any parents.
+	MkdirAll(name string, perm os.FileMode) error
+
+	// Remove removes the named file or empty directory.
+	Remove(name string) error
+}
+
+// newDependencies returns the dependencies derived from the given context.
+func newDependencies(ctx *Context) Dependencies {
+	return Dependencies{
+		Clock:      ctx.clock(),
+		HTTPClient: ctx.httpClient(),
+		FileSystem: ctx.fileSystem(),
+	}
+}
+
+// dispatchDependencies returns the dependencies of a command dispatched
+// to with ctx. As --no-remote may be given after the command is selected,
+// the HTTP client checks for it when each request is made.
+func dispatchDependencies(ctx *Context) Dependencies {
+	deps := newDependencies(ctx)
+	client := *deps.HTTPClient
+	client.Transport = remoteTransport{ctx: ctx, transport: client.Transport}
+	deps.HTTPClient = &client
+	return deps
+}
+
+// remoteTransport is an http.RoundTripper that refuses all requests once
+// --no-remote is in effect for the context, and otherwise passes them on
+// to transport, or to http.DefaultTransport if it is nil.
+type remoteTransport struct {
+	ctx       *Context
+	transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t remoteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.ctx.noRemote {
+		return nil, ErrNoRemote
+	}
+	if t.transport == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// osFileSystem implements FileSystem using the os package, resolving paths
+// relative to the context.
+type osFileSystem struct {
+	ctx *Context
+}
+
+// Open implements FileSystem.
+func (f osFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(f.ctx.AbsPath(name))
+}
+
+// Create implements FileSystem.
+func (f osFileSystem) Create(name string) (io.WriteCloser, error) {
+	return os.Create(f.ctx.AbsPath(name))
+}
+
+// Stat implements FileSystem.
+func (f osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(f.ctx.AbsPath(name))
+}
+
+// MkdirAll implements FileSystem.
+func (f osFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	return os.MkdirAll(f.ctx.AbsPath(name), perm)
+}
+
+// Remove implements FileSystem.
+func (f osFileSystem) Remove(name string) error {
+	return os.Remove(f.ctx.AbsPath(name))
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type DependenciesSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&DependenciesSuite{})
+
+type depsCommand struct {
+	cmd.CommandBase
+	deps  *cmd.Dependencies
+	clock clock.Clock
+	now   time.Time
+}
+
+func (c *depsCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "deps", Purpose: "use the dependencies", Aliases: []string{"dependencies"}}
+}
+
+func (c *depsCommand) Run(ctx *cmd.Context) error {
+	c.now = c.clock.Now()
+	w, err := c.deps.FileSystem.Create("out")
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = w.Write([]byte("hello"))
+	return err
+}
+
+func (s *DependenciesSuite) TestRegisterFunc(c *gc.C) {
+	var command *depsCommand
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.RegisterFunc(func(deps *cmd.Dependencies) cmd.Command {
+		// The clock is copied, so it must come from the Context the
+		// command is dispatched with.
+		command = &depsCommand{deps: deps, clock: deps.Clock}
+		return command
+	})
+
+	// The command registered has the default dependencies.
+	c.Assert(command.clock, gc.Equals, clock.WallClock)
+	c.Assert(command.deps.HTTPClient, gc.Equals, http.DefaultClient)
+
+	for _, name := range []string{"deps", "dependencies"} {
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		ctx := cmdtesting.Context(c)
+		ctx.Clock = testclock.NewClock(now)
+		code := cmd.Main(sc, ctx, []string{name})
+		c.Assert(code, gc.Equals, 0)
+		c.Assert(command.now, gc.Equals, now)
+
+		data, err := ioutil.ReadFile(filepath.Join(ctx.Dir, "out"))
+		c.Assert(err, gc.IsNil)
+		c.Assert(string(data), gc.Equals, "hello")
+	}
+}
+
+type httpCommand struct {
+	cmd.CommandBase
+	deps *cmd.Dependencies
+}
+
+func (c *httpCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "fetch", Purpose: "fetch something"}
+}
+
+func (c *httpCommand) Run(ctx *cmd.Context) error {
+	_, err := c.deps.HTTPClient.Get("http://0.1.2.3/")
+	return err
+}
+
+func (s *DependenciesSuite) TestNoRemote(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", NoRemoteFlag: true})
+	sc.RegisterFunc(func(deps *cmd.Dependencies) cmd.Command {
+		return &httpCommand{deps: deps}
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"--no-remote", "fetch"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(ctx.NoRemote(), gc.Equals, true)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Matches, `ERROR Get "http://0.1.2.3/": network access disabled by --no-remote\n`)
+}
+
+func (s *DependenciesSuite) TestNoRemoteNotAddedByDefault(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.RegisterFunc(func(deps *cmd.Dependencies) cmd.Command {
+		return &httpCommand{deps: deps}
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"--no-remote", "fetch"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR flag provided but not defined: --no-remote\n")
+}
+
+type fakeTransport struct{}
+
+func (fakeTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("fake transport")
+}
+
+type fakeFileSystem struct {
+	cmd.FileSystem
+	files map[string]*bytes.Buffer
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (f *fakeFileSystem) Create(name string) (io.WriteCloser, error) {
+	f.files[name] = &bytes.Buffer{}
+	return nopWriteCloser{f.files[name]}, nil
+}
+
+func (s *DependenciesSuite) TestContextDependencies(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.RegisterFunc(func(deps *cmd.Dependencies) cmd.Command {
+		return &depsCommand{deps: deps, clock: deps.Clock}
+	})
+	sc.RegisterFunc(func(deps *cmd.Dependencies) cmd.Command {
+		return &httpCommand{deps: deps}
+	})
+
+	fs := &fakeFileSystem{files: make(map[string]*bytes.Buffer)}
+	ctx := cmdtesting.Context(c)
+	ctx.FileSystem = fs
+	code := cmd.Main(sc, ctx, []string{"deps"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(fs.files["out"].String(), gc.Equals, "hello")
+	_, err := ioutil.ReadFile(filepath.Join(ctx.Dir, "out"))
+	c.Assert(err, gc.NotNil)
+
+	ctx = cmdtesting.Context(c)
+	ctx.HTTPClient = &http.Client{Transport: fakeTransport{}}
+	code = cmd.Main(sc, ctx, []string{"fetch"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR Get \"http://0.1.2.3/\": fake transport\n")
+}
@@ -0,0 +1,519 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// docRenderer writes the documentation of commands in a markup language.
+type docRenderer interface {
+	// extension returns the file name extension of the documents written.
+	extension() string
+
+	// comment returns text as a comment, which is not displayed.
+	comment(text string) string
+
+	// begin and end are written at the start and the end of each
+	// document, which is given the title.
+	begin(w io.Writer, title string) error
+	end(w io.Writer) error
+
+	// command writes the documentation of cmd.
+	command(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error
+
+	// index writes the index of the commands, which is nested by the
+	// depth of the entries if tree is true.
+	index(w io.Writer, entries []docIndexEntry, tree bool) error
+
+	// topic writes a help topic written in Markdown, with a title if
+	// title is not empty.
+	topic(w io.Writer, title, content string) error
+}
+
+// docIndexEntry is an entry in the index of commands.
+type docIndexEntry struct {
+	// number is the position of the command in the list of commands.
+	number int
+
+	// depth is the number of super commands above the command.
+	depth int
+
+	// name is the name of the command, and link the target of the link to
+	// its documentation, if there is any.
+	name string
+	link string
+
+	// purpose is the purpose of the command, if it is to be shown.
+	purpose string
+}
+
+// docRenderers holds the renderers of the formats supported by the
+// documentation command, keyed by the name of the format.
+var docRenderers = map[string]docRenderer{
+	"markdown": markdownRenderer{},
+	"rst":      rstRenderer{},
+	"html":     htmlRenderer{},
+}
+
+// docFileName returns name with the file name extension of r.
+func docFileName(r docRenderer, name string) string {
+	return strings.TrimSuffix(name, ".md") + "." + r.extension()
+}
+
+// markdownRenderer writes documentation in Markdown.
+type markdownRenderer struct{}
+
+func (markdownRenderer) extension() string {
+	return "md"
+}
+
+func (markdownRenderer) comment(text string) string {
+	return fmt.Sprintf("<!-- %s -->", text)
+}
+
+func (markdownRenderer) begin(io.Writer, string) error {
+	return nil
+}
+
+func (markdownRenderer) end(io.Writer) error {
+	return nil
+}
+
+func (markdownRenderer) command(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error {
+	return PrintMarkdown(w, cmd, opts)
+}
+
+func (markdownRenderer) index(w io.Writer, entries []docIndexEntry, tree bool) error {
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, "# Index\n")
+	for _, entry := range entries {
+		text := markdownLink(entry.name, func(string) string { return entry.link })
+		if entry.purpose != "" {
+			text += ": " + EscapeMarkdown(entry.purpose)
+		}
+		if tree {
+			fmt.Fprintf(&doc, "%s- %s\n", strings.Repeat("  ", entry.depth), text)
+		} else {
+			fmt.Fprintf(&doc, "%d. %s\n", entry.number, text)
+		}
+	}
+	fmt.Fprintf(&doc, "---\n\n")
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+func (markdownRenderer) topic(w io.Writer, title, content string) error {
+	var doc bytes.Buffer
+	if title != "" {
+		fmt.Fprintf(&doc, "# %s\n\n", title)
+	}
+	fmt.Fprintf(&doc, "%s\n", strings.TrimSpace(content))
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+// rstRenderer writes documentation in reStructuredText, for Sphinx.
+type rstRenderer struct{}
+
+func (rstRenderer) extension() string {
+	return "rst"
+}
+
+func (rstRenderer) comment(text string) string {
+	return ".. " + text
+}
+
+func (rstRenderer) begin(io.Writer, string) error {
+	return nil
+}
+
+func (rstRenderer) end(io.Writer) error {
+	return nil
+}
+
+func (rstRenderer) command(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error {
+	var doc bytes.Buffer
+	if opts.Anchor != "" {
+		fmt.Fprintf(&doc, ".. _%s:\n\n", slugify(opts.Anchor))
+	}
+	if opts.Title != "" {
+		rstHeading(&doc, opts.Title, '=')
+	}
+
+	info := cmd.Info()
+	if len(info.SeeAlso) > 0 {
+		links := make([]string, len(info.SeeAlso))
+		for i, name := range info.SeeAlso {
+			links[i] = rstLink(name, opts.LinkForCommand)
+		}
+		fmt.Fprintf(&doc, "See also: %s\n\n", strings.Join(links, ", "))
+	}
+	if len(info.Aliases) > 0 {
+		fmt.Fprintf(&doc, "**Aliases:** %s\n\n", rstEscape(strings.Join(info.Aliases, ", ")))
+	}
+	if len(opts.FormerNames) > 0 {
+		fmt.Fprintf(&doc, "**Formerly:** %s\n\n", rstEscape(strings.Join(opts.FormerNames, ", ")))
+	}
+
+	rstHeading(&doc, "Summary", '-')
+	fmt.Fprintf(&doc, "%s\n\n", rstEscape(info.Purpose))
+
+	if strings.TrimSpace(info.Args) != "" {
+		rstHeading(&doc, "Usage", '-')
+		fmt.Fprintf(&doc, "::\n\n    %s%s [%ss] %s\n\n", opts.UsagePrefix, info.Name, getFlagsName(info.FlagKnownAs), info.Args)
+	}
+
+	if byName := groupFlags(cmd); len(byName) > 0 {
+		rstHeading(&doc, "Options", '~')
+		fmt.Fprintf(&doc, ".. list-table::\n   :header-rows: 1\n\n   * - Flag\n     - Default\n     - Usage\n")
+		for _, fs := range byName {
+			names := make([]string, len(fs))
+			for i, f := range fs {
+				names[i] = "``" + flagName(f) + "``"
+			}
+			fmt.Fprintf(&doc, "   * - %s\n     - %s\n     - %s\n",
+				strings.Join(names, ", "),
+				rstCell(flagDefault(fs[0], opts.Reproducible)),
+				rstCell(fs[0].Usage),
+			)
+		}
+		fmt.Fprintln(&doc)
+	}
+
+	if info.Examples != "" {
+		rstHeading(&doc, "Examples", '-')
+		rstLiteral(&doc, info.Examples)
+	}
+
+	if info.Doc != "" {
+		rstHeading(&doc, "Details", '-')
+		rstText(&doc, info.Doc)
+	}
+
+	if names := subcommandNames(info.Subcommands); len(names) > 0 {
+		rstHeading(&doc, "Subcommands", '-')
+		for _, name := range names {
+			fmt.Fprintf(&doc, "- %s\n", rstLink(name, opts.LinkForSubcommand))
+		}
+		fmt.Fprintln(&doc)
+	}
+
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+func (rstRenderer) index(w io.Writer, entries []docIndexEntry, tree bool) error {
+	var doc bytes.Buffer
+	rstHeading(&doc, "Index", '=')
+	depth := 0
+	for _, entry := range entries {
+		if entry.depth != depth {
+			// Nested lists are separated from their parents by blank lines.
+			fmt.Fprintln(&doc)
+			depth = entry.depth
+		}
+		text := rstLink(entry.name, func(string) string { return entry.link })
+		if entry.purpose != "" {
+			text += ": " + rstEscape(entry.purpose)
+		}
+		bullet := "#."
+		if tree {
+			bullet = "-"
+		}
+		fmt.Fprintf(&doc, "%s%s %s\n", strings.Repeat("  ", entry.depth), bullet, text)
+	}
+	fmt.Fprintln(&doc)
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+func (rstRenderer) topic(w io.Writer, title, content string) error {
+	var doc bytes.Buffer
+	if title != "" {
+		rstHeading(&doc, title, '=')
+	}
+	rstText(&doc, MarkdownToText(content))
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+// rstHeading writes a section title, underlined with the given character.
+func rstHeading(w io.Writer, title string, underline rune) {
+	fmt.Fprintf(w, "%s\n%s\n\n", title, strings.Repeat(string(underline), utf8.RuneCountInString(title)))
+}
+
+// rstLink returns a link to the given key, using the linker as for
+// markdownLink. Links to anchors in the document refer to their labels.
+func rstLink(key string, linker func(string) string) string {
+	var target string
+	if linker != nil {
+		target = linker(key)
+	}
+	switch {
+	case target == "":
+		return rstEscape(key)
+	case strings.HasPrefix(target, "#"):
+		return fmt.Sprintf(":ref:`%s <%s>`", key, target[1:])
+	default:
+		return fmt.Sprintf("`%s <%s>`__", key, target)
+	}
+}
+
+// rstEscape returns s with the characters that start inline markup in
+// reStructuredText escaped.
+func rstEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "`", "\\`", "|", `\|`, "_", `\_`).Replace(s)
+}
+
+// rstCell returns s escaped for a cell of a list table.
+func rstCell(s string) string {
+	if s == "" {
+		return ""
+	}
+	return rstEscape(strings.ReplaceAll(s, "\n", " "))
+}
+
+// rstLiteral writes text as a literal block.
+func rstLiteral(w io.Writer, text string) {
+	fmt.Fprintf(w, "::\n\n")
+	for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			fmt.Fprintln(w)
+		} else {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// rstText writes plain text, in which indented blocks are literal.
+func rstText(w io.Writer, text string) {
+	for _, block := range textBlocks(text) {
+		if block.literal {
+			rstLiteral(w, block.text)
+		} else {
+			fmt.Fprintf(w, "%s\n\n", rstEscape(block.text))
+		}
+	}
+}
+
+// htmlRenderer writes documentation as standalone HTML documents.
+type htmlRenderer struct{}
+
+func (htmlRenderer) extension() string {
+	return "html"
+}
+
+func (htmlRenderer) comment(text string) string {
+	return fmt.Sprintf("<!-- %s -->", text)
+}
+
+func (htmlRenderer) begin(w io.Writer, title string) error {
+	_, err := fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+</head>
+<body>
+`, html.EscapeString(title))
+	return err
+}
+
+func (htmlRenderer) end(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "</body>\n</html>\n")
+	return err
+}
+
+func (htmlRenderer) command(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error {
+	var doc bytes.Buffer
+	if opts.Anchor != "" {
+		fmt.Fprintf(&doc, "<section id=\"%s\">\n", slugify(opts.Anchor))
+	} else {
+		fmt.Fprintf(&doc, "<section>\n")
+	}
+	if opts.Title != "" {
+		fmt.Fprintf(&doc, "<h1>%s</h1>\n", html.EscapeString(opts.Title))
+	}
+
+	info := cmd.Info()
+	if len(info.SeeAlso) > 0 {
+		links := make([]string, len(info.SeeAlso))
+		for i, name := range info.SeeAlso {
+			links[i] = htmlLink(name, opts.LinkForCommand)
+		}
+		fmt.Fprintf(&doc, "<p>See also: %s</p>\n", strings.Join(links, ", "))
+	}
+	if len(info.Aliases) > 0 {
+		fmt.Fprintf(&doc, "<p><strong>Aliases:</strong> %s</p>\n", html.EscapeString(strings.Join(info.Aliases, ", ")))
+	}
+	if len(opts.FormerNames) > 0 {
+		fmt.Fprintf(&doc, "<p><strong>Formerly:</strong> %s</p>\n", html.EscapeString(strings.Join(opts.FormerNames, ", ")))
+	}
+
+	fmt.Fprintf(&doc, "<h2>Summary</h2>\n<p>%s</p>\n", html.EscapeString(info.Purpose))
+
+	if strings.TrimSpace(info.Args) != "" {
+		usage := fmt.Sprintf("%s%s [%ss] %s", opts.UsagePrefix, info.Name, getFlagsName(info.FlagKnownAs), info.Args)
+		fmt.Fprintf(&doc, "<h2>Usage</h2>\n<pre><code>%s</code></pre>\n", html.EscapeString(usage))
+	}
+
+	if byName := groupFlags(cmd); len(byName) > 0 {
+		fmt.Fprintf(&doc, "<h3>Options</h3>\n<table>\n<thead><tr><th>Flag</th><th>Default</th><th>Usage</th></tr></thead>\n<tbody>\n")
+		for _, fs := range byName {
+			id := ""
+			if opts.Anchor != "" {
+				id = fmt.Sprintf(" id=\"%s\"", flagAnchor(opts.Anchor, fs[len(fs)-1].Name))
+			}
+			names := make([]string, len(fs))
+			for i, f := range fs {
+				names[i] = "<code>" + html.EscapeString(flagName(f)) + "</code>"
+			}
+			fmt.Fprintf(&doc, "<tr%s><td>%s</td><td>%s</td><td>%s</td></tr>\n", id,
+				strings.Join(names, ", "),
+				html.EscapeString(flagDefault(fs[0], opts.Reproducible)),
+				html.EscapeString(fs[0].Usage),
+			)
+		}
+		fmt.Fprintf(&doc, "</tbody>\n</table>\n")
+	}
+
+	if info.Examples != "" {
+		fmt.Fprintf(&doc, "<h2>Examples</h2>\n<pre><code>%s</code></pre>\n", html.EscapeString(strings.Trim(info.Examples, "\n")))
+	}
+
+	if info.Doc != "" {
+		fmt.Fprintf(&doc, "<h2>Details</h2>\n")
+		htmlText(&doc, info.Doc)
+	}
+
+	if names := subcommandNames(info.Subcommands); len(names) > 0 {
+		fmt.Fprintf(&doc, "<h2>Subcommands</h2>\n<ul>\n")
+		for _, name := range names {
+			fmt.Fprintf(&doc, "<li>%s</li>\n", htmlLink(name, opts.LinkForSubcommand))
+		}
+		fmt.Fprintf(&doc, "</ul>\n")
+	}
+	fmt.Fprintf(&doc, "</section>\n")
+
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+func (htmlRenderer) index(w io.Writer, entries []docIndexEntry, tree bool) error {
+	var doc bytes.Buffer
+	list := "ol"
+	if tree {
+		list = "ul"
+	}
+	fmt.Fprintf(&doc, "<nav>\n<h1>Index</h1>\n")
+	depth := -1
+	for _, entry := range entries {
+		if entry.depth > depth {
+			for ; depth < entry.depth; depth++ {
+				fmt.Fprintf(&doc, "\n<%s>\n", list)
+			}
+		} else {
+			fmt.Fprintf(&doc, "</li>\n")
+			for ; depth > entry.depth; depth-- {
+				fmt.Fprintf(&doc, "</%s>\n</li>\n", list)
+			}
+		}
+		text := htmlLink(entry.name, func(string) string { return entry.link })
+		if entry.purpose != "" {
+			text += ": " + html.EscapeString(entry.purpose)
+		}
+		fmt.Fprintf(&doc, "<li>%s", text)
+	}
+	if depth >= 0 {
+		fmt.Fprintf(&doc, "</li>\n")
+		for ; depth > 0; depth-- {
+			fmt.Fprintf(&doc, "</%s>\n</li>\n", list)
+		}
+		fmt.Fprintf(&doc, "</%s>\n", list)
+	}
+	fmt.Fprintf(&doc, "</nav>\n")
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+func (htmlRenderer) topic(w io.Writer, title, content string) error {
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, "<section>\n")
+	if title != "" {
+		fmt.Fprintf(&doc, "<h1>%s</h1>\n", html.EscapeString(title))
+	}
+	htmlText(&doc, MarkdownToText(content))
+	fmt.Fprintf(&doc, "</section>\n")
+	_, err := io.Copy(w, &doc)
+	return err
+}
+
+// htmlLink returns a link to the given key, using the linker as for
+// markdownLink.
+func htmlLink(key string, linker func(string) string) string {
+	var target string
+	if linker != nil {
+		target = linker(key)
+	}
+	if target == "" {
+		return html.EscapeString(key)
+	}
+	return fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(target), html.EscapeString(key))
+}
+
+// htmlText writes plain text as paragraphs, in which indented blocks are
+// preformatted.
+func htmlText(w io.Writer, text string) {
+	for _, block := range textBlocks(text) {
+		if block.literal {
+			fmt.Fprintf(w, "<pre><code>%s</code></pre>\n", html.EscapeString(block.text))
+		} else {
+			fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(block.text))
+		}
+	}
+}
+
+// textBlock is a paragraph of plain text, or a block of indented lines
+// that are shown as they are written.
+type textBlock struct {
+	literal bool
+	text    string
+}
+
+// textBlocks splits text into paragraphs, separated by blank lines, and
+// blocks of lines indented by at least four spaces or a tab.
+func textBlocks(text string) []textBlock {
+	var (
+		blocks []textBlock
+		lines  []string
+	)
+	literal := false
+	flush := func() {
+		if len(lines) > 0 {
+			blocks = append(blocks, textBlock{literal: literal, text: strings.Join(lines, "\n")})
+			lines = nil
+		}
+	}
+	for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		indented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		if indented != literal {
+			flush()
+			literal = indented
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return blocks
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// CheckStatus is the outcome of a doctor check.
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+// CheckResult is the result of running a doctor check.
+type CheckResult struct {
+	Status  CheckStatus
+	Message string
+}
+
+// DoctorCheck checks one aspect of the environment the command runs in,
+// e.g. that the config file is readable or that the log directory is
+// writable.
+type DoctorCheck func(ctx *Context) CheckResult
+
+// namedCheck is a DoctorCheck registered under a name.
+type namedCheck struct {
+	name  string
+	check DoctorCheck
+}
+
+// checkReport is the rendered form of a CheckResult.
+type checkReport struct {
+	Name    string      `json:"name" yaml:"name"`
+	Status  CheckStatus `json:"status" yaml:"status"`
+	Message string      `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// RegisterCheck adds a check run by the built-in "doctor" subcommand, which
+// is registered along with the first check. Checks run in the order they
+// are registered.
+func (c *SuperCommand) RegisterCheck(name string, check DoctorCheck) {
+	if c.doctor == nil {
+		c.doctor = &doctorCommand{}
+		c.insert(commandReference{name: "doctor", command: c.doctor})
+	}
+	for _, existing := range c.doctor.checks {
+		if existing.name == name {
+			panic(fmt.Sprintf("check already registered: %q", name))
+		}
+	}
+	c.doctor.checks = append(c.doctor.checks, namedCheck{name: name, check: check})
+}
+
+// doctorCommand runs the registered checks and reports their results.
+type doctorCommand struct {
+	CommandBase
+	checks []namedCheck
+	out    Output
+	strict bool
+}
+
+func (c *doctorCommand) Info() *Info {
+	return &Info{
+		Name:               "doctor",
+		Purpose:            "Check that the command is able to run correctly.",
+		NonInteractiveSafe: true,
+		Doc: `
+Run a series of checks on the environment the command runs in, and report
+whether each passed, raised a warning or failed. The command exits with a
+non-zero code if any check failed, or with --strict if any check raised a
+warning.`[1:],
+	}
+}
+
+func (c *doctorCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "tabular", map[string]Formatter{
+		"tabular": formatCheckReports,
+		"json":    FormatJson,
+		"yaml":    FormatYaml,
+	})
+	f.BoolVar(&c.strict, "strict", false, "Treat warnings as failures")
+}
+
+func (c *doctorCommand) Init(args []string) error {
+	return CheckEmpty(args)
+}
+
+func (c *doctorCommand) Run(ctx *Context) error {
+	reports := make([]checkReport, len(c.checks))
+	failed := false
+	for i, check := range c.checks {
+		result := check.check(ctx)
+		reports[i] = checkReport{
+			Name:    check.name,
+			Status:  result.Status,
+			Message: result.Message,
+		}
+		switch result.Status {
+		case CheckFail:
+			failed = true
+		case CheckWarn:
+			failed = failed || c.strict
+		}
+	}
+	if err := c.out.Write(ctx, reports); err != nil {
+		return errors.Trace(err)
+	}
+	if failed {
+		return ErrSilent
+	}
+	return nil
+}
+
+// formatCheckReports writes check reports as a table.
+func formatCheckReports(writer io.Writer, value interface{}) error {
+	reports, ok := value.([]checkReport)
+	if !ok {
+		return errors.Errorf("expected value of type %T, got %T", reports, value)
+	}
+	tw := tabwriter.NewWriter(writer, 0, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+	for _, report := range reports {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", report.Name, report.Status, report.Message)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type DoctorSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&DoctorSuite{})
+
+func check(status cmd.CheckStatus, message string) cmd.DoctorCheck {
+	return func(*cmd.Context) cmd.CheckResult {
+		return cmd.CheckResult{Status: status, Message: message}
+	}
+}
+
+func (s *DoctorSuite) run(c *gc.C, checks map[string]cmd.DoctorCheck, order []string, args ...string) (*cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	for _, name := range order {
+		sc.RegisterCheck(name, checks[name])
+	}
+	ctx := cmdtesting.Context(c)
+	return ctx, cmd.Main(sc, ctx, append([]string{"doctor"}, args...))
+}
+
+func (s *DoctorSuite) TestPass(c *gc.C) {
+	ctx, code := s.run(c, map[string]cmd.DoctorCheck{
+		"config":  check(cmd.CheckPass, ""),
+		"log-dir": check(cmd.CheckWarn, "log dir is nearly full"),
+	}, []string{"config", "log-dir"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+CHECK    STATUS  MESSAGE
+config   pass    
+log-dir  warn    log dir is nearly full
+`[1:])
+}
+
+func (s *DoctorSuite) TestStrict(c *gc.C) {
+	_, code := s.run(c, map[string]cmd.DoctorCheck{
+		"log-dir": check(cmd.CheckWarn, "log dir is nearly full"),
+	}, []string{"log-dir"}, "--strict")
+	c.Assert(code, gc.Equals, 1)
+}
+
+func (s *DoctorSuite) TestFailJSON(c *gc.C) {
+	ctx, code := s.run(c, map[string]cmd.DoctorCheck{
+		"plugins": check(cmd.CheckFail, "juju-foo is not executable"),
+		"config":  check(cmd.CheckPass, ""),
+	}, []string{"plugins", "config"}, "--format", "json")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals,
+		`[{"name":"plugins","status":"fail","message":"juju-foo is not executable"},{"name":"config","status":"pass"}]`+"\n")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *DoctorSuite) TestNotRegisteredWithoutChecks(c *gc.C) {
+	_, code := s.run(c, nil, nil)
+	c.Assert(code, gc.Equals, 2)
+}
+
+func (s *DoctorSuite) TestDuplicateCheck(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	sc.RegisterCheck("config", check(cmd.CheckPass, ""))
+	c.Assert(func() { sc.RegisterCheck("config", check(cmd.CheckPass, "")) },
+		gc.PanicMatches, `check already registered: "config"`)
+}
@@ -12,7 +12,9 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/juju/gnuflag"
 )
@@ -24,12 +26,15 @@ const (
 
 var doc string = `
 This command generates a markdown formatted document with all the commands, their descriptions, arguments, and examples.
+The documentation can be generated as reStructuredText for Sphinx, or as standalone HTML, using --doc-format.
+The json format writes the schema of the command tree, for use by other tools.
 `
 
 var documentationExamples = `
     juju documentation
     juju documentation --split 
     juju documentation --split --no-index --out /tmp/docs
+    juju documentation --doc-format rst --split --out /tmp/docs
 
 To render markdown documentation using a list of existing
 commands, you can use a file with the following syntax
@@ -43,6 +48,10 @@ For example:
     add-cloud: 1183
     add-secret: 1284
     remove-cloud: 4344
+    storage pools list: 5210
+
+Nested commands are given by their full path below the top-level
+command. Aliases are resolved to the id of the command they name.
 
 Then, the urls will be populated using the ids indicated
 in the file above.
@@ -55,17 +64,40 @@ type documentationCommand struct {
 	super   *SuperCommand
 	out     string
 	noIndex bool
+	// indexPurpose includes the purpose of each command in the index.
+	indexPurpose bool
+	// indexTree nests subcommands below their super command in the index.
+	indexTree bool
+	// topics includes the help topics written in Markdown.
+	topics  bool
 	split   bool
 	url     string
 	idsPath string
-	// ids is contains a numeric id of every command
+	// noHeader omits the header recording the version and time the
+	// documentation was generated from.
+	noHeader bool
+	// reproducible removes everything that may differ between runs from
+	// the documentation.
+	reproducible bool
+	// header is written at the top of every generated file.
+	header string
+	// format names the markup language the documentation is written in,
+	// which is written by renderer.
+	format   string
+	renderer docRenderer
+	// root is the top-level super command being documented, used to
+	// resolve links to nested commands.
+	root *SuperCommand
+	// ids is contains a numeric id of every command, keyed by the
+	// space separated path of the command below the top-level command
 	// add-cloud: 1112
 	// remove-user: 3333
+	// storage pools list: 5210
 	// etc...
 	ids map[string]string
 	// reverseAliases maintains a reverse map of the alias and the
-	// targetting command. This is used to find the ids corresponding
-	// to a given alias
+	// targetting command, both given by their path as for ids. This is
+	// used to find the ids corresponding to a given alias
 	reverseAliases map[string]string
 }
 
@@ -75,35 +107,68 @@ func newDocumentationCommand(s *SuperCommand) *documentationCommand {
 
 func (c *documentationCommand) Info() *Info {
 	return &Info{
-		Name:     "documentation",
-		Args:     "--out <target-folder> --no-index --split --url <base-url> --discourse-ids <filepath>",
-		Purpose:  "Generate the documentation for all commands",
-		Doc:      doc,
-		Examples: documentationExamples,
+		Name:               "documentation",
+		Args:               "--out <target-folder> --doc-format <format> --no-index --index-purpose --index-tree --topics --no-header --reproducible --split --url <base-url> --discourse-ids <filepath>",
+		Purpose:            "Generate the documentation for all commands",
+		NonInteractiveSafe: true,
+		Doc:                doc,
+		Examples:           documentationExamples,
 	}
 }
 
 // SetFlags adds command specific flags to the flag set.
 func (c *documentationCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.StringVar(&c.out, "out", "", "Documentation output folder if not set the result is displayed using the standard output")
+	f.StringVar(&c.format, "doc-format", "markdown", "The format of the documentation: markdown, rst, html or json")
 	f.BoolVar(&c.noIndex, "no-index", false, "Do not generate the commands index")
+	f.BoolVar(&c.indexPurpose, "index-purpose", false, "Include the purpose of each command in the index")
+	f.BoolVar(&c.indexTree, "index-tree", false, "Nest subcommands below their parent command in the index")
+	f.BoolVar(&c.topics, "topics", false, "Include the help topics written in Markdown")
+	f.BoolVar(&c.noHeader, "no-header", false, "Do not record the version and time of generation in the documentation")
+	f.BoolVar(&c.reproducible, "reproducible", false, "Generate identical documentation on every run, without timestamps or details of the environment")
 	f.BoolVar(&c.split, "split", false, "Generate a separate Markdown file for each command")
 	f.StringVar(&c.url, "url", "", "Documentation host URL")
 	f.StringVar(&c.idsPath, "discourse-ids", "", "File containing a mapping of commands and their discourse ids")
 }
 
 func (c *documentationCommand) Run(ctx *Context) error {
+	if c.format == "json" {
+		c.super.loadAllDynamicCommands(ctx)
+		return c.dumpSchema(ctx)
+	}
+	var ok bool
+	if c.renderer, ok = docRenderers[c.format]; !ok {
+		return fmt.Errorf("unknown documentation format %q, expected one of markdown, rst, html, json", c.format)
+	}
+	c.super.loadAllDynamicCommands(ctx)
+	c.root = c.super
+	c.header = ""
+	if !c.noHeader {
+		now := ctx.clock().Now()
+		if c.reproducible {
+			now = sourceDate(ctx)
+		}
+		if header := c.generatedHeader(now); header != "" {
+			c.header = c.renderer.comment(header)
+		}
+	}
+	// The flags are reset when the documentation command documents
+	// itself, so the topics are gathered first.
+	var topics []HelpTopic
+	if c.topics {
+		topics = c.markdownTopics()
+	}
 	if c.split {
 		if c.out == "" {
 			return errors.New("when using --split, you must set the output folder using --out=<folder>")
 		}
-		return c.dumpSeveralFiles()
+		return c.dumpSeveralFiles(topics)
 	}
-	return c.dumpOneFile(ctx)
+	return c.dumpOneFile(ctx, topics)
 }
 
 // dumpOneFile is invoked when the output is contained in a single output
-func (c *documentationCommand) dumpOneFile(ctx *Context) error {
+func (c *documentationCommand) dumpOneFile(ctx *Context, topics []HelpTopic) error {
 	var writer io.Writer
 	if c.out != "" {
 		_, err := os.Stat(c.out)
@@ -111,7 +176,7 @@ func (c *documentationCommand) dumpOneFile(ctx *Context) error {
 			return err
 		}
 
-		target := fmt.Sprintf("%s/%s", c.out, DocumentationFileName)
+		target := fmt.Sprintf("%s/%s", c.out, docFileName(c.render(), DocumentationFileName))
 
 		f, err := os.Create(target)
 		if err != nil {
@@ -123,37 +188,184 @@ func (c *documentationCommand) dumpOneFile(ctx *Context) error {
 		writer = ctx.Stdout
 	}
 
-	return c.dumpEntries(writer)
+	render := c.render()
+	if err := render.begin(writer, c.super.Name); err != nil {
+		return err
+	}
+	if err := c.writeHeader(writer); err != nil {
+		return err
+	}
+	if err := c.dumpEntries(writer); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		if err := render.topic(writer, strings.ToUpper(topic.Name), topic.Content); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer); err != nil {
+			return err
+		}
+	}
+	return render.end(writer)
+}
+
+// dumpSchema writes the schema of the command tree, as returned by
+// ExportSchema, to a single file or the standard output.
+func (c *documentationCommand) dumpSchema(ctx *Context) error {
+	if c.split {
+		return errors.New("the json format cannot be used with --split")
+	}
+	// Exporting the schema documents this command, which resets c.out.
+	out := c.out
+	schema, err := ExportSchema(c.super)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = ctx.Stdout.Write(schema)
+		return err
+	}
+	return os.WriteFile(filepath.Join(out, strings.TrimSuffix(DocumentationFileName, ".md")+".json"), schema, 0644)
+}
+
+// render returns the renderer of the documentation, which is Markdown
+// unless another format was chosen.
+func (c *documentationCommand) render() docRenderer {
+	if c.renderer == nil {
+		return markdownRenderer{}
+	}
+	return c.renderer
+}
+
+// markdownTopics returns the help topics written in Markdown.
+func (c *documentationCommand) markdownTopics() []HelpTopic {
+	var topics []HelpTopic
+	for _, topic := range c.super.HelpTopics() {
+		if topic.Markdown {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// writeTopics writes each help topic to its own file in the "topics"
+// directory of folder.
+func (c *documentationCommand) writeTopics(folder string, topics []HelpTopic) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	folder = filepath.Join(folder, "topics")
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return err
+	}
+	render := c.render()
+	for _, topic := range topics {
+		f, err := os.Create(filepath.Join(folder, docFileName(render, topic.Name)))
+		if err != nil {
+			return err
+		}
+		err = render.begin(f, topic.Name)
+		if err == nil {
+			err = c.writeHeader(f)
+		}
+		if err == nil {
+			err = render.topic(f, "", topic.Content)
+		}
+		if err == nil {
+			err = render.end(f)
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatedHeader returns a note recording the version of the application
+// the documentation was generated from, and when, which is written as a
+// comment. The time is omitted if it is zero.
+func (c *documentationCommand) generatedHeader(now time.Time) string {
+	var parts []string
+	if source := strings.TrimSpace(c.super.Name + " " + c.super.version); source != "" {
+		parts = append(parts, "from "+source)
+	}
+	if !now.IsZero() {
+		parts = append(parts, "on "+now.UTC().Format(time.RFC3339))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Generated %s.", strings.Join(parts, " "))
+}
+
+// sourceDate returns the time given by the SOURCE_DATE_EPOCH environment
+// variable, used by reproducible builds in place of the current time, or
+// the zero time if it is not set.
+func sourceDate(ctx *Context) time.Time {
+	epoch, err := strconv.ParseInt(ctx.lookupEnv("SOURCE_DATE_EPOCH"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(epoch, 0)
+}
+
+// writeHeader writes the header, if any, to w.
+func (c *documentationCommand) writeHeader(w io.Writer) error {
+	if c.header == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%s\n\n", c.header)
+	return err
 }
 
 // getSortedListCommands returns an array with the sorted list of
 // command names
 func (c *documentationCommand) getSortedListCommands() []string {
 	// sort the commands
-	sorted := make([]string, len(c.super.subcmds))
-	i := 0
-	for k := range c.super.subcmds {
-		sorted[i] = k
-		i++
+	sorted := make([]string, 0, len(c.super.subcmds))
+	for k, ref := range c.super.subcmds {
+		if ref.hidden(k) {
+			continue
+		}
+		sorted = append(sorted, k)
 	}
 	sort.Strings(sorted)
 	return sorted
 }
 
+// computeReverseAliases maps the path of every alias in the command tree
+// to the path of the command it names.
 func (c *documentationCommand) computeReverseAliases() {
 	c.reverseAliases = make(map[string]string)
+	c.addReverseAliases(c.super, nil)
+}
 
-	for name, content := range c.super.subcmds {
-		for _, alias := range content.command.Info().Aliases {
-			c.reverseAliases[alias] = name
+func (c *documentationCommand) addReverseAliases(super *SuperCommand, parents []string) {
+	for name, ref := range super.subcmds {
+		if ref.alias != "" {
+			// Aliases of nested commands, registered with
+			// RegisterSuperAlias, are already given by their path.
+			c.reverseAliases[commandPath(parents, name)] = commandPath(parents, ref.alias)
+			continue
+		}
+		if sc, ok := ref.command.(*SuperCommand); ok {
+			c.addReverseAliases(sc, append(parents[:len(parents):len(parents)], name))
 		}
 	}
+}
 
+// commandPath returns the space separated path of the named command below
+// the given parents.
+func commandPath(parents []string, name string) string {
+	return strings.Join(append(parents[:len(parents):len(parents)], name), " ")
 }
 
 // dumpSeveralFiles is invoked when every command is dumped into
 // a separated entity
-func (c *documentationCommand) dumpSeveralFiles() error {
+func (c *documentationCommand) dumpSeveralFiles(topics []HelpTopic) error {
 	if len(c.super.subcmds) == 0 {
 		fmt.Printf("No commands found for %s", c.super.Name)
 		return nil
@@ -174,30 +386,44 @@ func (c *documentationCommand) dumpSeveralFiles() error {
 			return err
 		}
 	}
+	c.computeReverseAliases()
 
 	// create index if indicated
 	if !c.noIndex {
-		target := fmt.Sprintf("%s/%s", c.out, DocumentationIndexFileName)
+		target := fmt.Sprintf("%s/%s", c.out, docFileName(c.render(), DocumentationIndexFileName))
 		f, err := os.Create(target)
 		if err != nil {
 			return err
 		}
 
+		if err := c.render().begin(f, "Index"); err != nil {
+			return err
+		}
+		if err := c.writeHeader(f); err != nil {
+			return err
+		}
 		err = c.writeIndex(f)
 		if err != nil {
 			return fmt.Errorf("writing index: %w", err)
 		}
+		if err := c.render().end(f); err != nil {
+			return err
+		}
 		f.Close()
 	}
 
-	return c.writeDocs(c.out, []string{c.super.Name}, true)
+	// Writing the documentation of this command resets c.out.
+	out := c.out
+	if err := c.writeDocs(out, []string{c.super.Name}, true); err != nil {
+		return err
+	}
+	return c.writeTopics(out, topics)
 }
 
 // writeDocs (recursively) writes docs for all commands in the given folder.
 func (c *documentationCommand) writeDocs(folder string, superCommands []string, printDefaultCommands bool) error {
-	c.computeReverseAliases()
-
-	for name, ref := range c.super.subcmds {
+	for _, name := range c.getSortedListCommands() {
+		ref := c.super.subcmds[name]
 		if !printDefaultCommands && isDefaultCommand(name) {
 			continue
 		}
@@ -206,7 +432,7 @@ func (c *documentationCommand) writeDocs(folder string, superCommands []string,
 
 		sc, isSuperCommand := ref.command.(*SuperCommand)
 		if !isSuperCommand || (isSuperCommand && !sc.SkipCommandDoc) {
-			target := fmt.Sprintf("%s.md", strings.Join(commandSeq[1:], "_"))
+			target := docFileName(c.render(), strings.Join(commandSeq[1:], "_"))
 			if err := c.writeDoc(folder, target, ref, commandSeq); err != nil {
 				return err
 			}
@@ -217,6 +443,13 @@ func (c *documentationCommand) writeDocs(folder string, superCommands []string,
 			continue
 		}
 
+		sc.documentation.header = c.header
+		sc.documentation.renderer = c.renderer
+		sc.documentation.reproducible = c.reproducible
+		sc.documentation.root = c.root
+		sc.documentation.url = c.url
+		sc.documentation.ids = c.ids
+		sc.documentation.reverseAliases = c.reverseAliases
 		if err := sc.documentation.writeDocs(folder, commandSeq, false); err != nil {
 			return err
 		}
@@ -235,10 +468,19 @@ func (c *documentationCommand) writeDoc(folder, target string, ref commandRefere
 	}
 	defer func() { _ = f.Close() }()
 
+	if err := c.render().begin(f, strings.Join(commandSeq, " ")); err != nil {
+		return err
+	}
+	if err := c.writeHeader(f); err != nil {
+		return err
+	}
 	formatted := c.formatCommand(ref, false, commandSeq)
 	if _, err = fmt.Fprintln(f, formatted); err != nil {
 		return err
 	}
+	if err := c.render().end(f); err != nil {
+		return err
+	}
 	_ = f.Sync()
 	return nil
 }
@@ -257,7 +499,7 @@ func (c *documentationCommand) readFileIds(path string) (map[string]string, erro
 		if len(items) != 2 {
 			return nil, fmt.Errorf("malformed line [%s]", line)
 		}
-		command := strings.TrimSpace(items[0])
+		command := strings.Join(strings.Fields(items[0]), " ")
 		id := strings.TrimSpace(items[1])
 		ids[command] = id
 	}
@@ -303,6 +545,9 @@ func (c *documentationCommand) writeSections(w io.Writer, superCommands []string
 		if !isSuperCommand {
 			continue
 		}
+		sc.documentation.root = c.root
+		sc.documentation.reproducible = c.reproducible
+		sc.documentation.renderer = c.renderer
 		if err := sc.documentation.writeSections(w, commandSeq, false); err != nil {
 			return err
 		}
@@ -312,28 +557,69 @@ func (c *documentationCommand) writeSections(w io.Writer, superCommands []string
 
 // writeIndex writes the command index to the specified writer.
 func (c *documentationCommand) writeIndex(w io.Writer) error {
-	_, err := fmt.Fprintf(w, "# Index\n")
-	if err != nil {
-		return err
+	var entries []docIndexEntry
+	if c.indexTree {
+		entries = c.indexTreeEntries(c.super, nil)
+	} else {
+		listCommands := c.getSortedListCommands()
+		for id, name := range listCommands {
+			if isDefaultCommand(name) {
+				continue
+			}
+			entries = append(entries, c.indexEntry(c.super.subcmds[name], nil, name, id))
+		}
 	}
+	return c.render().index(w, entries, c.indexTree)
+}
 
-	listCommands := c.getSortedListCommands()
-	for id, name := range listCommands {
+// indexTreeEntries returns an entry for each command of super, followed by
+// the entries of its subcommands.
+func (c *documentationCommand) indexTreeEntries(super *SuperCommand, parents []string) []docIndexEntry {
+	var entries []docIndexEntry
+	names := make([]string, 0, len(super.subcmds))
+	for name, ref := range super.subcmds {
+		if !ref.hidden(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
 		if isDefaultCommand(name) {
 			continue
 		}
-		_, err = fmt.Fprintf(w, "%d. [%s](%s)\n", id, name, c.linkForCommand(name))
-		if err != nil {
-			return err
+		ref := super.subcmds[name]
+		entries = append(entries, c.indexEntry(ref, parents, name, len(entries)))
+		// Aliases of super commands would repeat the commands they name.
+		sc, isSuperCommand := ref.command.(*SuperCommand)
+		if !isSuperCommand || ref.alias != "" {
+			continue
 		}
-		// TODO: handle subcommands ??
+		entries = append(entries, c.indexTreeEntries(sc, append(parents[:len(parents):len(parents)], name))...)
 	}
-	_, err = fmt.Fprintf(w, "---\n\n")
-	return err
+	return entries
+}
+
+// indexEntry returns the index entry for the named command below the given
+// parents, numbered as given: a link to its documentation, and its purpose
+// if requested.
+func (c *documentationCommand) indexEntry(ref commandReference, parents []string, name string, number int) docIndexEntry {
+	entry := docIndexEntry{
+		number: number,
+		depth:  len(parents),
+		name:   name,
+	}
+	if sc, ok := ref.command.(*SuperCommand); !ok || !sc.SkipCommandDoc {
+		// Otherwise there is no documentation to link to.
+		entry.link = c.linkForCommand(parents, name)
+	}
+	if purpose := strings.TrimSpace(ref.command.Info().Purpose); c.indexPurpose {
+		entry.purpose = purpose
+	}
+	return entry
 }
 
-// Return the URL/location for the given command
-func (c *documentationCommand) linkForCommand(cmd string) string {
+// Return the URL/location for the named command below the given parents
+func (c *documentationCommand) linkForCommand(parents []string, cmd string) string {
 	prefix := "#"
 	if c.ids != nil {
 		prefix = "/t/"
@@ -341,17 +627,20 @@ func (c *documentationCommand) linkForCommand(cmd string) string {
 	if c.url != "" {
 		prefix = c.url + "/"
 	}
+	if c.ids == nil {
+		return prefix + commandAnchor(append(parents[:len(parents):len(parents)], cmd))
+	}
 
-	target, err := c.getTargetCmd(cmd)
+	target, err := c.getTargetCmd(parents, cmd)
 	if err != nil {
-		fmt.Printf("[ERROR] command [%s] has no id, please add it to the list\n", cmd)
+		fmt.Printf("[ERROR] command [%s] has no id, please add it to the list\n", commandPath(parents, cmd))
 		return ""
 	}
 	return prefix + target
 }
 
 // formatCommand returns a string representation of the information contained
-// by a command in Markdown format. The title param can be used to set
+// by a command in the documentation format. The title param can be used to set
 // whether the command name should be a title or not. This is particularly
 // handy when splitting the commands in different files.
 func (c *documentationCommand) formatCommand(ref commandReference, title bool, commandSeq []string) string {
@@ -360,10 +649,22 @@ func (c *documentationCommand) formatCommand(ref commandReference, title bool, c
 		fmtedTitle = strings.ToUpper(strings.Join(commandSeq[1:], " "))
 	}
 
+	root := c.root
+	if root == nil {
+		root = c.super
+	}
+	var formerNames []string
+	if root != nil {
+		formerNames = root.formerNames(strings.Join(commandSeq[1:], " "))
+	}
+
 	var buf bytes.Buffer
-	PrintMarkdown(&buf, ref.command, MarkdownOptions{
-		Title:       fmtedTitle,
-		UsagePrefix: strings.Join(commandSeq[:len(commandSeq)-1], " ") + " ",
+	c.render().command(&buf, ref.command, MarkdownOptions{
+		Title:        fmtedTitle,
+		UsagePrefix:  strings.Join(commandSeq[:len(commandSeq)-1], " ") + " ",
+		Anchor:       commandAnchor(commandSeq[1:]),
+		Reproducible: c.reproducible,
+		FormerNames:  formerNames,
 		LinkForCommand: func(s string) string {
 			prefix := "#"
 			if c.ids != nil {
@@ -372,40 +673,68 @@ func (c *documentationCommand) formatCommand(ref commandReference, title bool, c
 			if c.url != "" {
 				prefix = c.url + "t/"
 			}
+			parents := commandSeq[1 : len(commandSeq)-1]
+			if c.ids == nil {
+				return prefix + c.anchorFor(parents, s)
+			}
 
-			target, err := c.getTargetCmd(s)
+			target, err := c.getTargetCmd(parents, s)
 			if err != nil {
 				fmt.Println(err.Error())
 			}
 			return fmt.Sprintf("%s%s", prefix, target)
 		},
 		LinkForSubcommand: func(s string) string {
-			return c.linkForCommand(strings.Join(append(commandSeq[1:], s), "_"))
+			return c.linkForCommand(commandSeq[1:], s)
 		},
 	})
 	return buf.String()
 }
 
+// commandAnchor returns the anchor id of the command with the given path
+// below the top-level command, e.g. "storage_pools_list".
+func commandAnchor(path []string) string {
+	return slugify(strings.Join(path, "_"))
+}
+
+// anchorFor returns the anchor id of the named command, which is looked up
+// below each of the parents in turn as for getTargetCmd.
+func (c *documentationCommand) anchorFor(parents []string, name string) string {
+	fields := strings.Fields(name)
+	if c.root != nil {
+		for i := len(parents); i >= 0; i-- {
+			path := append(parents[:i:i], fields...)
+			if c.root.resolves(strings.Join(path, " ")) {
+				return commandAnchor(path)
+			}
+		}
+	}
+	return commandAnchor(fields)
+}
+
 // getTargetCmd is an auxiliary function that returns the target command or
-// the corresponding id if available.
-func (d *documentationCommand) getTargetCmd(cmd string) (string, error) {
+// the corresponding id if available. The command is looked up below each of
+// the parents in turn, innermost first, so that nested commands may refer
+// to their peers by name as well as to commands at the top level.
+func (d *documentationCommand) getTargetCmd(parents []string, cmd string) (string, error) {
 	// no ids were set, return the original command
 	if d.ids == nil {
 		return cmd, nil
 	}
-	target, found := d.ids[cmd]
-	if found {
-		return target, nil
-	} else {
+	name := strings.Join(strings.Fields(cmd), " ")
+	for i := len(parents); i >= 0; i-- {
+		path := commandPath(parents[:i], name)
+		if target, found := d.ids[path]; found {
+			return target, nil
+		}
 		// check if this is an alias
-		targetCmd, found := d.reverseAliases[cmd]
-		fmt.Printf("use alias %s -> %s\n", cmd, targetCmd)
-		if !found {
-			// if we're working with ids, and we have to mmake the translation,
-			// we need to have an id per every requested command
-			return "", fmt.Errorf("requested id for command %s was not found", cmd)
+		if targetCmd, found := d.reverseAliases[path]; found {
+			if target, found := d.ids[targetCmd]; found {
+				return target, nil
+			}
 		}
-		return targetCmd, nil
-
 	}
+	// if we're working with ids, and we have to mmake the translation,
+	// we need to have an id per every requested command
+	return "", fmt.Errorf("requested id for command %s was not found", cmd)
 }
@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/juju/clock/testclock"
 	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
 )
 
 type documentationSuite struct{}
@@ -36,6 +41,8 @@ func (s *documentationSuite) TestFormatCommand(c *gc.C) {
 		},
 		title: false,
 		expected: (`
+<a id="add-cloud"></a>
+
 > See also: [clouds](#clouds), [update-cloud](#update-cloud), [remove-cloud](#remove-cloud), [update-credential](#update-credential)
 
 **Aliases:** cloud-add, import-cloud
@@ -49,9 +56,9 @@ summary for add-cloud...
 ### Options
 | Flag | Default | Usage |
 | --- | --- | --- |
-| ` + "`" + `-f` + "`" + `, ` + "`" + `--force` + "`" + ` | default value for "force" flag | description for "force" flag |
-| ` + "`" + `--format` + "`" + ` | default value for "format" flag | description for "format" flag |
-| ` + "`" + `-o` + "`" + `, ` + "`" + `--output` + "`" + ` | default value for "output" flag | description for "output" flag |
+| <a id="add-cloud--force"></a>` + "`" + `-f` + "`" + `, ` + "`" + `--force` + "`" + ` | default value for "force" flag | description for "force" flag |
+| <a id="add-cloud--format"></a>` + "`" + `--format` + "`" + ` | default value for "format" flag | description for "format" flag |
+| <a id="add-cloud--output"></a>` + "`" + `-o` + "`" + `, ` + "`" + `--output` + "`" + ` | default value for "output" flag | description for "output" flag |
 
 ## Examples
 examples for add-cloud...
@@ -74,6 +81,8 @@ details for add-cloud...
 		},
 		title: false,
 		expected: (`
+<a id="foo"></a>
+
 ## Summary
 insert summary here...
 
@@ -227,5 +236,333 @@ func (*documentationSuite) TestWriteIndex(c *gc.C) {
 	indexContents, err := os.ReadFile(indexPath)
 	c.Assert(err, gc.IsNil)
 	// Index should be non-empty
-	c.Assert(string(indexContents), gc.Matches, "(?m).*Index.*")
+	c.Assert(string(indexContents), gc.Matches, "(?ms).*^# Index$.*")
+}
+
+func (*documentationSuite) TestHeader(c *gc.C) {
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:    "juju",
+		Version: "3.0.0",
+	})
+	superCmd.Register(&docTestCommand{
+		info: &cmd.Info{Name: "add-cloud", Purpose: "Add a cloud."},
+	})
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = testclock.NewClock(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC))
+	code := cmd.Main(superCmd, ctx, []string{"documentation"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, "<!-- Generated from juju 3.0.0 on 2024-02-03T04:05:06Z. -->\n\n# Index\n(.|\n)*")
+
+	docsDir := c.MkDir()
+	code = cmd.Main(superCmd, ctx, []string{"documentation", "--split", "--out", docsDir})
+	c.Assert(code, gc.Equals, 0)
+	for _, name := range []string{"index.md", "add-cloud.md"} {
+		content, err := os.ReadFile(filepath.Join(docsDir, name))
+		c.Assert(err, gc.IsNil)
+		c.Assert(string(content), gc.Matches, "<!-- Generated from juju 3.0.0 on 2024-02-03T04:05:06Z. -->\n\n(.|\n)*")
+	}
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(superCmd, ctx, []string{"documentation", "--no-header"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, "# Index\n(.|\n)*")
+}
+
+// dataDirCommand has a flag whose default depends on the user's home
+// directory.
+type dataDirCommand struct {
+	cmd.CommandBase
+	dataDir string
+}
+
+func (c *dataDirCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "backup", Purpose: "Back up the client data."}
+}
+
+func (c *dataDirCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.dataDir, "data-dir", filepath.Join(os.Getenv("HOME"), ".local", "share", "juju"), "Directory holding the client data")
+}
+
+func (c *dataDirCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (*documentationSuite) TestReproducible(c *gc.C) {
+	defer testing.PatchEnvironment("HOME", "/home/alice")()
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:    "juju",
+		Version: "3.0.0",
+	})
+	superCmd.Register(&dataDirCommand{})
+
+	generate := func(now time.Time, env map[string]string) string {
+		ctx := cmdtesting.Context(c)
+		ctx.Clock = testclock.NewClock(now)
+		ctx.Env = env
+		code := cmd.Main(superCmd, ctx, []string{"documentation", "--reproducible"})
+		c.Assert(code, gc.Equals, 0)
+		return cmdtesting.Stdout(ctx)
+	}
+	first := generate(time.Now(), map[string]string{})
+	c.Assert(first, gc.Matches, "<!-- Generated from juju 3.0.0. -->\\n\\n(.|\\n)*")
+	c.Assert(first, gc.Matches, "(.|\\n)*\\| <a id=\"backup--data-dir\"></a>`--data-dir` \\| ~/.local/share/juju \\|(.|\\n)*")
+	c.Assert(first, gc.Not(gc.Matches), "(.|\\n)*/home/alice(.|\\n)*")
+	c.Assert(generate(time.Now().Add(time.Hour), map[string]string{}), gc.Equals, first)
+
+	withEpoch := generate(time.Now(), map[string]string{"SOURCE_DATE_EPOCH": "1700000000"})
+	c.Assert(withEpoch, gc.Matches, "<!-- Generated from juju 3.0.0 on 2023-11-14T22:13:20Z. -->\\n\\n(.|\\n)*")
+}
+
+func (*documentationSuite) TestDiscourseIdsForNestedCommands(c *gc.C) {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", Purpose: "Manage storage pools."})
+	pools.Register(&docTestCommand{
+		info: &cmd.Info{Name: "list", Aliases: []string{"ls"}, Purpose: "List pools.", SeeAlso: []string{"create", "add-cloud"}},
+	})
+	pools.Register(&docTestCommand{
+		info: &cmd.Info{Name: "create", Purpose: "Create a pool.", SeeAlso: []string{"ls"}},
+	})
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", Purpose: "Manage storage."})
+	storage.Register(pools)
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	superCmd.Register(storage)
+	superCmd.Register(&docTestCommand{
+		info: &cmd.Info{Name: "add-cloud", Purpose: "Add a cloud.", SeeAlso: []string{"storage pools ls"}},
+	})
+	superCmd.RegisterSuperAlias("list-pools", "storage", "pools", nil)
+
+	docsDir := c.MkDir()
+	idsPath := filepath.Join(docsDir, "ids")
+	err := os.WriteFile(idsPath, []byte(`
+add-cloud: 1183
+storage: 5100
+storage pools: 5200
+storage  pools list: 5210
+storage pools create: 5211
+`[1:]), 0644)
+	c.Assert(err, gc.IsNil)
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(superCmd, ctx, []string{"documentation", "--split", "--no-header", "--out", docsDir, "--discourse-ids", idsPath})
+	c.Assert(code, gc.Equals, 0)
+
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(docsDir, name))
+		c.Assert(err, gc.IsNil)
+		return string(content)
+	}
+	c.Check(read("index.md"), gc.Matches, `(?s).*\[list-pools\]\(/t/5200\).*`)
+	c.Check(read("add-cloud.md"), gc.Matches, `(?s).*> See also: \[storage pools ls\]\(/t/5210\)\n.*`)
+	c.Check(read("storage.md"), gc.Matches, `(?s).*- \[pools\]\(/t/5200\)\n.*`)
+	c.Check(read("storage_pools.md"), gc.Matches, `(?s).*- \[create\]\(/t/5211\)\n- \[list\]\(/t/5210\)\n.*`)
+	c.Check(read("storage_pools_list.md"), gc.Matches, `(?s).*> See also: \[create\]\(/t/5211\), \[add-cloud\]\(/t/1183\)\n.*`)
+	c.Check(read("storage_pools_create.md"), gc.Matches, `(?s).*> See also: \[ls\]\(/t/5210\)\n.*`)
+}
+
+func (*documentationSuite) TestAnchorLinksForNestedCommands(c *gc.C) {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", Purpose: "Manage storage pools."})
+	pools.Register(&docTestCommand{
+		info: &cmd.Info{Name: "list", Purpose: "List pools.", SeeAlso: []string{"create", "add-cloud"}},
+	})
+	pools.Register(&docTestCommand{
+		info: &cmd.Info{Name: "create", Purpose: "Create a pool."},
+	})
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", Purpose: "Manage storage."})
+	storage.Register(pools)
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	superCmd.Register(storage)
+	superCmd.Register(&docTestCommand{
+		info: &cmd.Info{Name: "add-cloud", Purpose: "Add a cloud.", SeeAlso: []string{"storage pools list"}},
+	})
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(superCmd, ctx, []string{"documentation", "--no-header"})
+	c.Assert(code, gc.Equals, 0)
+	out := cmdtesting.Stdout(ctx)
+	c.Check(out, gc.Matches, `(?s).*<a id="add-cloud"></a>\n\n# ADD-CLOUD\n\n> See also: \[storage pools list\]\(#storage_pools_list\)\n.*`)
+	c.Check(out, gc.Matches, `(?s).*<a id="storage_pools"></a>\n\n# STORAGE POOLS\n.*- \[create\]\(#storage_pools_create\)\n- \[list\]\(#storage_pools_list\)\n.*`)
+	c.Check(out, gc.Matches, `(?s).*<a id="storage_pools_list"></a>\n\n# STORAGE POOLS LIST\n\n> See also: \[create\]\(#storage_pools_create\), \[add-cloud\]\(#add-cloud\)\n.*`)
+	c.Check(out, gc.Matches, `(?s).*<a id="storage_pools_create"></a>\n\n# STORAGE POOLS CREATE\n.*`)
+}
+
+func (*documentationSuite) TestIndexOptions(c *gc.C) {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", Purpose: "Manage storage pools."})
+	pools.Register(&docTestCommand{info: &cmd.Info{Name: "list", Purpose: "List pools."}})
+	pools.Register(&docTestCommand{info: &cmd.Info{Name: "create", Purpose: "Create a pool | volume."}})
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", Purpose: "Manage storage."})
+	storage.Register(pools)
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	superCmd.Register(storage)
+	superCmd.Register(&docTestCommand{info: &cmd.Info{Name: "add-cloud", Purpose: "Add a cloud."}})
+	superCmd.RegisterSuperAlias("list-pools", "storage", "pools", nil)
+
+	index := func(args ...string) string {
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(superCmd, ctx, append([]string{"documentation", "--no-header"}, args...))
+		c.Assert(code, gc.Equals, 0)
+		out := cmdtesting.Stdout(ctx)
+		return out[:strings.Index(out, "---\n")]
+	}
+	c.Check(index(), gc.Equals, `
+# Index
+0. [add-cloud](#add-cloud)
+3. [list-pools](#list-pools)
+4. [storage](#storage)
+`[1:])
+	c.Check(index("--index-purpose"), gc.Equals, `
+# Index
+0. [add-cloud](#add-cloud): Add a cloud.
+3. [list-pools](#list-pools): Manage storage pools.
+4. [storage](#storage): Manage storage.
+`[1:])
+	c.Check(index("--index-tree"), gc.Equals, `
+# Index
+- [add-cloud](#add-cloud)
+- [list-pools](#list-pools)
+- [storage](#storage)
+  - [pools](#storage_pools)
+    - [create](#storage_pools_create)
+    - [list](#storage_pools_list)
+`[1:])
+	c.Check(index("--index-tree", "--index-purpose"), gc.Equals, `
+# Index
+- [add-cloud](#add-cloud): Add a cloud.
+- [list-pools](#list-pools): Manage storage pools.
+- [storage](#storage): Manage storage.
+  - [pools](#storage_pools): Manage storage pools.
+    - [create](#storage_pools_create): Create a pool &#x7c; volume.
+    - [list](#storage_pools_list): List pools.
+`[1:])
+}
+
+func (*documentationSuite) TestTopics(c *gc.C) {
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	superCmd.Register(&docTestCommand{info: &cmd.Info{Name: "add-cloud", Purpose: "Add a cloud."}})
+	superCmd.AddHelpTopic("basics", "Basic commands", "Plain text.")
+	superCmd.AddMarkdownHelpTopic("spaces", "About spaces", "A **space** is a group of subnets.\n")
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(superCmd, ctx, []string{"documentation", "--no-header", "--no-index", "--topics"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, "(?s).*\n# SPACES\n\nA \\*\\*space\\*\\* is a group of subnets.\n\n$")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Not(gc.Matches), "(?s).*Plain text.*")
+
+	out := c.MkDir()
+	code = cmd.Main(superCmd, cmdtesting.Context(c), []string{"documentation", "--no-header", "--split", "--out", out, "--topics"})
+	c.Assert(code, gc.Equals, 0)
+	content, err := os.ReadFile(filepath.Join(out, "topics", "spaces.md"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, "A **space** is a group of subnets.\n")
+	_, err = os.Stat(filepath.Join(out, "topics", "basics.md"))
+	c.Assert(os.IsNotExist(err), gc.Equals, true)
+}
+
+func (*documentationSuite) TestDocFormats(c *gc.C) {
+	superCmd := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	superCmd.Register(&docTestCommand{info: &cmd.Info{
+		Name:     "add-cloud",
+		Args:     "<cloud>",
+		Purpose:  "Add a cloud.",
+		Doc:      "Adds a *cloud*.\n\n    juju add-cloud <cloud>\n",
+		SeeAlso:  []string{"clouds"},
+		Examples: "    juju add-cloud aws\n",
+	}, flags: []testFlag{{name: "force", short: "f"}}})
+	superCmd.Register(&docTestCommand{info: &cmd.Info{Name: "clouds", Purpose: "List clouds."}})
+
+	document := func(format string) string {
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(superCmd, ctx, []string{"documentation", "--no-header", "--doc-format", format})
+		c.Assert(code, gc.Equals, 0)
+		return cmdtesting.Stdout(ctx)
+	}
+
+	rst := document("rst")
+	c.Check(rst, gc.Matches, "Index\n=====\n\n#. :ref:`add-cloud <add-cloud>`\n(.|\n)*")
+	// Backquotes are written as single quotes in the expected output.
+	c.Check(strings.Contains(rst, strings.ReplaceAll(`
+.. _add-cloud:
+
+ADD-CLOUD
+=========
+
+See also: :ref:'clouds <clouds>'
+
+Summary
+-------
+
+Add a cloud.
+
+Usage
+-----
+
+::
+
+    juju add-cloud [options] <cloud>
+
+Options
+~~~~~~~
+
+.. list-table::
+   :header-rows: 1
+
+   * - Flag
+     - Default
+     - Usage
+   * - ''-f'', ''--force''
+     - default value for "force" flag
+     - description for "force" flag
+
+Examples
+--------
+
+::
+
+        juju add-cloud aws
+
+Details
+-------
+
+Adds a \*cloud\*.
+
+::
+
+        juju add-cloud <cloud>
+
+`, "'", "`")), gc.Equals, true, gc.Commentf("%s", rst))
+
+	page := document("html")
+	c.Check(page, gc.Matches, "<!DOCTYPE html>\n(.|\n)*<title>juju</title>\n(.|\n)*</body>\n</html>\n")
+	c.Check(strings.Contains(page, `<nav>
+<h1>Index</h1>
+
+<ol>
+<li><a href="#add-cloud">add-cloud</a></li>
+`), gc.Equals, true, gc.Commentf("%s", page))
+	c.Check(strings.Contains(page, `<section id="add-cloud">
+<h1>ADD-CLOUD</h1>
+<p>See also: <a href="#clouds">clouds</a></p>
+<h2>Summary</h2>
+<p>Add a cloud.</p>
+<h2>Usage</h2>
+<pre><code>juju add-cloud [options] &lt;cloud&gt;</code></pre>
+<h3>Options</h3>
+`), gc.Equals, true, gc.Commentf("%s", page))
+	c.Check(strings.Contains(page, `<h2>Details</h2>
+<p>Adds a *cloud*.</p>
+<pre><code>    juju add-cloud &lt;cloud&gt;</code></pre>
+</section>
+`), gc.Equals, true, gc.Commentf("%s", page))
+
+	out := c.MkDir()
+	code := cmd.Main(superCmd, cmdtesting.Context(c), []string{"documentation", "--doc-format", "html", "--split", "--out", out})
+	c.Assert(code, gc.Equals, 0)
+	for _, name := range []string{"index.html", "add-cloud.html", "clouds.html"} {
+		content, err := os.ReadFile(filepath.Join(out, name))
+		c.Assert(err, gc.IsNil)
+		c.Check(string(content), gc.Matches, "<!DOCTYPE html>\n(.|\n)*<body>\n<!-- Generated from juju on .* -->\n\n(.|\n)*</html>\n")
+	}
+
+	ctx := cmdtesting.Context(c)
+	code = cmd.Main(superCmd, ctx, []string{"documentation", "--doc-format", "pdf"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `ERROR unknown documentation format "pdf", expected one of markdown, rst, html, json`+"\n")
 }
@@ -0,0 +1,85 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// containerMarkerFiles holds the paths of files that container runtimes
+// create to indicate that processes are running inside a container.
+var containerMarkerFiles = []string{
+	"/.dockerenv",
+	"/run/.containerenv",
+}
+
+// IsSnapConfined reports whether the command is running from within a
+// snap, in which case HOME points at the snap's private user data
+// directory rather than the user's real home directory.
+func (ctx *Context) IsSnapConfined() bool {
+	return isSnapConfined(ctx.lookupEnv)
+}
+
+// IsInContainer reports whether the command appears to be running inside a
+// container (e.g. LXD, docker or podman).
+func (ctx *Context) IsInContainer() bool {
+	if ctx.lookupEnv("container") != "" {
+		return true
+	}
+	for _, path := range containerMarkerFiles {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HomeDir returns the user's home directory. When running under a snap,
+// the real home directory is returned instead of the snap's private one.
+func (ctx *Context) HomeDir() string {
+	return homeDir(ctx.lookupEnv)
+}
+
+// lookupEnv looks up an environment variable in the context, falling back
+// to the process environment if the context has none set.
+func (ctx *Context) lookupEnv(key string) string {
+	if ctx.Env == nil {
+		return os.Getenv(key)
+	}
+	return ctx.Getenv(key)
+}
+
+// expandHome replaces a leading "~" in path with the user's home directory,
+// as returned by HomeDir.
+func (ctx *Context) expandHome(path string) string {
+	return expandHome(path, ctx.lookupEnv)
+}
+
+func isSnapConfined(getenv func(string) string) bool {
+	return getenv("SNAP_NAME") != ""
+}
+
+func homeDir(getenv func(string) string) string {
+	if isSnapConfined(getenv) {
+		if home := getenv("SNAP_REAL_HOME"); home != "" {
+			return home
+		}
+	}
+	if home := getenv("HOME"); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// expandHome replaces a leading "~" in path with the user's home directory,
+// as returned by homeDir.
+func expandHome(path string, getenv func(string) string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	return filepath.Join(homeDir(getenv), path[1:])
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type EnvironSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&EnvironSuite{})
+
+func (s *EnvironSuite) TestIsSnapConfined(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{}
+	c.Check(ctx.IsSnapConfined(), gc.Equals, false)
+	ctx.Env["SNAP_NAME"] = "juju"
+	c.Check(ctx.IsSnapConfined(), gc.Equals, true)
+}
+
+func (s *EnvironSuite) TestIsSnapConfinedFallsBackToProcessEnv(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	s.PatchEnvironment("SNAP_NAME", "juju")
+	c.Check(ctx.IsSnapConfined(), gc.Equals, true)
+}
+
+func (s *EnvironSuite) TestIsInContainer(c *gc.C) {
+	marker := filepath.Join(c.MkDir(), ".dockerenv")
+	s.PatchValue(cmd.ContainerMarkerFiles, []string{marker})
+
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{}
+	c.Check(ctx.IsInContainer(), gc.Equals, false)
+
+	ctx.Env["container"] = "lxc"
+	c.Check(ctx.IsInContainer(), gc.Equals, true)
+
+	delete(ctx.Env, "container")
+	err := ioutil.WriteFile(marker, nil, 0644)
+	c.Assert(err, gc.IsNil)
+	c.Check(ctx.IsInContainer(), gc.Equals, true)
+}
+
+func (s *EnvironSuite) TestHomeDir(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{
+		"HOME":           "/home/snap/juju/current",
+		"SNAP_REAL_HOME": "/home/bob",
+	}
+	c.Check(ctx.HomeDir(), gc.Equals, "/home/snap/juju/current")
+
+	ctx.Env["SNAP_NAME"] = "juju"
+	c.Check(ctx.HomeDir(), gc.Equals, "/home/bob")
+	c.Check(ctx.AbsPath("~/foo/bar"), gc.Equals, "/home/bob/foo/bar")
+}
+
+func (s *EnvironSuite) TestAliasFileUsesRealHome(c *gc.C) {
+	home := c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(home, "aliases"), []byte("foo = bar baz\n"), 0644)
+	c.Assert(err, gc.IsNil)
+	s.PatchEnvironment("HOME", c.MkDir())
+	s.PatchEnvironment("SNAP_NAME", "juju")
+	s.PatchEnvironment("SNAP_REAL_HOME", home)
+
+	aliases := cmd.ParseAliasFile("~/aliases")
+	c.Assert(aliases, gc.DeepEquals, map[string][]string{"foo": {"bar", "baz"}})
+}
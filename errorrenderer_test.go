@@ -0,0 +1,66 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ErrorRendererSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ErrorRendererSuite{})
+
+func (s *ErrorRendererSuite) run(c *gc.C, renderer cmd.ErrorRenderer, args ...string) (*cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:          "juju",
+		ErrorRenderer: renderer,
+	})
+	sc.Register(&TestCommand{Name: "blah"})
+	ctx := cmdtesting.Context(c)
+	return ctx, cmd.Main(sc, ctx, args)
+}
+
+func (s *ErrorRendererSuite) TestPrefix(c *gc.C) {
+	ctx, code := s.run(c, cmd.PrefixErrorRenderer("error: "), "blah", "--option", "error")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "error: BAM!\n")
+}
+
+func (s *ErrorRendererSuite) TestUsageError(c *gc.C) {
+	ctx, code := s.run(c, cmd.PrefixErrorRenderer("error: "), "unknown")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "error: unrecognized command: juju unknown\n")
+}
+
+func (s *ErrorRendererSuite) TestRedacted(c *gc.C) {
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+	cmd.RegisterRedactedValue("BAM")
+	ctx, code := s.run(c, cmd.PrefixErrorRenderer("error: "), "blah", "--option", "error")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "error: <redacted>!\n")
+}
+
+func (s *ErrorRendererSuite) TestFunc(c *gc.C) {
+	renderer := cmd.ErrorRendererFunc(func(w io.Writer, err error) {
+		fmt.Fprintf(w, "✗ %v\n", err)
+	})
+	ctx, code := s.run(c, renderer, "blah", "--option", "error")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "✗ BAM!\n")
+}
+
+func (s *ErrorRendererSuite) TestDefault(c *gc.C) {
+	ctx, code := s.run(c, nil, "blah", "--option", "error")
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR BAM!\n")
+}
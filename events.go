@@ -0,0 +1,51 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+// EventKind identifies the kind of an Event.
+type EventKind string
+
+const (
+	// EventWarning is emitted for each warning logged through the
+	// context, with its code if it has one.
+	EventWarning EventKind = "warning"
+
+	// EventProgress is emitted for each progress update written to the
+	// user, such as the keep-alive lines written by WithKeepAlive.
+	EventProgress EventKind = "progress"
+
+	// EventPrompt is emitted for each question put to the user, with the
+	// user's response.
+	EventPrompt EventKind = "prompt"
+)
+
+// Event is a structured record of something the framework told the user,
+// or asked them, on behalf of a command.
+type Event struct {
+	Kind EventKind
+
+	// Code holds the code of a warning, if it has one.
+	Code WarningCode
+
+	// Message holds the text of a warning or progress update, or the
+	// question asked by a prompt.
+	Message string
+
+	// Response holds the user's response to a prompt.
+	Response string
+}
+
+// EventObserver receives the events emitted through a Context. It is
+// mainly intended for tests, which can assert on events rather than on the
+// text written to Stderr. Events may be emitted concurrently.
+type EventObserver interface {
+	ObserveEvent(event Event)
+}
+
+// emit sends event to the context's observer, if it has one.
+func (ctx *Context) emit(event Event) {
+	if ctx.Observer != nil {
+		ctx.Observer.ObserveEvent(event)
+	}
+}
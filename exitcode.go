@@ -0,0 +1,71 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/utils/v4"
+)
+
+// UsageErrorCategory is the category of the errors that stop a command
+// before it runs, such as unknown flags or missing arguments, for use as
+// a key of SuperCommandParams.ExitCodes.
+const UsageErrorCategory = "usage"
+
+// codeError is an error that Main exits with a particular code.
+type codeError struct {
+	err  error
+	code int
+}
+
+// Error implements error.
+func (e *codeError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *codeError) Unwrap() error {
+	return e.err
+}
+
+// ErrWithCode returns an error that Main writes like err, but that makes
+// it exit with the given code. It returns nil if err is nil.
+func ErrWithCode(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	return &codeError{err: err, code: code}
+}
+
+// ExitCode returns the code that Main exits with for err, or zero if err
+// is nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return errorExitCode(err, nil, PartialSuccessExitCode, 1)
+}
+
+// errorExitCode returns the exit code for err. Codes given with
+// ErrWithCode or an RcPassthroughError come first, then those of bulk
+// errors, using partialCode for partial failures, and then codes mapped
+// from the category of err. If none apply, fallback is returned.
+func errorExitCode(err error, codes map[string]int, partialCode, fallback int) int {
+	var withCode *codeError
+	if errors.As(err, &withCode) {
+		return withCode.code
+	}
+	var rc *utils.RcPassthroughError
+	if errors.As(err, &rc) {
+		return rc.Code
+	}
+	var bulk *BulkError
+	if errors.As(err, &bulk) {
+		return bulk.exitCode(partialCode)
+	}
+	if code, ok := codes[errorCategory(err)]; ok {
+		return code
+	}
+	return fallback
+}
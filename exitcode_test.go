@@ -0,0 +1,100 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	"github.com/juju/utils/v4"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ExitCodeSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ExitCodeSuite{})
+
+var exitCodes = map[string]int{
+	cmd.UsageErrorCategory: 64,
+	"not-found":            4,
+	"unauthorized":         5,
+}
+
+func (s *ExitCodeSuite) run(c *gc.C, codes map[string]int, runErr error, args ...string) (*cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:      "juju",
+		ExitCodes: codes,
+	})
+	sc.Register(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return runErr },
+	})
+	ctx := cmdtesting.Context(c)
+	return ctx, cmd.Main(sc, ctx, append([]string{"blah"}, args...))
+}
+
+func (s *ExitCodeSuite) TestErrWithCode(c *gc.C) {
+	err := cmd.ErrWithCode(errors.New("kaboom"), 7)
+	c.Assert(err, gc.ErrorMatches, "kaboom")
+	c.Assert(cmd.ExitCode(err), gc.Equals, 7)
+	c.Assert(cmd.ErrWithCode(nil, 7), gc.IsNil)
+	c.Assert(cmd.ExitCode(nil), gc.Equals, 0)
+	c.Assert(cmd.ExitCode(errors.New("kaboom")), gc.Equals, 1)
+
+	ctx, code := s.run(c, nil, err)
+	c.Assert(code, gc.Equals, 7)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR kaboom\n")
+}
+
+func (s *ExitCodeSuite) TestErrWithCodeSilent(c *gc.C) {
+	ctx, code := s.run(c, nil, cmd.ErrWithCode(cmd.ErrSilent, 7))
+	c.Assert(code, gc.Equals, 7)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *ExitCodeSuite) TestErrWithCodeTakesPrecedence(c *gc.C) {
+	_, code := s.run(c, exitCodes, cmd.ErrWithCode(errors.NotFoundf("app"), 7))
+	c.Assert(code, gc.Equals, 7)
+}
+
+func (s *ExitCodeSuite) TestCategoryCodes(c *gc.C) {
+	ctx, code := s.run(c, exitCodes, errors.NotFoundf("app"))
+	c.Assert(code, gc.Equals, 4)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR app not found\n")
+
+	_, code = s.run(c, exitCodes, errors.Unauthorizedf("no"))
+	c.Assert(code, gc.Equals, 5)
+
+	_, code = s.run(c, exitCodes, cmd.SilenceError(errors.NotFoundf("app")))
+	c.Assert(code, gc.Equals, 4)
+
+	_, code = s.run(c, exitCodes, errors.New("kaboom"))
+	c.Assert(code, gc.Equals, 1)
+}
+
+func (s *ExitCodeSuite) TestCategoryCodesUnset(c *gc.C) {
+	_, code := s.run(c, nil, errors.NotFoundf("app"))
+	c.Assert(code, gc.Equals, 1)
+}
+
+func (s *ExitCodeSuite) TestUsageCode(c *gc.C) {
+	ctx, code := s.run(c, exitCodes, nil, "--unknown")
+	c.Assert(code, gc.Equals, 64)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR flag provided but not defined: --unknown\n")
+
+	_, code = s.run(c, nil, nil, "--unknown")
+	c.Assert(code, gc.Equals, 2)
+}
+
+func (s *ExitCodeSuite) TestWrappedCodes(c *gc.C) {
+	rc := errors.Annotate(utils.NewRcPassthroughError(9), "running hook")
+	c.Assert(cmd.ExitCode(rc), gc.Equals, 9)
+
+	_, code := s.run(c, exitCodes, errors.Annotate(bulkFailure(), "removing units"))
+	c.Assert(code, gc.Equals, cmd.BulkErrorExitCode)
+}
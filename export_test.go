@@ -3,6 +3,13 @@
 
 package cmd
 
+import (
+	"io"
+	"time"
+
+	"github.com/juju/clock"
+)
+
 func NewVersionCommand(version string, versionDetail interface{}) Command {
 	return newVersionCommand(version, versionDetail)
 }
@@ -12,3 +19,22 @@ func FormatCommand(command Command, super *SuperCommand, title bool, commandSeq
 	ref := commandReference{command: command}
 	return docCmd.formatCommand(ref, title, commandSeq)
 }
+
+var ContainerMarkerFiles = &containerMarkerFiles
+var SplitArgFile = splitArgFile
+var IsTerminal = &isTerminal
+var IsInputTerminal = &isInputTerminal
+var GOOS = &goos
+var QuoteArgFileArg = quoteArgFileArg
+var FileURLPath = fileURLPath
+
+func NewFormatterValue(initial string, formatters map[string]Formatter) interface {
+	Set(string) error
+	String() string
+} {
+	return newFormatterValue(initial, formatters)
+}
+
+func OpenRotatingFile(path string, maxSize int64, maxBackups int, maxAge time.Duration, clk clock.Clock) (io.WriteCloser, error) {
+	return openRotatingFile(path, maxSize, maxBackups, maxAge, clk)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+)
+
+// failedOutputDirname is the directory below the data directory in which
+// the output of failed commands is saved.
+const failedOutputDirname = "failed-output"
+
+// maxFailedOutput is the most output of a command that is kept to be
+// saved if it fails. Later output is not saved.
+const maxFailedOutput = 16 * 1024 * 1024
+
+// outputRecorder keeps the output written to it, and counts its lines.
+type outputRecorder struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines int
+}
+
+// Write implements io.Writer.
+func (r *outputRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines += bytes.Count(p, []byte("\n"))
+	if room := maxFailedOutput - r.buf.Len(); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		r.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// failedOutput records the output of a subcommand, to save it if the
+// subcommand fails after writing at least minLines lines.
+type failedOutput struct {
+	recorder       *outputRecorder
+	stdout, stderr io.Writer
+	dir            string
+	minLines       int
+}
+
+// recordOutput starts recording the output written to ctx, if the output
+// of failed commands is to be saved.
+func (c *SuperCommand) recordOutput(ctx *Context) *failedOutput {
+	if c.failedOutputDir == "" || c.failedOutputLines <= 0 {
+		return nil
+	}
+	f := &failedOutput{
+		recorder: &outputRecorder{},
+		stdout:   ctx.Stdout,
+		stderr:   ctx.Stderr,
+		dir:      c.failedOutputDir,
+		minLines: c.failedOutputLines,
+	}
+	ctx.Stdout = newCaptureWriter(ctx.Stdout, f.recorder)
+	ctx.Stderr = newCaptureWriter(ctx.Stderr, f.recorder)
+	return f
+}
+
+// finish stops recording the output of the command. If the command
+// failed with err after writing enough output, the output is saved to a
+// file named after the command and the time, and the path of the file is
+// written to Stderr.
+func (f *failedOutput) finish(ctx *Context, command string, err error) {
+	if f == nil {
+		return
+	}
+	ctx.Stdout, ctx.Stderr = f.stdout, f.stderr
+	f.recorder.mu.Lock()
+	lines, output := f.recorder.lines, f.recorder.buf.Bytes()
+	f.recorder.mu.Unlock()
+	if err == nil || lines < f.minLines {
+		return
+	}
+	name := fmt.Sprintf("%s-%s.log",
+		strings.Join(strings.Fields(command), "-"),
+		ctx.clock().Now().UTC().Format("20060102T150405Z"))
+	path := filepath.Join(f.dir, name)
+	if err := saveFailedOutput(path, output); err != nil {
+		logger.Debugf("cannot save the output of %q: %v", command, err)
+		return
+	}
+	ctx.Print(SeverityNotice, fmt.Sprintf("the full output has been saved to %s", path))
+}
+
+// saveFailedOutput writes output, with any secrets redacted, to path.
+func saveFailedOutput(path string, output []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(path, []byte(Redact(string(output))), 0600))
+}
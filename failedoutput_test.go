@@ -0,0 +1,87 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type FailedOutputSuite struct {
+	testing.IsolationSuite
+
+	dataDir string
+}
+
+var _ = gc.Suite(&FailedOutputSuite{})
+
+func (s *FailedOutputSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.dataDir = c.MkDir()
+}
+
+func (s *FailedOutputSuite) run(c *gc.C, lines int, fail bool) (*cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:                  "juju",
+		DataDir:               s.dataDir,
+		SaveFailedOutputLines: 3,
+	})
+	sc.Register(&TestCommand{
+		Name: "blah",
+		CustomRun: func(ctx *cmd.Context) error {
+			for i := 0; i < lines; i++ {
+				fmt.Fprintf(ctx.Stdout, "line %d\n", i)
+			}
+			fmt.Fprintln(ctx.Stderr, "progress")
+			if fail {
+				return errors.New("kaboom")
+			}
+			return nil
+		},
+	})
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = testclock.NewClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	return ctx, cmd.Main(sc, ctx, []string{"blah"})
+}
+
+func (s *FailedOutputSuite) TestSavesOutput(c *gc.C) {
+	ctx, code := s.run(c, 2, true)
+	c.Assert(code, gc.Equals, 1)
+
+	path := filepath.Join(s.dataDir, "failed-output", "juju-blah-20240501T120000Z.log")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, ""+
+		"progress\n"+
+		"ERROR kaboom\n"+
+		"NOTICE the full output has been saved to "+path+"\n")
+	data, err := os.ReadFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "line 0\nline 1\nprogress\nERROR kaboom\n")
+}
+
+func (s *FailedOutputSuite) TestTooLittleOutput(c *gc.C) {
+	ctx, code := s.run(c, 0, true)
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "progress\nERROR kaboom\n")
+	_, err := os.Stat(filepath.Join(s.dataDir, "failed-output"))
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
+
+func (s *FailedOutputSuite) TestSuccess(c *gc.C) {
+	ctx, code := s.run(c, 5, false)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "progress\n")
+	_, err := os.Stat(filepath.Join(s.dataDir, "failed-output"))
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
@@ -4,10 +4,17 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/juju/utils/v4"
 )
@@ -20,8 +27,18 @@ type FileVar struct {
 	// StdinMarkers are the Path values that should be interpreted as
 	// stdin. If it is empty then stdin is not supported.
 	StdinMarkers []string
+
+	// AllowURL allows Path to be an http, https or file URL, whose
+	// content is read in place of a local file. Requests are cancelled
+	// with the Context, and time out after URLTimeout if the Context has
+	// no deadline of its own.
+	AllowURL bool
 }
 
+// URLTimeout is the time allowed to fetch a URL given to a FileVar, when
+// the Context has no deadline.
+var URLTimeout = time.Minute
+
 var ErrNoPath = errors.New("path not set")
 
 // Set stores the chosen path name in f.Path.
@@ -57,6 +74,9 @@ func (f *FileVar) Open(ctx *Context) (io.ReadCloser, error) {
 	if f.IsStdin() {
 		return ioutil.NopCloser(ctx.Stdin), nil
 	}
+	if u, ok := f.url(); ok {
+		return openURL(ctx, u)
+	}
 
 	path, err := utils.NormalizePath(f.Path)
 	if err != nil {
@@ -73,6 +93,14 @@ func (f *FileVar) Read(ctx *Context) ([]byte, error) {
 	if f.IsStdin() {
 		return ioutil.ReadAll(ctx.Stdin)
 	}
+	if u, ok := f.url(); ok {
+		r, err := openURL(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
 
 	path, err := utils.NormalizePath(f.Path)
 	if err != nil {
@@ -85,3 +113,169 @@ func (f *FileVar) Read(ctx *Context) ([]byte, error) {
 func (f *FileVar) String() string {
 	return f.Path
 }
+
+// url returns the URL given as the path, if URLs are allowed.
+func (f *FileVar) url() (*url.URL, bool) {
+	if !f.AllowURL {
+		return nil, false
+	}
+	u, err := url.Parse(f.Path)
+	if err != nil {
+		return nil, false
+	}
+	switch u.Scheme {
+	case "http", "https", "file":
+		return u, true
+	}
+	return nil, false
+}
+
+// fileURLPath returns the local path named by the path of a file URL.
+// On Windows, the slash before a drive letter, as in "/C:/x", is dropped.
+func fileURLPath(p string) string {
+	if goos == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':' &&
+		('a' <= p[1] && p[1] <= 'z' || 'A' <= p[1] && p[1] <= 'Z') {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
+
+// openURL opens the content of the http, https or file URL u.
+func openURL(ctx *Context, u *url.URL) (io.ReadCloser, error) {
+	if u.Scheme == "file" {
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("cannot read %q: file URLs must not name a remote host", u)
+		}
+		return os.Open(fileURLPath(u.Path))
+	}
+	c := ctx.background()
+	cancel := func() {}
+	if _, ok := c.Deadline(); !ok {
+		c, cancel = context.WithTimeout(c, URLTimeout)
+	}
+	req, err := http.NewRequestWithContext(c, http.MethodGet, u.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := ctx.httpClient().Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("cannot fetch %q: %v", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("cannot fetch %q: %s", u, resp.Status)
+	}
+	return &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// cancelReadCloser cancels the request it reads the body of when closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel func()
+}
+
+// Close implements io.Closer.
+func (r *cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// MultiFileVar represents the paths of several files, given by repeating a
+// flag. Each path may be a glob pattern, as understood by filepath.Match,
+// standing for the files it matches.
+type MultiFileVar struct {
+	// Paths are the paths and patterns given.
+	Paths []string
+
+	// MaxSize, if not zero, is the largest number of bytes that may be
+	// read from any one of the files.
+	MaxSize int64
+}
+
+// Set adds the path or pattern to f.Paths.
+func (f *MultiFileVar) Set(v string) error {
+	f.Paths = append(f.Paths, v)
+	return nil
+}
+
+// String returns the paths and patterns, separated by commas.
+func (f *MultiFileVar) String() string {
+	return strings.Join(f.Paths, ",")
+}
+
+// ReadAll returns the contents of the files, keyed by their paths. Files
+// matched by a pattern are keyed by the path they were found at, relative
+// to the context's directory if the pattern was. It is an error for a
+// pattern to match no files, or for a file to be larger than MaxSize.
+func (f *MultiFileVar) ReadAll(ctx *Context) (map[string][]byte, error) {
+	if len(f.Paths) == 0 {
+		return nil, ErrNoPath
+	}
+	contents := make(map[string][]byte)
+	for _, given := range f.Paths {
+		paths, err := f.expand(ctx, given)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if _, ok := contents[path]; ok {
+				continue
+			}
+			if contents[path], err = f.read(ctx.AbsPath(path)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return contents, nil
+}
+
+// expand returns the paths of the files given by path, which may be a
+// glob pattern.
+func (f *MultiFileVar) expand(ctx *Context, path string) ([]string, error) {
+	path, err := utils.NormalizePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.ContainsAny(path, "*?[") {
+		return []string{path}, nil
+	}
+	matches, err := filepath.Glob(ctx.AbsPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", path, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files match %q", path)
+	}
+	if !filepath.IsAbs(path) {
+		for i, match := range matches {
+			if rel, err := filepath.Rel(ctx.Dir, match); err == nil {
+				matches[i] = rel
+			}
+		}
+	}
+	return matches, nil
+}
+
+// read returns the contents of the file at path, checking its size.
+func (f *MultiFileVar) read(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if f.MaxSize <= 0 {
+		return ioutil.ReadAll(file)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(file, f.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > f.MaxSize {
+		return nil, fmt.Errorf("file %q is larger than %d bytes", path, f.MaxSize)
+	}
+	return content, nil
+}
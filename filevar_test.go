@@ -7,8 +7,12 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/juju/gnuflag"
 	gitjujutesting "github.com/juju/testing"
@@ -195,3 +199,158 @@ func fs() (*gnuflag.FlagSet, *cmd.FileVar) {
 	fs.Var(&config, "config", "the config")
 	return fs, &config
 }
+
+type MultiFileVarSuite struct {
+	ctx *cmd.Context
+}
+
+var _ = gc.Suite(&MultiFileVarSuite{})
+
+func (s *MultiFileVarSuite) SetUpTest(c *gc.C) {
+	s.ctx = cmdtesting.Context(c)
+	for name, content := range map[string]string{
+		"bundle.yaml":          "bundle",
+		"overlays/one.yaml":    "one",
+		"overlays/two.yaml":    "two",
+		"overlays/readme.txt":  "readme",
+		"overlays/large.yaml~": "0123456789",
+	} {
+		path := filepath.Join(s.ctx.Dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), gc.IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0644), gc.IsNil)
+	}
+}
+
+func (s *MultiFileVarSuite) TestSet(c *gc.C) {
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	var files cmd.MultiFileVar
+	f.Var(&files, "file", "help")
+	err := f.Parse(false, []string{"--file", "bundle.yaml", "--file=overlays/*.yaml"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(files.Paths, gc.DeepEquals, []string{"bundle.yaml", "overlays/*.yaml"})
+	c.Assert(files.String(), gc.Equals, "bundle.yaml,overlays/*.yaml")
+}
+
+func (s *MultiFileVarSuite) TestReadAll(c *gc.C) {
+	files := cmd.MultiFileVar{Paths: []string{"bundle.yaml", "overlays/*.yaml", "overlays/one.yaml"}}
+	contents, err := files.ReadAll(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(contents, gc.DeepEquals, map[string][]byte{
+		"bundle.yaml":       []byte("bundle"),
+		"overlays/one.yaml": []byte("one"),
+		"overlays/two.yaml": []byte("two"),
+	})
+}
+
+func (s *MultiFileVarSuite) TestReadAllAbsolutePattern(c *gc.C) {
+	pattern := filepath.Join(s.ctx.Dir, "overlays", "*.txt")
+	files := cmd.MultiFileVar{Paths: []string{pattern}}
+	contents, err := files.ReadAll(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(contents, gc.DeepEquals, map[string][]byte{
+		filepath.Join(s.ctx.Dir, "overlays", "readme.txt"): []byte("readme"),
+	})
+}
+
+func (s *MultiFileVarSuite) TestReadAllMaxSize(c *gc.C) {
+	files := cmd.MultiFileVar{Paths: []string{"overlays/*"}, MaxSize: 6}
+	_, err := files.ReadAll(s.ctx)
+	c.Assert(err, gc.ErrorMatches, `file ".*large.yaml~" is larger than 6 bytes`)
+
+	files.Paths = []string{"overlays/*.txt", "bundle.yaml"}
+	contents, err := files.ReadAll(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(contents, gc.HasLen, 2)
+}
+
+func (s *MultiFileVarSuite) TestReadAllErrors(c *gc.C) {
+	_, err := (&cmd.MultiFileVar{}).ReadAll(s.ctx)
+	c.Assert(err, gc.Equals, cmd.ErrNoPath)
+
+	_, err = (&cmd.MultiFileVar{Paths: []string{"*.json"}}).ReadAll(s.ctx)
+	c.Assert(err, gc.ErrorMatches, `no files match "\*.json"`)
+
+	_, err = (&cmd.MultiFileVar{Paths: []string{"[.yaml"}}).ReadAll(s.ctx)
+	c.Assert(err, gc.ErrorMatches, `invalid pattern "\[.yaml": .*`)
+
+	_, err = (&cmd.MultiFileVar{Paths: []string{"missing.yaml"}}).ReadAll(s.ctx)
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+type FileVarURLSuite struct {
+	ctx    *cmd.Context
+	server *httptest.Server
+}
+
+var _ = gc.Suite(&FileVarURLSuite{})
+
+func (s *FileVarURLSuite) SetUpTest(c *gc.C) {
+	s.ctx = cmdtesting.Context(c)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bundle.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("remote bundle"))
+	}))
+}
+
+func (s *FileVarURLSuite) TearDownTest(c *gc.C) {
+	s.server.Close()
+}
+
+func (s *FileVarURLSuite) TestReadHTTP(c *gc.C) {
+	fv := cmd.FileVar{Path: s.server.URL + "/bundle.yaml", AllowURL: true}
+	content, err := fv.Read(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(content), gc.Equals, "remote bundle")
+
+	file, err := fv.Open(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	defer file.Close()
+	content, err = ioutil.ReadAll(file)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(content), gc.Equals, "remote bundle")
+}
+
+func (s *FileVarURLSuite) TestReadHTTPNotFound(c *gc.C) {
+	fv := cmd.FileVar{Path: s.server.URL + "/missing.yaml", AllowURL: true}
+	_, err := fv.Read(s.ctx)
+	c.Assert(err, gc.ErrorMatches, `cannot fetch ".*/missing.yaml": 404 Not Found`)
+}
+
+func (s *FileVarURLSuite) TestReadFileURL(c *gc.C) {
+	path := filepath.Join(s.ctx.Dir, "local.yaml")
+	c.Assert(ioutil.WriteFile(path, []byte("local bundle"), 0644), jc.ErrorIsNil)
+	u := url.URL{Scheme: "file", Path: "/" + strings.TrimPrefix(filepath.ToSlash(path), "/")}
+	fv := cmd.FileVar{Path: u.String(), AllowURL: true}
+	content, err := fv.Read(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(content), gc.Equals, "local bundle")
+}
+
+func (s *FileVarURLSuite) TestFileURLPath(c *gc.C) {
+	defer func(goos string) { *cmd.GOOS = goos }(*cmd.GOOS)
+	for i, test := range []struct {
+		goos string
+		path string
+		want string
+	}{
+		{"linux", "/home/x.yaml", "/home/x.yaml"},
+		{"linux", "/C:/x.yaml", "/C:/x.yaml"},
+		{"windows", "/C:/x.yaml", "C:/x.yaml"},
+		{"windows", "/c:/x.yaml", "c:/x.yaml"},
+		{"windows", "/share/x.yaml", "/share/x.yaml"},
+		{"windows", "/1:/x.yaml", "/1:/x.yaml"},
+	} {
+		c.Logf("test %d: %s %s", i, test.goos, test.path)
+		*cmd.GOOS = test.goos
+		c.Check(cmd.FileURLPath(test.path), gc.Equals, filepath.FromSlash(test.want))
+	}
+}
+
+func (s *FileVarURLSuite) TestURLNotAllowed(c *gc.C) {
+	fv := cmd.FileVar{Path: s.server.URL + "/bundle.yaml"}
+	_, err := fv.Read(s.ctx)
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
@@ -0,0 +1,229 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// DurationValue implements gnuflag.Value for a duration such as "30s",
+// "5m" or "1h30m". Values are parsed by ParseDuration in Lenient mode.
+type DurationValue time.Duration
+
+var _ gnuflag.Getter = (*DurationValue)(nil)
+
+// NewDurationValue is used to create the type passed into the gnuflag.FlagSet Var function.
+// f.Var(cmd.NewDurationValue(defaultValue, &someMember), "name", "help")
+func NewDurationValue(defaultValue time.Duration, target *time.Duration) *DurationValue {
+	*target = defaultValue
+	return (*DurationValue)(target)
+}
+
+// Implements gnuflag.Value Set.
+func (v *DurationValue) Set(s string) error {
+	d, err := ParseDuration(s, Lenient)
+	if err != nil {
+		return err
+	}
+	*v = DurationValue(d)
+	return nil
+}
+
+// Implements gnuflag.Value String.
+func (v *DurationValue) String() string {
+	return time.Duration(*v).String()
+}
+
+// Implements gnuflag.Getter Get.
+func (v *DurationValue) Get() interface{} {
+	return time.Duration(*v)
+}
+
+// sizeSuffixes holds the multipliers of the size suffixes accepted by
+// SizeValue, largest first. Sizes are binary, so "1K" is 1024 bytes.
+var sizeSuffixes = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	{"P", 1 << 50},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// SizeValue implements gnuflag.Value for a size in bytes, given as a number
+// of bytes or with one of the binary suffixes K, M, G, T or P, optionally
+// followed by "iB" or "B", e.g. "512M", "2G" or "1.5GiB". The number is
+// parsed by ParseFloat in Lenient mode, so "1,024K" is also accepted.
+type SizeValue uint64
+
+var _ gnuflag.Getter = (*SizeValue)(nil)
+
+// NewSizeValue is used to create the type passed into the gnuflag.FlagSet Var function.
+// f.Var(cmd.NewSizeValue(defaultValue, &someMember), "name", "help")
+func NewSizeValue(defaultValue uint64, target *uint64) *SizeValue {
+	*target = defaultValue
+	return (*SizeValue)(target)
+}
+
+// Implements gnuflag.Value Set.
+func (v *SizeValue) Set(s string) error {
+	size, err := parseSize(s)
+	if err != nil {
+		return err
+	}
+	*v = SizeValue(size)
+	return nil
+}
+
+// Implements gnuflag.Value String. The size is shown with the largest
+// suffix that represents it exactly.
+func (v *SizeValue) String() string {
+	size := uint64(*v)
+	for _, s := range sizeSuffixes {
+		if size != 0 && size%s.multiplier == 0 {
+			return strconv.FormatUint(size/s.multiplier, 10) + s.suffix
+		}
+	}
+	return strconv.FormatUint(size, 10)
+}
+
+// Implements gnuflag.Getter Get.
+func (v *SizeValue) Get() interface{} {
+	return uint64(*v)
+}
+
+// parseSize parses s as described for SizeValue.
+func parseSize(s string) (uint64, error) {
+	number := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := uint64(1)
+suffixes:
+	for _, suffix := range sizeSuffixes {
+		for _, form := range []string{suffix.suffix + "IB", suffix.suffix + "B", suffix.suffix} {
+			if strings.HasSuffix(number, form) {
+				number = strings.TrimSuffix(number, form)
+				multiplier = suffix.multiplier
+				break suffixes
+			}
+		}
+	}
+	if multiplier == 1 {
+		number = strings.TrimSuffix(number, "B")
+	}
+	value, err := ParseFloat(number, Lenient)
+	if err != nil || math.IsNaN(value) || value < 0 || value*float64(multiplier) >= math.MaxUint64 {
+		return 0, errors.Errorf("invalid size %q (expected e.g. 512M or 2G)", s)
+	}
+	return uint64(value * float64(multiplier)), nil
+}
+
+// PercentValue implements gnuflag.Value for a whole percentage from 0 to
+// 100, given with or without a trailing "%". The number is parsed by
+// ParseInt in Lenient mode.
+type PercentValue int
+
+var _ gnuflag.Getter = (*PercentValue)(nil)
+
+// NewPercentValue is used to create the type passed into the gnuflag.FlagSet Var function.
+// f.Var(cmd.NewPercentValue(defaultValue, &someMember), "name", "help")
+func NewPercentValue(defaultValue int, target *int) *PercentValue {
+	*target = defaultValue
+	return (*PercentValue)(target)
+}
+
+// Implements gnuflag.Value Set.
+func (v *PercentValue) Set(s string) error {
+	n, err := ParseInt(strings.TrimSuffix(strings.TrimSpace(s), "%"), Lenient)
+	if err != nil || n < 0 || n > 100 {
+		return errors.Errorf("invalid percentage %q (expected a whole number from 0 to 100)", s)
+	}
+	*v = PercentValue(n)
+	return nil
+}
+
+// Implements gnuflag.Value String.
+func (v *PercentValue) String() string {
+	return strconv.Itoa(int(*v)) + "%"
+}
+
+// Implements gnuflag.Getter Get.
+func (v *PercentValue) Get() interface{} {
+	return int(*v)
+}
+
+// EnumValue implements gnuflag.Value for a flag whose value is one of a
+// fixed set of choices. The choices are offered when the flag's value is
+// completed, and are added to the flag's usage in help and documentation:
+//
+//	f.Var(cmd.NewEnumValue([]string{"fast", "safe"}, "safe"), "mode", "Specify the upgrade mode")
+type EnumValue struct {
+	allowed []string
+	value   string
+}
+
+var (
+	_ gnuflag.Getter = (*EnumValue)(nil)
+	_ ValueCompleter = (*EnumValue)(nil)
+)
+
+// NewEnumValue returns an EnumValue accepting the allowed values, set to
+// def. The default must be one of the allowed values, or empty.
+func NewEnumValue(allowed []string, def string) *EnumValue {
+	v := &EnumValue{allowed: allowed}
+	if def != "" {
+		if err := v.Set(def); err != nil {
+			panic(err)
+		}
+	}
+	return v
+}
+
+// Implements gnuflag.Value Set.
+func (v *EnumValue) Set(s string) error {
+	for _, allowed := range v.allowed {
+		if s == allowed {
+			v.value = s
+			return nil
+		}
+	}
+	return errors.Errorf("invalid value %q (expected one of %s)", s, strings.Join(v.allowed, ", "))
+}
+
+// Implements gnuflag.Value String.
+func (v *EnumValue) String() string {
+	return v.value
+}
+
+// Implements gnuflag.Getter Get.
+func (v *EnumValue) Get() interface{} {
+	return v.value
+}
+
+// CompleteValue implements ValueCompleter.
+func (v *EnumValue) CompleteValue() []string {
+	return append([]string(nil), v.allowed...)
+}
+
+// describeChoices adds the allowed values of the EnumValue flags in f to
+// their usage, e.g. "Specify the upgrade mode (fast|safe)".
+func describeChoices(f *gnuflag.FlagSet) {
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		v, ok := flag.Value.(*EnumValue)
+		if !ok {
+			return
+		}
+		choices := "(" + strings.Join(v.allowed, "|") + ")"
+		if strings.HasSuffix(flag.Usage, choices) {
+			return
+		}
+		flag.Usage = strings.TrimSpace(flag.Usage + " " + choices)
+	})
+}
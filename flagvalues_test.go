@@ -0,0 +1,232 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/juju/gnuflag"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+type FlagValuesSuite struct{}
+
+var _ = gc.Suite(&FlagValuesSuite{})
+
+func (*FlagValuesSuite) parse(c *gc.C, value gnuflag.Value, args ...string) error {
+	f := gnuflag.NewFlagSet("test", gnuflag.ContinueOnError)
+	f.SetOutput(ioutil.Discard)
+	f.Var(value, "value", "help")
+	return f.Parse(false, args)
+}
+
+func (s *FlagValuesSuite) TestDurationValue(c *gc.C) {
+	for i, test := range []struct {
+		arg      string
+		expected time.Duration
+		err      string
+	}{{
+		arg:      "30s",
+		expected: 30 * time.Second,
+	}, {
+		arg:      " 1h30m ",
+		expected: 90 * time.Minute,
+	}, {
+		arg:      "1h 30m",
+		expected: 90 * time.Minute,
+	}, {
+		arg:      "1,500ms",
+		expected: 1500 * time.Millisecond,
+	}, {
+		arg: "5 minutes",
+		err: `invalid value "5 minutes" for flag --value: invalid duration "5 minutes" \(expected e.g. 30s, 5m or 1h30m\)`,
+	}} {
+		c.Logf("test %d: %q", i, test.arg)
+		var d time.Duration
+		err := s.parse(c, cmd.NewDurationValue(time.Minute, &d), "--value", test.arg)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(d, gc.Equals, test.expected)
+	}
+}
+
+func (s *FlagValuesSuite) TestDurationValueDefault(c *gc.C) {
+	var d time.Duration
+	value := cmd.NewDurationValue(5*time.Minute, &d)
+	c.Check(d, gc.Equals, 5*time.Minute)
+	c.Check(value.String(), gc.Equals, "5m0s")
+	c.Check(value.Get(), gc.Equals, 5*time.Minute)
+}
+
+func (s *FlagValuesSuite) TestSizeValue(c *gc.C) {
+	for i, test := range []struct {
+		arg      string
+		expected uint64
+		err      string
+	}{{
+		arg:      "1024",
+		expected: 1024,
+	}, {
+		arg:      "512M",
+		expected: 512 << 20,
+	}, {
+		arg:      "2g",
+		expected: 2 << 30,
+	}, {
+		arg:      "1.5GiB",
+		expected: 3 << 29,
+	}, {
+		arg:      "4KB",
+		expected: 4 << 10,
+	}, {
+		arg:      "100B",
+		expected: 100,
+	}, {
+		arg:      "1T",
+		expected: 1 << 40,
+	}, {
+		arg:      " 1,024K ",
+		expected: 1 << 20,
+	}, {
+		arg:      "1 536.5",
+		expected: 1536,
+	}, {
+		arg: "1,5G",
+		err: `invalid value "1,5G" for flag --value: invalid size "1,5G" .*`,
+	}, {
+		arg: "-1M",
+		err: `invalid value "-1M" for flag --value: invalid size "-1M" \(expected e.g. 512M or 2G\)`,
+	}, {
+		arg: "2X",
+		err: `invalid value "2X" for flag --value: invalid size "2X" .*`,
+	}, {
+		arg: "NaN",
+		err: `invalid value "NaN" for flag --value: invalid size "NaN" .*`,
+	}, {
+		arg: "",
+		err: `invalid value "" for flag --value: invalid size "" .*`,
+	}} {
+		c.Logf("test %d: %q", i, test.arg)
+		var size uint64
+		err := s.parse(c, cmd.NewSizeValue(0, &size), "--value", test.arg)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(size, gc.Equals, test.expected)
+	}
+}
+
+func (s *FlagValuesSuite) TestSizeValueString(c *gc.C) {
+	for _, test := range []struct {
+		size     uint64
+		expected string
+	}{
+		{0, "0"},
+		{1000, "1000"},
+		{2048, "2K"},
+		{512 << 20, "512M"},
+		{3 << 29, "1536M"},
+		{1 << 40, "1T"},
+	} {
+		var size uint64
+		value := cmd.NewSizeValue(test.size, &size)
+		c.Check(value.String(), gc.Equals, test.expected)
+		c.Check(value.Get(), gc.Equals, test.size)
+	}
+}
+
+func (s *FlagValuesSuite) TestPercentValue(c *gc.C) {
+	for i, test := range []struct {
+		arg      string
+		expected int
+		err      string
+	}{{
+		arg:      "50",
+		expected: 50,
+	}, {
+		arg:      "75%",
+		expected: 75,
+	}, {
+		arg:      "0",
+		expected: 0,
+	}, {
+		arg:      "100%",
+		expected: 100,
+	}, {
+		arg:      " 25 %",
+		expected: 25,
+	}, {
+		arg: "101",
+		err: `invalid value "101" for flag --value: invalid percentage "101" \(expected a whole number from 0 to 100\)`,
+	}, {
+		arg: "-5%",
+		err: `invalid value "-5%" for flag --value: invalid percentage "-5%" .*`,
+	}, {
+		arg: "12.5",
+		err: `invalid value "12.5" for flag --value: invalid percentage "12.5" .*`,
+	}} {
+		c.Logf("test %d: %q", i, test.arg)
+		var percent int
+		err := s.parse(c, cmd.NewPercentValue(10, &percent), "--value", test.arg)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(percent, gc.Equals, test.expected)
+	}
+}
+
+func (s *FlagValuesSuite) TestPercentValueDefault(c *gc.C) {
+	var percent int
+	value := cmd.NewPercentValue(10, &percent)
+	c.Check(percent, gc.Equals, 10)
+	c.Check(value.String(), gc.Equals, "10%")
+	c.Check(value.Get(), gc.Equals, 10)
+}
+
+func (s *FlagValuesSuite) TestEnumValue(c *gc.C) {
+	value := cmd.NewEnumValue([]string{"fast", "safe"}, "safe")
+	c.Check(value.String(), gc.Equals, "safe")
+	c.Check(value.CompleteValue(), gc.DeepEquals, []string{"fast", "safe"})
+
+	err := s.parse(c, value, "--value", "fast")
+	c.Check(err, gc.IsNil)
+	c.Check(value.Get(), gc.Equals, "fast")
+
+	err = s.parse(c, value, "--value", "slow")
+	c.Check(err, gc.ErrorMatches, `invalid value "slow" for flag --value: invalid value "slow" \(expected one of fast, safe\)`)
+	c.Check(value.String(), gc.Equals, "fast")
+}
+
+func (s *FlagValuesSuite) TestEnumValueUsage(c *gc.C) {
+	f := gnuflag.NewFlagSet("upgrade", gnuflag.ContinueOnError)
+	f.Var(cmd.NewEnumValue([]string{"fast", "safe"}, "safe"), "mode", "The upgrade mode")
+	info := &cmd.Info{Name: "upgrade"}
+	expected := `
+Usage: upgrade [flags]
+
+Flags:
+--mode  (= safe)
+    The upgrade mode (fast|safe)
+`[1:]
+	c.Check(string(info.Help(f)), gc.Equals, expected)
+	// The choices are only added once.
+	c.Check(string(info.Help(f)), gc.Equals, expected)
+}
+
+func (s *FlagValuesSuite) TestEnumValueDefaults(c *gc.C) {
+	c.Check(cmd.NewEnumValue([]string{"fast", "safe"}, "").String(), gc.Equals, "")
+	c.Check(func() {
+		cmd.NewEnumValue([]string{"fast", "safe"}, "slow")
+	}, gc.PanicMatches, `invalid value "slow" \(expected one of fast, safe\)`)
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/juju/cmd/v4"
+)
+
+// The fuzz targets below cover the parsers that read untrusted user files
+// and flag values. Run them with e.g.
+//
+//	go test -run '^$' -fuzz FuzzSplitArgFile
+
+func FuzzParseAliasFile(f *testing.F) {
+	f.Add("foo=bar --baz\n# comment\n\nbad line\n=missing\nempty=\n")
+	f.Add("  spaced  =  a   b  \r\n")
+	f.Fuzz(func(t *testing.T, content string) {
+		filename := filepath.Join(t.TempDir(), "aliases")
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		for name, args := range cmd.ParseAliasFile(filename) {
+			if name == "" || name != strings.TrimSpace(name) || strings.Contains(name, "=") {
+				t.Errorf("invalid alias name %q", name)
+			}
+			if len(args) == 0 {
+				t.Errorf("alias %q has no arguments", name)
+			}
+			for _, arg := range args {
+				if arg == "" || len(strings.Fields(arg)) != 1 {
+					t.Errorf("alias %q has invalid argument %q", name, arg)
+				}
+			}
+		}
+	})
+}
+
+func FuzzStringMapSet(f *testing.F) {
+	f.Add("key=value", "key=other")
+	f.Add("=", "a==b")
+	f.Fuzz(func(t *testing.T, first, second string) {
+		var mapping map[string]string
+		m := cmd.StringMap{Mapping: &mapping}
+		for _, s := range []string{first, second} {
+			before := len(mapping)
+			if err := m.Set(s); err != nil {
+				if len(mapping) != before {
+					t.Errorf("failed Set(%q) changed the mapping", s)
+				}
+				continue
+			}
+			key, value, _ := strings.Cut(s, "=")
+			if mapping[key] != value {
+				t.Errorf("Set(%q) stored %q for %q", s, mapping[key], key)
+			}
+		}
+		_ = m.String()
+	})
+}
+
+func FuzzFormatterValueSet(f *testing.F) {
+	f.Add("yaml")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, name string) {
+		v := cmd.NewFormatterValue("smart", cmd.DefaultFormatters.Formatters())
+		err := v.Set(name)
+		if _, ok := cmd.DefaultFormatters[name]; ok != (err == nil) {
+			t.Errorf("Set(%q) returned %v", name, err)
+		}
+		if err == nil && v.String() != name {
+			t.Errorf("Set(%q) stored %q", name, v.String())
+		} else if err != nil && v.String() != "smart" {
+			t.Errorf("failed Set(%q) changed the value to %q", name, v.String())
+		}
+	})
+}
+
+func FuzzSplitArgFile(f *testing.F) {
+	f.Add("deploy 'my app' --config \"a=b\" # comment\n\\#not-comment")
+	f.Add("'unterminated")
+	f.Add("trailing\\")
+	f.Fuzz(func(t *testing.T, content string) {
+		args, err := cmd.SplitArgFile(content)
+		if err != nil {
+			return
+		}
+		// Quoting the arguments must read them back unchanged.
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = cmd.QuoteArgFileArg(arg)
+		}
+		again, err := cmd.SplitArgFile(strings.Join(quoted, "\n"))
+		if err != nil {
+			t.Fatalf("cannot read back quoted %q: %v", args, err)
+		}
+		if len(args) == 0 && len(again) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(args, again) {
+			t.Errorf("quoted %q read back as %q", args, again)
+		}
+	})
+}
+
+func FuzzQuoteArgFileArg(f *testing.F) {
+	f.Add("plain")
+	f.Add("it's # \"odd\"\\")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, arg string) {
+		args, err := cmd.SplitArgFile(cmd.QuoteArgFileArg(arg))
+		if err != nil {
+			t.Fatalf("cannot read back %q: %v", arg, err)
+		}
+		if len(args) != 1 || args[0] != arg {
+			t.Errorf("%q read back as %q", arg, args)
+		}
+	})
+}
@@ -6,17 +6,19 @@ toolchain go1.21.6
 
 require (
 	github.com/juju/ansiterm v1.0.0
+	github.com/juju/clock v1.0.3
 	github.com/juju/errors v1.0.0
 	github.com/juju/gnuflag v1.0.0
 	github.com/juju/loggo/v2 v2.0.0
 	github.com/juju/testing v1.2.0
 	github.com/juju/utils/v4 v4.0.0
+	golang.org/x/sys v0.17.0
+	golang.org/x/term v0.17.0
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	github.com/juju/clock v1.0.3 // indirect
 	github.com/juju/loggo v1.0.0 // indirect
 	github.com/juju/utils/v3 v3.1.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
@@ -27,5 +29,4 @@ require (
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
 	golang.org/x/crypto v0.19.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
-	golang.org/x/sys v0.17.0 // indirect
 )
@@ -21,6 +21,29 @@ type helpCommand struct {
 
 	target      *commandReference
 	targetSuper *SuperCommand
+
+	// viaFlag is true when help was requested with -h or --help rather
+	// than the help command.
+	viaFlag bool
+}
+
+// HelpRequest describes a request for help, given to the NotifyHelpRequest
+// callback of a SuperCommand.
+type HelpRequest struct {
+	// Args holds the arguments received by the help command.
+	Args []string
+
+	// Command holds the full name of the command help was requested for,
+	// e.g. "juju add-model", or is empty if no registered command was
+	// found.
+	Command string
+
+	// Target holds the command help was requested for, if it was found.
+	Target Command
+
+	// ViaFlag is true when help was requested with the -h or --help
+	// flags, and false when it was requested with the help command.
+	ViaFlag bool
 }
 
 func (c *helpCommand) init() {
@@ -48,16 +71,18 @@ func echo(s string) func() string {
 	return func() string { return s }
 }
 
-func (c *helpCommand) addTopic(name, short string, long func() string, aliases ...string) {
+func (c *helpCommand) addTopic(name string, t topic, aliases ...string) {
 	if _, found := c.topics[name]; found {
 		panic(fmt.Sprintf("help topic already added: %s", name))
 	}
-	c.topics[name] = topic{short, long, false}
+	t.name = name
+	c.topics[name] = t
+	t.alias = true
 	for _, alias := range aliases {
 		if _, found := c.topics[alias]; found {
 			panic(fmt.Sprintf("help topic already added: %s", alias))
 		}
-		c.topics[alias] = topic{short, long, true}
+		c.topics[alias] = t
 	}
 }
 
@@ -97,13 +122,16 @@ command.
 
 	f := gnuflag.NewFlagSetWithFlagKnownAs("", gnuflag.ContinueOnError, c.super.FlagKnownAs)
 	c.super.SetCommonFlags(f)
+	describeChoices(f)
 	f.SetOutput(buf)
 	f.PrintDefaults()
 	return buf.String()
 }
 
+// topicList lists the topics, with those without a category first and
+// the rest grouped below a heading for each category.
 func (c *helpCommand) topicList() string {
-	var topics []string
+	byCategory := make(map[string][]string)
 	longest := 0
 	for name, topic := range c.topics {
 		if topic.alias {
@@ -112,22 +140,61 @@ func (c *helpCommand) topicList() string {
 		if len(name) > longest {
 			longest = len(name)
 		}
-		topics = append(topics, name)
+		byCategory[topic.category] = append(byCategory[topic.category], name)
+	}
+	var categories []string
+	for category := range byCategory {
+		if category != "" {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	var lines []string
+	for _, category := range append([]string{""}, categories...) {
+		topics := byCategory[category]
+		if len(topics) == 0 {
+			continue
+		}
+		if category != "" {
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, category+":")
+		}
+		sort.Strings(topics)
+		for _, name := range topics {
+			shortHelp := c.topics[name].short
+			lines = append(lines, fmt.Sprintf("%-*s  %s", longest, name, shortHelp))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// setCategory sets the category of the named topic and its aliases.
+func (c *helpCommand) setCategory(name, category string) {
+	t, ok := c.topics[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown help topic: %s", name))
 	}
-	sort.Strings(topics)
-	for i, name := range topics {
-		shortHelp := c.topics[name].short
-		topics[i] = fmt.Sprintf("%-*s  %s", longest, name, shortHelp)
+	if t.name != "" {
+		name = t.name
+	}
+	for key, other := range c.topics {
+		if key == name || (other.alias && other.name == name) {
+			other.category = category
+			c.topics[key] = other
+		}
 	}
-	return fmt.Sprintf("%s", strings.Join(topics, "\n"))
 }
 
 func (c *helpCommand) Info() *Info {
 	return &Info{
-		Name:        "help",
-		Args:        "[topic]",
-		FlagKnownAs: c.super.FlagKnownAs,
-		Purpose:     helpPurpose,
+		Name:               "help",
+		Args:               "[topic]",
+		FlagKnownAs:        c.super.FlagKnownAs,
+		Purpose:            helpPurpose,
+		NonInteractiveSafe: true,
 		Doc: `
 See also: topics
 `,
@@ -138,8 +205,29 @@ func (c *helpCommand) Init(args []string) error {
 	if c.super.notifyHelp != nil {
 		c.super.notifyHelp(args)
 	}
+	c.target, c.targetSuper = nil, nil
+	err := c.findTarget(args)
+	if c.super.notifyHelpRequest != nil {
+		request := HelpRequest{Args: args, ViaFlag: c.viaFlag}
+		if err == nil && c.target != nil {
+			request.Command = c.targetSuper.commandPath(c.target.name)
+			request.Target = c.target.command
+		}
+		c.super.notifyHelpRequest(request)
+	}
+	return err
+}
 
-	logger.Tracef("helpCommand.Init: %#v", args)
+// findTarget finds the topic or command help was requested for.
+func (c *helpCommand) findTarget(args []string) error {
+	if !c.super.showVersion {
+		c.super.recordHelp(HelpEvent{
+			Kind:    HelpRequested,
+			Command: c.super.commandPath(args...),
+		})
+	}
+
+	logger.Tracef("helpCommand.Init: %#v", redactAll(args))
 	if len(args) == 0 {
 		// If there is no help topic specified, print basic usage if it is
 		// there.
@@ -152,7 +240,7 @@ func (c *helpCommand) Init(args []string) error {
 	// Before we start walking down the subcommand list, we want to check
 	// to see if the first part is there.
 	if _, ok := c.super.subcmds[args[0]]; !ok {
-		if c.super.missingCallback == nil && len(args) > 1 {
+		if c.super.missingCallback == nil && c.super.missingFlagCallback == nil && len(args) > 1 {
 			return fmt.Errorf("extra arguments to command help: %q", args[1:])
 		}
 		logger.Tracef("help not found, setting topic")
@@ -172,6 +260,7 @@ func (c *helpCommand) Init(args []string) error {
 		// error out.
 		logger.Tracef("target name: %s", c.target.name)
 		if super, ok := c.target.command.(*SuperCommand); ok {
+			super.loadDynamicCommands(c.super.dynamicContext)
 			c.targetSuper = super
 		} else if len(args) > 0 {
 			return fmt.Errorf("extra arguments to command help: %q", args)
@@ -180,7 +269,7 @@ func (c *helpCommand) Init(args []string) error {
 	return nil
 }
 
-func (c *helpCommand) getCommandHelp(super *SuperCommand, command Command, alias string) []byte {
+func (c *helpCommand) getCommandHelp(ctx *Context, super *SuperCommand, command Command, alias string) []byte {
 	info := command.Info()
 
 	if command != super {
@@ -220,10 +309,21 @@ func (c *helpCommand) getCommandHelp(super *SuperCommand, command Command, alias
 	}
 	f := gnuflag.NewFlagSetWithFlagKnownAs(info.Name, gnuflag.ContinueOnError, flagsAKA)
 	command.SetFlags(f)
+	var preview bool
+	addPreviewFlag(f, command, &preview)
+
+	// Only the help for nested super commands lists the subcommands of
+	// their subcommands, as the list for the top-level command is long
+	// enough already.
+	if sc, ok := command.(*SuperCommand); ok && c.super.nestedHelp && (sc != c.super || sc.nested) && sc.action.command == nil {
+		info.nestedSubcommands = sc.describeNestedCommands()
+	}
 
 	superf := gnuflag.NewFlagSetWithFlagKnownAs(super.Info().Name, gnuflag.ContinueOnError, flagsAKA)
 	super.SetFlags(superf)
-	return info.HelpWithSuperFlags(superf, f)
+	return info.helpWithSuperFlags(superf, f, func(url string) string {
+		return ctx.Link(ctx.Stdout, url, "")
+	})
 }
 
 func (c *helpCommand) Run(ctx *Context) error {
@@ -236,7 +336,10 @@ func (c *helpCommand) Run(ctx *Context) error {
 
 	// If the topic is a registered subcommand, then run the help command with it
 	if c.target != nil {
-		ctx.Stdout.Write(c.getCommandHelp(c.targetSuper, c.target.command, c.target.alias))
+		if rename, ok := c.target.check.(*renameCheck); ok {
+			fmt.Fprintf(ctx.Stdout, "Note: %s.\n\n", rename.notice(c.target.name))
+		}
+		ctx.Stdout.Write(c.getCommandHelp(ctx, c.targetSuper, c.target.command, c.target.alias))
 		return nil
 	}
 
@@ -246,18 +349,31 @@ func (c *helpCommand) Run(ctx *Context) error {
 		// current action, but we want the info to be printed
 		// as if there was nothing selected.
 		c.super.action.command = nil
-		ctx.Stdout.Write(c.getCommandHelp(c.super, c.super, ""))
+		ctx.Stdout.Write(c.getCommandHelp(ctx, c.super, c.super, ""))
 		return nil
 	}
 
 	// Look to see if the topic is a registered topic.
 	topic, ok := c.topics[c.topic]
 	if ok {
-		fmt.Fprintf(ctx.Stdout, "%s\n", strings.TrimSpace(topic.long()))
+		fmt.Fprintf(ctx.Stdout, "%s\n", strings.TrimSpace(topic.text()))
 		return nil
 	}
 	// If we have a missing callback, call that with --help
-	if c.super.missingCallback != nil {
+	if c.super.missingFlagCallback != nil {
+		command := &missingCommand{
+			flagCallback: c.super.missingFlagCallback,
+			superName:    c.super.Name,
+			name:         c.topic,
+			flags:        map[string]string{"help": "true"},
+			args:         c.topicArgs,
+		}
+		err := command.Run(ctx)
+		_, isUnrecognized := err.(*UnrecognizedCommand)
+		if !isUnrecognized {
+			return err
+		}
+	} else if c.super.missingCallback != nil {
 		helpArgs := []string{"--help"}
 		if len(c.topicArgs) > 0 {
 			helpArgs = append(helpArgs, c.topicArgs...)
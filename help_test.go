@@ -182,3 +182,104 @@ func (s *HelpCommandSuite) TestNotifyHelp(c *gc.C) {
 
 	c.Assert(called, jc.DeepEquals, [][]string{{"blah"}})
 }
+
+func (s *HelpCommandSuite) TestNotifyHelpRequest(c *gc.C) {
+	for i, test := range []struct {
+		args    []string
+		code    int
+		request cmd.HelpRequest
+	}{{
+		args:    []string{"help", "blah"},
+		request: cmd.HelpRequest{Args: []string{"blah"}, Command: "super blah"},
+	}, {
+		args:    []string{"blah", "--help"},
+		request: cmd.HelpRequest{Args: []string{"blah"}, Command: "super blah", ViaFlag: true},
+	}, {
+		args:    []string{"help", "storage", "list"},
+		request: cmd.HelpRequest{Args: []string{"storage", "list"}, Command: "super storage list"},
+	}, {
+		args:    []string{"help", "plugin-command"},
+		code:    1,
+		request: cmd.HelpRequest{Args: []string{"plugin-command"}},
+	}, {
+		args:    []string{"help"},
+		request: cmd.HelpRequest{Args: []string{}},
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		var requests []cmd.HelpRequest
+		super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name: "super",
+			NotifyHelpRequest: func(request cmd.HelpRequest) {
+				request.Target = nil
+				requests = append(requests, request)
+			},
+		})
+		super.Register(&TestCommand{Name: "blah"})
+		storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", UsagePrefix: "super"})
+		storage.Register(&TestCommand{Name: "list"})
+		super.Register(storage)
+
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(super, ctx, test.args)
+		c.Check(code, gc.Equals, test.code)
+		c.Check(requests, jc.DeepEquals, []cmd.HelpRequest{test.request})
+	}
+}
+
+func (s *HelpCommandSuite) TestNotifyHelpRequestTarget(c *gc.C) {
+	var target cmd.Command
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "super",
+		NotifyHelpRequest: func(request cmd.HelpRequest) {
+			target = request.Target
+		},
+	})
+	blah := &TestCommand{Name: "blah"}
+	super.Register(blah)
+	code := cmd.Main(super, cmdtesting.Context(c), []string{"help", "blah"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(target, gc.Equals, blah)
+}
+
+func (s *HelpCommandSuite) newNestedSuper(nestedHelp bool) *cmd.SuperCommand {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", UsagePrefix: "juju storage", Purpose: "Manage pools."})
+	pools.Register(&TestCommand{Name: "create"})
+	pools.Register(&TestCommand{Name: "list"})
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", UsagePrefix: "juju", Purpose: "Manage storage."})
+	storage.Register(pools)
+	storage.Register(&TestCommand{Name: "attach"})
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju", HelpShowsNestedSubcommands: nestedHelp})
+	super.Register(storage)
+	return super
+}
+
+func (s *HelpCommandSuite) TestNestedSubcommands(c *gc.C) {
+	expected := "" +
+		"Subcommands:\n" +
+		"    attach - attach the juju\n" +
+		"    pools  - Manage pools.\n" +
+		"        create - create the juju\n" +
+		"        list   - list the juju\n"
+	for _, args := range [][]string{
+		{"help", "storage"},
+		{"storage", "--help"},
+		{"storage", "help"},
+	} {
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(s.newNestedSuper(true), ctx, args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(cmdtesting.Stdout(ctx), jc.Contains, expected, gc.Commentf("%v", args))
+	}
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newNestedSuper(false), ctx, []string{"help", "storage"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), jc.Contains, "Subcommands:\n    attach - attach the juju\n    pools  - Manage pools.\n")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Not(jc.Contains), "create the juju")
+
+	// The help for the top-level command only lists its own subcommands.
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(s.newNestedSuper(true), ctx, []string{"help"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Not(jc.Contains), "attach the juju")
+}
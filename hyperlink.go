@@ -0,0 +1,117 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ForceHyperlinkEnvKey is the environment variable that, when set to 1 or
+// 0, turns hyperlinks in the output on or off regardless of the terminal.
+const ForceHyperlinkEnvKey = "FORCE_HYPERLINK"
+
+// Hyperlink returns text as an OSC 8 hyperlink to url, which terminals
+// that support hyperlinks display as text that can be clicked to open url.
+func Hyperlink(url, text string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// HyperlinksEnabled reports whether hyperlinks should be written to w,
+// which is typically Stdout or Stderr. They are written to terminals known
+// to support them, unless colours and styles are turned off. Setting
+// FORCE_HYPERLINK to 1 or 0 overrides the detection.
+func (ctx *Context) HyperlinksEnabled(w io.Writer) bool {
+	switch ctx.lookupEnv(ForceHyperlinkEnvKey) {
+	case "1":
+		return true
+	case "0":
+		return false
+	}
+	if ctx.colorMode == ColorNever || ctx.lookupEnv("NO_COLOR") != "" || !isTerminal(w) {
+		return false
+	}
+	return supportsHyperlinks(ctx.lookupEnv)
+}
+
+// Link returns text as a hyperlink to url if hyperlinks are enabled for
+// w, and otherwise text followed by url, or url alone if text is empty or
+// the same as url.
+func (ctx *Context) Link(w io.Writer, url, text string) string {
+	if ctx.HyperlinksEnabled(w) {
+		if text == "" {
+			text = url
+		}
+		return Hyperlink(url, text)
+	}
+	if text == "" || text == url {
+		return url
+	}
+	return fmt.Sprintf("%s (%s)", text, url)
+}
+
+// supportsHyperlinks reports whether the terminal described by the
+// environment is known to support OSC 8 hyperlinks.
+func supportsHyperlinks(lookupEnv func(string) string) bool {
+	switch lookupEnv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "Hyper", "ghostty":
+		return true
+	}
+	if lookupEnv("WT_SESSION") != "" || lookupEnv("KITTY_WINDOW_ID") != "" || lookupEnv("DOMTERM") != "" {
+		return true
+	}
+	// VTE based terminals, such as GNOME Terminal, support hyperlinks
+	// since 0.50.
+	if version, err := strconv.Atoi(lookupEnv("VTE_VERSION")); err == nil && version >= 5000 {
+		return true
+	}
+	term := lookupEnv("TERM")
+	return term == "xterm-kitty" || strings.HasPrefix(term, "foot") || term == "alacritty"
+}
+
+// isURL reports whether s looks like a URL rather than, say, the name of
+// a command.
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+// learnMoreError is an error with a URL at which users can learn more
+// about it.
+type learnMoreError struct {
+	err error
+	url string
+}
+
+// Error implements error.
+func (e *learnMoreError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *learnMoreError) Unwrap() error {
+	return e.err
+}
+
+// WithLearnMoreURL returns an error that Main writes like err, followed by
+// a hint pointing to url to learn more about the error, e.g. a page on how
+// to fix it. It returns nil if err is nil.
+func WithLearnMoreURL(err error, url string) error {
+	if err == nil {
+		return nil
+	}
+	return &learnMoreError{err: err, url: url}
+}
+
+// learnMoreURL returns the URL given to WithLearnMoreURL for err, if any.
+func learnMoreURL(err error) string {
+	var learnMore *learnMoreError
+	if errors.As(err, &learnMore) {
+		return learnMore.url
+	}
+	return ""
+}
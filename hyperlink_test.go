@@ -0,0 +1,120 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type HyperlinkSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&HyperlinkSuite{})
+
+const docsURL = "https://juju.is/docs"
+
+func (s *HyperlinkSuite) TestHyperlink(c *gc.C) {
+	c.Assert(cmd.Hyperlink(docsURL, "docs"), gc.Equals, "\x1b]8;;https://juju.is/docs\x1b\\docs\x1b]8;;\x1b\\")
+}
+
+func (s *HyperlinkSuite) TestHyperlinksEnabled(c *gc.C) {
+	for i, test := range []struct {
+		mode     cmd.ColorMode
+		terminal bool
+		env      map[string]string
+		expected bool
+	}{
+		{terminal: true, env: map[string]string{"TERM_PROGRAM": "iTerm.app"}, expected: true},
+		{terminal: true, env: map[string]string{"WT_SESSION": "1"}, expected: true},
+		{terminal: true, env: map[string]string{"VTE_VERSION": "6003"}, expected: true},
+		{terminal: true, env: map[string]string{"VTE_VERSION": "4205"}, expected: false},
+		{terminal: true, env: map[string]string{"TERM": "xterm-kitty"}, expected: true},
+		{terminal: true, env: map[string]string{"TERM": "xterm-256color"}, expected: false},
+		{terminal: false, env: map[string]string{"TERM_PROGRAM": "iTerm.app"}, expected: false},
+		{terminal: true, env: map[string]string{"TERM_PROGRAM": "iTerm.app", "NO_COLOR": "1"}, expected: false},
+		{mode: cmd.ColorNever, terminal: true, env: map[string]string{"TERM_PROGRAM": "iTerm.app"}, expected: false},
+		{terminal: false, env: map[string]string{"FORCE_HYPERLINK": "1"}, expected: true},
+		{terminal: true, env: map[string]string{"TERM_PROGRAM": "iTerm.app", "FORCE_HYPERLINK": "0"}, expected: false},
+	} {
+		ctx := cmdtesting.Context(c)
+		if test.terminal {
+			ctx = cmdtesting.TerminalContext(c, 80, 24, true)
+		}
+		ctx.Env = test.env
+		ctx.SetColorMode(test.mode)
+		c.Check(ctx.HyperlinksEnabled(ctx.Stdout), gc.Equals, test.expected, gc.Commentf("test %d", i))
+	}
+}
+
+func (s *HyperlinkSuite) TestLink(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	c.Assert(ctx.Link(ctx.Stdout, docsURL, ""), gc.Equals, docsURL)
+	c.Assert(ctx.Link(ctx.Stdout, docsURL, docsURL), gc.Equals, docsURL)
+	c.Assert(ctx.Link(ctx.Stdout, docsURL, "docs"), gc.Equals, "docs (https://juju.is/docs)")
+
+	ctx.Env = map[string]string{"FORCE_HYPERLINK": "1"}
+	c.Assert(ctx.Link(ctx.Stdout, docsURL, ""), gc.Equals, cmd.Hyperlink(docsURL, docsURL))
+	c.Assert(ctx.Link(ctx.Stdout, docsURL, "docs"), gc.Equals, cmd.Hyperlink(docsURL, "docs"))
+}
+
+func (s *HyperlinkSuite) runHelp(c *gc.C, env map[string]string) string {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	sc.Register(&linkedCommand{TestCommand{Name: "blah"}})
+	ctx := cmdtesting.Context(c)
+	ctx.Env = env
+	code := cmd.Main(sc, ctx, []string{"help", "blah"})
+	c.Assert(code, gc.Equals, 0)
+	return cmdtesting.Stdout(ctx)
+}
+
+func (s *HyperlinkSuite) TestHelpLinks(c *gc.C) {
+	c.Assert(s.runHelp(c, nil), jc.HasSuffix, ""+
+		"See also:\n"+
+		" - bleh\n"+
+		" - https://juju.is/docs/blah\n"+
+		"\n"+
+		"Learn more: https://juju.is/docs\n")
+
+	c.Assert(s.runHelp(c, map[string]string{"FORCE_HYPERLINK": "1"}), jc.HasSuffix, ""+
+		"See also:\n"+
+		" - bleh\n"+
+		" - "+cmd.Hyperlink("https://juju.is/docs/blah", "https://juju.is/docs/blah")+"\n"+
+		"\n"+
+		"Learn more: "+cmd.Hyperlink(docsURL, docsURL)+"\n")
+}
+
+func (s *HyperlinkSuite) TestErrorLearnMore(c *gc.C) {
+	c.Assert(cmd.WithLearnMoreURL(nil, docsURL), gc.IsNil)
+
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	sc.Register(&TestCommand{
+		Name: "blah",
+		CustomRun: func(*cmd.Context) error {
+			return cmd.WithLearnMoreURL(errors.New("kaboom"), docsURL)
+		},
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR kaboom\nLearn more: https://juju.is/docs\n")
+}
+
+// linkedCommand is a command whose help links to further documentation.
+type linkedCommand struct {
+	TestCommand
+}
+
+func (c *linkedCommand) Info() *cmd.Info {
+	info := c.TestCommand.Info()
+	info.SeeAlso = []string{"bleh", "https://juju.is/docs/blah"}
+	info.LearnMoreURL = docsURL
+	return info
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/gnuflag"
+)
+
+// ImpactEstimator may be implemented by destructive commands to describe
+// what they would do. When a subcommand implements ImpactEstimator, the
+// SuperCommand adds a --preview flag to it; if the flag is given, the
+// estimate is printed and the command is not run.
+type ImpactEstimator interface {
+	// EstimateImpact returns a human readable description of the
+	// changes the command would make if run.
+	EstimateImpact(ctx *Context) (string, error)
+}
+
+const previewPurpose = "Show what the command would do, without running it"
+
+// addPreviewFlag adds the --preview flag to f if command implements
+// ImpactEstimator.
+func addPreviewFlag(f *gnuflag.FlagSet, command Command, preview *bool) {
+	if _, ok := command.(ImpactEstimator); !ok {
+		return
+	}
+	if f.Lookup("preview") != nil {
+		return
+	}
+	f.BoolVar(preview, "preview", false, previewPurpose)
+}
+
+// runPreview prints the impact estimate for the selected subcommand.
+func (c *SuperCommand) runPreview(ctx *Context, estimator ImpactEstimator) error {
+	estimate, err := estimator.EstimateImpact(ctx)
+	if err != nil {
+		return err
+	}
+	estimate = strings.TrimRight(estimate, "\n")
+	if estimate != "" {
+		fmt.Fprintln(ctx.Stdout, estimate)
+	}
+	return nil
+}
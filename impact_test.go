@@ -0,0 +1,80 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ImpactSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ImpactSuite{})
+
+type destroyCommand struct {
+	cmd.CommandBase
+	ran bool
+}
+
+func (c *destroyCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "destroy", Purpose: "destroy everything"}
+}
+
+func (c *destroyCommand) EstimateImpact(ctx *cmd.Context) (string, error) {
+	return fmt.Sprintf("would destroy 3 machines in %s\n", ctx.Dir), nil
+}
+
+func (c *destroyCommand) Run(ctx *cmd.Context) error {
+	c.ran = true
+	return nil
+}
+
+func (s *ImpactSuite) TestPreview(c *gc.C) {
+	command := &destroyCommand{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.Register(command)
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"destroy", "--preview"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.ran, gc.Equals, false)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, fmt.Sprintf("would destroy 3 machines in %s\n", ctx.Dir))
+}
+
+func (s *ImpactSuite) TestNoPreview(c *gc.C) {
+	command := &destroyCommand{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.Register(command)
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"destroy"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.ran, gc.Equals, true)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+}
+
+func (s *ImpactSuite) TestPreviewOnlyForEstimators(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.Register(&TestCommand{Name: "blah"})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"blah", "--preview"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR flag provided but not defined: --preview\n")
+}
+
+func (s *ImpactSuite) TestPreviewInHelp(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.Register(&destroyCommand{})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"help", "destroy"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, "(?s).*--preview  \\(= false\\)\n    Show what the command would do, without running it\n.*")
+}
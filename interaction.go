@@ -0,0 +1,41 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ErrTerminalRequired is returned when a command with RequiresTTY set in its
+// Info is run without a terminal.
+var ErrTerminalRequired = errors.New("this command requires an interactive terminal")
+
+// IsCI reports whether the command is running in a continuous integration
+// system, as indicated by the CI environment variable that they set.
+func (ctx *Context) IsCI() bool {
+	switch strings.ToLower(ctx.lookupEnv("CI")) {
+	case "", "0", "false", "no":
+		return false
+	}
+	return true
+}
+
+// checkInteraction checks that the way the command described by info
+// interacts with the user is possible in ctx. Only commands that declare
+// that they prompt, by setting RequiresTTY, are checked.
+func checkInteraction(ctx *Context, info *Info) error {
+	if info == nil || !info.RequiresTTY || info.NonInteractiveSafe {
+		return nil
+	}
+	if !ctx.IsStdinTerminal() {
+		return ErrTerminalRequired
+	}
+	if ctx.IsCI() {
+		// CI systems may provide a terminal, but nobody to answer.
+		ctx.WarningWithCodef(WarningNonInteractive, "%q may prompt for input, which cannot be given in CI", info.Name)
+	}
+	return nil
+}
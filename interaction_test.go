@@ -0,0 +1,119 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type InteractionSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&InteractionSuite{})
+
+// interactiveCommand declares how it interacts with the user.
+type interactiveCommand struct {
+	cmd.CommandBase
+	requiresTTY bool
+	safe        bool
+	ran         bool
+}
+
+func (c *interactiveCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:               "login",
+		Purpose:            "log in",
+		RequiresTTY:        c.requiresTTY,
+		NonInteractiveSafe: c.safe,
+	}
+}
+
+func (c *interactiveCommand) Run(ctx *cmd.Context) error {
+	c.ran = true
+	return nil
+}
+
+func (s *InteractionSuite) TestRequiresTTY(c *gc.C) {
+	command := &interactiveCommand{requiresTTY: true}
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(command, ctx, nil)
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(command.ran, gc.Equals, false)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR this command requires an interactive terminal\n")
+
+	s.PatchValue(cmd.IsInputTerminal, func(io.Reader) bool { return true })
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(command, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(command.ran, gc.Equals, true)
+}
+
+func (s *InteractionSuite) TestRequiresTTYSubcommand(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	command := &interactiveCommand{requiresTTY: true}
+	jc.Register(command)
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(jc, ctx, []string{"login"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(command.ran, gc.Equals, false)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR this command requires an interactive terminal\n")
+}
+
+func (s *InteractionSuite) TestWarnInCI(c *gc.C) {
+	for i, test := range []struct {
+		ci          string
+		requiresTTY bool
+		safe        bool
+		warnings    []cmd.Warning
+	}{{
+		ci:          "true",
+		requiresTTY: true,
+		warnings: []cmd.Warning{{
+			Code:    cmd.WarningNonInteractive,
+			Message: `"login" may prompt for input, which cannot be given in CI`,
+		}},
+	}, {
+		ci:          "true",
+		requiresTTY: true,
+		safe:        true,
+	}, {
+		ci: "1",
+	}, {
+		ci:          "false",
+		requiresTTY: true,
+	}, {
+		ci:          "",
+		requiresTTY: true,
+	}} {
+		c.Logf("test %d", i)
+		s.PatchEnvironment("CI", test.ci)
+		s.PatchValue(cmd.IsInputTerminal, func(io.Reader) bool { return true })
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+		jc.Register(&interactiveCommand{requiresTTY: test.requiresTTY, safe: test.safe})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(jc, ctx, []string{"login"})
+		c.Check(code, gc.Equals, 0)
+		c.Check(ctx.Warnings(), gc.DeepEquals, test.warnings)
+	}
+}
+
+func (s *InteractionSuite) TestNotCheckedByDefault(c *gc.C) {
+	s.PatchEnvironment("CI", "true")
+	for _, args := range [][]string{{"login"}, {"version"}, {"help"}} {
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", Version: "1.2.3"})
+		jc.Register(&interactiveCommand{})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(jc, ctx, args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(ctx.Warnings(), gc.HasLen, 0, gc.Commentf("%v", args))
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, "")
+	}
+}
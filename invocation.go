@@ -0,0 +1,55 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CommandPath returns the names of the commands being run, from the top
+// level command down, e.g. []string{"juju", "storage", "pools", "list"}.
+// Aliases are resolved to the names of the commands they stand for, so
+// that the path is the same however the command was invoked.
+func (ctx *Context) CommandPath() []string {
+	return append([]string(nil), ctx.commandPath...)
+}
+
+// InvokedViaAlias reports whether the command being run was invoked by one
+// of its aliases, including those in the user's alias file, rather than by
+// its name.
+func (ctx *Context) InvokedViaAlias() bool {
+	return ctx.viaAlias
+}
+
+// BinaryName returns the name of the executable being run, which may differ
+// from the name of the top level command, e.g. when it is run through a
+// symbolic link.
+func (ctx *Context) BinaryName() string {
+	if len(os.Args) == 0 {
+		return ""
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// startInvocation records that the top level command with the given name is
+// being run, replacing the path of any command run before it with ctx.
+func (ctx *Context) startInvocation(name string) {
+	ctx.commandPath = []string{name}
+	ctx.viaAlias = false
+}
+
+// addInvocation appends the subcommand referred to by ref to the path of
+// the command being run.
+func (ctx *Context) addInvocation(ref commandReference, viaUserAlias bool) {
+	if ref.alias != "" {
+		ctx.commandPath = append(ctx.commandPath, strings.Fields(ref.alias)...)
+	} else {
+		ctx.commandPath = append(ctx.commandPath, ref.name)
+	}
+	if ref.alias != "" || viaUserAlias {
+		ctx.viaAlias = true
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type InvocationSuite struct{}
+
+var _ = gc.Suite(&InvocationSuite{})
+
+// pathCommand records the invocation path of the context it is run with.
+type pathCommand struct {
+	cmd.CommandBase
+	path     []string
+	viaAlias bool
+}
+
+func (c *pathCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "list", Purpose: "list pools", Aliases: []string{"ls"}}
+}
+
+func (c *pathCommand) Run(ctx *cmd.Context) error {
+	c.path = ctx.CommandPath()
+	c.viaAlias = ctx.InvokedViaAlias()
+	return nil
+}
+
+func (s *InvocationSuite) TestCommandPath(c *gc.C) {
+	aliases := filepath.Join(c.MkDir(), "aliases")
+	err := ioutil.WriteFile(aliases, []byte("pl = storage pools list\n"), 0644)
+	c.Assert(err, gc.IsNil)
+
+	for i, test := range []struct {
+		args     []string
+		viaAlias bool
+	}{{
+		args: []string{"storage", "pools", "list"},
+	}, {
+		args:     []string{"storage", "pools", "ls"},
+		viaAlias: true,
+	}, {
+		args:     []string{"list-pools", "list"},
+		viaAlias: true,
+	}, {
+		args:     []string{"pl"},
+		viaAlias: true,
+	}, {
+		args: []string{"storage", "pools", "list", "--", "storage", "pools", "list"},
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:                "juju",
+			UserAliasesFilename: aliases,
+			ChainSeparator:      "--",
+		})
+		storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", UsagePrefix: "juju"})
+		pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", UsagePrefix: "juju storage"})
+		list := &pathCommand{}
+		pools.Register(list)
+		storage.Register(pools)
+		jc.Register(storage)
+		jc.RegisterSuperAlias("list-pools", "storage", "pools", nil)
+
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(jc, ctx, test.args)
+		c.Assert(code, gc.Equals, 0, gc.Commentf("%s", cmdtesting.Stderr(ctx)))
+		expected := []string{"juju", "storage", "pools", "list"}
+		c.Check(list.path, gc.DeepEquals, expected)
+		c.Check(list.viaAlias, gc.Equals, test.viaAlias)
+		c.Check(ctx.CommandPath(), gc.DeepEquals, expected)
+	}
+}
+
+func (s *InvocationSuite) TestBinaryName(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	c.Assert(ctx.BinaryName(), gc.Equals, filepath.Base(os.Args[0]))
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// lockRetryInterval is how often a waiting command retries a held lock.
+const lockRetryInterval = 100 * time.Millisecond
+
+// LockFlags holds the --wait and --no-wait flags of a command that takes
+// a resource lock. The two flags set the same value, so the last one given
+// wins.
+type LockFlags struct {
+	wait bool
+}
+
+// AddFlags adds the --wait and --no-wait flags to f. defaultWait is used
+// when neither flag is given.
+func (l *LockFlags) AddFlags(f *gnuflag.FlagSet, defaultWait bool) {
+	l.wait = defaultWait
+	f.Var(&waitValue{target: &l.wait, value: true}, "wait", "Wait for other commands using the same resource to finish")
+	f.Var(&waitValue{target: &l.wait, value: false}, "no-wait", "Fail immediately if another command is using the same resource")
+}
+
+// Wait reports whether the command should wait for a held lock.
+func (l *LockFlags) Wait() bool {
+	return l.wait
+}
+
+// waitValue is a boolean flag that stores value in target when it is
+// set, and its negation when it is set to false.
+type waitValue struct {
+	target *bool
+	value  bool
+}
+
+// Set implements gnuflag.Value.
+func (v *waitValue) Set(s string) error {
+	b, err := ParseBool(s, Strict)
+	if err != nil {
+		return err
+	}
+	*v.target = b == v.value
+	return nil
+}
+
+// String implements gnuflag.Value.
+func (v *waitValue) String() string {
+	if v.target == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v.target == v.value)
+}
+
+// IsBoolFlag implements gnuflag.Value.
+func (v *waitValue) IsBoolFlag() bool {
+	return true
+}
+
+// LockedError is returned by AcquireLock when the lock is held by another
+// process and the caller chose not to wait.
+type LockedError struct {
+	// Name is the name of the lock.
+	Name string
+
+	// PID and Command identify the process holding the lock, if known.
+	PID     int
+	Command string
+}
+
+// Error implements error.
+func (e *LockedError) Error() string {
+	if holder := holderDescription(e.PID, e.Command); holder != "" {
+		return fmt.Sprintf("%s is locked by %s", e.Name, holder)
+	}
+	return fmt.Sprintf("%s is locked", e.Name)
+}
+
+// holderDescription describes the process holding a lock, or returns
+// the empty string if it is unknown.
+func holderDescription(pid int, command string) string {
+	if pid == 0 {
+		return ""
+	}
+	if command == "" {
+		return fmt.Sprintf("PID %d", pid)
+	}
+	return fmt.Sprintf("PID %d (%s)", pid, command)
+}
+
+// AcquireLock takes the lock called name, which is held in a file in dir,
+// so that commands mutating the same resource (e.g. a model) are
+// serialised rather than corrupting its state. If another process holds
+// the lock and wait is true, a "waiting for lock" message naming the
+// holder is written and AcquireLock retries until the lock is free or the
+// context is done; otherwise a *LockedError is returned. The returned
+// function releases the lock.
+func (ctx *Context) AcquireLock(dir, name string, wait bool) (func() error, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Annotatef(err, "cannot create lock directory")
+	}
+	path := filepath.Join(dir, lockFilename(name))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot open lock %s", name)
+	}
+	waiting := false
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, errors.Annotatef(err, "cannot lock %s", name)
+		}
+		if locked {
+			break
+		}
+		pid, command := readLockHolder(f)
+		if !wait {
+			f.Close()
+			return nil, &LockedError{Name: name, PID: pid, Command: command}
+		}
+		if !waiting {
+			if holder := holderDescription(pid, command); holder != "" {
+				ctx.Infof("waiting for lock on %s held by %s", name, holder)
+			} else {
+				ctx.Infof("waiting for lock on %s", name)
+			}
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, errors.Annotatef(ctx.Err(), "waiting for lock on %s", name)
+		case <-ctx.clock().After(lockRetryInterval):
+		}
+	}
+	if err := writeLockHolder(f); err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, errors.Annotatef(err, "cannot record holder of lock %s", name)
+	}
+	return func() error {
+		// The holder is cleared before unlocking; the file itself is left
+		// in place, as removing it would race with other processes that
+		// have it open.
+		_ = f.Truncate(0)
+		err := unlockFile(f)
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		return err
+	}, nil
+}
+
+// lockFilename returns the name of the file holding the lock called name,
+// escaping any characters that are not safe in file names.
+func lockFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "%%%02x", r)
+		}
+	}
+	return b.String() + ".lock"
+}
+
+// writeLockHolder records the current process as the holder of the lock
+// file f.
+func writeLockHolder(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	command := Redact(strings.Join(os.Args, " "))
+	_, err := f.WriteAt([]byte(fmt.Sprintf("%d\n%s\n", os.Getpid(), command)), 0)
+	return err
+}
+
+// readLockHolder returns the process recorded as holding the lock file f,
+// or a zero PID if it is unknown.
+func readLockHolder(f *os.File) (int, string) {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 4096))
+	if err != nil {
+		return 0, ""
+	}
+	lines := strings.SplitN(string(data), "\n", 3)
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return 0, ""
+	}
+	command := ""
+	if len(lines) > 1 {
+		command = lines[1]
+	}
+	return pid, command
+}
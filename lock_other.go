@@ -0,0 +1,20 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/juju/errors"
+)
+
+func tryLockFile(f *os.File) (bool, error) {
+	return false, errors.NotSupportedf("file locking on this platform")
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type LockSuite struct {
+	testing.IsolationSuite
+	dir string
+}
+
+var _ = gc.Suite(&LockSuite{})
+
+func (s *LockSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.dir = filepath.Join(c.MkDir(), "locks")
+}
+
+func (s *LockSuite) TestAcquireAndRelease(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	release, err := ctx.AcquireLock(s.dir, "model/foo", false)
+	c.Assert(err, gc.IsNil)
+	_, err = os.Stat(filepath.Join(s.dir, "model%2ffoo.lock"))
+	c.Assert(err, gc.IsNil)
+
+	_, err = ctx.AcquireLock(s.dir, "model/foo", false)
+	c.Assert(err, gc.FitsTypeOf, &cmd.LockedError{})
+	locked := err.(*cmd.LockedError)
+	c.Assert(locked.PID, gc.Equals, os.Getpid())
+	c.Assert(locked.Command, gc.Not(gc.Equals), "")
+	c.Assert(err, gc.ErrorMatches, `model/foo is locked by PID [0-9]+ \(.*\)`)
+
+	// Other resources are unaffected.
+	releaseOther, err := ctx.AcquireLock(s.dir, "model/bar", false)
+	c.Assert(err, gc.IsNil)
+	c.Assert(releaseOther(), gc.IsNil)
+
+	c.Assert(release(), gc.IsNil)
+	release, err = ctx.AcquireLock(s.dir, "model/foo", false)
+	c.Assert(err, gc.IsNil)
+	c.Assert(release(), gc.IsNil)
+}
+
+func (s *LockSuite) TestWait(c *gc.C) {
+	clock := testclock.NewClock(time.Now())
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = clock
+	release, err := ctx.AcquireLock(s.dir, "model-foo", false)
+	c.Assert(err, gc.IsNil)
+
+	waiter := cmdtesting.Context(c)
+	waiter.Clock = clock
+	result := make(chan error, 1)
+	go func() {
+		release, err := waiter.AcquireLock(s.dir, "model-foo", true)
+		if err == nil {
+			err = release()
+		}
+		result <- err
+	}()
+	c.Assert(clock.WaitAdvance(time.Second, testing.LongWait, 1), gc.IsNil)
+	c.Assert(release(), gc.IsNil)
+	timeout := time.After(testing.LongWait)
+	for acquired := false; !acquired; {
+		select {
+		case err := <-result:
+			c.Assert(err, gc.IsNil)
+			acquired = true
+		case <-time.After(testing.ShortWait):
+			clock.Advance(time.Second)
+		case <-timeout:
+			c.Fatalf("lock not acquired")
+		}
+	}
+	c.Assert(cmdtesting.Stderr(waiter), gc.Matches, `waiting for lock on model-foo held by PID [0-9]+ \(.*\)\n`)
+}
+
+func (s *LockSuite) TestWaitCancelled(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	release, err := ctx.AcquireLock(s.dir, "model-foo", false)
+	c.Assert(err, gc.IsNil)
+	defer release()
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = ctx.With(cctx).AcquireLock(s.dir, "model-foo", true)
+	c.Assert(err, gc.ErrorMatches, "waiting for lock on model-foo: context canceled")
+}
+
+func (s *LockSuite) TestLockFlags(c *gc.C) {
+	for i, test := range []struct {
+		defaultWait bool
+		args        []string
+		wait        bool
+	}{
+		{defaultWait: true, wait: true},
+		{defaultWait: false, wait: false},
+		{defaultWait: true, args: []string{"--no-wait"}, wait: false},
+		{defaultWait: false, args: []string{"--wait"}, wait: true},
+		{defaultWait: true, args: []string{"--no-wait", "--wait"}, wait: true},
+		{defaultWait: true, args: []string{"--wait=false"}, wait: false},
+	} {
+		c.Logf("test %d: %v", i, test.args)
+		var flags cmd.LockFlags
+		fs := gnuflag.NewFlagSetWithFlagKnownAs("test", gnuflag.ContinueOnError, "option")
+		flags.AddFlags(fs, test.defaultWait)
+		c.Assert(fs.Parse(true, test.args), gc.IsNil)
+		c.Assert(flags.Wait(), gc.Equals, test.wait)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRegion is the region of a lock file that is locked. It lies beyond
+// the holder recorded in the file, as Windows locks are mandatory and
+// would otherwise stop waiting processes from reading it.
+var lockRegion = windows.Overlapped{Offset: 0xffffffff, OffsetHigh: 0x7fffffff}
+
+// tryLockFile takes an exclusive lock on f without blocking, reporting
+// whether the lock was taken.
+func tryLockFile(f *os.File) (bool, error) {
+	overlapped := lockRegion
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	switch err {
+	case nil:
+		return true, nil
+	case windows.ERROR_LOCK_VIOLATION:
+		return false, nil
+	}
+	return false, err
+}
+
+// unlockFile releases the lock on f.
+func unlockFile(f *os.File) error {
+	overlapped := lockRegion
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &overlapped)
+}
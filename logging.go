@@ -4,9 +4,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/juju/ansiterm"
 	"github.com/juju/gnuflag"
@@ -27,16 +29,54 @@ type Log struct {
 	ShowLog       bool
 	Config        string
 
+	// Format is the format log entries are written in, either "text" or
+	// "json". If empty, "text" is used.
+	Format string
+
+	// MaxSizeMB is the size in megabytes the log file at Path may grow to
+	// before it is rotated: moved aside to a backup named with the time
+	// of rotation, e.g. "juju-2024-01-02T15-04-05.000.log", and replaced
+	// by an empty file. If zero, the log file is never rotated.
+	MaxSizeMB int
+
+	// MaxBackups is the number of rotated log files to keep. If zero, all
+	// are kept, unless they are older than MaxAgeDays.
+	MaxBackups int
+
+	// MaxAgeDays is the number of days to keep rotated log files for. If
+	// zero, they are kept regardless of age. Old files are removed when
+	// the log file is rotated.
+	MaxAgeDays int
+
 	// NewWriter creates a new logging writer for a specified target.
 	NewWriter func(target io.Writer) loggo.Writer
 }
 
-// GetLogWriter returns a logging writer for the specified target.
+// GetLogWriter returns a logging writer for the specified target. When the
+// format is "json", each entry is written as a JSON object on its own line,
+// with the timestamp, level, module, message and location of the entry.
+// Registered secrets are redacted from the messages (see Redact).
 func (l *Log) GetLogWriter(target io.Writer) loggo.Writer {
+	if l.Format == "json" {
+		return NewJSONLogWriter(target)
+	}
 	if l.NewWriter != nil {
-		return l.NewWriter(target)
+		return redactingWriter{l.NewWriter(target)}
 	}
-	return loggocolor.NewWriter(target)
+	return redactingWriter{loggocolor.NewWriter(target)}
+}
+
+// redactingWriter redacts registered secrets from the messages of log
+// entries before passing them on, so that every log writer created by the
+// framework masks them.
+type redactingWriter struct {
+	loggo.Writer
+}
+
+// Write implements loggo's Writer interface.
+func (w redactingWriter) Write(entry loggo.Entry) {
+	entry.Message = Redact(entry.Message)
+	w.Writer.Write(entry)
 }
 
 // AddFlags adds appropriate flags to f.
@@ -49,6 +89,7 @@ func (l *Log) AddFlags(f *gnuflag.FlagSet) {
 	f.BoolVar(&l.Debug, "debug", false, "Equivalent to --show-log --logging-config=<root>=DEBUG")
 	f.StringVar(&l.Config, "logging-config", l.DefaultConfig, "Specify log levels for modules")
 	f.BoolVar(&l.ShowLog, "show-log", false, "If set, write the log file to stderr")
+	f.StringVar(&l.Format, "logging-format", "text", "Specify the format of the log: text or json")
 }
 
 // Start starts logging using the given Context.
@@ -56,15 +97,27 @@ func (log *Log) Start(ctx *Context) error {
 	if log.Verbose && log.Quiet {
 		return fmt.Errorf(`"verbose" and "quiet" flags clash, please use one or the other, not both`)
 	}
+	switch log.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("unknown logging format %q, expected one of text, json", log.Format)
+	}
 	ctx.quiet = log.Quiet
 	ctx.verbose = log.Verbose
 	if log.Path != "" {
 		path := ctx.AbsPath(log.Path)
-		target, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		target, err := openRotatingFile(path,
+			int64(log.MaxSizeMB)*1024*1024,
+			log.MaxBackups,
+			time.Duration(log.MaxAgeDays)*24*time.Hour,
+			ctx.clock(),
+		)
 		if err != nil {
 			return err
 		}
 		writer := log.GetLogWriter(target)
+		// Logging may be started again when commands are chained.
+		_, _ = loggo.RemoveWriter("logfile")
 		err = loggo.RegisterWriter("logfile", writer)
 		if err != nil {
 			return err
@@ -92,6 +145,7 @@ func (log *Log) Start(ctx *Context) error {
 		}
 	} else {
 		_, _ = loggo.RemoveWriter("default")
+		_, _ = loggo.RemoveWriter("warning")
 		// Create a simple writer that doesn't show filenames, or timestamps,
 		// and only shows warning or above.
 		writer := NewWarningWriter(ctx.Stderr)
@@ -111,7 +165,7 @@ func (log *Log) Start(ctx *Context) error {
 // by the callers of a command. This way the logged output can also
 // be displayed otherwise, e.g. on the screen.
 func NewCommandLogWriter(name string, out, err io.Writer) loggo.Writer {
-	return &commandLogWriter{name, out, err}
+	return redactingWriter{&commandLogWriter{name, out, err}}
 }
 
 // commandLogWriter filters the log messages for name.
@@ -132,6 +186,45 @@ func (s *commandLogWriter) Write(entry loggo.Entry) {
 	}
 }
 
+// NewJSONLogWriter returns a loggo writer that writes each log entry to
+// target as a JSON object on its own line, for CI systems and log
+// aggregators.
+func NewJSONLogWriter(target io.Writer) loggo.Writer {
+	return redactingWriter{&jsonLogWriter{target}}
+}
+
+// jsonLogWriter writes log entries as JSON objects.
+type jsonLogWriter struct {
+	target io.Writer
+}
+
+// jsonLogEntry is the JSON form of a log entry.
+type jsonLogEntry struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Module    string `json:"module"`
+	Message   string `json:"message"`
+	Location  string `json:"location,omitempty"`
+}
+
+// Write implements loggo's Writer interface.
+func (w *jsonLogWriter) Write(entry loggo.Entry) {
+	e := jsonLogEntry{
+		Timestamp: entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		Level:     entry.Level.String(),
+		Module:    entry.Module,
+		Message:   entry.Message,
+	}
+	if entry.Filename != "" {
+		e.Location = fmt.Sprintf("%s:%d", filepath.Base(entry.Filename), entry.Line)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_, _ = w.target.Write(append(data, '\n'))
+}
+
 type warningWriter struct {
 	writer *ansiterm.Writer
 }
@@ -139,13 +232,17 @@ type warningWriter struct {
 // NewWarningWriter will write out colored severity levels if the writer is
 // outputting to a terminal.
 func NewWarningWriter(writer io.Writer) loggo.Writer {
-	w := &warningWriter{ansiterm.NewWriter(writer)}
-	return loggo.NewMinimumLevelWriter(w, loggo.WARNING)
+	w := &warningWriter{NewColorWriter(writer)}
+	return loggo.NewMinimumLevelWriter(redactingWriter{w}, loggo.WARNING)
 }
 
 // Write implements Writer.
 // WARNING The message...
 func (w *warningWriter) Write(entry loggo.Entry) {
+	if severity, ok := severityForLevel(entry.Level); ok {
+		printSeverity(w.writer, severity, entry.Message)
+		return
+	}
 	loggocolor.SeverityColor[entry.Level].Fprintf(w.writer, entry.Level.String())
 	fmt.Fprintf(w.writer, " %s\n", entry.Message)
 }
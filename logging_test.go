@@ -4,8 +4,12 @@
 package cmd_test
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/juju/loggo/v2"
 	"github.com/juju/testing"
@@ -189,6 +193,75 @@ func (s *LogSuite) TestOutputQuiet(c *gc.C) {
 	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
 }
 
+func (s *LogSuite) TestOutputWriters(c *gc.C) {
+	l := &cmd.Log{}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+
+	fmt.Fprint(ctx.InfoWriter(), "extracting charm\nextracted 3 files\n")
+	fmt.Fprint(ctx.VerboseWriter(), "charm/metadata.yaml\n")
+	w := ctx.ErrorWriter()
+	fmt.Fprint(w, "checksum mismatch")
+	c.Assert(w.Close(), gc.IsNil)
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Matches, "extracting charm\nextracted 3 files\n.*ERROR.* checksum mismatch\n")
+}
+
+func (s *LogSuite) TestOutputWritersPartialLines(c *gc.C) {
+	l := &cmd.Log{}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+
+	w := ctx.InfoWriter()
+	fmt.Fprint(w, "extracting ")
+	fmt.Fprint(w, "charm... ")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+	fmt.Fprint(w, "done\nextracted ")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "extracting charm... done\n")
+	fmt.Fprint(w, "3 files")
+	c.Assert(w.Close(), gc.IsNil)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "extracting charm... done\nextracted 3 files\n")
+}
+
+func (s *LogSuite) TestOutputWritersFlushedByMain(c *gc.C) {
+	l := &cmd.Log{}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+
+	command := &TestCommand{Name: "verb", CustomRun: func(ctx *cmd.Context) error {
+		fmt.Fprint(ctx.InfoWriter(), "extracting charm")
+		return nil
+	}}
+	code := cmd.Main(command, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "extracting charm\n")
+}
+
+func (s *LogSuite) TestOutputWritersVerbose(c *gc.C) {
+	l := &cmd.Log{Verbose: true}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+
+	fmt.Fprint(ctx.VerboseWriter(), "charm/metadata.yaml\ncharm/config.yaml\n")
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "charm/metadata.yaml\ncharm/config.yaml\n")
+}
+
+func (s *LogSuite) TestOutputWritersQuiet(c *gc.C) {
+	l := &cmd.Log{Quiet: true}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+
+	fmt.Fprint(ctx.InfoWriter(), "extracting charm\n")
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
 func (s *LogSuite) TestOutputQuietLogs(c *gc.C) {
 	l := &cmd.Log{Quiet: true, Path: "foo.log", Config: "<root>=INFO"}
 	ctx := cmdtesting.Context(c)
@@ -241,3 +314,62 @@ func (s *LogSuite) TestOutputWarning(c *gc.C) {
 
 	c.Assert(cmdtesting.Stderr(ctx), gc.Matches, `^.* WARN .* Writing warning output\n.*`)
 }
+
+func (s *LogSuite) TestLoggingFormatFlag(c *gc.C) {
+	log := newLogWithFlags(c, "")
+	c.Assert(log.Format, gc.Equals, "text")
+	log = newLogWithFlags(c, "", "--logging-format", "json")
+	c.Assert(log.Format, gc.Equals, "json")
+}
+
+func (s *LogSuite) TestJSONFormat(c *gc.C) {
+	l := &cmd.Log{ShowLog: true, Format: "json", Path: "foo.log"}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+
+	logger.Infof("deploying %q", "mysql")
+
+	content, err := ioutil.ReadFile(filepath.Join(ctx.Dir, "foo.log"))
+	c.Assert(err, gc.IsNil)
+	for _, output := range []string{cmdtesting.Stderr(ctx), string(content)} {
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		c.Assert(lines, gc.HasLen, 1)
+		var entry map[string]string
+		c.Assert(json.Unmarshal([]byte(lines[0]), &entry), gc.IsNil)
+		c.Check(entry["level"], gc.Equals, "INFO")
+		c.Check(entry["module"], gc.Equals, "juju.test")
+		c.Check(entry["message"], gc.Equals, `deploying "mysql"`)
+		c.Check(entry["location"], gc.Matches, `logging_test\.go:\d+`)
+		_, err = time.Parse(time.RFC3339Nano, entry["timestamp"])
+		c.Check(err, gc.IsNil)
+	}
+}
+
+func (s *LogSuite) TestUnknownFormat(c *gc.C) {
+	l := &cmd.Log{Format: "xml"}
+	err := l.Start(cmdtesting.Context(c))
+	c.Assert(err, gc.ErrorMatches, `unknown logging format "xml", expected one of text, json`)
+}
+
+func (s *LogSuite) TestRedaction(c *gc.C) {
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+	cmd.RegisterRedactedValue("hunter2")
+	for i, l := range []*cmd.Log{
+		{ShowLog: true},
+		{ShowLog: true, Format: "json"},
+		{},
+	} {
+		c.Logf("test %d: %+v", i, l)
+		ctx := cmdtesting.Context(c)
+		c.Assert(l.Start(ctx), gc.IsNil)
+		logger.Warningf("password is hunter2")
+		c.Check(cmdtesting.Stderr(ctx), gc.Matches, `(?s).*password is (<|\\u003c)redacted.*`)
+		c.Check(cmdtesting.Stderr(ctx), gc.Not(gc.Matches), `(?s).*hunter2.*`)
+	}
+
+	var out, errOut strings.Builder
+	writer := cmd.NewCommandLogWriter("juju.test", &out, &errOut)
+	writer.Write(loggo.Entry{Module: "juju.test", Level: loggo.INFO, Message: "using hunter2"})
+	c.Assert(out.String(), gc.Equals, "using <redacted>\n")
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/clock"
+	"github.com/juju/errors"
+)
+
+// backupTimeFormat is the format of the time a log file was rotated at, as
+// it appears in the name of the backup, e.g. "foo-2024-01-02T15-04-05.000.log".
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile is a log file that is moved aside to a timestamped backup
+// when writing to it would make it larger than maxSize bytes. Backups
+// beyond the newest maxBackups, or older than maxAge, are removed when
+// the file is rotated. A zero limit is no limit.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	clock      clock.Clock
+	file       *os.File
+	size       int64
+}
+
+// openRotatingFile opens the log file at path for appending, creating it
+// if necessary.
+func openRotatingFile(path string, maxSize int64, maxBackups int, maxAge time.Duration, clk clock.Clock) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		maxAge:     maxAge,
+		clock:      clk,
+	}
+	if err := f.open(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return f, nil
+}
+
+// open opens the log file and records its size.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer, rotating the file first if p would not fit
+// in it. A single write larger than the limit is written to a file of its
+// own rather than split.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, errors.Annotate(err, "rotating log file")
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// rotate moves the log file to a backup, opens a new one and removes the
+// backups that are no longer wanted.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + f.clock.Now().UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	return f.prune()
+}
+
+// prune removes the backups beyond the newest maxBackups and those older
+// than maxAge.
+func (f *rotatingFile) prune() error {
+	if f.maxBackups <= 0 && f.maxAge <= 0 {
+		return nil
+	}
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	cutoff := f.clock.Now().Add(-f.maxAge)
+	for i, backup := range backups {
+		expired := f.maxAge > 0 && backup.rotated.Before(cutoff)
+		excess := f.maxBackups > 0 && i >= f.maxBackups
+		if expired || excess {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// logBackup is a rotated log file.
+type logBackup struct {
+	path    string
+	rotated time.Time
+}
+
+// backups returns the backups of the log file, newest first.
+func (f *rotatingFile) backups() ([]logBackup, error) {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []logBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		rotated, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{
+			path:    filepath.Join(dir, name),
+			rotated: rotated,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotated.After(backups[j].rotated)
+	})
+	return backups, nil
+}
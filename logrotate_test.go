@@ -0,0 +1,128 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type LogRotateSuite struct {
+	dir   string
+	clock *testclock.Clock
+}
+
+var _ = gc.Suite(&LogRotateSuite{})
+
+func (s *LogRotateSuite) SetUpTest(c *gc.C) {
+	s.dir = c.MkDir()
+	s.clock = testclock.NewClock(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC))
+}
+
+func (s *LogRotateSuite) open(c *gc.C, maxSize int64, maxBackups int, maxAge time.Duration) io.WriteCloser {
+	f, err := cmd.OpenRotatingFile(filepath.Join(s.dir, "juju.log"), maxSize, maxBackups, maxAge, s.clock)
+	c.Assert(err, gc.IsNil)
+	return f
+}
+
+func (s *LogRotateSuite) write(c *gc.C, f io.Writer, line string) {
+	_, err := f.Write([]byte(line))
+	c.Assert(err, gc.IsNil)
+	s.clock.Advance(time.Second)
+}
+
+// files returns the names and contents of the files in the log directory.
+func (s *LogRotateSuite) files(c *gc.C) map[string]string {
+	infos, err := ioutil.ReadDir(s.dir)
+	c.Assert(err, gc.IsNil)
+	files := make(map[string]string)
+	for _, info := range infos {
+		content, err := ioutil.ReadFile(filepath.Join(s.dir, info.Name()))
+		c.Assert(err, gc.IsNil)
+		files[info.Name()] = string(content)
+	}
+	return files
+}
+
+func (s *LogRotateSuite) TestNoRotation(c *gc.C) {
+	f := s.open(c, 0, 0, 0)
+	defer f.Close()
+	s.write(c, f, "one\n")
+	s.write(c, f, "two\n")
+	c.Assert(s.files(c), gc.DeepEquals, map[string]string{"juju.log": "one\ntwo\n"})
+}
+
+func (s *LogRotateSuite) TestRotateBySize(c *gc.C) {
+	f := s.open(c, 8, 0, 0)
+	defer f.Close()
+	s.write(c, f, "one\n")
+	s.write(c, f, "two\n")
+	s.write(c, f, "three\n")
+	s.write(c, f, "a long line\n")
+	c.Assert(s.files(c), gc.DeepEquals, map[string]string{
+		"juju-2024-01-02T15-04-07.000.log": "one\ntwo\n",
+		"juju-2024-01-02T15-04-08.000.log": "three\n",
+		"juju.log":                         "a long line\n",
+	})
+}
+
+func (s *LogRotateSuite) TestExistingFileSize(c *gc.C) {
+	err := ioutil.WriteFile(filepath.Join(s.dir, "juju.log"), []byte("earlier\n"), 0644)
+	c.Assert(err, gc.IsNil)
+	f := s.open(c, 10, 0, 0)
+	defer f.Close()
+	s.write(c, f, "later\n")
+	c.Assert(s.files(c), gc.DeepEquals, map[string]string{
+		"juju-2024-01-02T15-04-05.000.log": "earlier\n",
+		"juju.log":                         "later\n",
+	})
+}
+
+func (s *LogRotateSuite) TestMaxBackups(c *gc.C) {
+	f := s.open(c, 4, 2, 0)
+	defer f.Close()
+	for _, line := range []string{"one\n", "two\n", "six\n", "ten\n"} {
+		s.write(c, f, line)
+	}
+	c.Assert(s.files(c), gc.DeepEquals, map[string]string{
+		"juju-2024-01-02T15-04-07.000.log": "two\n",
+		"juju-2024-01-02T15-04-08.000.log": "six\n",
+		"juju.log":                         "ten\n",
+	})
+}
+
+func (s *LogRotateSuite) TestMaxAge(c *gc.C) {
+	f := s.open(c, 4, 0, time.Hour)
+	defer f.Close()
+	s.write(c, f, "one\n")
+	s.write(c, f, "two\n")
+	s.clock.Advance(2 * time.Hour)
+	s.write(c, f, "six\n")
+	var names []string
+	for name := range s.files(c) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.Assert(names, gc.DeepEquals, []string{"juju-2024-01-02T17-04-07.000.log", "juju.log"})
+}
+
+func (s *LogRotateSuite) TestLogStart(c *gc.C) {
+	l := &cmd.Log{Path: "foo.log", Config: "<root>=INFO", MaxSizeMB: 1, MaxBackups: 1}
+	ctx := cmdtesting.Context(c)
+	err := l.Start(ctx)
+	c.Assert(err, gc.IsNil)
+	logger.Infof("hello")
+	content, err := ioutil.ReadFile(filepath.Join(ctx.Dir, "foo.log"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Matches, `^.* INFO .* hello\n`)
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"sort"
 	"strings"
 
@@ -38,6 +39,20 @@ type MarkdownOptions struct {
 	// LinkForSubcommand maps each sub-command name to the link target for that
 	//command (e.g. a section of the Markdown doc, or a webpage).
 	LinkForSubcommand func(string) string
+	// Anchor is the id of an HTML anchor written at the top of the
+	// document, so that it may be linked to even if its title changes. The
+	// anchor of each flag is Anchor followed by "--" and the flag's long
+	// name, e.g. "add-cloud--force". If this field is empty, no anchors will
+	// be written.
+	Anchor string
+	// Reproducible removes details of the environment the document is
+	// generated in, such as the user's home directory in flag defaults,
+	// so that the same document is printed everywhere.
+	Reproducible bool
+	// FormerNames lists the deprecated names the command was renamed from,
+	// e.g. "add-relation (removed in 4.0)". They are printed below the
+	// aliases of the command.
+	FormerNames []string
 }
 
 // PrintMarkdown prints Markdown documentation about the given command to the
@@ -49,6 +64,9 @@ func PrintMarkdown(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error {
 	// single write - we can just check at the end when we copy over.
 	var doc bytes.Buffer
 
+	if opts.Anchor != "" {
+		fmt.Fprintf(&doc, "<a id=\"%s\"></a>\n\n", slugify(opts.Anchor))
+	}
 	if opts.Title != "" {
 		fmt.Fprintf(&doc, "# %s\n\n", opts.Title)
 	}
@@ -67,6 +85,10 @@ func PrintMarkdown(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error {
 		fmt.Fprintln(&doc)
 	}
 
+	if len(opts.FormerNames) > 0 {
+		fmt.Fprintf(&doc, "**Formerly:** %s\n\n", strings.Join(opts.FormerNames, ", "))
+	}
+
 	// Summary
 	fmt.Fprintln(&doc, "## Summary")
 	fmt.Fprintln(&doc, info.Purpose)
@@ -84,7 +106,7 @@ func PrintMarkdown(w io.Writer, cmd InfoCommand, opts MarkdownOptions) error {
 	}
 
 	// Options
-	printFlags(&doc, cmd)
+	printFlags(&doc, cmd, opts.Anchor, opts.Reproducible)
 
 	// Examples
 	if info.Examples != "" {
@@ -139,12 +161,50 @@ func getFlagsName(fka string) string {
 	return fka
 }
 
-func printFlags(w io.Writer, cmd InfoCommand) {
+func printFlags(w io.Writer, cmd InfoCommand, anchor string, reproducible bool) {
+	byName := groupFlags(cmd)
+	if len(byName) == 0 {
+		// No flags, so we won't print this section
+		return
+	}
+
+	fmt.Fprintln(w, "### Options")
+	fmt.Fprintln(w, "| Flag | Default | Usage |")
+	fmt.Fprintln(w, "| --- | --- | --- |")
+
+	for _, fs := range byName {
+		// Collect all flag aliases (usually a short one and a plain one, like -v / --verbose)
+		formattedFlags := ""
+		if anchor != "" {
+			// The longest name is the most descriptive, and the least
+			// likely to be reassigned to another flag.
+			formattedFlags = fmt.Sprintf("<a id=\"%s\"></a>", flagAnchor(anchor, fs[len(fs)-1].Name))
+		}
+		for i, f := range fs {
+			if i > 0 {
+				formattedFlags += ", "
+			}
+			formattedFlags += fmt.Sprintf("`%s`", flagName(f))
+		}
+		// display all the flags aliases and the default value and description of the shortest one.
+		// Escape Markdown in description in order to display it cleanly in the final documentation.
+		fmt.Fprintf(w, "| %s | %s | %s |\n", formattedFlags,
+			EscapeMarkdown(flagDefault(fs[0], reproducible)),
+			strings.ReplaceAll(EscapeMarkdown(fs[0].Usage), "\n", " "),
+		)
+	}
+	fmt.Fprintln(w)
+}
+
+// groupFlags returns the flags of cmd, grouped by the value they set and
+// sorted by name.
+func groupFlags(cmd InfoCommand) flagsByName {
 	info := cmd.Info()
 
 	flagKnownAs := getFlagsName(info.FlagKnownAs)
 	f := gnuflag.NewFlagSetWithFlagKnownAs(info.Name, gnuflag.ContinueOnError, flagKnownAs)
 	cmd.SetFlags(f)
+	describeChoices(f)
 
 	// group together all flags for a given value, meaning that flag which sets the same value are
 	// grouped together and displayed with the same description, as below:
@@ -154,10 +214,6 @@ func printFlags(w io.Writer, cmd InfoCommand) {
 	f.VisitAll(func(f *gnuflag.Flag) {
 		flags[f.Value] = append(flags[f.Value], f)
 	})
-	if len(flags) == 0 {
-		// No flags, so we won't print this section
-		return
-	}
 
 	// sort the output flags by shortest name for each group.
 	// Caution: this mean that description/default value displayed in documentation will
@@ -169,32 +225,62 @@ func printFlags(w io.Writer, cmd InfoCommand) {
 		byName = append(byName, fl)
 	}
 	sort.Sort(byName)
+	return byName
+}
 
-	fmt.Fprintln(w, "### Options")
-	fmt.Fprintln(w, "| Flag | Default | Usage |")
-	fmt.Fprintln(w, "| --- | --- | --- |")
+// flagName returns the name of f as it is given on the command line.
+func flagName(f *gnuflag.Flag) string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
 
-	for _, fs := range byName {
-		// Collect all flag aliases (usually a short one and a plain one, like -v / --verbose)
-		formattedFlags := ""
-		for i, f := range fs {
-			if i > 0 {
-				formattedFlags += ", "
-			}
-			if len(f.Name) == 1 {
-				formattedFlags += fmt.Sprintf("`-%s`", f.Name)
-			} else {
-				formattedFlags += fmt.Sprintf("`--%s`", f.Name)
-			}
+// flagDefault returns the default value of f as it is documented.
+func flagDefault(f *gnuflag.Flag, reproducible bool) string {
+	if source := defaultSource(f); source != "" {
+		return fmt.Sprintf("%s (%s)", DynamicDefault, source)
+	} else if placeholder := defaultPlaceholder(f); placeholder != "" {
+		return placeholder
+	} else if reproducible {
+		return withoutHomeDir(f.DefValue)
+	}
+	return f.DefValue
+}
+
+// slugify returns s in a form usable as an anchor id: lower case, with
+// every run of characters other than letters, digits, '-' and '_' replaced
+// by a single '-'.
+func slugify(s string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			slug.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && slug.Len() > 0 {
+			slug.WriteRune('-')
+			dash = true
 		}
-		// display all the flags aliases and the default value and description of the shortest one.
-		// Escape Markdown in description in order to display it cleanly in the final documentation.
-		fmt.Fprintf(w, "| %s | %s | %s |\n", formattedFlags,
-			EscapeMarkdown(fs[0].DefValue),
-			strings.ReplaceAll(EscapeMarkdown(fs[0].Usage), "\n", " "),
-		)
 	}
-	fmt.Fprintln(w)
+	return strings.TrimSuffix(slug.String(), "-")
+}
+
+// flagAnchor returns the anchor id of the named flag of the command with
+// the given anchor.
+func flagAnchor(anchor, flag string) string {
+	return slugify(anchor) + "--" + slugify(flag)
+}
+
+// withoutHomeDir replaces the user's home directory in s with "~".
+func withoutHomeDir(s string) string {
+	home := homeDir(os.Getenv)
+	if home == "" || home == "/" {
+		return s
+	}
+	return strings.ReplaceAll(s, home, "~")
 }
 
 // flagsByLength is a slice of flags implementing sort.Interface,
@@ -235,15 +321,7 @@ func printSubcommands(
 	subcommands map[string]string,
 	linkForSubcommand func(string) string,
 ) {
-	sorted := []string{}
-	for name := range subcommands {
-		if isDefaultCommand(name) {
-			continue
-		}
-		sorted = append(sorted, name)
-	}
-	sort.Strings(sorted)
-
+	sorted := subcommandNames(subcommands)
 	if len(sorted) > 0 {
 		fmt.Fprintln(w, "## Subcommands")
 		for _, name := range sorted {
@@ -255,6 +333,20 @@ func printSubcommands(
 	}
 }
 
+// subcommandNames returns the sorted names of the subcommands to be
+// documented.
+func subcommandNames(subcommands map[string]string) []string {
+	sorted := []string{}
+	for name := range subcommands {
+		if isDefaultCommand(name) {
+			continue
+		}
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
 // markdownLink uses the provided linker function to generate a Markdown
 // hyperlink for the given key. It attempts to call the linker function on the
 // given key to get the link target. If the function is nil or the output is
@@ -105,3 +105,29 @@ func (*markdownSuite) TestOutput(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Check(buf.String(), gc.Equals, string(expected))
 }
+
+// TestAnchors checks that anchors are written for the command and each of
+// its flags when requested.
+func (*markdownSuite) TestAnchors(c *gc.C) {
+	command := &docTestCommand{
+		info: &cmd.Info{
+			Name:    "add-cloud",
+			Purpose: "Add a cloud.",
+		},
+		flags: []testFlag{{name: "force", short: "f"}},
+	}
+
+	var buf bytes.Buffer
+	err := cmd.PrintMarkdown(&buf, command, cmd.MarkdownOptions{
+		Title:  "ADD CLOUD",
+		Anchor: "Storage Pools_add-cloud!",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(buf.String(), gc.Matches, "<a id=\"storage-pools_add-cloud\"></a>\n\n# ADD CLOUD\n(.|\n)*")
+	c.Check(buf.String(), gc.Matches, "(.|\n)*\\| <a id=\"storage-pools_add-cloud--force\"></a>`-f`, `--force` \\|(.|\n)*")
+
+	buf.Reset()
+	err = cmd.PrintMarkdown(&buf, command, cmd.MarkdownOptions{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(buf.String(), gc.Not(gc.Matches), "(.|\n)*<a id=(.|\n)*")
+}
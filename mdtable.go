@@ -0,0 +1,94 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+)
+
+// FormatMarkdown writes out value as a GitHub flavoured Markdown table,
+// so that it can be pasted into issues and documentation. The columns are
+// padded to line up when read in a terminal. See FormatCSVColumns for the
+// values accepted.
+func FormatMarkdown(writer io.Writer, value interface{}) error {
+	return formatMarkdownTable(writer, value, nil)
+}
+
+// FormatMarkdownColumns is like FormatCSVColumns, but returns a Formatter
+// writing a Markdown table, as in "--format markdown=name,status".
+func FormatMarkdownColumns(columns string) (Formatter, error) {
+	selected, err := parseColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	return func(writer io.Writer, value interface{}) error {
+		return formatMarkdownTable(writer, value, selected)
+	}, nil
+}
+
+// formatMarkdownTable writes value as a Markdown table, with the given
+// columns if not empty.
+func formatMarkdownTable(writer io.Writer, value interface{}, columns []string) error {
+	if value == nil {
+		return nil
+	}
+	columns, records, err := projectColumns(value, columns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(columns) == 0 {
+		// A table without columns cannot be written in Markdown.
+		return nil
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = markdownCell(column)
+	}
+	for _, record := range records {
+		for i, cell := range record {
+			record[i] = markdownCell(cell)
+		}
+	}
+
+	// The delimiter row needs at least three dashes.
+	widths := make([]int, len(columns))
+	for i := range widths {
+		widths[i] = 3
+	}
+	for _, row := range append([][]string{header}, records...) {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	delimiter := make([]string, len(columns))
+	for i, width := range widths {
+		delimiter[i] = strings.Repeat("-", width)
+	}
+
+	var b strings.Builder
+	for _, row := range append([][]string{header, delimiter}, records...) {
+		b.WriteString("|")
+		for i, cell := range row {
+			b.WriteString(" " + cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)) + " |")
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(writer, b.String())
+	return errors.Trace(err)
+}
+
+// markdownCell escapes text for a cell of a Markdown table, in which pipes
+// end the cell and line breaks end the row.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "\r\n", "<br>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type MarkdownTableSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&MarkdownTableSuite{})
+
+func (s *MarkdownTableSuite) TestFormatMarkdown(c *gc.C) {
+	for i, test := range []struct {
+		value  interface{}
+		output string
+	}{{
+		value: units,
+		output: `
+| name        | status                    | age | ports  |
+| ----------- | ------------------------- | --- | ------ |
+| mysql/0     | active                    | 3   | [3306] |
+| wordpress/0 | blocked, waiting for "db" |     |        |
+`[1:],
+	}, {
+		value: map[string]string{"a|b": "line one\nline two", "c": `back\slash`},
+		output: `
+| key  | value                |
+| ---- | -------------------- |
+| a\|b | line one<br>line two |
+| c    | back\\slash          |
+`[1:],
+	}, {
+		value:  []string{"é"},
+		output: "| value |\n| ----- |\n| é     |\n",
+	}, {
+		value:  []string{},
+		output: "",
+	}, {
+		value:  nil,
+		output: "",
+	}} {
+		c.Logf("test %d", i)
+		var buf bytes.Buffer
+		c.Check(cmd.FormatMarkdown(&buf, test.value), gc.IsNil)
+		c.Check(buf.String(), gc.Equals, test.output)
+	}
+}
+
+func (s *MarkdownTableSuite) TestFormatMarkdownColumns(c *gc.C) {
+	formatter, err := cmd.FormatMarkdownColumns("status, name")
+	c.Assert(err, gc.IsNil)
+	var buf bytes.Buffer
+	c.Assert(formatter(&buf, units[:1]), gc.IsNil)
+	c.Assert(buf.String(), gc.Equals, "| status | name    |\n| ------ | ------- |\n| active | mysql/0 |\n")
+
+	err = formatter(&buf, []string{"a"})
+	c.Assert(err, gc.ErrorMatches, `unknown column "status", expected one of value`)
+
+	_, err = cmd.FormatMarkdownColumns("")
+	c.Assert(err, gc.ErrorMatches, "no columns specified")
+}
+
+func (s *MarkdownTableSuite) TestFormatFlag(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&OutputCommand{value: units[:1]}, ctx, []string{"--format", "markdown=name"})
+	c.Check(code, gc.Equals, 0)
+	c.Check(cmdtesting.Stdout(ctx), gc.Equals, "| name    |\n| ------- |\n| mysql/0 |\n")
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+// RunFunc runs the subcommand with the given name, which is the full path
+// of the command, e.g. "juju add-model".
+type RunFunc func(ctx *Context, name string) error
+
+// Middleware returns a RunFunc that wraps next, e.g. to do something before
+// or after running a subcommand, or instead of it.
+type Middleware func(next RunFunc) RunFunc
+
+// runAction validates and runs the chosen subcommand, wrapped by the
+// middleware. Nested super commands apply the middleware to their own
+// subcommands instead.
+func (c *SuperCommand) runAction(ctx *Context, action commandReference) error {
+	run := func(ctx *Context, _ string) error {
+		if err := validate(action.command, ctx); err != nil {
+			return err
+		}
+		if estimator, ok := action.command.(ImpactEstimator); ok && c.preview {
+			return c.runPreview(ctx, estimator)
+		}
+		return action.command.Run(ctx)
+	}
+	if action.command.IsSuperCommand() {
+		return run(ctx, "")
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		run = c.middleware[i](run)
+	}
+	return run(ctx, c.commandPath(action.name))
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type MiddlewareSuite struct{}
+
+var _ = gc.Suite(&MiddlewareSuite{})
+
+// recordMiddleware returns middleware recording the commands it wraps in
+// calls, tagged with tag.
+func recordMiddleware(tag string, calls *[]string) cmd.Middleware {
+	return func(next cmd.RunFunc) cmd.RunFunc {
+		return func(ctx *cmd.Context, name string) error {
+			*calls = append(*calls, fmt.Sprintf("%s before %s", tag, name))
+			err := next(ctx, name)
+			*calls = append(*calls, fmt.Sprintf("%s after %s: %v", tag, name, err))
+			return err
+		}
+	}
+}
+
+func (s *MiddlewareSuite) TestMiddlewareOrder(c *gc.C) {
+	var calls []string
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "jujutest",
+		Middleware: []cmd.Middleware{
+			recordMiddleware("outer", &calls),
+			recordMiddleware("inner", &calls),
+		},
+	})
+	jc.Register(&TestCommand{Name: "blah"})
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(jc, ctx, []string{"blah", "--option", "error"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(calls, gc.DeepEquals, []string{
+		"outer before jujutest blah",
+		"inner before jujutest blah",
+		"inner after jujutest blah: BAM!",
+		"outer after jujutest blah: BAM!",
+	})
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR BAM!\n")
+}
+
+func (s *MiddlewareSuite) TestMiddlewarePreventsRun(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "jujutest",
+		Middleware: []cmd.Middleware{func(next cmd.RunFunc) cmd.RunFunc {
+			return func(ctx *cmd.Context, name string) error {
+				return errors.Errorf("not allowed to run %q", name)
+			}
+		}},
+	})
+	jc.Register(&TestCommand{Name: "blah"})
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(jc, ctx, []string{"blah", "--option", "hello"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR not allowed to run \"jujutest blah\"\n")
+}
+
+func (s *MiddlewareSuite) TestNestedSuperCommand(c *gc.C) {
+	var calls []string
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:       "juju",
+		Middleware: []cmd.Middleware{recordMiddleware("juju", &calls)},
+	})
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", UsagePrefix: "juju"})
+	storage.Register(&TestCommand{Name: "list"})
+	jc.Register(storage)
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(jc, ctx, []string{"storage", "list", "--option", "hello"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(calls, gc.DeepEquals, []string{
+		"juju before juju storage list",
+		"juju after juju storage list: <nil>",
+	})
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "hello\n")
+}
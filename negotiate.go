@@ -0,0 +1,128 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ContentTyper is implemented by formatters that know the MIME type of
+// the output they write, such as TypeFormatter.
+type ContentTyper interface {
+	// ContentType returns the MIME type of the formatted output.
+	ContentType() string
+}
+
+// mediaRange holds one entry of an Accept header.
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// specificity returns 0 for "*/*", 1 for "type/*" and 2 otherwise.
+func (r mediaRange) specificity() int {
+	switch {
+	case r.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(r.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// matches reports whether the media type t is within the range.
+func (r mediaRange) matches(t string) bool {
+	switch r.specificity() {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(t, strings.TrimSuffix(r.mediaType, "*"))
+	}
+	return r.mediaType == t
+}
+
+// NegotiateFormat chooses the name of the formatter in available whose
+// content type best matches accept, which is given in the form of an HTTP
+// Accept header, e.g. "application/json, text/*;q=0.5". The fallback
+// formatter is preferred when a wildcard matches it, and is returned if
+// accept is empty. Otherwise ties are broken by the formatter name.
+func NegotiateFormat(accept string, available map[string]TypeFormatter, fallback string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		if _, ok := available[fallback]; !ok {
+			return "", errors.Errorf("unknown format %q", fallback)
+		}
+		return fallback, nil
+	}
+	ranges := parseAccept(accept)
+
+	// Formatters explicitly given a zero quality are never chosen.
+	excluded := make(map[string]bool)
+	for _, r := range ranges {
+		if r.q == 0 && r.specificity() == 2 {
+			excluded[r.mediaType] = true
+		}
+	}
+	names := make([]string, 0, len(available))
+	for name := range available {
+		if !excluded[contentType(available[name])] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := available[fallback]; ok && !excluded[contentType(available[fallback])] {
+		names = append([]string{fallback}, names...)
+	}
+
+	for _, r := range ranges {
+		if r.q == 0 {
+			continue
+		}
+		for _, name := range names {
+			if r.matches(contentType(available[name])) {
+				return name, nil
+			}
+		}
+	}
+	return "", errors.Errorf("no output format matches %q", accept)
+}
+
+// contentType returns the lower case MIME type of f, without parameters.
+func contentType(f ContentTyper) string {
+	t, _, err := mime.ParseMediaType(f.ContentType())
+	if err != nil {
+		return strings.ToLower(f.ContentType())
+	}
+	return t
+}
+
+// parseAccept returns the media ranges in accept, ordered by preference.
+// Invalid entries are ignored.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		t, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		r := mediaRange{mediaType: t, q: 1}
+		if q, ok := params["q"]; ok {
+			if r.q, err = strconv.ParseFloat(q, 64); err != nil || r.q < 0 || r.q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
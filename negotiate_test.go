@@ -0,0 +1,57 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+type NegotiateSuite struct{}
+
+var _ = gc.Suite(&NegotiateSuite{})
+
+func (s *NegotiateSuite) TestContentType(c *gc.C) {
+	var typer cmd.ContentTyper = cmd.DefaultFormatters["json"]
+	c.Assert(typer.ContentType(), gc.Equals, "application/json")
+	c.Assert(cmd.DefaultFormatters["smart"].ContentType(), gc.Equals, "text/plain")
+}
+
+func (s *NegotiateSuite) TestNegotiateFormat(c *gc.C) {
+	for i, test := range []struct {
+		accept string
+		expect string
+	}{
+		{"", "smart"},
+		{"*/*", "smart"},
+		{"application/json", "json"},
+		{"Application/JSON; charset=utf-8", "json"},
+		{"application/yaml;q=0.5, application/json", "json"},
+		{"text/csv;q=0.2, application/yaml;q=0.8", "yaml"},
+		{"text/*", "smart"},
+		{"text/*, text/markdown", "markdown"},
+		{"text/plain;q=0, text/*", "csv"},
+		{"application/*", "json"},
+		{"image/png, */*;q=0.1", "smart"},
+		{"bogus, application/json", "json"},
+	} {
+		c.Logf("test %d: %q", i, test.accept)
+		name, err := cmd.NegotiateFormat(test.accept, cmd.DefaultFormatters, "smart")
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(name, gc.Equals, test.expect)
+	}
+}
+
+func (s *NegotiateSuite) TestNegotiateFormatNoMatch(c *gc.C) {
+	_, err := cmd.NegotiateFormat("image/png", cmd.DefaultFormatters, "smart")
+	c.Assert(err, gc.ErrorMatches, `no output format matches "image/png"`)
+
+	_, err = cmd.NegotiateFormat("application/json;q=0", cmd.DefaultFormatters, "smart")
+	c.Assert(err, gc.ErrorMatches, `no output format matches .*`)
+
+	_, err = cmd.NegotiateFormat("", cmd.DefaultFormatters, "tabular")
+	c.Assert(err, gc.ErrorMatches, `unknown format "tabular"`)
+}
@@ -4,12 +4,15 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/juju/gnuflag"
 	goyaml "gopkg.in/yaml.v2"
@@ -53,6 +56,41 @@ func FormatJson(writer io.Writer, value interface{}) error {
 	return err
 }
 
+// FormatJsonIndent writes out value as json indented for people to read.
+func FormatJsonIndent(writer io.Writer, value interface{}) error {
+	return formatJsonIndent(writer, value, "  ")
+}
+
+// FormatJsonArgument returns a Formatter for the argument given with
+// "--format json=<arg>", which is "pretty" for FormatJsonIndent, or the
+// number of spaces to indent with.
+func FormatJsonArgument(arg string) (Formatter, error) {
+	if arg == "pretty" {
+		return FormatJsonIndent, nil
+	}
+	spaces, err := strconv.Atoi(arg)
+	if err != nil || spaces < 0 || spaces > 8 {
+		return nil, fmt.Errorf("expected \"pretty\" or an indent of 0 to 8 spaces, got %q", arg)
+	}
+	if spaces == 0 {
+		return FormatJson, nil
+	}
+	indent := strings.Repeat(" ", spaces)
+	return func(writer io.Writer, value interface{}) error {
+		return formatJsonIndent(writer, value, indent)
+	}, nil
+}
+
+func formatJsonIndent(writer io.Writer, value interface{}, indent string) error {
+	result, err := json.MarshalIndent(value, "", indent)
+	if err != nil {
+		return err
+	}
+	result = append(result, '\n')
+	_, err = writer.Write(result)
+	return err
+}
+
 // FormatSmart marshals value into a []byte according to the following rules:
 //   - string:        untouched
 //   - bool:          converted to `True` or `False` (to match pyjuju)
@@ -85,11 +123,32 @@ func FormatSmart(writer io.Writer, value interface{}) error {
 	return err
 }
 
+// FormatterWithArgument returns a Formatter configured by the argument
+// given after "=" in the --format flag, e.g. the columns in
+// "--format csv=name,status".
+type FormatterWithArgument func(arg string) (Formatter, error)
+
 // TypeFormatter describes a formatting type that can define if a type is
 // serialisable.
 type TypeFormatter struct {
 	Formatter    Formatter
 	Serialisable bool
+
+	// WithArgument, if not nil, allows the formatter to be configured
+	// with an argument.
+	WithArgument FormatterWithArgument
+
+	// MediaType holds the MIME type of the formatted output. If empty,
+	// the output is taken to be text/plain.
+	MediaType string
+}
+
+// ContentType implements ContentTyper.
+func (f TypeFormatter) ContentType() string {
+	if f.MediaType == "" {
+		return "text/plain"
+	}
+	return f.MediaType
 }
 
 type formatters map[string]TypeFormatter
@@ -107,15 +166,29 @@ func (f formatters) Formatters() map[string]Formatter {
 // DefaultFormatters holds the formatters that can be
 // specified with the --format flag.
 var DefaultFormatters = formatters{
-	"smart": TypeFormatter{Formatter: FormatSmart, Serialisable: false},
-	"yaml":  TypeFormatter{Formatter: FormatYaml, Serialisable: true},
-	"json":  TypeFormatter{Formatter: FormatJson, Serialisable: true},
+	"smart":    TypeFormatter{Formatter: FormatSmart, Serialisable: false},
+	"yaml":     TypeFormatter{Formatter: FormatYaml, Serialisable: true, MediaType: "application/yaml"},
+	"json":     TypeFormatter{Formatter: FormatJson, Serialisable: true, WithArgument: FormatJsonArgument, MediaType: "application/json"},
+	"csv":      TypeFormatter{Formatter: FormatCSV, Serialisable: true, WithArgument: FormatCSVColumns, MediaType: "text/csv"},
+	"tsv":      TypeFormatter{Formatter: FormatTSV, Serialisable: true, WithArgument: FormatTSVColumns, MediaType: "text/tab-separated-values"},
+	"markdown": TypeFormatter{Formatter: FormatMarkdown, Serialisable: false, WithArgument: FormatMarkdownColumns, MediaType: "text/markdown"},
 }
 
 // formatterValue implements gnuflag.Value for the --format flag.
 type formatterValue struct {
 	name       string
 	formatters map[string]Formatter
+
+	// types holds the TypeFormatters the formatters were taken from, if
+	// any.
+	types map[string]TypeFormatter
+
+	// configured is the formatter returned for the argument given with
+	// the name, if any.
+	configured Formatter
+
+	// arg holds the argument the configured formatter was created with.
+	arg string
 }
 
 // newFormatterValue returns a new formatterValue. The initial Formatter name
@@ -128,17 +201,50 @@ func newFormatterValue(initial string, formatters map[string]Formatter) *formatt
 	return v
 }
 
-// Set stores the chosen formatter name in v.name.
+// newTypeFormatterValue returns a new formatterValue for the given
+// TypeFormatters, which may accept an argument. The initial Formatter name
+// must be present in types.
+func newTypeFormatterValue(initial string, types map[string]TypeFormatter) *formatterValue {
+	formatters := make(map[string]Formatter, len(types))
+	for name, t := range types {
+		formatters[name] = t.Formatter
+	}
+	v := &formatterValue{formatters: formatters, types: types}
+	if err := v.Set(initial); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Set stores the chosen formatter name in v.name. A TypeFormatter that
+// accepts an argument may be followed by "=" and the argument.
 func (v *formatterValue) Set(value string) error {
-	if v.formatters[value] == nil {
+	name, arg, hasArg := strings.Cut(value, "=")
+	if v.formatters[name] == nil {
 		return fmt.Errorf("unknown format %q", value)
 	}
-	v.name = value
+	v.configured, v.arg = nil, ""
+	if hasArg {
+		withArgument := v.types[name].WithArgument
+		if withArgument == nil {
+			return fmt.Errorf("format %q does not take an argument", name)
+		}
+		configured, err := withArgument(arg)
+		if err != nil {
+			return fmt.Errorf("invalid %s format: %v", name, err)
+		}
+		v.configured, v.arg = configured, arg
+	}
+	v.name = name
 	return nil
 }
 
-// String returns the chosen formatter name.
+// String returns the chosen formatter name, followed by "=" and its
+// argument if one was given.
 func (v *formatterValue) String() string {
+	if v.configured != nil {
+		return v.name + "=" + v.arg
+	}
 	return v.name
 }
 
@@ -154,44 +260,137 @@ func (v *formatterValue) doc() string {
 	return "Specify output format (" + strings.Join(choices, "|") + ")"
 }
 
+// formatter returns the chosen formatter.
+func (v *formatterValue) formatter() Formatter {
+	if v.configured != nil {
+		return v.configured
+	}
+	return v.formatters[v.name]
+}
+
+// serialisable reports whether the chosen format is machine readable, as
+// marked in the TypeFormatters given, or else in DefaultFormatters.
+func (v *formatterValue) serialisable() bool {
+	t, ok := v.types[v.name]
+	if !ok {
+		t = DefaultFormatters[v.name]
+	}
+	return t.Serialisable
+}
+
 // format runs the chosen formatter on value.
 func (v *formatterValue) format(writer io.Writer, value interface{}) error {
-	return v.formatters[v.name](writer, value)
+	return v.formatter()(writer, value)
 }
 
 // Output is responsible for interpreting output-related command line flags
 // and writing a value to a file or to stdout as directed.
 type Output struct {
-	formatter *formatterValue
-	outPath   string
+	formatter  *formatterValue
+	outPath    string
+	color      ColorMode
+	maxLines   int
+	maxOutput  uint64
+	noTruncate bool
+	processors []OutputProcessor
+}
+
+// OutputInfo describes where formatted output is being written, for the
+// benefit of an OutputProcessor.
+type OutputInfo struct {
+	// Format holds the name of the chosen --format, which is empty when
+	// the command wrote with its own formatter.
+	Format string
+
+	// Terminal reports whether the output is written to a terminal.
+	Terminal bool
+
+	// Color reports whether the output may contain colors.
+	Color bool
+}
+
+// OutputProcessor transforms formatted output before it is written, e.g.
+// to colorise it or redact secrets from it.
+type OutputProcessor func(info OutputInfo, output []byte) ([]byte, error)
+
+// AddProcessor registers p to process the output written by c. Processors
+// are run in the order they are added, between formatting the value and
+// writing the result, so that policies applying to the output of many
+// commands do not each need a custom formatter.
+func (c *Output) AddProcessor(p OutputProcessor) {
+	c.processors = append(c.processors, p)
 }
 
 // AddFlags injects the --format and --output command line flags into f.
+// The --color flag is added separately with AddColorFlag, as existing
+// commands may already define a --color flag of their own, which gnuflag
+// would refuse to redefine.
 func (c *Output) AddFlags(f *gnuflag.FlagSet, defaultFormatter string, formatters map[string]Formatter) {
-	c.formatter = newFormatterValue(defaultFormatter, formatters)
+	c.addFlags(f, newFormatterValue(defaultFormatter, formatters))
+}
+
+// AddFormatFlags is like AddFlags, but takes TypeFormatters, such as
+// DefaultFormatters. A format whose TypeFormatter
+// has WithArgument may be given an argument, e.g. "--format csv=name,age".
+func (c *Output) AddFormatFlags(f *gnuflag.FlagSet, defaultFormatter string, formatters map[string]TypeFormatter) {
+	c.addFlags(f, newTypeFormatterValue(defaultFormatter, formatters))
+}
+
+func (c *Output) addFlags(f *gnuflag.FlagSet, formatter *formatterValue) {
+	c.formatter = formatter
 	f.Var(c.formatter, "format", c.formatter.doc())
 	f.StringVar(&c.outPath, "o", "", "Specify an output file")
 	f.StringVar(&c.outPath, "output", "", "")
 }
 
+// AddColorFlag injects the --color command line flag into f, which sets
+// whether colours are written to the output (see Context.SetColorMode).
+func (c *Output) AddColorFlag(f *gnuflag.FlagSet) {
+	f.Var(&c.color, "color", "Use colors in the output (auto|always|never)")
+}
+
+// AddTruncationFlags injects the --max-lines, --max-output and --no-truncate
+// command line flags into f, for commands whose output may be too large to
+// read. Output written to a terminal is cut short after the maximum number
+// of lines, or bytes given as a size such as "64K", with a notice on stderr
+// saying how to see all of it. Output written to a file, or to stdout when
+// it is not a terminal, is never truncated. A defaultMaxLines of zero does
+// not truncate unless --max-lines or --max-output is given.
+func (c *Output) AddTruncationFlags(f *gnuflag.FlagSet, defaultMaxLines int) {
+	f.IntVar(&c.maxLines, "max-lines", defaultMaxLines, "Truncate output to a terminal after this many lines (0 for no limit)")
+	f.Var(NewSizeValue(0, &c.maxOutput), "max-output", "Truncate output to a terminal after this many bytes, e.g. 64K (0 for no limit)")
+	f.BoolVar(&c.noTruncate, "no-truncate", false, "Do not truncate output to a terminal")
+}
+
 // Write formats and outputs the value as directed by the --format and
 // --output command line flags.
 func (c *Output) Write(ctx *Context, value interface{}) (err error) {
-	formatterName := c.formatter.name
-	formatter := c.formatter.formatters[formatterName]
-	if err := c.writeFormatter(ctx, formatter, value); err != nil {
+	if err := c.write(ctx, c.formatter.name, c.formatter.formatter(), value); err != nil {
 		return err
 	}
 	return nil
 }
 
+// WriteSummaryAndDetail outputs the human readable summary when the
+// chosen format is meant for people, and the structured value when it is
+// a machine readable format (one marked Serialisable in the TypeFormatters
+// given to AddFormatFlags, or in DefaultFormatters).
+// It is intended for commands that change state, which report a short
+// message to people and the full result to scripts.
+func (c *Output) WriteSummaryAndDetail(ctx *Context, summary string, value interface{}) error {
+	if c.formatter.serialisable() {
+		return c.Write(ctx, value)
+	}
+	return c.write(ctx, "smart", FormatSmart, summary)
+}
+
 // WriteFormatter formats and outputs the value with the given formatter,
 // to the output directed by the --output command line flag.
 func (c *Output) WriteFormatter(ctx *Context, formatter Formatter, value interface{}) (err error) {
-	return c.writeFormatter(ctx, formatter, value)
+	return c.write(ctx, "", formatter, value)
 }
 
-func (c *Output) writeFormatter(ctx *Context, formatter Formatter, value interface{}) (err error) {
+func (c *Output) write(ctx *Context, format string, formatter Formatter, value interface{}) (err error) {
 	var target io.Writer
 	if c.outPath == "" {
 		target = ctx.Stdout
@@ -204,8 +403,42 @@ func (c *Output) writeFormatter(ctx *Context, formatter Formatter, value interfa
 		defer f.Close()
 		target = f
 	}
-	if err := formatter(target, value); err != nil {
-		return err
+	if c.color != "" {
+		ctx.SetColorMode(c.color)
+	}
+	color := ctx.ColorEnabled(target)
+	terminal := isTerminal(target)
+	var limit *limitWriter
+	if c.outPath == "" && (c.maxLines > 0 || c.maxOutput > 0) && !c.noTruncate && terminal {
+		limit = &limitWriter{target: target, maxLines: c.maxLines, maxBytes: c.maxOutput}
+		target = limit
+	}
+	if len(c.processors) == 0 {
+		if err := formatter(newColorWriter(target, color), value); err != nil {
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := formatter(newColorWriter(&buf, color), value); err != nil {
+			return err
+		}
+		info := OutputInfo{Format: format, Terminal: terminal, Color: color}
+		output := buf.Bytes()
+		for _, p := range c.processors {
+			if output, err = p(info, output); err != nil {
+				return err
+			}
+		}
+		if _, err := target.Write(output); err != nil {
+			return err
+		}
+	}
+	if limit != nil && limit.truncated {
+		after := fmt.Sprintf("%d bytes", limit.written)
+		if limit.lineLimited {
+			after = fmt.Sprintf("%d lines", c.maxLines)
+		}
+		fmt.Fprintf(ctx.Stderr, "... output truncated after %s, use --no-truncate or --output <file> to see all of it\n", after)
 	}
 	// Suppress the handling of errors on stdout when a machine formatter is used.
 	ctx.outputFormatUsed = true
@@ -216,3 +449,58 @@ func (c *Output) writeFormatter(ctx *Context, formatter Formatter, value interfa
 func (c *Output) Name() string {
 	return c.formatter.name
 }
+
+// limitWriter writes what is written to it to target, until the maximum
+// number of lines or bytes has been written, and discards the rest. A
+// maximum of zero is no limit.
+type limitWriter struct {
+	target    io.Writer
+	maxLines  int
+	maxBytes  uint64
+	lines     int
+	written   uint64
+	truncated bool
+
+	// lineLimited reports whether the output was truncated because of
+	// the maximum number of lines.
+	lineLimited bool
+}
+
+// Write implements io.Writer. It reports p as written even when some or
+// all of it is discarded, so that formatters do not fail.
+func (w *limitWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if w.truncated {
+		return n, nil
+	}
+	keep := p
+	if w.maxBytes > 0 && uint64(len(keep)) > w.maxBytes-w.written {
+		keep = keep[:w.maxBytes-w.written]
+		// Do not split a multi-byte character.
+		for len(keep) > 0 && !utf8.RuneStart(p[len(keep)]) {
+			keep = keep[:len(keep)-1]
+		}
+	}
+	if w.maxLines > 0 {
+		for i, b := range keep {
+			if w.lines == w.maxLines {
+				keep = keep[:i]
+				break
+			}
+			if b == '\n' {
+				w.lines++
+			}
+		}
+		if w.lines == w.maxLines && len(keep) < n {
+			w.lineLimited = true
+		}
+	}
+	w.truncated = len(keep) < n
+	w.written += uint64(len(keep))
+	if len(keep) > 0 {
+		if _, err := w.target.Write(keep); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
@@ -4,6 +4,11 @@
 package cmd_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
 	"github.com/juju/gnuflag"
 	"github.com/juju/loggo/v2"
 	"github.com/juju/testing"
@@ -30,11 +35,7 @@ func (c *OutputCommand) Info() *cmd.Info {
 }
 
 func (c *OutputCommand) SetFlags(f *gnuflag.FlagSet) {
-	formatters := make(map[string]cmd.Formatter, len(cmd.DefaultFormatters))
-	for k, v := range cmd.DefaultFormatters {
-		formatters[k] = v.Formatter
-	}
-	c.out.AddFlags(f, "smart", formatters)
+	c.out.AddFormatFlags(f, "smart", cmd.DefaultFormatters)
 }
 
 func (c *OutputCommand) Init(args []string) error {
@@ -45,6 +46,9 @@ func (c *OutputCommand) Run(ctx *cmd.Context) error {
 	if value, ok := c.value.(overrideFormatter); ok {
 		return c.out.WriteFormatter(ctx, value.formatter, value.value)
 	}
+	if value, ok := c.value.(summaryAndDetail); ok {
+		return c.out.WriteSummaryAndDetail(ctx, value.summary, value.detail)
+	}
 	return c.out.Write(ctx, c.value)
 }
 
@@ -53,6 +57,11 @@ type overrideFormatter struct {
 	value     interface{}
 }
 
+type summaryAndDetail struct {
+	summary string
+	detail  interface{}
+}
+
 // use a struct to control field ordering.
 var defaultValue = struct {
 	Juju   int
@@ -206,3 +215,206 @@ func (s *OutputSuite) TestFormatters(c *gc.C) {
 		c.Assert(ok, gc.Equals, true)
 	}
 }
+
+func (s *OutputSuite) TestWriteSummaryAndDetail(c *gc.C) {
+	value := summaryAndDetail{
+		summary: "Added 2 units",
+		detail:  map[string][]string{"units": {"mysql/0", "mysql/1"}},
+	}
+	for i, test := range []struct {
+		args   []string
+		output string
+	}{
+		{output: "Added 2 units\n"},
+		{args: []string{"--format", "smart"}, output: "Added 2 units\n"},
+		{args: []string{"--format", "json"}, output: `{"units":["mysql/0","mysql/1"]}` + "\n"},
+		{args: []string{"--format", "yaml"}, output: "units:\n- mysql/0\n- mysql/1\n"},
+	} {
+		c.Logf("test %d: %v", i, test.args)
+		s.SetUpTest(c)
+		result := cmd.Main(&OutputCommand{value: value}, s.ctx, test.args)
+		c.Check(result, gc.Equals, 0)
+		c.Check(bufferString(s.ctx.Stdout), gc.Equals, test.output)
+		s.TearDownTest(c)
+	}
+}
+
+// truncatedCommand writes a list with the output truncation flags.
+type truncatedCommand struct {
+	OutputCommand
+}
+
+func (c *truncatedCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.OutputCommand.SetFlags(f)
+	c.out.AddTruncationFlags(f, 3)
+}
+
+func (s *OutputSuite) TestTruncation(c *gc.C) {
+	value := []string{"one", "two", "three", "four", "five"}
+	for i, test := range []struct {
+		args     []string
+		terminal bool
+		output   string
+		notice   string
+	}{{
+		terminal: true,
+		output:   "- one\n- two\n- three\n",
+		notice:   "... output truncated after 3 lines, use --no-truncate or --output <file> to see all of it\n",
+	}, {
+		args:     []string{"--max-lines", "4"},
+		terminal: true,
+		output:   "- one\n- two\n- three\n- four\n",
+		notice:   "... output truncated after 4 lines, use --no-truncate or --output <file> to see all of it\n",
+	}, {
+		args:     []string{"--max-lines", "5"},
+		terminal: true,
+		output:   "- one\n- two\n- three\n- four\n- five\n",
+	}, {
+		args:     []string{"--max-lines", "0"},
+		terminal: true,
+		output:   "- one\n- two\n- three\n- four\n- five\n",
+	}, {
+		args:     []string{"--max-output", "12"},
+		terminal: true,
+		output:   "- one\n- two\n",
+		notice:   "... output truncated after 12 bytes, use --no-truncate or --output <file> to see all of it\n",
+	}, {
+		args:     []string{"--max-output", "1K", "--max-lines", "2"},
+		terminal: true,
+		output:   "- one\n- two\n",
+		notice:   "... output truncated after 2 lines, use --no-truncate or --output <file> to see all of it\n",
+	}, {
+		args:     []string{"--max-output", "1K"},
+		terminal: true,
+		output:   "- one\n- two\n- three\n",
+		notice:   "... output truncated after 3 lines, use --no-truncate or --output <file> to see all of it\n",
+	}, {
+		args:     []string{"--max-lines", "0", "--max-output", "10"},
+		terminal: true,
+		output:   "- one\n- tw",
+		notice:   "... output truncated after 10 bytes, use --no-truncate or --output <file> to see all of it\n",
+	}, {
+		args:     []string{"--max-output", "12", "--no-truncate"},
+		terminal: true,
+		output:   "- one\n- two\n- three\n- four\n- five\n",
+	}, {
+		args:     []string{"--no-truncate"},
+		terminal: true,
+		output:   "- one\n- two\n- three\n- four\n- five\n",
+	}, {
+		output: "- one\n- two\n- three\n- four\n- five\n",
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		ctx := cmdtesting.Context(c)
+		if test.terminal {
+			ctx = cmdtesting.TerminalContext(c, 80, 24, false)
+		}
+		args := append([]string{"--format", "yaml"}, test.args...)
+		result := cmd.Main(&truncatedCommand{OutputCommand{value: value}}, ctx, args)
+		c.Check(result, gc.Equals, 0)
+		c.Check(cmdtesting.Stdout(ctx), gc.Equals, test.output)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.notice)
+	}
+}
+
+func (s *OutputSuite) TestProcessors(c *gc.C) {
+	command := &OutputCommand{value: map[string]string{"password": "hunter2", "user": "admin"}}
+	var infos []cmd.OutputInfo
+	command.out.AddProcessor(func(info cmd.OutputInfo, output []byte) ([]byte, error) {
+		infos = append(infos, info)
+		return bytes.Replace(output, []byte("hunter2"), []byte("********"), -1), nil
+	})
+	command.out.AddProcessor(func(info cmd.OutputInfo, output []byte) ([]byte, error) {
+		return bytes.ToUpper(output), nil
+	})
+	ctx := cmdtesting.Context(c)
+	result := cmd.Main(command, ctx, []string{"--format", "json"})
+	c.Assert(result, gc.Equals, 0)
+	c.Assert(bufferString(ctx.Stdout), gc.Equals, `{"PASSWORD":"********","USER":"ADMIN"}`+"\n")
+	c.Assert(infos, gc.DeepEquals, []cmd.OutputInfo{{Format: "json"}})
+
+	infos = nil
+	ctx = cmdtesting.TerminalContext(c, 80, 24, false)
+	command.value = overrideFormatter{formatter: cmd.FormatSmart, value: "hunter2"}
+	result = cmd.Main(command, ctx, nil)
+	c.Assert(result, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "********\n")
+	c.Assert(infos, gc.DeepEquals, []cmd.OutputInfo{{Terminal: true}})
+}
+
+func (s *OutputSuite) TestProcessorError(c *gc.C) {
+	command := &OutputCommand{value: "secret"}
+	command.out.AddProcessor(func(cmd.OutputInfo, []byte) ([]byte, error) {
+		return nil, errors.New("cannot redact output")
+	})
+	ctx := cmdtesting.Context(c)
+	result := cmd.Main(command, ctx, nil)
+	c.Assert(result, gc.Equals, 1)
+	c.Assert(bufferString(ctx.Stdout), gc.Equals, "")
+	c.Assert(bufferString(ctx.Stderr), gc.Equals, "ERROR cannot redact output\n")
+}
+
+func (s *OutputSuite) TestFormatJsonIndent(c *gc.C) {
+	value := map[string]interface{}{"name": "mysql/0", "ports": []int{3306}}
+	for i, test := range []struct {
+		format string
+		output string
+		err    string
+	}{{
+		format: "json",
+		output: `{"name":"mysql/0","ports":[3306]}` + "\n",
+	}, {
+		format: "json=pretty",
+		output: "{\n  \"name\": \"mysql/0\",\n  \"ports\": [\n    3306\n  ]\n}\n",
+	}, {
+		format: "json=4",
+		output: "{\n    \"name\": \"mysql/0\",\n    \"ports\": [\n        3306\n    ]\n}\n",
+	}, {
+		format: "json=0",
+		output: `{"name":"mysql/0","ports":[3306]}` + "\n",
+	}, {
+		format: "json=ugly",
+		err:    `ERROR invalid value "json=ugly" for flag --format: invalid json format: expected "pretty" or an indent of 0 to 8 spaces, got "ugly"` + "\n",
+	}} {
+		c.Logf("test %d: %s", i, test.format)
+		ctx := cmdtesting.Context(c)
+		result := cmd.Main(&OutputCommand{value: value}, ctx, []string{"--format", test.format})
+		c.Check(bufferString(ctx.Stdout), gc.Equals, test.output)
+		c.Check(bufferString(ctx.Stderr), gc.Equals, test.err)
+		if test.err == "" {
+			c.Check(result, gc.Equals, 0)
+		} else {
+			c.Check(result, gc.Equals, 2)
+		}
+	}
+
+	var buf bytes.Buffer
+	c.Assert(cmd.FormatJsonIndent(&buf, []string{"a"}), gc.IsNil)
+	c.Assert(buf.String(), gc.Equals, "[\n  \"a\"\n]\n")
+}
+
+// ownFormatCommand has a json formatter of its own.
+type ownFormatCommand struct {
+	OutputCommand
+}
+
+func (c *ownFormatCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "json", map[string]cmd.Formatter{
+		"json": func(w io.Writer, value interface{}) error {
+			_, err := fmt.Fprintf(w, "own %v\n", value)
+			return err
+		},
+	})
+}
+
+func (s *OutputSuite) TestFormatArgumentUsesCommandFormatters(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	result := cmd.Main(&ownFormatCommand{OutputCommand{value: 1}}, ctx, []string{"--format", "json=pretty"})
+	c.Check(result, gc.Equals, 2)
+	c.Check(bufferString(ctx.Stderr), gc.Equals, `ERROR invalid value "json=pretty" for flag --format: format "json" does not take an argument`+"\n")
+
+	ctx = cmdtesting.Context(c)
+	result = cmd.Main(&ownFormatCommand{OutputCommand{value: 1}}, ctx, nil)
+	c.Check(result, gc.Equals, 0)
+	c.Check(bufferString(ctx.Stdout), gc.Equals, "own 1\n")
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/juju/errors"
+)
+
+// ParseMode controls how strictly flag values are parsed by ParseBool,
+// ParseInt, ParseFloat and ParseDuration.
+type ParseMode int
+
+const (
+	// Lenient accepts the common spellings users type regardless of locale:
+	// yes/no, on/off and y/n for booleans, and thousands separators for
+	// numbers. A full stop is always a decimal point, never a separator.
+	Lenient ParseMode = iota
+
+	// Strict accepts only the formats understood by the strconv package.
+	Strict
+)
+
+// ParseBool parses s as a boolean flag value. In Lenient mode the
+// comparison is case insensitive, surrounding whitespace is ignored, and
+// "yes", "y", "on", "no", "n" and "off" are accepted in addition to the
+// values accepted in Strict mode.
+func ParseBool(s string, mode ParseMode) (bool, error) {
+	if mode == Strict {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return false, errors.Errorf("invalid boolean value %q", s)
+		}
+		return b, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, errors.Errorf("invalid boolean value %q (expected yes/no, true/false or 1/0)", s)
+}
+
+// thousandsSeparators holds the digit grouping characters accepted in
+// Lenient mode: comma, underscore, apostrophe, space, no-break space and
+// narrow no-break space. A full stop is deliberately absent: it is always
+// read as a decimal point, so "1.500" means the same to every parser.
+const thousandsSeparators = ",_' \u00a0\u202f"
+
+// ParseInt parses s as a base 10 integer flag value. In Lenient mode
+// surrounding whitespace is ignored and digits may be grouped in threes by
+// a single kind of thousands separator, so "1,000,000", "1'000'000" and
+// "1 000 000" are all one million. A full stop is a decimal point, so
+// "1.500" is rejected rather than misread as fifteen hundred.
+func ParseInt(s string, mode ParseMode) (int64, error) {
+	if mode == Strict {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, errors.Errorf("invalid integer value %q", s)
+		}
+		return n, nil
+	}
+	digits, ok := ungroupDigits(strings.TrimSpace(s))
+	if !ok {
+		return 0, errors.Errorf("invalid integer value %q", s)
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid integer value %q", s)
+	}
+	return n, nil
+}
+
+// ParseFloat parses s as a decimal flag value. In Lenient mode
+// surrounding whitespace is ignored and the digits before the decimal point
+// may be grouped as for ParseInt, and a full stop is always the
+// decimal point, so "1,234.5" and "1 234.5" are both accepted. A decimal
+// comma is rejected rather than misread.
+func ParseFloat(s string, mode ParseMode) (float64, error) {
+	number := s
+	if mode == Lenient {
+		number = ungroupDecimal(strings.TrimSpace(s))
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+// ParseDuration parses s as a duration flag value such as "30s" or
+// "1h30m". In Lenient mode surrounding whitespace is ignored, units may be
+// separated by spaces, and each number is read as for ParseFloat, so
+// "1h 30m" and "1,500ms" are both accepted.
+func ParseDuration(s string, mode ParseMode) (time.Duration, error) {
+	value := s
+	if mode == Lenient {
+		value = normaliseDuration(strings.TrimSpace(s))
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid duration %q (expected e.g. 30s, 5m or 1h30m)", s)
+	}
+	return d, nil
+}
+
+// normaliseDuration removes the spaces between units of s and the
+// thousands separators from each of its numbers.
+func normaliseDuration(s string) string {
+	var result strings.Builder
+	for s != "" {
+		i := strings.IndexFunc(s, unicode.IsLetter)
+		if i < 0 {
+			i = len(s)
+		}
+		result.WriteString(ungroupDecimal(strings.TrimSpace(s[:i])))
+		s = s[i:]
+		j := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+		if j < 0 {
+			j = len(s)
+		}
+		result.WriteString(s[:j])
+		s = s[j:]
+	}
+	return result.String()
+}
+
+// ungroupDecimal removes thousands separators from the digits before the
+// decimal point of s, returning s unchanged if they are not well formed.
+func ungroupDecimal(s string) string {
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, fraction = s[:i], s[i:]
+	}
+	if digits, ok := ungroupDigits(whole); ok {
+		return digits + fraction
+	}
+	return s
+}
+
+// ungroupDigits removes thousands separators from s, reporting false if
+// they are mixed or do not separate groups of three digits.
+func ungroupDigits(s string) (string, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	sep := rune(-1)
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			continue
+		}
+		if !strings.ContainsRune(thousandsSeparators, r) || (sep != -1 && r != sep) {
+			return "", false
+		}
+		sep = r
+	}
+	if sep == -1 {
+		return sign + s, true
+	}
+	groups := strings.Split(s, string(sep))
+	if len(groups[0]) < 1 || len(groups[0]) > 3 {
+		return "", false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return "", false
+		}
+	}
+	return sign + strings.Join(groups, ""), true
+}
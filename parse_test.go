@@ -0,0 +1,169 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"time"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+type ParseSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ParseSuite{})
+
+func (s *ParseSuite) TestParseBool(c *gc.C) {
+	for i, test := range []struct {
+		value  string
+		mode   cmd.ParseMode
+		expect bool
+		err    string
+	}{
+		{value: "true", expect: true},
+		{value: "Yes", expect: true},
+		{value: " on ", expect: true},
+		{value: "1", expect: true},
+		{value: "NO", expect: false},
+		{value: "off", expect: false},
+		{value: "0", expect: false},
+		{value: "maybe", err: `invalid boolean value "maybe" \(expected yes/no, true/false or 1/0\)`},
+		{value: "TRUE", mode: cmd.Strict, expect: true},
+		{value: "0", mode: cmd.Strict, expect: false},
+		{value: "yes", mode: cmd.Strict, err: `invalid boolean value "yes"`},
+	} {
+		c.Logf("test %d: %q", i, test.value)
+		b, err := cmd.ParseBool(test.value, test.mode)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(b, gc.Equals, test.expect)
+	}
+}
+
+func (s *ParseSuite) TestParseInt(c *gc.C) {
+	for i, test := range []struct {
+		value  string
+		mode   cmd.ParseMode
+		expect int64
+		err    string
+	}{
+		{value: "42", expect: 42},
+		{value: " -42 ", expect: -42},
+		{value: "1,000,000", expect: 1000000},
+		{value: "1'000'000", expect: 1000000},
+		{value: "1 000", expect: 1000},
+		{value: "1 000", expect: 1000},
+		{value: "12'345", expect: 12345},
+		{value: "+1_000", expect: 1000},
+		{value: "1.5", err: `invalid integer value "1.5"`},
+		{value: "1.500", err: `invalid integer value "1.500"`},
+		{value: "1.000.000", err: `invalid integer value "1.000.000"`},
+		{value: "1,000.000", err: `invalid integer value "1,000.000"`},
+		{value: "1000,000", err: `invalid integer value "1000,000"`},
+		{value: ",000", err: `invalid integer value ",000"`},
+		{value: "ten", err: `invalid integer value "ten"`},
+		{value: "-7", mode: cmd.Strict, expect: -7},
+		{value: "1,000", mode: cmd.Strict, err: `invalid integer value "1,000"`},
+	} {
+		c.Logf("test %d: %q", i, test.value)
+		n, err := cmd.ParseInt(test.value, test.mode)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(n, gc.Equals, test.expect)
+	}
+}
+
+func (s *ParseSuite) TestParseFloat(c *gc.C) {
+	for i, test := range []struct {
+		value  string
+		mode   cmd.ParseMode
+		expect float64
+		err    string
+	}{
+		{value: "1.5", expect: 1.5},
+		{value: " -0.25 ", expect: -0.25},
+		{value: "1,234.5", expect: 1234.5},
+		{value: "1 000 000", expect: 1000000},
+		{value: ".5", expect: 0.5},
+		{value: "1e3", expect: 1000},
+		{value: "1,5", err: `invalid number "1,5"`},
+		{value: "1.000.000", err: `invalid number "1.000.000"`},
+		{value: "half", err: `invalid number "half"`},
+		{value: "2.5", mode: cmd.Strict, expect: 2.5},
+		{value: "1,234.5", mode: cmd.Strict, err: `invalid number "1,234.5"`},
+		{value: " 1", mode: cmd.Strict, err: `invalid number " 1"`},
+	} {
+		c.Logf("test %d: %q", i, test.value)
+		f, err := cmd.ParseFloat(test.value, test.mode)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(f, gc.Equals, test.expect)
+	}
+}
+
+func (s *ParseSuite) TestParseDuration(c *gc.C) {
+	for i, test := range []struct {
+		value  string
+		mode   cmd.ParseMode
+		expect time.Duration
+		err    string
+	}{
+		{value: "30s", expect: 30 * time.Second},
+		{value: " 1h30m ", expect: 90 * time.Minute},
+		{value: "1h 30m", expect: 90 * time.Minute},
+		{value: "1,500ms", expect: 1500 * time.Millisecond},
+		{value: "1.5h", expect: 90 * time.Minute},
+		{value: "-2m", expect: -2 * time.Minute},
+		{value: "1.500s", expect: 1500 * time.Millisecond},
+		{value: "5 minutes", err: `invalid duration "5 minutes" \(expected e.g. 30s, 5m or 1h30m\)`},
+		{value: "1,5s", err: `invalid duration "1,5s" .*`},
+		{value: "1h30m", mode: cmd.Strict, expect: 90 * time.Minute},
+		{value: "1h 30m", mode: cmd.Strict, err: `invalid duration "1h 30m" .*`},
+		{value: "1,500ms", mode: cmd.Strict, err: `invalid duration "1,500ms" .*`},
+	} {
+		c.Logf("test %d: %q", i, test.value)
+		d, err := cmd.ParseDuration(test.value, test.mode)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, gc.IsNil)
+		c.Check(d, gc.Equals, test.expect)
+	}
+}
+
+func (s *ParseSuite) TestFullStopIsDecimalPoint(c *gc.C) {
+	// Every parser reads "1.500" as one and a half, so it is not a
+	// valid integer anywhere.
+	_, err := cmd.ParseInt("1.500", cmd.Lenient)
+	c.Check(err, gc.ErrorMatches, `invalid integer value "1.500"`)
+
+	f, err := cmd.ParseFloat("1.500", cmd.Lenient)
+	c.Check(err, gc.IsNil)
+	c.Check(f, gc.Equals, 1.5)
+
+	d, err := cmd.ParseDuration("1.500s", cmd.Lenient)
+	c.Check(err, gc.IsNil)
+	c.Check(d, gc.Equals, 1500*time.Millisecond)
+
+	var size uint64
+	c.Check(cmd.NewSizeValue(0, &size).Set("1.500K"), gc.IsNil)
+	c.Check(size, gc.Equals, uint64(1536))
+
+	var percent int
+	c.Check(cmd.NewPercentValue(0, &percent).Set("1.500"), gc.ErrorMatches, `invalid percentage "1.500" .*`)
+}
@@ -0,0 +1,316 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultKeepAliveInterval is the period of silence after which
+// WithKeepAlive reports that the command is still working, when no other
+// interval is given.
+const DefaultKeepAliveInterval = 30 * time.Second
+
+// WithKeepAlive returns a copy of ctx that reports progress on a long
+// running activity. Whenever interval (or DefaultKeepAliveInterval, if
+// interval is zero) passes without anything being written to the returned
+// context's Stdout or Stderr, a line such as
+//
+//	still working on deploying... (1m30s elapsed, 3m30s left)
+//
+// is written to Stderr, where the time left is shown when the context has a
+// deadline. Keep-alive lines are only written when Stderr is a terminal and
+// the command is neither quiet nor producing machine readable output, so
+// that scripts are not polluted by them. The returned function stops the
+// reports and must be called when the activity finishes.
+func (ctx *Context) WithKeepAlive(activity string, interval time.Duration) (*Context, func()) {
+	if interval <= 0 {
+		interval = DefaultKeepAliveInterval
+	}
+	if !isTerminal(ctx.Stderr) || ctx.quiet || ctx.serialisable {
+		return ctx, func() {}
+	}
+	clock := ctx.clock()
+	p := &keepAlive{
+		ctx:      ctx,
+		activity: activity,
+		interval: interval,
+		start:    clock.Now(),
+		done:     make(chan struct{}),
+	}
+	p.last = p.start
+	newCtx := *ctx
+	newCtx.Stdout = &activityWriter{Writer: ctx.Stdout, p: p}
+	newCtx.Stderr = &activityWriter{Writer: ctx.Stderr, p: p}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.loop()
+	}()
+	var once sync.Once
+	return &newCtx, func() {
+		once.Do(func() {
+			close(p.done)
+			wg.Wait()
+		})
+	}
+}
+
+// keepAlive tracks output produced during an activity.
+type keepAlive struct {
+	ctx      *Context
+	activity string
+	interval time.Duration
+	start    time.Time
+	done     chan struct{}
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// touch records that output has been produced.
+func (p *keepAlive) touch() {
+	now := p.ctx.clock().Now()
+	p.mu.Lock()
+	p.last = now
+	p.mu.Unlock()
+}
+
+// loop writes a keep-alive line each time the interval passes in
+// silence, until done is closed.
+func (p *keepAlive) loop() {
+	clock := p.ctx.clock()
+	for {
+		p.mu.Lock()
+		wait := p.last.Add(p.interval).Sub(clock.Now())
+		p.mu.Unlock()
+		select {
+		case <-clock.After(wait):
+		case <-p.done:
+			return
+		}
+		now := clock.Now()
+		p.mu.Lock()
+		if now.Sub(p.last) >= p.interval {
+			message := fmt.Sprintf("still working on %s... (%s)", p.activity, p.elapsed(now))
+			fmt.Fprintln(p.ctx.Stderr, message)
+			p.ctx.emit(Event{Kind: EventProgress, Message: message})
+			p.last = now
+		}
+		p.mu.Unlock()
+	}
+}
+
+// elapsed describes how long the activity has taken, and how long remains
+// before the context's deadline if it has one.
+func (p *keepAlive) elapsed(now time.Time) string {
+	s := fmt.Sprintf("%s elapsed", now.Sub(p.start).Round(time.Second))
+	if deadline, ok := p.ctx.Deadline(); ok {
+		if left := deadline.Sub(now); left > 0 {
+			s += fmt.Sprintf(", %s left", left.Round(time.Second))
+		} else {
+			s += ", deadline passed"
+		}
+	}
+	return s
+}
+
+// activityWriter records output written during an activity.
+type activityWriter struct {
+	io.Writer
+	p *keepAlive
+}
+
+// Write implements io.Writer.
+func (w *activityWriter) Write(b []byte) (int, error) {
+	w.p.touch()
+	return w.Writer.Write(b)
+}
+
+const (
+	// ProgressRefreshInterval is how often a Progress redraws its spinner
+	// or progress bar on a terminal.
+	ProgressRefreshInterval = 100 * time.Millisecond
+
+	// ProgressLogInterval is the least time between the lines that a
+	// Progress writes when Stderr is not a terminal.
+	ProgressLogInterval = 10 * time.Second
+)
+
+// progressBarWidth is the number of characters in a progress bar, between
+// the brackets.
+const progressBarWidth = 20
+
+var spinnerFrames = []rune{'|', '/', '-', '\\'}
+
+// Progress reports the progress of a long running activity, such as a
+// deploy or an upgrade. When Stderr is a terminal, a progress bar, or a
+// spinner if the total is not known, is drawn on it and redrawn as the
+// activity proceeds. Otherwise, a line such as
+//
+//	deploying: 45/100 (45%)
+//
+// is written at most once every ProgressLogInterval, so that logs are not
+// flooded. Nothing is drawn when the command is quiet or producing machine
+// readable output, although the lines are still logged.
+//
+// The methods of Progress may be called from any goroutine.
+type Progress struct {
+	ctx      *Context
+	activity string
+	terminal bool
+	start    time.Time
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+
+	mu      sync.Mutex
+	total   int64
+	current int64
+	frame   int
+	logged  time.Time
+}
+
+// NewProgress returns a Progress reporting on the given activity, which
+// is complete when total units of work are done. If total is zero or less,
+// a spinner is drawn in place of a progress bar. Done must be called when
+// the activity finishes.
+func (ctx *Context) NewProgress(activity string, total int64) *Progress {
+	clock := ctx.clock()
+	p := &Progress{
+		ctx:      ctx,
+		activity: activity,
+		terminal: isTerminal(ctx.Stderr) && supportsANSI(ctx.Stderr) && !ctx.quiet && !ctx.serialisable,
+		start:    clock.Now(),
+		done:     make(chan struct{}),
+		total:    total,
+	}
+	p.logged = p.start
+	if p.terminal {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.loop()
+		}()
+	}
+	return p
+}
+
+// SetTotal changes the number of units of work in the activity, for when
+// it only becomes known once the activity has started.
+func (p *Progress) SetTotal(total int64) {
+	p.mu.Lock()
+	p.total = total
+	p.mu.Unlock()
+}
+
+// Set records that current units of work are done.
+func (p *Progress) Set(current int64) {
+	p.mu.Lock()
+	p.current = current
+	p.mu.Unlock()
+	p.update()
+}
+
+// Add records that n more units of work are done.
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	p.current += n
+	p.mu.Unlock()
+	p.update()
+}
+
+// Done stops reporting progress. On a terminal, the progress bar or
+// spinner is replaced by a final line saying how long the activity took.
+func (p *Progress) Done() {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		message := fmt.Sprintf("%s: done (%s)", p.activity, p.ctx.clock().Now().Sub(p.start).Round(time.Second))
+		if p.terminal {
+			fmt.Fprintf(p.ctx.Stderr, "\r\x1b[K%s\n", message)
+		} else {
+			p.ctx.Infof("%s", message)
+		}
+		p.ctx.emit(Event{Kind: EventProgress, Message: message})
+	})
+}
+
+// update writes a progress line if Stderr is not a terminal and none has
+// been written for ProgressLogInterval.
+func (p *Progress) update() {
+	if p.terminal {
+		return
+	}
+	now := p.ctx.clock().Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now.Sub(p.logged) < ProgressLogInterval {
+		return
+	}
+	p.logged = now
+	message := fmt.Sprintf("%s: %s", p.activity, p.status())
+	p.ctx.Infof("%s", message)
+	p.ctx.emit(Event{Kind: EventProgress, Message: message})
+}
+
+// loop redraws the progress on the terminal every ProgressRefreshInterval,
+// until done is closed.
+func (p *Progress) loop() {
+	clock := p.ctx.clock()
+	for {
+		p.mu.Lock()
+		fmt.Fprintf(p.ctx.Stderr, "\r\x1b[K%s", p.render())
+		p.frame++
+		p.mu.Unlock()
+		select {
+		case <-clock.After(ProgressRefreshInterval):
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// render returns the progress bar, or the spinner if the total is not
+// known, for the current state.
+func (p *Progress) render() string {
+	if p.total <= 0 {
+		s := fmt.Sprintf("%c %s", spinnerFrames[p.frame%len(spinnerFrames)], p.activity)
+		if p.current > 0 {
+			s += fmt.Sprintf(" (%d)", p.current)
+		}
+		return s
+	}
+	filled := int(p.fraction() * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	return fmt.Sprintf("%s [%s] %s", p.activity, bar, p.status())
+}
+
+// status describes how much of the activity is done.
+func (p *Progress) status() string {
+	if p.total <= 0 {
+		return fmt.Sprintf("%d done", p.current)
+	}
+	return fmt.Sprintf("%d/%d (%d%%)", p.current, p.total, int(p.fraction()*100))
+}
+
+// fraction returns the fraction of the activity that is done, between 0
+// and 1.
+func (p *Progress) fraction() float64 {
+	f := float64(p.current) / float64(p.total)
+	if f < 0 {
+		return 0
+	} else if f > 1 {
+		return 1
+	}
+	return f
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type KeepAliveSuite struct {
+	testing.IsolationSuite
+	clock *testclock.Clock
+}
+
+var _ = gc.Suite(&KeepAliveSuite{})
+
+func (s *KeepAliveSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = testclock.NewClock(time.Now())
+	s.PatchValue(cmd.IsTerminal, func(io.Writer) bool { return true })
+}
+
+func (s *KeepAliveSuite) advance(c *gc.C, d time.Duration) {
+	c.Assert(s.clock.WaitAdvance(d, testing.LongWait, 1), gc.IsNil)
+}
+
+func (s *KeepAliveSuite) TestKeepAlive(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	kctx, stop := ctx.WithKeepAlive("deploying", 10*time.Second)
+	defer stop()
+
+	s.advance(c, 10*time.Second)
+	s.advance(c, 5*time.Second)
+	fmt.Fprintln(kctx.Stdout, "output")
+	s.advance(c, 5*time.Second)
+	s.advance(c, 5*time.Second)
+	s.advance(c, 0)
+	stop()
+
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "output\n")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `
+still working on deploying... (10s elapsed)
+still working on deploying... (25s elapsed)
+`[1:])
+	c.Assert(cmdtesting.Events(ctx), gc.DeepEquals, []cmd.Event{
+		{Kind: cmd.EventProgress, Message: "still working on deploying... (10s elapsed)"},
+		{Kind: cmd.EventProgress, Message: "still working on deploying... (25s elapsed)"},
+	})
+}
+
+func (s *KeepAliveSuite) TestDeadline(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	dctx, cancel := context.WithDeadline(ctx, s.clock.Now().Add(time.Hour))
+	defer cancel()
+	_, stop := ctx.With(dctx).WithKeepAlive("deploying", 10*time.Second)
+	defer stop()
+
+	s.advance(c, 10*time.Second)
+	s.advance(c, 0)
+	stop()
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "still working on deploying... (10s elapsed, 59m50s left)\n")
+}
+
+func (s *KeepAliveSuite) TestNotTerminal(c *gc.C) {
+	s.PatchValue(cmd.IsTerminal, func(io.Writer) bool { return false })
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	kctx, stop := ctx.WithKeepAlive("deploying", 0)
+	defer stop()
+	c.Assert(kctx, gc.Equals, ctx)
+}
+
+func (s *KeepAliveSuite) TestQuiet(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	log := &cmd.Log{Quiet: true}
+	c.Assert(log.Start(ctx), gc.IsNil)
+	kctx, stop := ctx.WithKeepAlive("deploying", 0)
+	defer stop()
+	c.Assert(kctx, gc.Equals, ctx)
+}
+
+type ProgressSuite struct {
+	testing.IsolationSuite
+	clock *testclock.Clock
+}
+
+var _ = gc.Suite(&ProgressSuite{})
+
+func (s *ProgressSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.clock = testclock.NewClock(time.Now())
+}
+
+// nextFrame waits until the current frame has been drawn, and then advances
+// the clock by d, which draws the next frame if d is the refresh interval.
+func (s *ProgressSuite) nextFrame(c *gc.C, d time.Duration) {
+	c.Assert(s.clock.WaitAdvance(d, testing.LongWait, 1), gc.IsNil)
+}
+
+func (s *ProgressSuite) TestProgressBar(c *gc.C) {
+	s.PatchValue(cmd.IsTerminal, func(io.Writer) bool { return true })
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	p := ctx.NewProgress("deploying", 100)
+	s.nextFrame(c, 0)
+	p.Set(25)
+	p.Add(25)
+	s.nextFrame(c, cmd.ProgressRefreshInterval)
+	s.nextFrame(c, 0)
+	p.Done()
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, ""+
+		"\r\x1b[Kdeploying [                    ] 0/100 (0%)"+
+		"\r\x1b[Kdeploying [==========          ] 50/100 (50%)"+
+		"\r\x1b[Kdeploying: done (0s)\n")
+	c.Assert(cmdtesting.Events(ctx), gc.DeepEquals, []cmd.Event{
+		{Kind: cmd.EventProgress, Message: "deploying: done (0s)"},
+	})
+}
+
+func (s *ProgressSuite) TestSpinner(c *gc.C) {
+	s.PatchValue(cmd.IsTerminal, func(io.Writer) bool { return true })
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	p := ctx.NewProgress("waiting", 0)
+	s.nextFrame(c, cmd.ProgressRefreshInterval)
+	s.nextFrame(c, 0)
+	p.Add(3)
+	s.nextFrame(c, cmd.ProgressRefreshInterval)
+	s.nextFrame(c, 0)
+	p.Done()
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, ""+
+		"\r\x1b[K| waiting"+
+		"\r\x1b[K/ waiting"+
+		"\r\x1b[K- waiting (3)"+
+		"\r\x1b[Kwaiting: done (0s)\n")
+}
+
+func (s *ProgressSuite) TestNotTerminal(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	p := ctx.NewProgress("upgrading", 10)
+	p.Set(1)
+	s.clock.Advance(cmd.ProgressLogInterval)
+	p.Set(4)
+	s.clock.Advance(time.Second)
+	p.Set(5)
+	s.clock.Advance(cmd.ProgressLogInterval)
+	p.Set(12)
+	p.Done()
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, `
+upgrading: 4/10 (40%)
+upgrading: 12/10 (100%)
+upgrading: done (21s)
+`[1:])
+	c.Assert(cmdtesting.Events(ctx), gc.HasLen, 3)
+}
+
+func (s *ProgressSuite) TestQuiet(c *gc.C) {
+	s.PatchValue(cmd.IsTerminal, func(io.Writer) bool { return true })
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = s.clock
+	c.Assert((&cmd.Log{Quiet: true}).Start(ctx), gc.IsNil)
+	p := ctx.NewProgress("deploying", 10)
+	p.Set(10)
+	p.Done()
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
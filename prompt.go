@@ -0,0 +1,135 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// Prompt writes msg to Stderr and returns the line the user enters on
+// Stdin, without the line ending. When Stdin is not a terminal, the next
+// line is read from it just the same, so that scripts may pipe answers to
+// commands. It returns io.EOF if Stdin is closed before anything is read.
+func (ctx *Context) Prompt(msg string) (string, error) {
+	fmt.Fprint(ctx.Stderr, msg)
+	line, err := readLine(ctx.Stdin)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	ctx.emit(Event{Kind: EventPrompt, Message: msg, Response: line})
+	return line, nil
+}
+
+// PromptPassword works like Prompt, but the terminal does not echo what
+// the user types. The response is registered with RegisterRedactedValue,
+// so that it is not revealed by errors or logs. If the context is
+// cancelled, or the process is interrupted, while the user is typing, the
+// terminal echo is restored and an error is returned.
+func (ctx *Context) PromptPassword(msg string) (string, error) {
+	fmt.Fprint(ctx.Stderr, msg)
+	read := readLine
+	if fd, ok := terminalFd(ctx.Stdin); ok && isTerminalFd(fd) {
+		state, err := disableEcho(fd)
+		if err != nil {
+			return "", errors.Annotate(err, "disabling terminal echo")
+		}
+		defer func() {
+			_ = restore(fd, state)
+			// The newline typed by the user was not echoed either.
+			fmt.Fprintln(ctx.Stderr)
+		}()
+		read = func(r io.Reader) (string, error) {
+			return readLineInterruptibly(ctx, r)
+		}
+	}
+	password, err := read(ctx.Stdin)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	RegisterRedactedValue(password)
+	ctx.emit(Event{Kind: EventPrompt, Message: msg, Response: Redacted})
+	return password, nil
+}
+
+// Confirm asks the user the yes or no question in msg, followed by the
+// choices, and returns the answer. An empty response chooses defaultYes.
+// The question is asked again until the response is understood.
+func (ctx *Context) Confirm(msg string, defaultYes bool) (bool, error) {
+	choices := "[y/N]"
+	if defaultYes {
+		choices = "[Y/n]"
+	}
+	for {
+		response, err := ctx.Prompt(fmt.Sprintf("%s %s: ", msg, choices))
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "":
+			return defaultYes, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintf(ctx.Stderr, "Invalid response %q, please answer yes or no.\n", response)
+	}
+}
+
+// readLineInterruptibly works like readLine, but returns early if ctx is
+// cancelled or the process receives one of ShutdownSignals, so that the
+// caller can restore the terminal instead of leaving it without echo.
+func readLineInterruptibly(ctx *Context, r io.Reader) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, ShutdownSignals...)
+	defer signal.Stop(signals)
+	read := make(chan result, 1)
+	go func() {
+		line, err := readLine(r)
+		read <- result{line: line, err: err}
+	}()
+	select {
+	case result := <-read:
+		return result.line, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case sig := <-signals:
+		return "", errors.Errorf("interrupted by %v", sig)
+	}
+}
+
+// readLine reads a line from r, without the line ending. It reads a byte
+// at a time, so that nothing after the line is consumed from r.
+func readLine(r io.Reader) (string, error) {
+	if r == nil {
+		return "", io.EOF
+	}
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
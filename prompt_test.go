@@ -0,0 +1,91 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type PromptSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&PromptSuite{})
+
+func (s *PromptSuite) context(c *gc.C, input string) *cmd.Context {
+	ctx := cmdtesting.Context(c)
+	ctx.Stdin = bytes.NewBufferString(input)
+	return ctx
+}
+
+func (s *PromptSuite) TestPrompt(c *gc.C) {
+	ctx := s.context(c, "mysql\r\nwordpress")
+	name, err := ctx.Prompt("Application: ")
+	c.Assert(err, gc.IsNil)
+	c.Assert(name, gc.Equals, "mysql")
+	name, err = ctx.Prompt("Application: ")
+	c.Assert(err, gc.IsNil)
+	c.Assert(name, gc.Equals, "wordpress")
+	_, err = ctx.Prompt("Application: ")
+	c.Assert(errors.Cause(err), gc.Equals, io.EOF)
+
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "Application: Application: Application: ")
+	c.Assert(cmdtesting.Events(ctx, cmd.EventPrompt), gc.DeepEquals, []cmd.Event{
+		{Kind: cmd.EventPrompt, Message: "Application: ", Response: "mysql"},
+		{Kind: cmd.EventPrompt, Message: "Application: ", Response: "wordpress"},
+	})
+}
+
+func (s *PromptSuite) TestPromptPassword(c *gc.C) {
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+	ctx := s.context(c, "s3cret\n")
+	password, err := ctx.PromptPassword("Password: ")
+	c.Assert(err, gc.IsNil)
+	c.Assert(password, gc.Equals, "s3cret")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "Password: ")
+	c.Assert(cmdtesting.Events(ctx, cmd.EventPrompt), gc.DeepEquals, []cmd.Event{
+		{Kind: cmd.EventPrompt, Message: "Password: ", Response: cmd.Redacted},
+	})
+	c.Assert(cmd.Redact("login failed for s3cret"), gc.Equals, "login failed for "+cmd.Redacted)
+}
+
+func (s *PromptSuite) TestConfirm(c *gc.C) {
+	for i, test := range []struct {
+		input      string
+		defaultYes bool
+		expected   bool
+		stderr     string
+	}{
+		{input: "y\n", expected: true, stderr: "Continue? [y/N]: "},
+		{input: "YES\n", expected: true, stderr: "Continue? [y/N]: "},
+		{input: "n\n", defaultYes: true, expected: false, stderr: "Continue? [Y/n]: "},
+		{input: "\n", defaultYes: true, expected: true, stderr: "Continue? [Y/n]: "},
+		{input: "\n", expected: false, stderr: "Continue? [y/N]: "},
+		{
+			input:    "maybe\nno\n",
+			expected: false,
+			stderr:   "Continue? [y/N]: Invalid response \"maybe\", please answer yes or no.\nContinue? [y/N]: ",
+		},
+	} {
+		c.Logf("test %d", i)
+		ctx := s.context(c, test.input)
+		answer, err := ctx.Confirm("Continue?", test.defaultYes)
+		c.Check(err, gc.IsNil)
+		c.Check(answer, gc.Equals, test.expected)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+	}
+}
+
+func (s *PromptSuite) TestConfirmEOF(c *gc.C) {
+	_, err := s.context(c, "").Confirm("Continue?", true)
+	c.Assert(errors.Cause(err), gc.Equals, io.EOF)
+}
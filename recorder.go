@@ -0,0 +1,76 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+// Recorder receives telemetry events from a SuperCommand, allowing CLI
+// owners to measure how the command line is used. Recorder methods are
+// called inline, so implementations should return quickly.
+type Recorder interface {
+	// RecordHelp is called when help is requested, or when the user is
+	// shown an unrecognized command or usage error.
+	RecordHelp(event HelpEvent)
+}
+
+// HelpEventKind describes why a HelpEvent was recorded.
+type HelpEventKind string
+
+const (
+	// HelpRequested is recorded when help is explicitly requested, either
+	// with the help command or the -h/--help flags.
+	HelpRequested HelpEventKind = "help-requested"
+
+	// HelpUnrecognizedCommand is recorded when the requested subcommand
+	// does not exist.
+	HelpUnrecognizedCommand HelpEventKind = "unrecognized-command"
+
+	// HelpUsageError is recorded when a subcommand's flags or arguments
+	// are invalid.
+	HelpUsageError HelpEventKind = "usage-error"
+)
+
+// HelpEvent holds the details of a help or discoverability event.
+type HelpEvent struct {
+	// Kind describes why the event was recorded.
+	Kind HelpEventKind
+
+	// Command is the full name of the supercommand, followed by the
+	// subcommand or topic that was requested, if any.
+	Command string
+
+	// Args holds the arguments that followed the requested subcommand.
+	Args []string
+
+	// Suggestions holds the names of commands that are close to an
+	// unrecognized command.
+	Suggestions []string
+
+	// Err holds the usage error, if any.
+	Err error
+}
+
+// recordHelp sends the event to the supercommand's recorder, if it has one.
+// Any registered secrets are redacted from the event's arguments.
+func (c *SuperCommand) recordHelp(event HelpEvent) {
+	if c.recorder == nil {
+		return
+	}
+	event.Command = Redact(event.Command)
+	event.Args = redactAll(event.Args)
+	c.recorder.RecordHelp(event)
+}
+
+// commandPath returns the full name of the supercommand followed by the
+// given names.
+func (c *SuperCommand) commandPath(names ...string) string {
+	path := c.Name
+	if c.usagePrefix != "" && c.usagePrefix != c.Name {
+		path = c.usagePrefix + " " + path
+	}
+	for _, name := range names {
+		if name != "" {
+			path += " " + name
+		}
+	}
+	return path
+}
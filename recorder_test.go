@@ -0,0 +1,113 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type RecorderSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&RecorderSuite{})
+
+type fakeRecorder struct {
+	helpEvents []cmd.HelpEvent
+}
+
+func (r *fakeRecorder) RecordHelp(event cmd.HelpEvent) {
+	r.helpEvents = append(r.helpEvents, event)
+}
+
+func (s *RecorderSuite) newSuperCommand(recorder cmd.Recorder) *cmd.SuperCommand {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:        "jujutest",
+		UsagePrefix: "juju",
+		Recorder:    recorder,
+	})
+	sc.Register(&TestCommand{Name: "defenestrate"})
+	return sc
+}
+
+func (s *RecorderSuite) TestRecordUnrecognizedCommand(c *gc.C) {
+	recorder := &fakeRecorder{}
+	code := cmd.Main(s.newSuperCommand(recorder), cmdtesting.Context(c), []string{"defenestrat", "foo"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(recorder.helpEvents, gc.DeepEquals, []cmd.HelpEvent{{
+		Kind:        cmd.HelpUnrecognizedCommand,
+		Command:     "juju jujutest defenestrat",
+		Args:        []string{"foo"},
+		Suggestions: []string{"defenestrate"},
+	}})
+}
+
+func (s *RecorderSuite) TestRecordUsageError(c *gc.C) {
+	recorder := &fakeRecorder{}
+	code := cmd.Main(s.newSuperCommand(recorder), cmdtesting.Context(c), []string{"defenestrate", "foo"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(recorder.helpEvents, gc.HasLen, 1)
+	event := recorder.helpEvents[0]
+	c.Check(event.Kind, gc.Equals, cmd.HelpUsageError)
+	c.Check(event.Command, gc.Equals, "juju jujutest defenestrate")
+	c.Check(event.Args, gc.DeepEquals, []string{"foo"})
+	c.Check(event.Err, gc.ErrorMatches, `unrecognized args: \["foo"\]`)
+
+	recorder.helpEvents = nil
+	code = cmd.Main(s.newSuperCommand(recorder), cmdtesting.Context(c), []string{"defenestrate", "--bad-flag"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(recorder.helpEvents, gc.HasLen, 1)
+	c.Check(recorder.helpEvents[0].Kind, gc.Equals, cmd.HelpUsageError)
+	c.Check(recorder.helpEvents[0].Err, gc.ErrorMatches, "flag provided but not defined: --bad-flag")
+}
+
+func (s *RecorderSuite) TestRecordHelpRequested(c *gc.C) {
+	for _, args := range [][]string{
+		{"help", "defenestrate"},
+		{"defenestrate", "--help"},
+	} {
+		recorder := &fakeRecorder{}
+		code := cmd.Main(s.newSuperCommand(recorder), cmdtesting.Context(c), args)
+		c.Assert(code, gc.Equals, 0)
+		c.Assert(recorder.helpEvents, gc.DeepEquals, []cmd.HelpEvent{{
+			Kind:    cmd.HelpRequested,
+			Command: "juju jujutest defenestrate",
+		}})
+	}
+}
+
+func (s *RecorderSuite) TestNoRecorder(c *gc.C) {
+	code := cmd.Main(s.newSuperCommand(nil), cmdtesting.Context(c), []string{"unknown"})
+	c.Assert(code, gc.Equals, 2)
+}
+
+func (s *RecorderSuite) TestRecordNested(c *gc.C) {
+	recorder := &fakeRecorder{}
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", UsagePrefix: "juju"})
+	storage.Register(&TestCommand{Name: "list"})
+	storage.Register(&TestCommand{Name: "lost", Aliases: []string{"lust"}})
+	storage.Register(&markedCommand{TestCommand: TestCommand{Name: "lint"}, hidden: true})
+	sc := s.newSuperCommand(recorder)
+	sc.Register(storage)
+
+	code := cmd.Main(sc, cmdtesting.Context(c), []string{"storage", "lisx"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(recorder.helpEvents, gc.DeepEquals, []cmd.HelpEvent{{
+		Kind:        cmd.HelpUnrecognizedCommand,
+		Command:     "juju storage lisx",
+		Args:        []string{},
+		Suggestions: []string{"list", "lost", "lust"},
+	}})
+
+	recorder.helpEvents = nil
+	code = cmd.Main(sc, cmdtesting.Context(c), []string{"storage", "list", "foo"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(recorder.helpEvents, gc.HasLen, 1)
+	c.Check(recorder.helpEvents[0].Kind, gc.Equals, cmd.HelpUsageError)
+	c.Check(recorder.helpEvents[0].Command, gc.Equals, "juju storage list")
+}
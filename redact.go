@@ -0,0 +1,80 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted is the text that replaces redacted values.
+const Redacted = "<redacted>"
+
+// redactions holds the values and patterns that are masked by Redact.
+var redactions = struct {
+	mu       sync.RWMutex
+	values   []string
+	patterns []*regexp.Regexp
+}{}
+
+// RegisterRedactedValue registers a secret value that will be masked
+// wherever the framework writes or records text, e.g. errors written by
+// WriteError and arguments written to the debug log. Empty values are
+// ignored.
+func RegisterRedactedValue(value string) {
+	if value == "" {
+		return
+	}
+	redactions.mu.Lock()
+	defer redactions.mu.Unlock()
+	for _, v := range redactions.values {
+		if v == value {
+			return
+		}
+	}
+	redactions.values = append(redactions.values, value)
+}
+
+// RegisterRedactionPattern registers a pattern whose matches will be masked
+// wherever the framework writes or records text.
+func RegisterRedactionPattern(pattern *regexp.Regexp) {
+	redactions.mu.Lock()
+	defer redactions.mu.Unlock()
+	redactions.patterns = append(redactions.patterns, pattern)
+}
+
+// ResetRedactions removes all registered values and patterns.
+func ResetRedactions() {
+	redactions.mu.Lock()
+	defer redactions.mu.Unlock()
+	redactions.values = nil
+	redactions.patterns = nil
+}
+
+// Redact returns s with all registered secret values and patterns
+// replaced by Redacted.
+func Redact(s string) string {
+	redactions.mu.RLock()
+	defer redactions.mu.RUnlock()
+	for _, value := range redactions.values {
+		s = strings.ReplaceAll(s, value, Redacted)
+	}
+	for _, pattern := range redactions.patterns {
+		s = pattern.ReplaceAllLiteralString(s, Redacted)
+	}
+	return s
+}
+
+// redactAll returns a copy of args with each one redacted.
+func redactAll(args []string) []string {
+	if args == nil {
+		return nil
+	}
+	result := make([]string, len(args))
+	for i, arg := range args {
+		result[i] = Redact(arg)
+	}
+	return result
+}
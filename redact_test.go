@@ -0,0 +1,57 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type RedactSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&RedactSuite{})
+
+func (s *RedactSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+}
+
+func (s *RedactSuite) TestRedact(c *gc.C) {
+	c.Assert(cmd.Redact("password is hunter2"), gc.Equals, "password is hunter2")
+
+	cmd.RegisterRedactedValue("hunter2")
+	cmd.RegisterRedactedValue("")
+	cmd.RegisterRedactionPattern(regexp.MustCompile(`token=\S+`))
+	c.Assert(cmd.Redact("password is hunter2, token=abc123 ok"), gc.Equals, "password is <redacted>, <redacted> ok")
+	c.Assert(cmd.Redact("nothing to see"), gc.Equals, "nothing to see")
+
+	cmd.ResetRedactions()
+	c.Assert(cmd.Redact("password is hunter2"), gc.Equals, "password is hunter2")
+}
+
+func (s *RedactSuite) TestWriteErrorRedacts(c *gc.C) {
+	cmd.RegisterRedactedValue("hunter2")
+	var buf bytes.Buffer
+	cmd.WriteError(&buf, errors.New(`login with "hunter2" failed`))
+	c.Assert(buf.String(), gc.Equals, "ERROR login with \"<redacted>\" failed\n")
+}
+
+func (s *RedactSuite) TestRecordedArgsRedacted(c *gc.C) {
+	cmd.RegisterRedactedValue("hunter2")
+	recorder := &fakeRecorder{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", Recorder: recorder})
+	code := cmd.Main(sc, cmdtesting.Context(c), []string{"login", "--password", "hunter2"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(recorder.helpEvents, gc.HasLen, 1)
+	c.Assert(recorder.helpEvents[0].Args, gc.DeepEquals, []string{"--password", "<redacted>"})
+}
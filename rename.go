@@ -0,0 +1,220 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// RegisterRenames registers each old command path in renames as a
+// deprecated alias of its new path, e.g.
+//
+//	err := super.RegisterRenames(map[string]string{
+//		"add-relation":   "integrate",
+//		"show-config":    "model config",
+//		"model get":      "model show",
+//	}, "4.0")
+//
+// Paths are space separated command names below super. The new path of a
+// command must be below the supercommand that its old path was in, and both
+// must already be registered as far as the parent of the old path, and
+// the old name must not already be in use. If any rename does not meet
+// these requirements, an error is returned and none of them are
+// registered.
+//
+// Running an old path warns that it is deprecated, naming its replacement
+// and the version it is removed in, and its help starts with the same
+// notice. The documentation of the new path lists the old paths. Once the
+// version of super reaches removedIn, the aliases are no longer
+// registered. If removedIn is empty, they are never removed.
+func (c *SuperCommand) RegisterRenames(renames map[string]string, removedIn string) error {
+	olds := make([]string, 0, len(renames))
+	for old := range renames {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+	type oldName struct {
+		parent *SuperCommand
+		name   string
+	}
+	seen := make(map[oldName]bool)
+	resolved := make([]rename, 0, len(olds))
+	for _, old := range olds {
+		r, err := c.resolveRename(strings.Fields(old), strings.Fields(renames[old]), removedIn)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		key := oldName{parent: r.parent, name: r.name}
+		if seen[key] {
+			return errors.Errorf("cannot rename %q more than once", old)
+		}
+		seen[key] = true
+		resolved = append(resolved, r)
+	}
+	for _, r := range resolved {
+		r.register()
+	}
+	return nil
+}
+
+// rename holds a renamed command, resolved by resolveRename.
+type rename struct {
+	// parent is the supercommand that the old name is registered in.
+	parent *SuperCommand
+	name   string
+
+	// target is the command with the new name, and alias is its path
+	// below parent.
+	target commandReference
+	alias  string
+	check  *renameCheck
+}
+
+// resolveRename finds the supercommand of oldPath and the command named by
+// newPath, returning an error if they cannot be registered as a rename.
+func (c *SuperCommand) resolveRename(oldPath, newPath []string, removedIn string) (rename, error) {
+	if len(oldPath) == 0 || len(newPath) == 0 {
+		return rename{}, errors.Errorf("empty command path renaming %q to %q", strings.Join(oldPath, " "), strings.Join(newPath, " "))
+	}
+	check := &renameCheck{
+		replacement: strings.Join(newPath, " "),
+		removedIn:   removedIn,
+		version:     c.version,
+	}
+	parent := c
+	for i, name := range oldPath[:len(oldPath)-1] {
+		if len(newPath) < 2 || newPath[0] != name {
+			return rename{}, errors.Errorf("cannot rename %q to %q: %q is not below %q",
+				strings.Join(oldPath, " "), check.replacement, check.replacement, strings.Join(oldPath[:i+1], " "))
+		}
+		var err error
+		if parent, err = parent.subSuperCommand(name); err != nil {
+			return rename{}, errors.Trace(err)
+		}
+		newPath = newPath[1:]
+	}
+	target := parent
+	for _, sub := range newPath[:len(newPath)-1] {
+		var err error
+		if target, err = target.subSuperCommand(sub); err != nil {
+			return rename{}, errors.Trace(err)
+		}
+	}
+	action, found := target.subcmds[newPath[len(newPath)-1]]
+	if !found {
+		return rename{}, errors.Errorf("%q not found when registering alias", check.replacement)
+	}
+	name := oldPath[len(oldPath)-1]
+	if _, found := parent.subcmds[name]; found && !check.Obsolete() {
+		return rename{}, errors.Errorf("cannot rename %q to %q: %q is already registered",
+			strings.Join(oldPath, " "), check.replacement, strings.Join(oldPath, " "))
+	}
+	return rename{
+		parent: parent,
+		name:   name,
+		target: action,
+		alias:  strings.Join(newPath, " "),
+		check:  check,
+	}, nil
+}
+
+// register registers the old name of the command as an alias.
+func (r rename) register() {
+	if !strings.Contains(r.alias, " ") {
+		r.parent.RegisterAlias(r.name, r.alias, r.check)
+		return
+	}
+	if r.check.Obsolete() {
+		logger.Infof("%q alias not registered as it is obsolete", r.name)
+		return
+	}
+	if r.parent.isDisabled(r.name) {
+		logger.Tracef("%q alias not registered as it is disabled", r.name)
+		return
+	}
+	r.parent.insert(commandReference{
+		name:       r.name,
+		command:    r.target.command,
+		alias:      r.alias,
+		check:      r.check,
+		newCommand: r.target.newCommand,
+	})
+}
+
+// subSuperCommand returns the registered SuperCommand with the given name.
+func (c *SuperCommand) subSuperCommand(name string) (*SuperCommand, error) {
+	action, found := c.subcmds[name]
+	if !found {
+		return nil, errors.Errorf("%q not found when registering alias", name)
+	}
+	super, ok := action.command.(*SuperCommand)
+	if !ok {
+		return nil, errors.Errorf("%q is not a SuperCommand", name)
+	}
+	return super, nil
+}
+
+// formerNames returns the paths below c that were registered by
+// RegisterRenames as old names of the command with the given path, each
+// followed by the version it is removed in, if any.
+func (c *SuperCommand) formerNames(path string) []string {
+	var names []string
+	var walk func(super *SuperCommand, parents []string)
+	walk = func(super *SuperCommand, parents []string) {
+		for name, ref := range super.subcmds {
+			if check, ok := ref.check.(*renameCheck); ok && ref.alias != "" {
+				if commandPath(parents, ref.alias) == path {
+					names = append(names, check.annotation(commandPath(parents, name)))
+				}
+				continue
+			}
+			if sc, ok := ref.command.(*SuperCommand); ok && ref.alias == "" {
+				walk(sc, append(parents[:len(parents):len(parents)], name))
+			}
+		}
+	}
+	walk(c, nil)
+	sort.Strings(names)
+	return names
+}
+
+// renameCheck is the DeprecationCheck of a renamed command.
+type renameCheck struct {
+	replacement string
+	removedIn   string
+	version     string
+}
+
+// Deprecated implements DeprecationCheck.
+func (r *renameCheck) Deprecated() (bool, string) {
+	return true, r.replacement
+}
+
+// Obsolete implements DeprecationCheck.
+func (r *renameCheck) Obsolete() bool {
+	return r.removedIn != "" && r.version != "" && compareVersions(r.version, r.removedIn) >= 0
+}
+
+// notice returns the notice shown when the command is run under the old
+// name, or its help is shown.
+func (r *renameCheck) notice(name string) string {
+	notice := fmt.Sprintf("%q is deprecated, please use %q", name, r.replacement)
+	if r.removedIn != "" {
+		notice += fmt.Sprintf(" (it will be removed in version %s)", r.removedIn)
+	}
+	return notice
+}
+
+// annotation returns the note on the old name of the command in the
+// documentation of the new one.
+func (r *renameCheck) annotation(name string) string {
+	if r.removedIn == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (removed in %s)", name, r.removedIn)
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type RenameSuite struct{}
+
+var _ = gc.Suite(&RenameSuite{})
+
+var renames = map[string]string{
+	"add-relation": "integrate",
+	"show-config":  "model config",
+	"model get":    "model config",
+}
+
+func (s *RenameSuite) newSuper(c *gc.C, version string) *cmd.SuperCommand {
+	juju := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:    "juju",
+		Version: version,
+		Log:     &cmd.Log{},
+	})
+	juju.Register(&TestCommand{Name: "integrate"})
+	model := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:        "model",
+		UsagePrefix: "juju",
+		Purpose:     "manage models",
+	})
+	model.Register(&TestCommand{Name: "config"})
+	juju.Register(model)
+	err := juju.RegisterRenames(renames, "4.0")
+	c.Assert(err, jc.ErrorIsNil)
+	return juju
+}
+
+func (s *RenameSuite) TestRun(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		stderr string
+	}{{
+		args:   []string{"add-relation", "--option", "x"},
+		stderr: `WARNING "add-relation" is deprecated, please use "integrate" (it will be removed in version 4.0)` + "\n",
+	}, {
+		args:   []string{"show-config", "--option", "x"},
+		stderr: `WARNING "show-config" is deprecated, please use "model config" (it will be removed in version 4.0)` + "\n",
+	}, {
+		args:   []string{"model", "get", "--option", "x"},
+		stderr: `WARNING "get" is deprecated, please use "model config" (it will be removed in version 4.0)` + "\n",
+	}, {
+		args: []string{"integrate", "--option", "x"},
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(s.newSuper(c, "3.1"), ctx, test.args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(cmdtesting.Stdout(ctx), gc.Equals, "x\n")
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+	}
+}
+
+func (s *RenameSuite) TestHelp(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newSuper(c, "3.1"), ctx, []string{"help", "show-config"})
+	c.Assert(code, gc.Equals, 0)
+	help := cmdtesting.Stdout(ctx)
+	c.Check(strings.HasPrefix(help, `Note: "show-config" is deprecated, please use "model config" (it will be removed in version 4.0).`+"\n\nUsage: juju model config"), gc.Equals, true, gc.Commentf("%s", help))
+}
+
+func (s *RenameSuite) TestHiddenFromCommandList(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newSuper(c, "3.1"), ctx, []string{"help", "commands"})
+	c.Assert(code, gc.Equals, 0)
+	c.Check(cmdtesting.Stdout(ctx), gc.Not(gc.Matches), "(?s).*add-relation.*")
+}
+
+func (s *RenameSuite) TestObsolete(c *gc.C) {
+	for _, args := range [][]string{{"add-relation"}, {"show-config"}, {"model", "get"}} {
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(s.newSuper(c, "4.0.1"), ctx, args)
+		c.Check(code, gc.Equals, 2)
+		c.Check(cmdtesting.Stderr(ctx), gc.Matches, "ERROR unrecognized command: .*\n")
+	}
+}
+
+func (s *RenameSuite) TestNotBelowParent(c *gc.C) {
+	juju := s.newSuper(c, "3.1")
+	err := juju.RegisterRenames(map[string]string{
+		"model list": "integrate",
+		"add-model":  "model config",
+	}, "")
+	c.Assert(err, gc.ErrorMatches, `cannot rename "model list" to "integrate": "integrate" is not below "model"`)
+
+	// None of the renames are registered.
+	ctx := cmdtesting.Context(c)
+	c.Assert(cmd.Main(juju, ctx, []string{"add-model"}), gc.Equals, 2)
+}
+
+func (s *RenameSuite) TestTargetNotFound(c *gc.C) {
+	juju := s.newSuper(c, "3.1")
+	err := juju.RegisterRenames(map[string]string{"show-model": "model show"}, "")
+	c.Assert(err, gc.ErrorMatches, `"model show" not found when registering alias`)
+
+	err = juju.RegisterRenames(map[string]string{"show-model": "integrate show"}, "")
+	c.Assert(err, gc.ErrorMatches, `"integrate" is not a SuperCommand`)
+
+	err = juju.RegisterRenames(map[string]string{"show-model": ""}, "")
+	c.Assert(err, gc.ErrorMatches, `empty command path renaming "show-model" to ""`)
+}
+
+func (s *RenameSuite) TestAlreadyRegistered(c *gc.C) {
+	juju := s.newSuper(c, "3.1")
+	juju.Register(&TestCommand{Name: "status"})
+	err := juju.RegisterRenames(map[string]string{
+		"add-integration": "integrate",
+		"status":          "integrate",
+	}, "")
+	c.Assert(err, gc.ErrorMatches, `cannot rename "status" to "integrate": "status" is already registered`)
+
+	err = juju.RegisterRenames(map[string]string{
+		"add-integration":  "integrate",
+		"add-integration ": "model config",
+	}, "")
+	c.Assert(err, gc.ErrorMatches, `cannot rename "add-integration ?" more than once`)
+
+	// None of the renames are registered.
+	ctx := cmdtesting.Context(c)
+	c.Assert(cmd.Main(juju, ctx, []string{"add-integration"}), gc.Equals, 2)
+}
+
+func (s *RenameSuite) TestDocumentation(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newSuper(c, "3.1"), ctx, []string{"documentation", "--no-index"})
+	c.Assert(code, gc.Equals, 0)
+	doc := cmdtesting.Stdout(ctx)
+	c.Check(doc, gc.Matches, "(?s).*# INTEGRATE\n\n\\*\\*Formerly:\\*\\* add-relation \\(removed in 4.0\\)\n.*")
+	c.Check(doc, gc.Matches, "(?s).*# MODEL CONFIG\n\n\\*\\*Formerly:\\*\\* model get \\(removed in 4.0\\), show-config \\(removed in 4.0\\)\n.*")
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// CommandSchema describes a command, and the commands below it, for tools
+// such as documentation sites, completion generators and GUIs.
+type CommandSchema struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version,omitempty"`
+	Purpose     string          `json:"purpose,omitempty"`
+	Doc         string          `json:"doc,omitempty"`
+	Args        string          `json:"args,omitempty"`
+	Aliases     []string        `json:"aliases,omitempty"`
+	Category    string          `json:"category,omitempty"`
+	Examples    string          `json:"examples,omitempty"`
+	SeeAlso     []string        `json:"see-also,omitempty"`
+	Deprecated  bool            `json:"deprecated,omitempty"`
+	Replacement string          `json:"replacement,omitempty"`
+	Flags       []FlagSchema    `json:"flags,omitempty"`
+	Subcommands []CommandSchema `json:"subcommands,omitempty"`
+}
+
+// FlagSchema describes a flag of a command.
+type FlagSchema struct {
+	// Name is the longest name of the flag, and Aliases its other names.
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Type is the Go type of the flag's value, e.g. "string", "bool" or
+	// "time.Duration". Flags with values of other types are given as
+	// strings, so their type is "string".
+	Type    string `json:"type"`
+	Default string `json:"default,omitempty"`
+	Usage   string `json:"usage,omitempty"`
+}
+
+// ExportSchema returns the JSON schema of super: its names, purposes,
+// arguments, aliases, flags, examples and "see also" entries, along with
+// those of every command registered below it. Hidden commands are left
+// out, and aliases are given with the commands they name.
+func ExportSchema(super *SuperCommand) ([]byte, error) {
+	data, err := json.MarshalIndent(commandSchema(super), "", "  ")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(data, '\n'), nil
+}
+
+// commandSchema returns the schema of the SuperCommand c.
+func commandSchema(c *SuperCommand) CommandSchema {
+	c.init()
+	schema := CommandSchema{
+		Name:    c.Name,
+		Version: c.version,
+		Purpose: c.Purpose,
+		Doc:     c.Doc,
+		Flags:   flagSchemas(c),
+	}
+
+	aliases := make(map[string][]string)
+	var names []string
+	for name, ref := range c.subcmds {
+		if ref.hidden(name) {
+			continue
+		}
+		if ref.alias != "" {
+			aliases[ref.alias] = append(aliases[ref.alias], name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ref := c.subcmds[name]
+		var sub CommandSchema
+		if sc, ok := ref.command.(*SuperCommand); ok {
+			sub = commandSchema(sc)
+		} else {
+			info := ref.command.Info()
+			sub = CommandSchema{
+				Purpose:  info.Purpose,
+				Doc:      info.Doc,
+				Args:     info.Args,
+				Category: info.Category,
+				Examples: info.Examples,
+				SeeAlso:  info.SeeAlso,
+				Flags:    flagSchemas(ref.command),
+			}
+		}
+		sub.Name = name
+		sub.Aliases = aliases[name]
+		sort.Strings(sub.Aliases)
+		sub.Deprecated, sub.Replacement = ref.Deprecated()
+		schema.Subcommands = append(schema.Subcommands, sub)
+	}
+	return schema
+}
+
+// flagSchemas returns the schema of each flag of cmd.
+func flagSchemas(cmd InfoCommand) []FlagSchema {
+	var flags []FlagSchema
+	for _, fs := range groupFlags(cmd) {
+		longest := fs[len(fs)-1]
+		flag := FlagSchema{
+			Name:    longest.Name,
+			Type:    flagType(longest),
+			Default: flagDefault(fs[0], false),
+			Usage:   fs[0].Usage,
+		}
+		for _, f := range fs[:len(fs)-1] {
+			flag.Aliases = append(flag.Aliases, f.Name)
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+// flagType returns the name of the type of the value of f.
+func flagType(f *gnuflag.Flag) string {
+	if getter, ok := f.Value.(gnuflag.Getter); ok {
+		if value := getter.Get(); value != nil {
+			return fmt.Sprintf("%T", value)
+		}
+	}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return "bool"
+	}
+	return "string"
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type SchemaSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&SchemaSuite{})
+
+// deployCommand has flags of several types.
+type deployCommand struct {
+	cmd.CommandBase
+	force   bool
+	count   int
+	timeout time.Duration
+	channel string
+}
+
+func (c *deployCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:     "deploy",
+		Args:     "<charm>",
+		Purpose:  "Deploy a charm.",
+		Doc:      "Deploys a charm.",
+		Aliases:  []string{"dp"},
+		Category: "applications",
+		Examples: "    juju deploy mysql",
+		SeeAlso:  []string{"remove-application"},
+	}
+}
+
+func (c *deployCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&c.force, "force", false, "Deploy anyway")
+	f.IntVar(&c.count, "n", 1, "Number of units")
+	f.IntVar(&c.count, "num-units", 1, "")
+	f.DurationVar(&c.timeout, "timeout", time.Minute, "How long to wait")
+	f.StringVar(&c.channel, "channel", "", "The channel to deploy from")
+}
+
+func (c *deployCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (s *SchemaSuite) superCommand() *cmd.SuperCommand {
+	pools := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "pools", Purpose: "Manage storage pools."})
+	pools.Register(&TestCommand{Name: "list", Minimal: true})
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju", Version: "3.0.0", Purpose: "Manage models."})
+	super.Register(&deployCommand{})
+	super.Register(pools)
+	super.RegisterDeprecated(&TestCommand{Name: "old", Minimal: true}, deprecate{replacement: "deploy"})
+	return super
+}
+
+func (s *SchemaSuite) TestExportSchema(c *gc.C) {
+	data, err := cmd.ExportSchema(s.superCommand())
+	c.Assert(err, gc.IsNil)
+	var schema cmd.CommandSchema
+	c.Assert(json.Unmarshal(data, &schema), gc.IsNil)
+
+	c.Assert(schema.Name, gc.Equals, "juju")
+	c.Assert(schema.Version, gc.Equals, "3.0.0")
+	c.Assert(schema.Purpose, gc.Equals, "Manage models.")
+	var names []string
+	for _, sub := range schema.Subcommands {
+		names = append(names, sub.Name)
+	}
+	c.Assert(names, gc.DeepEquals, []string{"deploy", "documentation", "help", "old", "pools", "version"})
+
+	c.Assert(schema.Subcommands[0], gc.DeepEquals, cmd.CommandSchema{
+		Name:     "deploy",
+		Purpose:  "Deploy a charm.",
+		Doc:      "Deploys a charm.",
+		Args:     "<charm>",
+		Aliases:  []string{"dp"},
+		Category: "applications",
+		Examples: "    juju deploy mysql",
+		SeeAlso:  []string{"remove-application"},
+		Flags: []cmd.FlagSchema{
+			{Name: "channel", Type: "string", Usage: "The channel to deploy from"},
+			{Name: "force", Type: "bool", Default: "false", Usage: "Deploy anyway"},
+			{Name: "num-units", Aliases: []string{"n"}, Type: "int", Default: "1", Usage: "Number of units"},
+			{Name: "timeout", Type: "time.Duration", Default: "1m0s", Usage: "How long to wait"},
+		},
+	})
+	c.Assert(schema.Subcommands[3].Deprecated, gc.Equals, true)
+	c.Assert(schema.Subcommands[3].Replacement, gc.Equals, "deploy")
+
+	pools := schema.Subcommands[4]
+	c.Assert(pools.Purpose, gc.Equals, "Manage storage pools.")
+	c.Assert(pools.Subcommands, gc.HasLen, 3)
+	c.Assert(pools.Subcommands[2].Name, gc.Equals, "list")
+}
+
+func (s *SchemaSuite) TestDocumentationFormat(c *gc.C) {
+	super := s.superCommand()
+	expected, err := cmd.ExportSchema(super)
+	c.Assert(err, gc.IsNil)
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"documentation", "--doc-format", "json"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, string(expected))
+
+	out := c.MkDir()
+	code = cmd.Main(super, cmdtesting.Context(c), []string{"documentation", "--doc-format", "json", "--out", out})
+	c.Assert(code, gc.Equals, 0)
+	content, err := os.ReadFile(filepath.Join(out, "documentation.json"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, string(expected))
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(super, ctx, []string{"documentation", "--doc-format", "json", "--split", "--out", out})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR the json format cannot be used with --split\n")
+}
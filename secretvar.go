@@ -0,0 +1,135 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// SecretVar represents a flag holding a password, token or other secret.
+// So that the secret does not appear in the process arguments or shell
+// history, the flag names where to read it from rather than giving the
+// secret itself:
+//
+//	file:<path>        the content of the file
+//	env:<name>         the value of the environment variable
+//	fd:<n>             the content read from the open file descriptor
+//	prompt:[<message>] the response to a prompt that does not echo
+//
+// String returns where the secret is read from, never the secret, so that
+// the flag is safely shown in help, logs and saved invocations.
+type SecretVar struct {
+	// Source holds where the secret is read from, as given to Set.
+	Source string
+
+	value    string
+	resolved bool
+}
+
+const secretSyntax = "file:<path>, env:<name>, fd:<n> or prompt:[<message>]"
+
+// Set implements gnuflag.Value. The secret is not read until Value is
+// called.
+func (v *SecretVar) Set(s string) error {
+	kind, arg, ok := strings.Cut(s, ":")
+	switch {
+	case !ok:
+		// s may be the secret itself, so it is not included in the error.
+		return errors.Errorf("secret must be given as %s", secretSyntax)
+	case kind == "file" || kind == "env":
+		if arg == "" {
+			return errors.Errorf("missing %s name in %q", kind, s)
+		}
+	case kind == "fd":
+		if _, err := strconv.ParseUint(arg, 10, 0); err != nil {
+			return errors.Errorf("invalid file descriptor in %q", s)
+		}
+	case kind == "prompt":
+	default:
+		return errors.Errorf("secret must be given as %s", secretSyntax)
+	}
+	v.Source = s
+	v.value, v.resolved = "", false
+	return nil
+}
+
+// String implements gnuflag.Value. It returns the source of the secret.
+func (v *SecretVar) String() string {
+	return v.Source
+}
+
+// IsSet reports whether a source for the secret has been given.
+func (v *SecretVar) IsSet() bool {
+	return v.Source != ""
+}
+
+// Value reads the secret from its source, once, and returns it. Trailing
+// line endings are removed from secrets read from files. The secret is
+// registered with RegisterRedactedValue, so that it is not revealed by
+// errors or logs. An empty string is returned if no source was given.
+func (v *SecretVar) Value(ctx *Context) (string, error) {
+	if v.resolved || v.Source == "" {
+		return v.value, nil
+	}
+	kind, arg, _ := strings.Cut(v.Source, ":")
+	var value string
+	switch kind {
+	case "file":
+		data, err := ioutil.ReadFile(ctx.AbsPath(arg))
+		if err != nil {
+			return "", errors.Annotate(err, "reading secret")
+		}
+		value = trimLineEnding(string(data))
+	case "env":
+		value = ctx.lookupEnv(arg)
+		if value == "" {
+			return "", errors.Errorf("environment variable %q is not set", arg)
+		}
+	case "fd":
+		fd, _ := strconv.ParseUint(arg, 10, 0)
+		var r io.Reader
+		if fd <= 2 {
+			// The standard streams are still used by the command, so
+			// they are read without being closed.
+			r = []*os.File{os.Stdin, os.Stdout, os.Stderr}[fd]
+		} else {
+			f := os.NewFile(uintptr(fd), "fd:"+arg)
+			if f == nil {
+				return "", errors.Errorf("invalid file descriptor %s", arg)
+			}
+			defer f.Close()
+			r = f
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return "", errors.Annotate(err, "reading secret")
+		}
+		value = trimLineEnding(string(data))
+	case "prompt":
+		msg := arg
+		if msg == "" {
+			msg = "Enter secret: "
+		}
+		var err error
+		if value, err = ctx.PromptPassword(msg); err != nil {
+			return "", errors.Trace(err)
+		}
+	default:
+		return "", errors.Errorf("secret must be given as %s", secretSyntax)
+	}
+	RegisterRedactedValue(value)
+	v.value, v.resolved = value, true
+	return value, nil
+}
+
+// trimLineEnding returns s without a trailing "\n" or "\r\n".
+func trimLineEnding(s string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+}
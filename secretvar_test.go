@@ -0,0 +1,125 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/gnuflag"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type SecretVarSuite struct {
+	testing.IsolationSuite
+	ctx *cmd.Context
+}
+
+var _ = gc.Suite(&SecretVarSuite{})
+
+func (s *SecretVarSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+	s.ctx = cmdtesting.Context(c)
+}
+
+func (s *SecretVarSuite) parse(c *gc.C, args ...string) (*cmd.SecretVar, error) {
+	var secret cmd.SecretVar
+	f := gnuflag.NewFlagSetWithFlagKnownAs("test", gnuflag.ContinueOnError, "option")
+	f.SetOutput(ioutil.Discard)
+	f.Var(&secret, "password", "the password")
+	return &secret, f.Parse(true, args)
+}
+
+func (s *SecretVarSuite) TestFile(c *gc.C) {
+	path := filepath.Join(s.ctx.Dir, "password")
+	c.Assert(ioutil.WriteFile(path, []byte("hunter2\n"), 0600), jc.ErrorIsNil)
+	secret, err := s.parse(c, "--password", "file:password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(secret.String(), gc.Equals, "file:password")
+
+	value, err := secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "hunter2")
+	c.Assert(secret.String(), gc.Equals, "file:password")
+	c.Assert(cmd.Redact("password is hunter2"), gc.Equals, "password is "+cmd.Redacted)
+
+	// The secret is read only once.
+	c.Assert(os.Remove(path), jc.ErrorIsNil)
+	value, err = secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "hunter2")
+}
+
+func (s *SecretVarSuite) TestEnv(c *gc.C) {
+	s.ctx.Env = map[string]string{"DB_PASSWORD": "hunter2"}
+	secret, err := s.parse(c, "--password=env:DB_PASSWORD")
+	c.Assert(err, jc.ErrorIsNil)
+	value, err := secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "hunter2")
+
+	secret, err = s.parse(c, "--password=env:MISSING")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = secret.Value(s.ctx)
+	c.Assert(err, gc.ErrorMatches, `environment variable "MISSING" is not set`)
+}
+
+func (s *SecretVarSuite) TestPrompt(c *gc.C) {
+	s.ctx.Stdin = bytes.NewBufferString("hunter2\n")
+	secret, err := s.parse(c, "--password", "prompt:")
+	c.Assert(err, jc.ErrorIsNil)
+	value, err := secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "hunter2")
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "Enter secret: ")
+
+	s.ctx = cmdtesting.Context(c)
+	s.ctx.Stdin = bytes.NewBufferString("sekrit\n")
+	secret, err = s.parse(c, "--password", "prompt:Database password: ")
+	c.Assert(err, jc.ErrorIsNil)
+	value, err = secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "sekrit")
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "Database password: ")
+}
+
+func (s *SecretVarSuite) TestNotSet(c *gc.C) {
+	secret, err := s.parse(c)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(secret.IsSet(), jc.IsFalse)
+	value, err := secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "")
+}
+
+func (s *SecretVarSuite) TestInvalid(c *gc.C) {
+	for _, test := range []struct {
+		arg string
+		err string
+	}{{
+		arg: "hunter2",
+		err: `invalid value "hunter2" for option --password: secret must be given as file:<path>, env:<name>, fd:<n> or prompt:\[<message>\]`,
+	}, {
+		arg: "env:",
+		err: `invalid value "env:" for option --password: missing env name in "env:"`,
+	}, {
+		arg: "fd:stdin",
+		err: `invalid value "fd:stdin" for option --password: invalid file descriptor in "fd:stdin"`,
+	}, {
+		arg: "pass:hunter2",
+		err: `.*secret must be given as .*`,
+	}} {
+		c.Logf("arg %q", test.arg)
+		_, err := s.parse(c, "--password", test.arg)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
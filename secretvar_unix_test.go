@@ -0,0 +1,55 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package cmd_test
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+func (s *SecretVarSuite) TestFd(c *gc.C) {
+	r, w, err := os.Pipe()
+	c.Assert(err, jc.ErrorIsNil)
+	defer r.Close()
+	_, err = w.Write([]byte("hunter2\r\n"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(w.Close(), jc.ErrorIsNil)
+
+	// The secret's file descriptor is closed once it has been read.
+	fd, err := syscall.Dup(int(r.Fd()))
+	c.Assert(err, jc.ErrorIsNil)
+	var secret cmd.SecretVar
+	c.Assert(secret.Set("fd:"+strconv.Itoa(fd)), jc.ErrorIsNil)
+	value, err := secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "hunter2")
+}
+
+func (s *SecretVarSuite) TestFdStdinNotClosed(c *gc.C) {
+	r, w, err := os.Pipe()
+	c.Assert(err, jc.ErrorIsNil)
+	defer r.Close()
+	s.PatchValue(&os.Stdin, r)
+	_, err = w.Write([]byte("hunter2\n"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(w.Close(), jc.ErrorIsNil)
+
+	var secret cmd.SecretVar
+	c.Assert(secret.Set("fd:0"), jc.ErrorIsNil)
+	value, err := secret.Value(s.ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(value, gc.Equals, "hunter2")
+
+	// Stdin is left open for the rest of the command.
+	_, err = r.Stat()
+	c.Assert(err, jc.ErrorIsNil)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// ShutdownSignals are the signals that request a graceful shutdown of an
+// agent running as a service.
+var ShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// WithShutdownSignals returns a copy of ctx whose context.Context is
+// cancelled when one of the given signals is received, or one of
+// ShutdownSignals if none are given. Agent commands select on ctx.Done() to
+// shut down gracefully. Only the first signal is caught, so that another
+// one terminates an agent that does not stop. The returned function stops
+// listening for the signals and must be called when the command finishes.
+func (ctx *Context) WithShutdownSignals(signals ...os.Signal) (*Context, func()) {
+	newCtx := ctx.With(ctx.background())
+	return newCtx, newCtx.cancelOnShutdownSignals(signals...)
+}
+
+// RunService runs an agent as the service called name. When the process
+// was started by the Windows service control manager, run is called with a
+// context that is cancelled when the service is asked to stop, and the
+// service manager is kept informed of the service's state, with
+// NotifyReady reporting it as running. Otherwise run is called with a
+// context that is cancelled by ShutdownSignals, as with
+// WithShutdownSignals.
+func RunService(ctx *Context, name string, run func(*Context) error) error {
+	if handled, err := runWindowsService(ctx, name, run); handled {
+		return err
+	}
+	ctx, stop := ctx.WithShutdownSignals()
+	defer stop()
+	return run(ctx)
+}
+
+// NotifyService sends state to the service manager using the systemd
+// notification protocol, e.g. "READY=1" or "STATUS=syncing". It does
+// nothing when the service manager has not asked for notifications by
+// setting NOTIFY_SOCKET. Under the Windows service control manager,
+// "READY=1" and "STOPPING=1" update the state of the service.
+func (ctx *Context) NotifyService(state string) error {
+	if ctx.serviceNotify != nil {
+		return ctx.serviceNotify(state)
+	}
+	socket := ctx.lookupEnv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// A leading "@" denotes a socket in the abstract namespace.
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return errors.Annotate(err, "connecting to service manager")
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return errors.Annotate(err, "notifying service manager")
+}
+
+// NotifyReady tells the service manager that the service has started.
+func (ctx *Context) NotifyReady() error {
+	return ctx.NotifyService("READY=1")
+}
+
+// NotifyStopping tells the service manager that the service is shutting
+// down.
+func (ctx *Context) NotifyStopping() error {
+	return ctx.NotifyService("STOPPING=1")
+}
+
+// WritePIDFile writes the process ID to path, replacing any stale file
+// left behind by a previous run. It fails if the file names another
+// process that is still running. The returned function removes the file,
+// unless it has since been replaced by another process.
+func WritePIDFile(path string) (func() error, error) {
+	pid := strconv.Itoa(os.Getpid())
+	if content, err := ioutil.ReadFile(path); err == nil {
+		other, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err == nil && other != os.Getpid() && processAlive(other) {
+			return nil, errors.Errorf("pid file %q is in use by running process %d", path, other)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Annotate(err, "reading pid file")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return nil, errors.Annotate(err, "creating pid file")
+	}
+	_, err = tmp.WriteString(pid + "\n")
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, errors.Annotate(err, "writing pid file")
+	}
+	return func() error {
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+		if strings.TrimSpace(string(content)) != pid {
+			return nil
+		}
+		return errors.Trace(os.Remove(path))
+	}, nil
+}
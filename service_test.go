@@ -0,0 +1,132 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ServiceSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ServiceSuite{})
+
+func (s *ServiceSuite) TestNotifyServiceWithoutSocket(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{}
+	c.Assert(ctx.NotifyReady(), gc.IsNil)
+}
+
+func (s *ServiceSuite) TestNotifyService(c *gc.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("unix sockets are not used for service notification on windows")
+	}
+	socket := filepath.Join(c.MkDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	c.Assert(err, gc.IsNil)
+	defer conn.Close()
+
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{"NOTIFY_SOCKET": socket}
+	c.Assert(ctx.NotifyReady(), gc.IsNil)
+	c.Assert(ctx.NotifyStopping(), gc.IsNil)
+
+	buf := make([]byte, 64)
+	for _, expect := range []string{"READY=1", "STOPPING=1"} {
+		n, err := conn.Read(buf)
+		c.Assert(err, gc.IsNil)
+		c.Assert(string(buf[:n]), gc.Equals, expect)
+	}
+}
+
+func (s *ServiceSuite) TestWithShutdownSignals(c *gc.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("sending signals is not supported on windows")
+	}
+	ctx, stop := cmdtesting.Context(c).WithShutdownSignals(os.Interrupt)
+	defer stop()
+	c.Assert(ctx.Err(), gc.IsNil)
+
+	p, err := os.FindProcess(os.Getpid())
+	c.Assert(err, gc.IsNil)
+	c.Assert(p.Signal(os.Interrupt), gc.IsNil)
+	select {
+	case <-ctx.Done():
+	case <-time.After(testing.LongWait):
+		c.Fatalf("context not cancelled")
+	}
+}
+
+func (s *ServiceSuite) TestRunService(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{}
+	err := cmd.RunService(ctx, "agent", func(ctx *cmd.Context) error {
+		c.Assert(ctx.Err(), gc.IsNil)
+		c.Assert(ctx.NotifyReady(), gc.IsNil)
+		return errors.New("kaboom")
+	})
+	c.Assert(err, gc.ErrorMatches, "kaboom")
+}
+
+// exitedPID returns the ID of a process that has finished.
+func exitedPID(c *gc.C) int {
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	c.Assert(child.Run(), jc.ErrorIsNil)
+	return child.Process.Pid
+}
+
+func (s *ServiceSuite) TestWritePIDFile(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "agent.pid")
+	c.Assert(ioutil.WriteFile(path, []byte(strconv.Itoa(exitedPID(c))+"\n"), 0644), gc.IsNil)
+
+	remove, err := cmd.WritePIDFile(path)
+	c.Assert(err, gc.IsNil)
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, strconv.Itoa(os.Getpid())+"\n")
+
+	c.Assert(remove(), gc.IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), gc.Equals, true)
+}
+
+func (s *ServiceSuite) TestWritePIDFileInUse(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "agent.pid")
+	running := strconv.Itoa(os.Getppid())
+	c.Assert(ioutil.WriteFile(path, []byte(running+"\n"), 0644), gc.IsNil)
+
+	_, err := cmd.WritePIDFile(path)
+	c.Assert(err, gc.ErrorMatches, `pid file ".*" is in use by running process `+running)
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(content), gc.Equals, running+"\n")
+}
+
+func (s *ServiceSuite) TestWritePIDFileReplaced(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "agent.pid")
+	remove, err := cmd.WritePIDFile(path)
+	c.Assert(err, gc.IsNil)
+
+	// A file written by another process is left alone.
+	c.Assert(ioutil.WriteFile(path, []byte("1\n"), 0644), gc.IsNil)
+	c.Assert(remove(), gc.IsNil)
+	_, err = os.Stat(path)
+	c.Assert(err, gc.IsNil)
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build windows
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/juju/errors"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// stillActive is the exit code reported for a process that is running.
+const stillActive = 259
+
+// processAlive reports whether the process with the given ID is running.
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// runWindowsService runs run under the service control manager, if the
+// process was started by it, and reports whether it did so.
+func runWindowsService(ctx *Context, name string, run func(*Context) error) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return true, errors.Annotate(err, "checking for service control manager")
+	}
+	if !isService {
+		return false, nil
+	}
+	h := &serviceHandler{ctx: ctx, run: run}
+	if err := svc.Run(name, h); err != nil {
+		return true, errors.Annotatef(err, "running service %q", name)
+	}
+	return true, h.err
+}
+
+// serviceHandler implements svc.Handler for a command run as a service.
+type serviceHandler struct {
+	ctx *Context
+	run func(*Context) error
+	err error
+}
+
+// Execute implements svc.Handler.
+func (h *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	c, cancel := context.WithCancel(h.ctx.background())
+	defer cancel()
+	ctx := h.ctx.With(c)
+	ctx.serviceNotify = func(state string) error {
+		switch state {
+		case "READY=1":
+			changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+		case "STOPPING=1":
+			changes <- svc.Status{State: svc.StopPending}
+		}
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- h.run(ctx)
+	}()
+	for {
+		select {
+		case h.err = <-done:
+			if h.err != nil {
+				return true, uint32(ExitCode(h.err))
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				changes <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/juju/ansiterm"
+	"github.com/juju/loggo/v2"
+)
+
+// Severity describes how serious a message written with Print is.
+type Severity int
+
+const (
+	// SeverityError is for errors that stop a command.
+	SeverityError Severity = iota
+
+	// SeverityWarning is for problems that do not stop a command.
+	SeverityWarning
+
+	// SeverityNotice is for information the user should not miss, e.g.
+	// that a newer version is available.
+	SeverityNotice
+)
+
+// severityStyles holds the prefix and colour of each severity.
+var severityStyles = map[Severity]struct {
+	prefix string
+	color  *ansiterm.Context
+}{
+	SeverityError:   {"ERROR", ansiterm.Foreground(ansiterm.BrightRed)},
+	SeverityWarning: {"WARNING", ansiterm.Foreground(ansiterm.Yellow)},
+	SeverityNotice:  {"NOTICE", ansiterm.Foreground(ansiterm.BrightBlue)},
+}
+
+// String returns the prefix written before messages of the severity.
+func (s Severity) String() string {
+	if style, ok := severityStyles[s]; ok {
+		return style.prefix
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// severityForLevel returns the severity used for log entries of the given
+// level, if there is one.
+func severityForLevel(level loggo.Level) (Severity, bool) {
+	switch level {
+	case loggo.ERROR:
+		return SeverityError, true
+	case loggo.WARNING:
+		return SeverityWarning, true
+	}
+	return 0, false
+}
+
+// Print writes msg, which is usually an error or a string, to writer on
+// one line after the prefix of its severity, e.g. "WARNING msg". The prefix
+// is coloured if writer is a terminal that allows it. Any registered
+// secrets are redacted from the message (see Redact).
+func Print(writer io.Writer, severity Severity, msg interface{}) {
+	printSeverity(NewColorWriter(writer), severity, msg)
+}
+
+// Print writes msg to Stderr as the package level Print does, with colour
+// if the context allows it.
+func (ctx *Context) Print(severity Severity, msg interface{}) {
+	w := NewColorWriter(ctx.Stderr)
+	w.SetColorCapable(ctx.ColorEnabled(ctx.Stderr))
+	printSeverity(w, severity, msg)
+}
+
+// printSeverity writes msg to w after the prefix of its severity.
+func printSeverity(w *ansiterm.Writer, severity Severity, msg interface{}) {
+	var text string
+	if err, ok := msg.(error); ok {
+		text = err.Error()
+	} else {
+		text = fmt.Sprint(msg)
+	}
+	if style, ok := severityStyles[severity]; ok {
+		style.color.Fprintf(w, style.prefix)
+	} else {
+		fmt.Fprint(w, severity)
+	}
+	fmt.Fprintf(w, " %s\n", Redact(text))
+}
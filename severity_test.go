@@ -0,0 +1,64 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type SeveritySuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&SeveritySuite{})
+
+func (s *SeveritySuite) TestPrint(c *gc.C) {
+	for i, test := range []struct {
+		severity cmd.Severity
+		msg      interface{}
+		expect   string
+	}{
+		{cmd.SeverityError, errors.New("boom"), "ERROR boom\n"},
+		{cmd.SeverityWarning, "disk nearly full", "WARNING disk nearly full\n"},
+		{cmd.SeverityNotice, "a new version is available", "NOTICE a new version is available\n"},
+		{cmd.Severity(42), 3, "Severity(42) 3\n"},
+	} {
+		c.Logf("test %d", i)
+		var buf bytes.Buffer
+		cmd.Print(&buf, test.severity, test.msg)
+		c.Check(buf.String(), gc.Equals, test.expect)
+	}
+}
+
+func (s *SeveritySuite) TestPrintColor(c *gc.C) {
+	ctx := cmdtesting.TerminalContext(c, 80, 24, true)
+	cmd.Print(ctx.Stderr, cmd.SeverityWarning, "careful")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "\x1b[33mWARNING\x1b[0m careful\n")
+}
+
+func (s *SeveritySuite) TestContextPrint(c *gc.C) {
+	ctx := cmdtesting.TerminalContext(c, 80, 24, true)
+	ctx.SetColorMode(cmd.ColorNever)
+	ctx.Print(cmd.SeverityNotice, "upgrade available")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "NOTICE upgrade available\n")
+
+	ctx = cmdtesting.Context(c)
+	ctx.Print(cmd.SeverityError, errors.New("boom"))
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR boom\n")
+}
+
+func (s *SeveritySuite) TestPrintRedacts(c *gc.C) {
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+	cmd.RegisterRedactedValue("hunter2")
+	var buf bytes.Buffer
+	cmd.Print(&buf, cmd.SeverityWarning, `password "hunter2" expires soon`)
+	c.Assert(buf.String(), gc.Equals, `WARNING password "<redacted>" expires soon`+"\n")
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// shellQuoters holds the functions that write an export statement for each
+// supported shell.
+var shellQuoters = map[string]func(name, value string) string{
+	"bash": exportPosix,
+	"zsh":  exportPosix,
+	"fish": func(name, value string) string {
+		value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+		return fmt.Sprintf("set -gx %s '%s';", name, value)
+	},
+	"powershell": func(name, value string) string {
+		return fmt.Sprintf("$Env:%s = '%s'", name, strings.Replace(value, "'", "''", -1))
+	},
+}
+
+func exportPosix(name, value string) string {
+	return fmt.Sprintf("export %s='%s'", name, strings.Replace(value, "'", `'\''`, -1))
+}
+
+// shellEnvCommand prints statements that export variables in the user's
+// shell.
+type shellEnvCommand struct {
+	CommandBase
+	name  string
+	vars  func(ctx *Context) (map[string]string, error)
+	shell string
+}
+
+// NewShellEnvCommand returns a command with the given name that prints
+// statements exporting the variables returned by vars, for use as
+//
+//	eval "$(app shellenv)"
+//
+// The shell is detected from the environment, and can be forced with the
+// --shell flag. Bash, zsh, fish and PowerShell are supported.
+func NewShellEnvCommand(name string, vars func(ctx *Context) (map[string]string, error)) Command {
+	return &shellEnvCommand{name: name, vars: vars}
+}
+
+func (c *shellEnvCommand) Info() *Info {
+	return &Info{
+		Name:               c.name,
+		Purpose:            "Print commands to set up the shell environment.",
+		NonInteractiveSafe: true,
+		Doc: fmt.Sprintf(`
+Print the commands that export the environment variables used by this
+application, in the syntax of the current shell. Evaluate the output to
+apply them, for example:
+
+    eval "$(%s)"`[1:], c.name),
+	}
+}
+
+func (c *shellEnvCommand) SetFlags(f *gnuflag.FlagSet) {
+	shells := make([]string, 0, len(shellQuoters))
+	for shell := range shellQuoters {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	f.StringVar(&c.shell, "shell", "", fmt.Sprintf("The shell to print commands for (%s); detected if not specified", strings.Join(shells, "|")))
+}
+
+func (c *shellEnvCommand) Init(args []string) error {
+	if c.shell != "" {
+		if _, ok := shellQuoters[c.shell]; !ok {
+			return errors.NotSupportedf("shell %q", c.shell)
+		}
+	}
+	return CheckEmpty(args)
+}
+
+func (c *shellEnvCommand) Run(ctx *Context) error {
+	shell := c.shell
+	if shell == "" {
+		shell = detectShell(ctx)
+	}
+	vars, err := c.vars(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	export := shellQuoters[shell]
+	for _, name := range names {
+		fmt.Fprintln(ctx.Stdout, export(name, vars[name]))
+	}
+	return nil
+}
+
+// detectShell returns the user's shell, based on the SHELL environment
+// variable. PowerShell is recognised by PSModulePath, and bash is assumed
+// when the shell is unknown.
+func detectShell(ctx *Context) string {
+	shell := filepath.Base(ctx.lookupEnv("SHELL"))
+	if _, ok := shellQuoters[shell]; ok {
+		return shell
+	}
+	if ctx.lookupEnv("SHELL") == "" && ctx.lookupEnv("PSModulePath") != "" {
+		return "powershell"
+	}
+	return "bash"
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ShellEnvSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ShellEnvSuite{})
+
+func shellVars(*cmd.Context) (map[string]string, error) {
+	return map[string]string{
+		"JUJU_MODEL": "prod",
+		"JUJU_DATA":  "/home/o'brien/.juju",
+	}, nil
+}
+
+func (s *ShellEnvSuite) run(c *gc.C, env map[string]string, args ...string) (string, int) {
+	ctx := cmdtesting.Context(c)
+	ctx.Env = env
+	code := cmd.Main(cmd.NewShellEnvCommand("shellenv", shellVars), ctx, args)
+	return cmdtesting.Stdout(ctx) + cmdtesting.Stderr(ctx), code
+}
+
+func (s *ShellEnvSuite) TestShells(c *gc.C) {
+	for i, test := range []struct {
+		env    map[string]string
+		args   []string
+		expect string
+	}{{
+		env: map[string]string{"SHELL": "/bin/bash"},
+		expect: `
+export JUJU_DATA='/home/o'\''brien/.juju'
+export JUJU_MODEL='prod'
+`[1:],
+	}, {
+		env: map[string]string{"SHELL": "/usr/bin/fish"},
+		expect: `
+set -gx JUJU_DATA '/home/o\'brien/.juju';
+set -gx JUJU_MODEL 'prod';
+`[1:],
+	}, {
+		env: map[string]string{"PSModulePath": `C:\Modules`},
+		expect: `
+$Env:JUJU_DATA = '/home/o''brien/.juju'
+$Env:JUJU_MODEL = 'prod'
+`[1:],
+	}, {
+		env:  map[string]string{"SHELL": "/usr/bin/fish"},
+		args: []string{"--shell", "zsh"},
+		expect: `
+export JUJU_DATA='/home/o'\''brien/.juju'
+export JUJU_MODEL='prod'
+`[1:],
+	}, {
+		env: map[string]string{"SHELL": "/bin/tcsh"},
+		expect: `
+export JUJU_DATA='/home/o'\''brien/.juju'
+export JUJU_MODEL='prod'
+`[1:],
+	}} {
+		c.Logf("test %d: %v %v", i, test.env, test.args)
+		out, code := s.run(c, test.env, test.args...)
+		c.Check(code, gc.Equals, 0)
+		c.Check(out, gc.Equals, test.expect)
+	}
+}
+
+func (s *ShellEnvSuite) TestUnsupportedShell(c *gc.C) {
+	out, code := s.run(c, map[string]string{}, "--shell", "tcsh")
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(out, gc.Equals, "ERROR shell \"tcsh\" not supported\n")
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"errors"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type SilentSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&SilentSuite{})
+
+func (s *SilentSuite) TestSilenceError(c *gc.C) {
+	c.Assert(cmd.SilenceError(nil), gc.IsNil)
+	c.Assert(cmd.SilenceError(cmd.ErrSilent), gc.Equals, cmd.ErrSilent)
+
+	original := errors.New("connection refused")
+	err := cmd.SilenceError(original)
+	c.Assert(cmd.IsErrSilent(err), gc.Equals, true)
+	c.Assert(cmd.UnwrapSilent(err), gc.Equals, original)
+	c.Assert(errors.Is(err, original), gc.Equals, true)
+	c.Assert(cmd.UnwrapSilent(original), gc.Equals, original)
+}
+
+func (s *SilentSuite) TestMainSilenced(c *gc.C) {
+	original := errors.New("connection refused")
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&TestCommand{
+		Name:      "blah",
+		CustomRun: func(*cmd.Context) error { return cmd.SilenceError(original) },
+	}, ctx, nil)
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+	c.Assert(ctx.LastError(), gc.Equals, original)
+}
+
+func (s *SilentSuite) TestMainSuccess(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&TestCommand{Name: "blah"}, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(ctx.LastError(), gc.IsNil)
+}
+
+func (s *SilentSuite) TestSuperCommand(c *gc.C) {
+	for i, test := range []struct {
+		option string
+		stderr string
+		err    string
+	}{
+		{option: "error", stderr: "ERROR BAM!\n", err: "BAM!"},
+		{option: "silent-error", err: "cmd: error out silently"},
+	} {
+		c.Logf("test %d: %s", i, test.option)
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+		sc.Register(&TestCommand{Name: "blah"})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(sc, ctx, []string{"blah", "--option", test.option})
+		c.Check(code, gc.Equals, 1)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+		c.Check(ctx.LastError(), gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *SilentSuite) TestUsageError(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"unknown"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(ctx.LastError(), gc.ErrorMatches, "unrecognized command: juju unknown")
+}
@@ -0,0 +1,277 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/yaml.v2"
+)
+
+// CommandSpecs is a file of command specifications, written in YAML or
+// JSON, e.g.
+//
+//	commands:
+//	- name: add-user
+//	  purpose: Adds a user.
+//	  args: <name>
+//	  min-args: 1
+//	  max-args: 1
+//	  handler: add-user
+//	  flags:
+//	  - name: admin
+//	    type: bool
+//	    usage: Make the user an administrator
+type CommandSpecs struct {
+	Commands []CommandSpec `json:"commands" yaml:"commands"`
+}
+
+// CommandSpec describes a command whose metadata is kept out of code. The
+// work of the command is done by the SpecHandler registered under the key
+// Handler.
+type CommandSpec struct {
+	Name     string     `json:"name" yaml:"name"`
+	Purpose  string     `json:"purpose,omitempty" yaml:"purpose,omitempty"`
+	Doc      string     `json:"doc,omitempty" yaml:"doc,omitempty"`
+	Args     string     `json:"args,omitempty" yaml:"args,omitempty"`
+	Aliases  []string   `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Category string     `json:"category,omitempty" yaml:"category,omitempty"`
+	Examples string     `json:"examples,omitempty" yaml:"examples,omitempty"`
+	SeeAlso  []string   `json:"see-also,omitempty" yaml:"see-also,omitempty"`
+	Flags    []FlagSpec `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Handler  string     `json:"handler" yaml:"handler"`
+
+	// MinArgs and MaxArgs bound the number of positional arguments. A
+	// nil MaxArgs allows any number.
+	MinArgs int  `json:"min-args,omitempty" yaml:"min-args,omitempty"`
+	MaxArgs *int `json:"max-args,omitempty" yaml:"max-args,omitempty"`
+}
+
+// FlagSpec describes a flag of a command defined by a CommandSpec.
+type FlagSpec struct {
+	Name    string   `json:"name" yaml:"name"`
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+
+	// Type is one of "string", "bool", "int", "duration" or "strings",
+	// a comma separated list. If empty, "string" is used.
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	Usage   string `json:"usage,omitempty" yaml:"usage,omitempty"`
+}
+
+// SpecHandler does the work of a command defined by a CommandSpec.
+type SpecHandler func(ctx *Context, input *SpecInput) error
+
+// SpecInput holds the flags and arguments a command defined by a
+// CommandSpec was run with.
+type SpecInput struct {
+	// Args are the positional arguments of the command.
+	Args []string
+
+	values map[string]interface{}
+}
+
+// String returns the value of the string flag with the given name.
+func (in *SpecInput) String(name string) string {
+	v, _ := in.values[name].(*string)
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+// Bool returns the value of the bool flag with the given name.
+func (in *SpecInput) Bool(name string) bool {
+	v, _ := in.values[name].(*bool)
+	return v != nil && *v
+}
+
+// Int returns the value of the int flag with the given name.
+func (in *SpecInput) Int(name string) int {
+	v, _ := in.values[name].(*int)
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// Duration returns the value of the duration flag with the given name.
+func (in *SpecInput) Duration(name string) time.Duration {
+	v, _ := in.values[name].(*time.Duration)
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// Strings returns the values of the strings flag with the given name.
+func (in *SpecInput) Strings(name string) []string {
+	v, _ := in.values[name].(*[]string)
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// ParseCommandSpecs parses a file of command specifications, in YAML or
+// JSON. Unknown fields are an error, to catch mistakes in the file.
+func ParseCommandSpecs(data []byte) ([]CommandSpec, error) {
+	var specs CommandSpecs
+	if err := yaml.UnmarshalStrict(data, &specs); err != nil {
+		return nil, errors.Annotate(err, "parsing command specs")
+	}
+	return specs.Commands, nil
+}
+
+// NewSpecCommand returns a Command defined by spec, which runs the handler
+// registered in handlers under spec.Handler. Its Info and flags come from
+// the spec, so that documentation, completion and schemas are generated
+// from the same definition as the command itself.
+func NewSpecCommand(spec CommandSpec, handlers map[string]SpecHandler) (Command, error) {
+	if spec.Name == "" {
+		return nil, errors.NotValidf("command spec without a name")
+	}
+	handler, ok := handlers[spec.Handler]
+	if !ok {
+		return nil, errors.NotFoundf("handler %q for command %q", spec.Handler, spec.Name)
+	}
+	for _, flag := range spec.Flags {
+		if _, err := newSpecFlagValue(flag); err != nil {
+			return nil, errors.Annotatef(err, "command %q", spec.Name)
+		}
+	}
+	if spec.MaxArgs != nil && *spec.MaxArgs < spec.MinArgs {
+		return nil, errors.NotValidf("command %q with max-args less than min-args", spec.Name)
+	}
+	return &specCommand{spec: spec, handler: handler}, nil
+}
+
+// RegisterCommandSpecs parses a file of command specifications and
+// registers a command for each of them with super.
+func RegisterCommandSpecs(super *SuperCommand, data []byte, handlers map[string]SpecHandler) error {
+	specs, err := ParseCommandSpecs(data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	commands := make([]Command, len(specs))
+	for i, spec := range specs {
+		if commands[i], err = NewSpecCommand(spec, handlers); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, command := range commands {
+		super.Register(command)
+	}
+	return nil
+}
+
+// specCommand is a Command defined by a CommandSpec.
+type specCommand struct {
+	CommandBase
+	spec    CommandSpec
+	handler SpecHandler
+	input   SpecInput
+}
+
+// Info implements Command.
+func (c *specCommand) Info() *Info {
+	return &Info{
+		Name:     c.spec.Name,
+		Args:     c.spec.Args,
+		Purpose:  c.spec.Purpose,
+		Doc:      c.spec.Doc,
+		Aliases:  c.spec.Aliases,
+		Category: c.spec.Category,
+		Examples: c.spec.Examples,
+		SeeAlso:  c.spec.SeeAlso,
+	}
+}
+
+// SetFlags implements Command.
+func (c *specCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.input.values = make(map[string]interface{})
+	for _, flag := range c.spec.Flags {
+		// The flags were checked by NewSpecCommand.
+		value, _ := newSpecFlagValue(flag)
+		c.input.values[flag.Name] = value.target
+		for _, name := range append([]string{flag.Name}, flag.Aliases...) {
+			f.Var(value.Value, name, flag.Usage)
+		}
+	}
+}
+
+// Init implements Command.
+func (c *specCommand) Init(args []string) error {
+	if len(args) < c.spec.MinArgs {
+		return errors.Errorf("expected at least %d %s", c.spec.MinArgs, plural(c.spec.MinArgs, "argument"))
+	}
+	if max := c.spec.MaxArgs; max != nil && len(args) > *max {
+		if *max == 0 {
+			return CheckEmpty(args)
+		}
+		return NewArgError(args[*max], *max, fmt.Sprintf("expected at most %d %s, got %d", *max, plural(*max, "argument"), len(args)))
+	}
+	c.input.Args = args
+	return nil
+}
+
+// Run implements Command.
+func (c *specCommand) Run(ctx *Context) error {
+	return c.handler(ctx, &c.input)
+}
+
+// plural returns word, followed by "s" unless n is 1.
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// specFlagValue is the value of a flag defined by a FlagSpec, and the
+// variable it sets.
+type specFlagValue struct {
+	gnuflag.Value
+	target interface{}
+}
+
+// newSpecFlagValue returns a new value for the flag, set to its default.
+func newSpecFlagValue(flag FlagSpec) (specFlagValue, error) {
+	if flag.Name == "" {
+		return specFlagValue{}, errors.NotValidf("flag spec without a name")
+	}
+	if flag.Type == "strings" {
+		var defaults []string
+		if flag.Default != "" {
+			defaults = strings.Split(flag.Default, ",")
+		}
+		target := new([]string)
+		return specFlagValue{Value: NewStringsValue(defaults, target), target: target}, nil
+	}
+	f := gnuflag.NewFlagSet("", gnuflag.ContinueOnError)
+	var target interface{}
+	switch flag.Type {
+	case "", "string":
+		target = f.String(flag.Name, "", "")
+	case "bool":
+		target = f.Bool(flag.Name, false, "")
+	case "int":
+		target = f.Int(flag.Name, 0, "")
+	case "duration":
+		target = f.Duration(flag.Name, 0, "")
+	default:
+		return specFlagValue{}, errors.NotValidf("type %q of flag %q", flag.Type, flag.Name)
+	}
+	value := f.Lookup(flag.Name).Value
+	if flag.Default != "" {
+		if err := value.Set(flag.Default); err != nil {
+			return specFlagValue{}, errors.Annotatef(err, "default %q of flag %q", flag.Default, flag.Name)
+		}
+	}
+	return specFlagValue{Value: value, target: target}, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type SpecSuite struct{}
+
+var _ = gc.Suite(&SpecSuite{})
+
+const userSpecs = `
+commands:
+- name: add-user
+  purpose: Adds a user.
+  args: <name>
+  aliases: [create-user]
+  min-args: 1
+  max-args: 1
+  handler: add-user
+  flags:
+  - name: admin
+    type: bool
+    usage: Make the user an administrator
+  - name: quota
+    aliases: [q]
+    type: int
+    default: "10"
+  - name: groups
+    type: strings
+    default: staff
+  - name: expires
+    type: duration
+  - name: display-name
+- name: list-users
+  purpose: Lists the users.
+  max-args: 0
+  handler: list-users
+`
+
+func (s *SpecSuite) handlers(calls *[]string) map[string]cmd.SpecHandler {
+	return map[string]cmd.SpecHandler{
+		"add-user": func(ctx *cmd.Context, in *cmd.SpecInput) error {
+			*calls = append(*calls, fmt.Sprintf("add-user %v admin=%v quota=%d groups=%v expires=%v name=%q",
+				in.Args, in.Bool("admin"), in.Int("quota"), in.Strings("groups"), in.Duration("expires"), in.String("display-name")))
+			return nil
+		},
+		"list-users": func(ctx *cmd.Context, in *cmd.SpecInput) error {
+			*calls = append(*calls, "list-users")
+			return nil
+		},
+	}
+}
+
+func (s *SpecSuite) TestRun(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		code   int
+		call   string
+		stderr string
+	}{{
+		args: []string{"add-user", "bob"},
+		call: `add-user [bob] admin=false quota=10 groups=[staff] expires=0s name=""`,
+	}, {
+		args: []string{"create-user", "--admin", "-q", "5", "--groups", "a,b", "--expires", "1h", "--display-name", "Bob", "bob"},
+		call: `add-user [bob] admin=true quota=5 groups=[a b] expires=1h0m0s name="Bob"`,
+	}, {
+		args: []string{"list-users"},
+		call: "list-users",
+	}, {
+		args:   []string{"add-user"},
+		code:   2,
+		stderr: "ERROR expected at least 1 argument\n",
+	}, {
+		args:   []string{"add-user", "bob", "alice"},
+		code:   2,
+		stderr: "ERROR expected at most 1 argument, got 2\n",
+	}, {
+		args:   []string{"list-users", "bob"},
+		code:   2,
+		stderr: "ERROR unrecognized args: [\"bob\"]\n",
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		var calls []string
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+		err := cmd.RegisterCommandSpecs(sc, []byte(userSpecs), s.handlers(&calls))
+		c.Assert(err, gc.IsNil)
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(sc, ctx, test.args)
+		c.Check(code, gc.Equals, test.code)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+		if test.call != "" {
+			c.Check(calls, gc.DeepEquals, []string{test.call})
+		} else {
+			c.Check(calls, gc.HasLen, 0)
+		}
+	}
+}
+
+func (s *SpecSuite) TestJSON(c *gc.C) {
+	specs, err := cmd.ParseCommandSpecs([]byte(`{"commands": [{"name": "status", "handler": "status", "see-also": ["show-unit"]}]}`))
+	c.Assert(err, gc.IsNil)
+	c.Assert(specs, gc.HasLen, 1)
+	c.Check(specs[0].Name, gc.Equals, "status")
+	c.Check(specs[0].SeeAlso, gc.DeepEquals, []string{"show-unit"})
+}
+
+func (s *SpecSuite) TestSchema(c *gc.C) {
+	var calls []string
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	err := cmd.RegisterCommandSpecs(sc, []byte(userSpecs), s.handlers(&calls))
+	c.Assert(err, gc.IsNil)
+	data, err := cmd.ExportSchema(sc)
+	c.Assert(err, gc.IsNil)
+	schema := string(data)
+	c.Check(strings.Contains(schema, `"name": "add-user"`), gc.Equals, true)
+	c.Check(strings.Contains(schema, `"create-user"`), gc.Equals, true)
+	c.Check(strings.Contains(schema, `"type": "time.Duration"`), gc.Equals, true)
+	c.Check(strings.Contains(schema, `"default": "10"`), gc.Equals, true)
+}
+
+func (s *SpecSuite) TestErrors(c *gc.C) {
+	for i, test := range []struct {
+		spec string
+		err  string
+	}{{
+		spec: "commands:\n- name: foo\n  handler: missing\n",
+		err:  `handler "missing" for command "foo" not found`,
+	}, {
+		spec: "commands:\n- handler: add-user\n",
+		err:  `command spec without a name not valid`,
+	}, {
+		spec: "commands:\n- name: foo\n  handler: add-user\n  flags:\n  - name: bar\n    type: float\n",
+		err:  `command "foo": type "float" of flag "bar" not valid`,
+	}, {
+		spec: "commands:\n- name: foo\n  handler: add-user\n  flags:\n  - name: bar\n    type: int\n    default: lots\n",
+		err:  `command "foo": default "lots" of flag "bar": .*`,
+	}, {
+		spec: "commands:\n- name: foo\n  handler: add-user\n  min-args: 2\n  max-args: 1\n",
+		err:  `command "foo" with max-args less than min-args not valid`,
+	}, {
+		spec: "commands:\n- name: foo\n  handler: add-user\n  purpose: Foo.\n  summary: Foo.\n",
+		err:  `(?s)parsing command specs: .*field summary not found.*`,
+	}} {
+		c.Logf("test %d", i)
+		var calls []string
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+		err := cmd.RegisterCommandSpecs(sc, []byte(test.spec), s.handlers(&calls))
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
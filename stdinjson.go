@@ -0,0 +1,107 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+)
+
+// stdinJSONFlag is the flag that makes a command read its flags and
+// arguments from stdin.
+const stdinJSONFlag = "stdin-json"
+
+// stdinInput is the JSON object read by --stdin-json. Flags are named as
+// in the command's schema, without leading dashes.
+type stdinInput struct {
+	Flags map[string]interface{} `json:"flags"`
+	Args  []interface{}          `json:"args"`
+}
+
+// readStdinJSON reads a JSON object from r, sets the flags it names in f
+// and returns its positional arguments. Strings, numbers and booleans are
+// given to flags and arguments as they would be written on the command
+// line; a list sets a flag once for each of its elements, and a null flag
+// is left unset.
+func readStdinJSON(r io.Reader, f *gnuflag.FlagSet) ([]string, error) {
+	if r == nil {
+		return nil, errors.New("no input for --" + stdinJSONFlag)
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	var input stdinInput
+	if err := decoder.Decode(&input); err != nil {
+		return nil, errors.Annotate(err, "reading --"+stdinJSONFlag+" input")
+	}
+
+	names := make([]string, 0, len(input.Flags))
+	for name := range input.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == stdinJSONFlag || f.Lookup(name) == nil {
+			return nil, errors.Errorf("unknown flag %q in --%s input", name, stdinJSONFlag)
+		}
+		values, ok := input.Flags[name].([]interface{})
+		if !ok {
+			values = []interface{}{input.Flags[name]}
+		}
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+			s, err := jsonScalar(value)
+			if err != nil {
+				return nil, errors.Annotatef(err, "flag %q", name)
+			}
+			if err := f.Set(name, s); err != nil {
+				return nil, errors.Annotatef(err, "invalid value %q for flag %q", s, name)
+			}
+		}
+	}
+
+	args := make([]string, len(input.Args))
+	for i, value := range input.Args {
+		s, err := jsonScalar(value)
+		if err != nil {
+			return nil, errors.Annotatef(err, "argument %d", i+1)
+		}
+		args[i] = s
+	}
+	return args, nil
+}
+
+// jsonScalar returns the command line form of a decoded JSON string,
+// number or boolean.
+func jsonScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", errors.Errorf("expected a string, number or boolean, got %s", jsonKind(value))
+}
+
+// jsonKind returns the name of the JSON type of a decoded value.
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"strings"
+
+	"github.com/juju/gnuflag"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type StdinJSONSuite struct{}
+
+var _ = gc.Suite(&StdinJSONSuite{})
+
+// flagsCommand records its flags and the arguments it is initialised with.
+type flagsCommand struct {
+	cmd.CommandBase
+	model string
+	force bool
+	count int
+	args  []string
+}
+
+func (c *flagsCommand) Info() *cmd.Info {
+	return &cmd.Info{Name: "deploy", Purpose: "deploy an application"}
+}
+
+func (c *flagsCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.model, "m", "", "")
+	f.StringVar(&c.model, "model", "", "the model to deploy to")
+	f.BoolVar(&c.force, "force", false, "deploy regardless")
+	f.IntVar(&c.count, "n", 1, "the number of units")
+}
+
+func (c *flagsCommand) Init(args []string) error {
+	c.args = args
+	return nil
+}
+
+func (c *flagsCommand) Run(ctx *cmd.Context) error {
+	return nil
+}
+
+func (s *StdinJSONSuite) run(c *gc.C, enabled bool, stdin string, args ...string) (*flagsCommand, *cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:      "juju",
+		StdinJSON: enabled,
+	})
+	deploy := &flagsCommand{}
+	sc.Register(deploy)
+	ctx := cmdtesting.Context(c)
+	ctx.Stdin = strings.NewReader(stdin)
+	code := cmd.Main(sc, ctx, args)
+	return deploy, ctx, code
+}
+
+func (s *StdinJSONSuite) TestFlagsAndArgs(c *gc.C) {
+	deploy, ctx, code := s.run(c, true,
+		`{"flags": {"model": "prod", "force": true, "n": 3}, "args": ["it's \"quoted\"", 42]}`,
+		"deploy", "--stdin-json")
+	c.Assert(code, gc.Equals, 0, gc.Commentf("%s", cmdtesting.Stderr(ctx)))
+	c.Check(deploy.model, gc.Equals, "prod")
+	c.Check(deploy.force, gc.Equals, true)
+	c.Check(deploy.count, gc.Equals, 3)
+	c.Check(deploy.args, gc.DeepEquals, []string{`it's "quoted"`, "42"})
+}
+
+func (s *StdinJSONSuite) TestFlagAliasAndNull(c *gc.C) {
+	deploy, ctx, code := s.run(c, true, `{"flags": {"m": "dev", "force": null}}`, "deploy", "--stdin-json")
+	c.Assert(code, gc.Equals, 0, gc.Commentf("%s", cmdtesting.Stderr(ctx)))
+	c.Check(deploy.model, gc.Equals, "dev")
+	c.Check(deploy.force, gc.Equals, false)
+	c.Check(deploy.args, gc.HasLen, 0)
+}
+
+func (s *StdinJSONSuite) TestNotEnabled(c *gc.C) {
+	_, ctx, code := s.run(c, false, `{}`, "deploy", "--stdin-json")
+	c.Check(code, gc.Equals, 2)
+	c.Check(cmdtesting.Stderr(ctx), gc.Equals, "ERROR flag provided but not defined: --stdin-json\n")
+}
+
+func (s *StdinJSONSuite) TestNotGiven(c *gc.C) {
+	deploy, _, code := s.run(c, true, `{"flags": {"model": "prod"}}`, "deploy", "app")
+	c.Check(code, gc.Equals, 0)
+	c.Check(deploy.model, gc.Equals, "")
+	c.Check(deploy.args, gc.DeepEquals, []string{"app"})
+}
+
+func (s *StdinJSONSuite) TestErrors(c *gc.C) {
+	for i, test := range []struct {
+		stdin string
+		args  []string
+		err   string
+	}{{
+		stdin: `{"flags": {"region": "east"}}`,
+		err:   `unknown flag "region" in --stdin-json input`,
+	}, {
+		stdin: `{"flags": {"stdin-json": true}}`,
+		err:   `unknown flag "stdin-json" in --stdin-json input`,
+	}, {
+		stdin: `{"flags": {"n": "many"}}`,
+		err:   `invalid value "many" for flag "n": .*`,
+	}, {
+		stdin: `{"flags": {"model": {"name": "prod"}}}`,
+		err:   `flag "model": expected a string, number or boolean, got object`,
+	}, {
+		stdin: `{"args": [["app"]]}`,
+		err:   `argument 1: expected a string, number or boolean, got list`,
+	}, {
+		stdin: `{"arguments": ["app"]}`,
+		err:   `reading --stdin-json input: json: unknown field "arguments"`,
+	}, {
+		stdin: `not json`,
+		err:   `reading --stdin-json input: .*`,
+	}, {
+		stdin: `{}`,
+		args:  []string{"app"},
+		err:   `arguments cannot be given with --stdin-json`,
+	}} {
+		c.Logf("test %d: %s", i, test.stdin)
+		_, ctx, code := s.run(c, true, test.stdin, append([]string{"deploy", "--stdin-json"}, test.args...)...)
+		c.Check(code, gc.Equals, 2)
+		c.Check(cmdtesting.Stderr(ctx), gc.Matches, "ERROR "+test.err+"\n")
+	}
+}
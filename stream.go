@@ -0,0 +1,116 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/juju/errors"
+)
+
+// StreamWriter writes a stream of records, formatted one at a time, as
+// directed by the --format and --output command line flags.
+type StreamWriter interface {
+	// Write formats value and writes it to the stream, flushing the
+	// output so that it can be read straight away.
+	Write(value interface{}) error
+
+	// Close ends the stream.
+	Close() error
+}
+
+// flusher is implemented by buffered writers.
+type flusher interface {
+	Flush() error
+}
+
+// BeginStream starts a stream of records to the output directed by the
+// --output command line flag, for commands that produce many records over
+// time, such as watchers and log tails. Each record is formatted as soon
+// as it is written, so JSON is written as newline-delimited JSON and YAML
+// as a sequence of YAML documents. Output processors are run on each
+// record, but streamed output is never truncated. The stream must be
+// closed when the command is done writing to it.
+func (c *Output) BeginStream(ctx *Context) (StreamWriter, error) {
+	s := &outputStream{
+		ctx:        ctx,
+		format:     c.formatter.name,
+		formatter:  c.formatter.formatter(),
+		processors: c.processors,
+		target:     ctx.Stdout,
+	}
+	if c.outPath != "" {
+		f, err := os.Create(ctx.AbsPath(c.outPath))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		s.target, s.file = f, f
+	}
+	if c.color != "" {
+		ctx.SetColorMode(c.color)
+	}
+	s.color = ctx.ColorEnabled(s.target)
+	s.terminal = isTerminal(s.target)
+	return s, nil
+}
+
+// outputStream is the StreamWriter returned by Output.BeginStream.
+type outputStream struct {
+	ctx        *Context
+	format     string
+	formatter  Formatter
+	processors []OutputProcessor
+	target     io.Writer
+	file       *os.File
+	color      bool
+	terminal   bool
+	written    bool
+	closed     bool
+}
+
+// Write implements StreamWriter.
+func (s *outputStream) Write(value interface{}) error {
+	if s.closed {
+		return errors.New("write to closed stream")
+	}
+	var buf bytes.Buffer
+	if s.written && s.format == "yaml" {
+		buf.WriteString("---\n")
+	}
+	if err := s.formatter(newColorWriter(&buf, s.color), value); err != nil {
+		return errors.Trace(err)
+	}
+	output := buf.Bytes()
+	info := OutputInfo{Format: s.format, Terminal: s.terminal, Color: s.color}
+	for _, p := range s.processors {
+		var err error
+		if output, err = p(info, output); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if _, err := s.target.Write(output); err != nil {
+		return errors.Trace(err)
+	}
+	s.written = true
+	if f, ok := s.target.(flusher); ok {
+		return errors.Trace(f.Flush())
+	}
+	return nil
+}
+
+// Close implements StreamWriter.
+func (s *outputStream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	// Suppress the handling of errors on stdout when a machine formatter is used.
+	s.ctx.outputFormatUsed = true
+	if s.file != nil {
+		return errors.Trace(s.file.Close())
+	}
+	return nil
+}
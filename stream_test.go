@@ -0,0 +1,124 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type StreamSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&StreamSuite{})
+
+// streamCommand streams its records with Output.BeginStream.
+type streamCommand struct {
+	OutputCommand
+	records []interface{}
+}
+
+func (c *streamCommand) Run(ctx *cmd.Context) error {
+	stream, err := c.out.BeginStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for _, record := range c.records {
+		if err := stream.Write(record); err != nil {
+			return err
+		}
+	}
+	return stream.Close()
+}
+
+var streamRecords = []interface{}{
+	map[string]string{"unit": "app/0", "status": "active"},
+	map[string]string{"unit": "app/1", "status": "blocked"},
+}
+
+func (s *StreamSuite) TestStreamJSON(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&streamCommand{records: streamRecords}, ctx, []string{"--format", "json"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, ""+
+		`{"status":"active","unit":"app/0"}`+"\n"+
+		`{"status":"blocked","unit":"app/1"}`+"\n")
+}
+
+func (s *StreamSuite) TestStreamYAML(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(&streamCommand{records: streamRecords}, ctx, []string{"--format", "yaml"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, ""+
+		"status: active\nunit: app/0\n"+
+		"---\n"+
+		"status: blocked\nunit: app/1\n")
+}
+
+func (s *StreamSuite) TestStreamToFile(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	path := filepath.Join(c.MkDir(), "out.json")
+	code := cmd.Main(&streamCommand{records: streamRecords}, ctx, []string{"--format", "json", "--output", path})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+	data, err := os.ReadFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, ""+
+		`{"status":"active","unit":"app/0"}`+"\n"+
+		`{"status":"blocked","unit":"app/1"}`+"\n")
+}
+
+func (s *StreamSuite) TestStreamProcessors(c *gc.C) {
+	command := &streamCommand{records: []interface{}{"one", "two"}}
+	command.out.AddProcessor(func(info cmd.OutputInfo, output []byte) ([]byte, error) {
+		return bytes.ToUpper(output), nil
+	})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(command, ctx, nil)
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "ONE\nTWO\n")
+}
+
+func (s *StreamSuite) TestStreamFlushes(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	stdout := &flushingWriter{}
+	ctx.Stdout = stdout
+	code := cmd.Main(&streamCommand{records: streamRecords}, ctx, []string{"--format", "json"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(stdout.flushed, gc.DeepEquals, []string{
+		`{"status":"active","unit":"app/0"}` + "\n",
+		`{"status":"active","unit":"app/0"}` + "\n" + `{"status":"blocked","unit":"app/1"}` + "\n",
+	})
+}
+
+func (s *StreamSuite) TestWriteAfterClose(c *gc.C) {
+	command := &OutputCommand{}
+	ctx := cmdtesting.Context(c)
+	c.Assert(cmdtesting.InitCommand(command, nil), jc.ErrorIsNil)
+	stream, err := command.out.BeginStream(ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stream.Close(), jc.ErrorIsNil)
+	c.Assert(stream.Write("one"), gc.ErrorMatches, "write to closed stream")
+}
+
+// flushingWriter records what had been written each time it is flushed.
+type flushingWriter struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (w *flushingWriter) Flush() error {
+	w.flushed = append(w.flushed, w.String())
+	return nil
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"errors"
+	"sort"
 	"strings"
 )
 
@@ -49,3 +50,13 @@ func (m StringMap) String() string {
 	}
 	return strings.Join(pairs, ";")
 }
+
+// elements implements repeatedValue.
+func (m StringMap) elements() []string {
+	pairs := make([]string, 0, len(*m.Mapping))
+	for key, value := range *m.Mapping {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
@@ -4,10 +4,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/gnuflag"
@@ -23,6 +28,15 @@ type topic struct {
 	// Help aliases are not output when topics are listed, but are used
 	// to search for the help topic
 	alias bool
+	// name is the name the topic was added with, which differs from the
+	// name an alias is found by.
+	name string
+	// markdown is true when long returns Markdown, which is rendered as
+	// plain text by the help command.
+	markdown bool
+	// category groups the topic with related topics when topics are
+	// listed.
+	category string
 }
 
 // UnrecognizedCommand defines an error that specifies when a command is not
@@ -49,10 +63,35 @@ func (e *UnrecognizedCommand) Error() string {
 	return e.message
 }
 
+// NoArgsAction determines what a SuperCommand does when it is run without
+// any arguments.
+type NoArgsAction int
+
+const (
+	// NoArgsShowHelp shows the help for the SuperCommand.
+	NoArgsShowHelp NoArgsAction = iota
+
+	// NoArgsShowUsage writes a short usage hint to Stderr, and Main exits
+	// with code 2.
+	NoArgsShowUsage
+
+	// NoArgsRunCommand runs the subcommand named by NoArgsCommand, or by
+	// DefaultCommand if NoArgsCommand is empty.
+	NoArgsRunCommand
+)
+
 // MissingCallback defines a function that will be used by the SuperCommand if
 // the requested subcommand isn't found.
 type MissingCallback func(ctx *Context, subcommand string, args []string) error
 
+// MissingCallbackWithFlags defines a function that will be used by the
+// SuperCommand if the requested subcommand isn't found. Unlike
+// MissingCallback, any common flags (e.g. --debug, --show-log) found in the
+// subcommand's arguments are parsed and applied before the callback is
+// called. The flags that were set are passed in flags, keyed by name, and
+// the remaining arguments in args.
+type MissingCallbackWithFlags func(ctx *Context, subcommand string, flags map[string]string, args []string) error
+
 // SuperCommandParams provides a way to have default parameter to the
 // `NewSuperCommand` call.
 type SuperCommandParams struct {
@@ -66,6 +105,12 @@ type SuperCommandParams struct {
 	// is about to run a sub-command.
 	NotifyRun func(cmdName string)
 
+	// NotifyFinish, if not nil, is called when a subcommand has finished,
+	// with the full path of the command, the error it returned, if any,
+	// and how long it ran for. Nested super commands without a
+	// NotifyFinish of their own use that of their parent.
+	NotifyFinish func(cmdName string, err error, duration time.Duration)
+
 	// NotifyHelp is called just before help is printed, with the
 	// arguments received by the help command. This can be
 	// used, for example, to load command information for external
@@ -73,6 +118,11 @@ type SuperCommandParams struct {
 	// in the help output.
 	NotifyHelp func([]string)
 
+	// NotifyHelpRequest, if not nil, is called like NotifyHelp, but once
+	// the command help was requested for has been found, with the details
+	// of the request.
+	NotifyHelpRequest func(HelpRequest)
+
 	Name     string
 	Purpose  string
 	Doc      string
@@ -84,8 +134,11 @@ type SuperCommandParams struct {
 	// supercommand which will also be available on all subcommands.
 	GlobalFlags     FlagAdder
 	MissingCallback MissingCallback
-	Aliases         []string
-	Version         string
+	// MissingCallbackWithFlags, if not nil, is used in preference to
+	// MissingCallback when the requested subcommand isn't found.
+	MissingCallbackWithFlags MissingCallbackWithFlags
+	Aliases                  []string
+	Version                  string
 	// VersionDetail is a freeform information that is output when the default version
 	// subcommand is passed --all. Output is formatted using the user-selected formatter.
 	// Exported fields should specify yaml and json field tags.
@@ -112,6 +165,214 @@ type SuperCommandParams struct {
 	// documentation command is at the wrong abstraction, so we need to
 	// hack around it.
 	SkipCommandDoc bool
+
+	// EnabledCommands, if not empty, restricts the commands that can be
+	// registered to those named. Commands not in the list, along with any
+	// aliases for them, are silently skipped at registration time, which
+	// allows cut-down variants of a CLI to be built from the same
+	// registration code.
+	EnabledCommands []string
+
+	// DisabledCommands names commands and aliases that will not be
+	// registered, even if they appear in EnabledCommands.
+	DisabledCommands []string
+
+	// Recorder, if not nil, is notified of help and usage error events.
+	// Nested super commands without a Recorder of their own use that of
+	// their parent.
+	Recorder Recorder
+
+	// SuppressWarnings holds the codes of warnings that should not be
+	// emitted when running subcommands.
+	SuppressWarnings []WarningCode
+
+	// UserConfigFilename is the file holding the default flag values of
+	// subcommands, which users view and change with the built-in "config"
+	// subcommand. If empty, the "config" subcommand is not registered. A
+	// leading "~" is replaced with the user's home directory. The defaults
+	// apply to flags not given on the command line, or in the environment
+	// when FlagEnvPrefix is set.
+	UserConfigFilename string
+
+	// FlagEnvPrefix, if not empty, makes a flag of a subcommand that is
+	// not given on the command line default to the environment variable
+	// named by the prefix followed by the flag's long name in upper case,
+	// with dashes replaced by underscores, e.g. JUJU_FORMAT for --format
+	// with the prefix "JUJU_". Common flags, such as --help and --debug,
+	// are not read from the environment.
+	FlagEnvPrefix string
+
+	// Changelog, if not nil, returns the changelog entries of the command,
+	// newest first, and enables the built-in "whatsnew" subcommand. It is
+	// typically fed from an embedded file.
+	Changelog func() ([]ChangelogEntry, error)
+
+	// DataDir is the directory in which the command framework tracks state
+	// between runs, such as the version of the last run. A leading "~" is
+	// replaced with the user's home directory. When DataDir, Changelog and
+	// Version are all set, a one line notice is shown after an upgrade.
+	DataDir string
+
+	// UsageStats enables counting how often each subcommand is run, and
+	// fails, in DataDir, and adds the built-in "stats" subcommand to show
+	// the counts. The counts are recorded after NotifyFinish is called,
+	// and only the names of commands are recorded. Users opt out with the
+	// --no-telemetry flag or the DO_NOT_TRACK environment variable. It has
+	// no effect unless DataDir is set.
+	UsageStats bool
+
+	// UsageUploader, if not nil, is given the usage counts after each
+	// subcommand is recorded, e.g. to send them to a server the user has
+	// agreed to. Without it, the counts never leave the machine.
+	UsageUploader UsageUploader
+
+	// SaveFailedOutputLines, if greater than zero, saves the output of
+	// a subcommand that fails after writing at least this many lines to
+	// Stdout and Stderr, to a file named after the command and the time
+	// in the "failed-output" directory of DataDir. The path of the file is
+	// shown after the error, to help debug intermittent failures. It has
+	// no effect unless DataDir is set.
+	SaveFailedOutputLines int
+
+	// ExpandArgFiles enables "@file" arguments, which are replaced with
+	// the whitespace separated arguments read from the file before they
+	// are parsed. This is useful when lists of targets exceed the limits of
+	// the shell. It also adds the --save-invocation flag, which writes the
+	// resolved arguments of a command to a file in the same format, so
+	// that it can be run again later.
+	ExpandArgFiles bool
+
+	// StdinJSON adds the --stdin-json flag, which makes a command read its
+	// flags and arguments from a JSON object on stdin instead of the
+	// command line, e.g. {"flags": {"model": "prod"}, "args": ["app"]}.
+	// Flags are named as in the schema returned by ExportSchema. This lets
+	// programs run commands without having to quote their arguments.
+	StdinJSON bool
+
+	// NoRemoteFlag adds the --no-remote flag, which prevents any network
+	// access by the framework: usage statistics are not uploaded, the
+	// requirements of subcommands are not checked against Capabilities,
+	// and the HTTP client of the Context refuses all requests. Nested
+	// super commands add the flag if their parent does.
+	NoRemoteFlag bool
+
+	// TimeFlag adds the --time flag, which reports how long the command
+	// took to run, and how long was spent in each phase, on Stderr when
+	// it finishes. Nested super commands add the flag if their parent
+	// does.
+	TimeFlag bool
+
+	// CancelOnShutdownSignals makes Main cancel the Context while the
+	// command runs when the process receives one of ShutdownSignals, so
+	// that long running commands can stop gracefully. Only the first
+	// signal is caught, so that another one terminates a command that
+	// does not stop. Otherwise the signals have their default effect.
+	CancelOnShutdownSignals bool
+
+	// ErrorRenderer, if not nil, writes errors that stop a subcommand in
+	// place of WriteError, so that applications can use their own style,
+	// e.g. PrefixErrorRenderer("error: ").
+	ErrorRenderer ErrorRenderer
+
+	// PartialSuccessExitCode is the exit code used when a subcommand
+	// fails with a BulkError that also recorded successes. If zero,
+	// PartialSuccessExitCode is used.
+	PartialSuccessExitCode int
+
+	// ExitCodes maps the categories of the errors that stop a subcommand
+	// to the codes that Main exits with, so that scripts can tell failures
+	// apart, e.g. {"usage": 2, "not-found": 4, "unauthorized": 5}. The
+	// categories are "usage" for errors found before the subcommand runs,
+	// and "cancelled", "timeout", "not-found", "unauthorized", "forbidden",
+	// "not-valid", "already-exists", "not-supported" and "requirement".
+	// Errors in other categories exit with code 1. Codes given with
+	// ErrWithCode take precedence, followed by those of a BulkError
+	// (BulkErrorExitCode or the partial success code), so avoid mapping
+	// categories to those codes.
+	ExitCodes map[string]int
+
+	// ShellCompletion enables the built-in "completion" subcommand, which
+	// prints a script that completes subcommands and flags in bash, zsh,
+	// fish or PowerShell, and the hidden "__complete" subcommand the
+	// scripts run to complete the arguments of commands that implement
+	// Completer.
+	ShellCompletion bool
+
+	// HelpShowsNestedSubcommands lists the subcommands of each subcommand
+	// in the help for a nested super command, indented below their parent,
+	// so that users can find deeper commands without asking for help at
+	// each level.
+	HelpShowsNestedSubcommands bool
+
+	// ListCommands enables the built-in "commands" subcommand, which lists
+	// every registered command with its purpose, category and status, in
+	// a form suited to scripts and tooling.
+	ListCommands bool
+
+	// DynamicCommands, if not nil, returns subcommands that are registered
+	// in addition to those registered with Register, each time the command
+	// line is dispatched with a Context, such as by Main. This allows the
+	// command set to depend on runtime state, such as the features of the
+	// connected controller or the installed plugins. Help, documentation
+	// and completion reflect the commands returned. Commands whose names
+	// are already in use are not registered.
+	DynamicCommands func(ctx *Context) []Command
+
+	// Capabilities, if not nil, describes the server that subcommands talk
+	// to. Before a subcommand is run, the MinServerVersion and
+	// RequiredFeatures in its Info are checked against it, and a
+	// *RequirementError is returned if they are not met. The checks are
+	// skipped when --no-remote is given.
+	Capabilities CapabilitySource
+
+	// ServerKnownAs is what the server is called in the errors returned
+	// when a subcommand's requirements are not met, e.g. "controller". If
+	// empty, "server" is used.
+	ServerKnownAs string
+
+	// NoArgsAction determines what the SuperCommand does when it is run
+	// without any arguments. By default, the help is shown.
+	NoArgsAction NoArgsAction
+
+	// NoArgsCommand is the name of the subcommand that is run when the
+	// SuperCommand is run without any arguments and NoArgsAction is
+	// NoArgsRunCommand, e.g. "status". NewSuperCommand panics if
+	// NoArgsAction is NoArgsRunCommand and neither NoArgsCommand nor
+	// DefaultCommand is set.
+	NoArgsCommand string
+
+	// DefaultCommand, if not empty, names the subcommand that is run,
+	// with all of the arguments, when the first argument is not the name
+	// of a subcommand, e.g. "app mymodel" runs "app switch mymodel". So
+	// that mistyped commands are still reported, the default subcommand is
+	// not run if the first argument is within one edit (two, for names of
+	// four or more characters) of the name of a visible subcommand.
+	// DefaultCommand takes precedence over MissingCallback.
+	DefaultCommand string
+
+	// CompletionLogLevel is the level at which the completion of each
+	// subcommand is logged, with its path, duration, exit code and, if it
+	// failed, the category of the error. If unspecified, INFO is used.
+	CompletionLogLevel loggo.Level
+
+	// ChainSeparator, if not empty, allows several subcommands to be run
+	// by one invocation, separated by the given argument, e.g. with "--",
+	// "app deploy mysql -- expose mysql". Each subcommand is run by
+	// Main in turn, as if it had been invoked alone, with the same
+	// Context, until one fails. As the separator cannot then be given as
+	// an argument, it should not be one that subcommands accept.
+	ChainSeparator string
+
+	// SuggestCommands adds the names of subcommands that are within a
+	// couple of edits of an unrecognized command to the error, e.g.
+	// `unrecognized command: juju statuss — did you mean "status"?`.
+	SuggestCommands bool
+
+	// Middleware wraps the running of each subcommand, e.g. to check
+	// authorisation, record telemetry or guard features behind flags. The
+	// first Middleware is outermost. Nested super commands without any
+	// Middleware of their own use that of their parent.
+	Middleware []Middleware
 }
 
 // FlagAdder represents a value that has associated flags.
@@ -123,6 +384,9 @@ type FlagAdder interface {
 // NewSuperCommand creates and initializes a new `SuperCommand`, and returns
 // the fully initialized structure.
 func NewSuperCommand(params SuperCommandParams) *SuperCommand {
+	if params.NoArgsAction == NoArgsRunCommand && params.NoArgsCommand == "" && params.DefaultCommand == "" {
+		panic(fmt.Sprintf("%q: NoArgsRunCommand requires NoArgsCommand or DefaultCommand", params.Name))
+	}
 	command := &SuperCommand{
 		Name:     params.Name,
 		Purpose:  params.Purpose,
@@ -134,13 +398,54 @@ func NewSuperCommand(params SuperCommandParams) *SuperCommand {
 		globalFlags:         params.GlobalFlags,
 		usagePrefix:         params.UsagePrefix,
 		missingCallback:     params.MissingCallback,
+		missingFlagCallback: params.MissingCallbackWithFlags,
 		version:             params.Version,
 		versionDetail:       params.VersionDetail,
 		notifyRun:           params.NotifyRun,
+		notifyFinish:        params.NotifyFinish,
 		notifyHelp:          params.NotifyHelp,
+		notifyHelpRequest:   params.NotifyHelpRequest,
 		userAliasesFilename: params.UserAliasesFilename,
+		userConfigFilename:  params.UserConfigFilename,
+		flagEnvPrefix:       params.FlagEnvPrefix,
+		changelog:           params.Changelog,
+		dataDir:             params.DataDir,
+		usageStats:          params.UsageStats && params.DataDir != "",
+		usageUploader:       params.UsageUploader,
+		failedOutputLines:   params.SaveFailedOutputLines,
+		expandArgFiles:      params.ExpandArgFiles,
+		stdinJSON:           params.StdinJSON,
+		noRemoteFlag:        params.NoRemoteFlag,
+		timeFlag:            params.TimeFlag,
+		cancelOnSignals:     params.CancelOnShutdownSignals,
+		renderer:            params.ErrorRenderer,
 		FlagKnownAs:         params.FlagKnownAs,
 		SkipCommandDoc:      params.SkipCommandDoc,
+		enabledCommands:     params.EnabledCommands,
+		disabledCommands:    params.DisabledCommands,
+		recorder:            params.Recorder,
+		suppressWarnings:    params.SuppressWarnings,
+		shellCompletion:     params.ShellCompletion,
+		listCommands:        params.ListCommands,
+		dynamicCommands:     params.DynamicCommands,
+		capabilities:        params.Capabilities,
+		serverKnownAs:       params.ServerKnownAs,
+		noArgsAction:        params.NoArgsAction,
+		noArgsCommand:       params.NoArgsCommand,
+		defaultCommand:      params.DefaultCommand,
+		completionLogLevel:  params.CompletionLogLevel,
+		chainSeparator:      params.ChainSeparator,
+		suggestCommands:     params.SuggestCommands,
+		middleware:          params.Middleware,
+		exitCodes:           params.ExitCodes,
+		nestedHelp:          params.HelpShowsNestedSubcommands,
+	}
+	command.partialExitCode = params.PartialSuccessExitCode
+	if command.partialExitCode == 0 {
+		command.partialExitCode = PartialSuccessExitCode
+	}
+	if command.serverKnownAs == "" {
+		command.serverKnownAs = "server"
 	}
 	command.init()
 	return command
@@ -164,6 +469,10 @@ type commandReference struct {
 	command Command
 	alias   string
 	check   DeprecationCheck
+	// newCommand is set for commands registered with RegisterFunc, and
+	// creates the command that is run with the dependencies derived from
+	// the Context it is dispatched with.
+	newCommand NewCommandFunc
 }
 
 // SuperCommand is a Command that selects a subcommand and assumes its
@@ -183,20 +492,75 @@ type SuperCommand struct {
 	versionDetail       interface{}
 	usagePrefix         string
 	userAliasesFilename string
+	userConfigFilename  string
+	flagEnvPrefix       string
+	// configPrefix is the path of a nested SuperCommand below the one
+	// whose user config file it uses.
+	configPrefix        string
+	changelog           func() ([]ChangelogEntry, error)
+	dataDir             string
+	usageStats          bool
+	usageUploader       UsageUploader
+	usageStatsDir       string
+	noTelemetry         bool
+	failedOutputLines   int
+	failedOutputDir     string
+	expandArgFiles      bool
+	recordInvocation    bool
+	invocationFile      string
+	invocation          []string
+	defaulted           map[interface{}]bool
+	stdinJSON           bool
+	readStdinJSON       bool
+	renderer            ErrorRenderer
 	userAliases         map[string][]string
 	subcmds             map[string]commandReference
 	help                *helpCommand
 	documentation       *documentationCommand
+	doctor              *doctorCommand
 	commonflags         *gnuflag.FlagSet
 	flags               *gnuflag.FlagSet
 	action              commandReference
 	showHelp            bool
+	ignoredArgs         []string
 	showDescription     bool
 	showVersion         bool
 	noAlias             bool
+	viaUserAlias        bool
+	noRemoteFlag        bool
+	noRemote            bool
+	timeFlag            bool
+	cancelOnSignals     bool
+	showTime            bool
+	preview             bool
 	missingCallback     MissingCallback
+	missingFlagCallback MissingCallbackWithFlags
 	notifyRun           func(string)
+	notifyFinish        func(string, error, time.Duration)
 	notifyHelp          func([]string)
+	notifyHelpRequest   func(HelpRequest)
+	enabledCommands     []string
+	disabledCommands    []string
+	recorder            Recorder
+	suppressWarnings    []WarningCode
+	partialExitCode     int
+	exitCodes           map[string]int
+	nestedHelp          bool
+	nested              bool
+	shellCompletion     bool
+	listCommands        bool
+	dynamicCommands     func(*Context) []Command
+	dynamicNames        []string
+	dynamicContext      *Context
+	capabilities        CapabilitySource
+	serverKnownAs       string
+	noArgsAction        NoArgsAction
+	noArgsCommand       string
+	defaultCommand      string
+	completionLogLevel  loggo.Level
+	chainSeparator      string
+	suggestCommands     bool
+	middleware          []Middleware
 
 	// FlagKnownAs allows different projects to customise what their flags are
 	// known as, e.g. 'flag', 'option', 'item'. All error/log messages
@@ -220,6 +584,11 @@ func (c *SuperCommand) IsSuperCommand() bool {
 	return true
 }
 
+// errorRenderer returns the renderer used by Main for errors from c.
+func (c *SuperCommand) errorRenderer() ErrorRenderer {
+	return c.renderer
+}
+
 func (c *SuperCommand) init() {
 	if c.subcmds != nil {
 		return
@@ -249,6 +618,40 @@ func (c *SuperCommand) init() {
 			command: newVersionCommand(c.version, c.versionDetail),
 		}
 	}
+	if c.changelog != nil {
+		c.subcmds["whatsnew"] = commandReference{
+			command: &whatsNewCommand{super: c},
+			name:    "whatsnew",
+		}
+	}
+	if c.shellCompletion {
+		c.subcmds["completion"] = commandReference{
+			command: &completionCommand{super: c},
+			name:    "completion",
+		}
+		c.subcmds["__complete"] = commandReference{
+			command: &completeCommand{super: c},
+			name:    "__complete",
+		}
+	}
+	if c.listCommands {
+		c.subcmds["commands"] = commandReference{
+			command: &commandsCommand{super: c},
+			name:    "commands",
+		}
+	}
+	if c.usageStats {
+		c.subcmds["stats"] = commandReference{
+			command: &statsCommand{super: c},
+			name:    "stats",
+		}
+	}
+	if c.userConfigFilename != "" {
+		c.subcmds["config"] = commandReference{
+			command: &configCommand{super: c},
+			name:    "config",
+		}
+	}
 
 	c.userAliases = ParseAliasFile(c.userAliasesFilename)
 }
@@ -256,24 +659,65 @@ func (c *SuperCommand) init() {
 // AddHelpTopic adds a new help topic with the description being the short
 // param, and the full text being the long param.  The description is shown in
 // 'help topics', and the full text is shown when the command 'help <name>' is
-// called.
+// called. The full text is also shown for 'help <alias>' with any of the
+// given aliases, which are not listed in 'help topics'. Adding a topic or
+// alias with a name already in use panics.
 func (c *SuperCommand) AddHelpTopic(name, short, long string, aliases ...string) {
-	c.help.addTopic(name, short, echo(long), aliases...)
+	c.help.addTopic(name, topic{short: short, long: echo(long)}, aliases...)
 }
 
 // AddHelpTopicCallback adds a new help topic with the description being the
 // short param, and the full text being defined by the callback function.
-func (c *SuperCommand) AddHelpTopicCallback(name, short string, longCallback func() string) {
-	c.help.addTopic(name, short, longCallback)
+// Aliases are handled as by AddHelpTopic.
+func (c *SuperCommand) AddHelpTopicCallback(name, short string, longCallback func() string, aliases ...string) {
+	c.help.addTopic(name, topic{short: short, long: longCallback}, aliases...)
+}
+
+// SetHelpTopicCategory groups the named help topics under a heading for
+// category in 'help topics'. Topics without a category are listed first.
+// An empty category moves the topics back to the first group. Naming a
+// topic that has not been added panics.
+func (c *SuperCommand) SetHelpTopicCategory(category string, names ...string) {
+	for _, name := range names {
+		c.help.setCategory(name, category)
+	}
+}
+
+// AddMarkdownHelpTopic adds a new help topic like AddHelpTopic, with the
+// full text written in Markdown. The command 'help <name>' shows it as
+// plain text, while the documentation keeps the Markdown.
+func (c *SuperCommand) AddMarkdownHelpTopic(name, short, long string, aliases ...string) {
+	c.help.addTopic(name, topic{short: short, long: echo(long), markdown: true}, aliases...)
 }
 
 // Register makes a subcommand available for use on the command line. The
 // command will be available via its own name, and via any supplied aliases.
 func (c *SuperCommand) Register(subcmd Command) {
+	c.register(subcmd, nil)
+}
+
+// RegisterFunc makes a subcommand created by newCommand available for use
+// on the command line, in the same way as Register. The command that is
+// run is created when it is dispatched to, with Dependencies derived from
+// the Context. For its Info, help and documentation, newCommand is called
+// with the default Dependencies.
+func (c *SuperCommand) RegisterFunc(newCommand NewCommandFunc) {
+	deps := newDependencies(&Context{})
+	c.register(newCommand(&deps), newCommand)
+}
+
+func (c *SuperCommand) register(subcmd Command, newCommand NewCommandFunc) {
 	info := subcmd.Info()
-	c.insert(commandReference{name: info.Name, command: subcmd})
+	if !c.isEnabled(info.Name) {
+		logger.Tracef("%q command not registered as it is disabled", info.Name)
+		return
+	}
+	c.insert(commandReference{name: info.Name, command: subcmd, newCommand: newCommand})
 	for _, name := range info.Aliases {
-		c.insert(commandReference{name: name, command: subcmd, alias: info.Name})
+		if c.isDisabled(name) {
+			continue
+		}
+		c.insert(commandReference{name: name, command: subcmd, alias: info.Name, newCommand: newCommand})
 	}
 }
 
@@ -290,8 +734,15 @@ func (c *SuperCommand) RegisterDeprecated(subcmd Command, check DeprecationCheck
 		logger.Infof("%q command not registered as it is obsolete", info.Name)
 		return
 	}
+	if !c.isEnabled(info.Name) {
+		logger.Tracef("%q command not registered as it is disabled", info.Name)
+		return
+	}
 	c.insert(commandReference{name: info.Name, command: subcmd, check: check})
 	for _, name := range info.Aliases {
+		if c.isDisabled(name) {
+			continue
+		}
 		c.insert(commandReference{name: name, command: subcmd, alias: info.Name, check: check})
 	}
 }
@@ -304,15 +755,20 @@ func (c *SuperCommand) RegisterAlias(name, forName string, check DeprecationChec
 		logger.Infof("%q alias not registered as it is obsolete", name)
 		return
 	}
+	if c.isDisabled(name) || !c.isEnabled(forName) {
+		logger.Tracef("%q alias not registered as it is disabled", name)
+		return
+	}
 	action, found := c.subcmds[forName]
 	if !found {
 		panic(fmt.Sprintf("%q not found when registering alias", forName))
 	}
 	c.insert(commandReference{
-		name:    name,
-		command: action.command,
-		alias:   forName,
-		check:   check,
+		name:       name,
+		command:    action.command,
+		alias:      forName,
+		check:      check,
+		newCommand: action.newCommand,
 	})
 }
 
@@ -325,6 +781,10 @@ func (c *SuperCommand) RegisterSuperAlias(name, super, forName string, check Dep
 		logger.Infof("%q alias not registered as it is obsolete", name)
 		return
 	}
+	if c.isDisabled(name) || !c.isEnabled(super) {
+		logger.Tracef("%q alias not registered as it is disabled", name)
+		return
+	}
 	action, found := c.subcmds[super]
 	if !found {
 		panic(fmt.Sprintf("%q not found when registering alias", super))
@@ -340,13 +800,42 @@ func (c *SuperCommand) RegisterSuperAlias(name, super, forName string, check Dep
 	}
 
 	c.insert(commandReference{
-		name:    name,
-		command: action.command,
-		alias:   super + " " + forName,
-		check:   check,
+		name:       name,
+		command:    action.command,
+		alias:      super + " " + forName,
+		check:      check,
+		newCommand: action.newCommand,
 	})
 }
 
+// isDisabled reports whether the named command or alias appears in the
+// disabled command list.
+func (c *SuperCommand) isDisabled(name string) bool {
+	for _, disabled := range c.disabledCommands {
+		if name == disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// isEnabled reports whether the named command may be registered, according
+// to the enabled and disabled command lists.
+func (c *SuperCommand) isEnabled(name string) bool {
+	if c.isDisabled(name) {
+		return false
+	}
+	if len(c.enabledCommands) == 0 {
+		return true
+	}
+	for _, enabled := range c.enabledCommands {
+		if name == enabled {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *SuperCommand) insert(value commandReference) {
 	if _, found := c.subcmds[value.name]; found {
 		panic(fmt.Sprintf("command already registered: %q", value.name))
@@ -354,23 +843,90 @@ func (c *SuperCommand) insert(value commandReference) {
 	c.subcmds[value.name] = value
 }
 
+// loadDynamicCommands registers the commands returned by the
+// DynamicCommands hook for ctx, in place of those registered for a previous
+// Context. The Context is kept so that nested super commands can load
+// theirs when they are dispatched to.
+func (c *SuperCommand) loadDynamicCommands(ctx *Context) {
+	if ctx == nil || ctx == c.dynamicContext {
+		return
+	}
+	c.dynamicContext = ctx
+	if c.dynamicCommands == nil {
+		return
+	}
+	for _, name := range c.dynamicNames {
+		delete(c.subcmds, name)
+	}
+	c.dynamicNames = nil
+	for _, subcmd := range c.dynamicCommands(ctx) {
+		info := subcmd.Info()
+		if !c.isEnabled(info.Name) {
+			continue
+		}
+		if _, found := c.subcmds[info.Name]; found {
+			logger.Warningf("dynamic command %q not registered as the name is in use", info.Name)
+			continue
+		}
+		c.insert(commandReference{name: info.Name, command: subcmd})
+		c.dynamicNames = append(c.dynamicNames, info.Name)
+		for _, name := range info.Aliases {
+			if _, found := c.subcmds[name]; found || c.isDisabled(name) {
+				continue
+			}
+			c.insert(commandReference{name: name, command: subcmd, alias: info.Name})
+			c.dynamicNames = append(c.dynamicNames, name)
+		}
+	}
+}
+
+// loadAllDynamicCommands loads the dynamic commands of c and of every
+// super command below it, for output that covers the whole command tree.
+func (c *SuperCommand) loadAllDynamicCommands(ctx *Context) {
+	c.loadDynamicCommands(ctx)
+	for _, ref := range c.subcmds {
+		if sc, ok := ref.command.(*SuperCommand); ok && ref.alias == "" {
+			sc.loadAllDynamicCommands(ctx)
+		}
+	}
+}
+
 // describeCommands returns a short description of each registered subcommand.
 func (c *SuperCommand) describeCommands() map[string]string {
 	result := make(map[string]string, len(c.subcmds))
 	for name, action := range c.subcmds {
-		if deprecated, _ := action.Deprecated(); deprecated {
+		if deprecated, _ := action.Deprecated(); deprecated || action.hidden(name) {
 			continue
 		}
 		info := action.command.Info()
 		purpose := info.Purpose
 		if action.alias != "" {
 			purpose = "Alias for '" + action.alias + "'."
+		} else if info.Experimental {
+			purpose += " (experimental)"
 		}
 		result[name] = purpose
 	}
 	return result
 }
 
+// describeNestedCommands returns a short description of the subcommands of
+// each registered subcommand that is a super command.
+func (c *SuperCommand) describeNestedCommands() map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for name, action := range c.subcmds {
+		sc, ok := action.command.(*SuperCommand)
+		if !ok || action.alias != "" {
+			continue
+		}
+		if deprecated, _ := action.Deprecated(); deprecated || action.hidden(name) {
+			continue
+		}
+		result[name] = sc.describeCommands()
+	}
+	return result
+}
+
 // Info returns a description of the currently selected subcommand, or of the
 // SuperCommand itself if no subcommand has been specified.
 func (c *SuperCommand) Info() *Info {
@@ -411,6 +967,21 @@ func (c *SuperCommand) SetCommonFlags(f *gnuflag.FlagSet) {
 	// The Purpose attribute will be printed (if defined), allowing
 	// plugins to provide a sensible line of text for 'juju help plugins'.
 	f.BoolVar(&c.showDescription, "description", false, "Show short description of plugin, if any")
+	if c.noRemoteFlag {
+		f.BoolVar(&c.noRemote, "no-remote", false, "Prevent any network access by the command framework")
+	}
+	if c.timeFlag {
+		f.BoolVar(&c.showTime, "time", false, "Show how long the command took to run")
+	}
+	if c.usageStats {
+		f.BoolVar(&c.noTelemetry, noTelemetryFlag, false, "Do not record how often commands are run")
+	}
+	if c.expandArgFiles {
+		f.StringVar(&c.invocationFile, saveInvocationFlag, "", "Save the resolved arguments to a file, to run the command again with @file")
+	}
+	if c.stdinJSON {
+		f.BoolVar(&c.readStdinJSON, stdinJSONFlag, false, "Read the command's flags and arguments as a JSON object from stdin")
+	}
 	c.commonflags = gnuflag.NewFlagSetWithFlagKnownAs(c.Info().Name, gnuflag.ContinueOnError, FlagAlias(c, "flag"))
 	c.commonflags.SetOutput(ioutil.Discard)
 	f.VisitAll(func(flag *gnuflag.Flag) {
@@ -447,19 +1018,67 @@ func (c *SuperCommand) Init(args []string) error {
 	if c.showDescription {
 		return CheckEmpty(args)
 	}
+	if c.expandArgFiles {
+		var err error
+		if args, err = expandArgFiles(c.dynamicContext, args); err != nil {
+			return err
+		}
+	}
+	commandLine := args
 	if len(args) == 0 {
-		c.action = c.subcmds["help"]
-		return c.action.command.Init(args)
+		switch c.noArgsAction {
+		case NoArgsShowUsage:
+			err := fmt.Errorf("no command specified\nUsage: %s <command> ...\nSee %q for a list of commands.",
+				c.commandPath(), c.commandPath("help"))
+			c.recordHelp(HelpEvent{
+				Kind:    HelpUsageError,
+				Command: c.commandPath(),
+				Err:     err,
+			})
+			return err
+		case NoArgsRunCommand:
+			args = []string{c.noArgsCommand}
+			if c.noArgsCommand == "" {
+				args = []string{c.defaultCommand}
+			}
+		default:
+			c.action = c.subcmds["help"]
+			return c.action.command.Init(args)
+		}
 	}
 
+	c.viaUserAlias = false
 	if userAlias, found := c.userAliases[args[0]]; found && !c.noAlias {
-		logger.Debugf("using alias %q=%q", args[0], strings.Join(userAlias, " "))
+		c.viaUserAlias = true
+		logger.Debugf("using alias %q=%q", args[0], Redact(strings.Join(userAlias, " ")))
 		args = append(userAlias, args[1:]...)
 	}
 	found := false
 
 	// Look for the command.
-	if c.action, found = c.subcmds[args[0]]; !found {
+	c.action, found = c.subcmds[args[0]]
+	if !found && c.defaultCommand != "" && !c.nearSubCommand(args[0]) {
+		if c.action, found = c.subcmds[c.defaultCommand]; found {
+			args = append([]string{c.defaultCommand}, args...)
+		}
+	}
+	if !found {
+		if c.missingFlagCallback != nil {
+			flags, rest, err := parseCommonFlags(c.commonflags, args[1:])
+			if err != nil {
+				return err
+			}
+			c.action = commandReference{
+				command: &missingCommand{
+					flagCallback: c.missingFlagCallback,
+					superName:    c.Name,
+					name:         args[0],
+					flags:        flags,
+					args:         rest,
+				},
+			}
+			return nil
+		}
 		if c.missingCallback != nil {
 			c.action = commandReference{
 				command: &missingCommand{
@@ -472,33 +1091,180 @@ func (c *SuperCommand) Init(args []string) error {
 			// Yes return here, no Init called on missing Command.
 			return nil
 		}
+		c.recordHelp(HelpEvent{
+			Kind:        HelpUnrecognizedCommand,
+			Command:     c.commandPath(args[0]),
+			Args:        args[1:],
+			Suggestions: c.suggestions(args[0]),
+		})
+		if c.suggestCommands {
+			if hint := didYouMean(c.suggestions(args[0])); hint != "" {
+				return fmt.Errorf("unrecognized command: %s %s — %s", c.Name, args[0], hint)
+			}
+		}
 		return fmt.Errorf("unrecognized command: %s %s", c.Name, args[0])
 	}
 
 	args = args[1:]
+	if c.action.newCommand != nil {
+		ctx := c.dynamicContext
+		if ctx == nil {
+			ctx = &Context{}
+		}
+		deps := dispatchDependencies(ctx)
+		c.action.command = c.action.newCommand(&deps)
+	}
 	subcmd := c.action.command
+	if sc, ok := subcmd.(*SuperCommand); ok {
+		sc.loadDynamicCommands(c.dynamicContext)
+		sc.noRemoteFlag = sc.noRemoteFlag || c.noRemoteFlag
+		sc.timeFlag = sc.timeFlag || c.timeFlag
+		sc.recordInvocation = c.expandArgFiles || c.recordInvocation
+		if sc.flagEnvPrefix == "" {
+			sc.flagEnvPrefix = c.flagEnvPrefix
+		}
+		if sc.userConfigFilename == "" || sc.configPrefix != "" {
+			sc.userConfigFilename, sc.configPrefix = c.userConfigFilename, c.configKey(c.action)
+		}
+		if sc.recorder == nil {
+			sc.recorder = c.recorder
+		}
+	}
 	if subcmd.IsSuperCommand() {
 		f := gnuflag.NewFlagSetWithFlagKnownAs(c.Info().Name, gnuflag.ContinueOnError, FlagAlias(subcmd, "flag"))
 		f.SetOutput(ioutil.Discard)
 		subcmd.SetFlags(f)
 	} else {
 		subcmd.SetFlags(c.commonflags)
+		addPreviewFlag(c.commonflags, subcmd, &c.preview)
 	}
 	if err := c.commonflags.Parse(subcmd.AllowInterspersedFlags(), args); err != nil {
+		c.recordUsageError(args, err)
 		return err
 	}
 
 	args = c.commonflags.Args()
+	c.ignoredArgs = nil
 	if c.showHelp {
 		// We want to treat help for the command the same way we would if we went "help foo".
+		// Any arguments are ignored, as "help foo bar" would look for
+		// help on a "bar" subcommand of foo.
+		c.ignoredArgs = args
 		args = []string{c.action.name}
 		c.action = c.subcmds["help"]
 	}
-	return c.action.command.Init(args)
+	c.help.viaFlag = c.showHelp
+	if c.readStdinJSON && !c.showHelp && !subcmd.IsSuperCommand() {
+		if len(args) > 0 {
+			err := errors.Errorf("arguments cannot be given with --%s", stdinJSONFlag)
+			c.recordUsageError(args, err)
+			return err
+		}
+		var stdin io.Reader
+		if c.dynamicContext != nil {
+			stdin = c.dynamicContext.Stdin
+		}
+		var err error
+		if args, err = readStdinJSON(stdin, c.commonflags); err != nil {
+			c.recordUsageError(args, err)
+			return err
+		}
+	}
+	if !c.showHelp && !subcmd.IsSuperCommand() {
+		if err := c.applyFlagDefaults(c.commonflags, c.configKey(c.action)); err != nil {
+			c.recordUsageError(args, err)
+			return err
+		}
+	}
+	if !c.showHelp {
+		if err := ResolveDefaults(c.commonflags); err != nil {
+			return err
+		}
+		if c.expandArgFiles || c.recordInvocation {
+			c.invocation = c.invocationArgs(c.action.name, args)
+		}
+	}
+	if err := c.action.command.Init(args); err != nil {
+		// Nested supercommands record their own usage errors, with the
+		// recorder of their parent if they have none of their own.
+		if !c.action.command.IsSuperCommand() {
+			c.recordUsageError(args, err)
+		}
+		if argErr, ok := asArgError(err); ok && c.Log != nil && c.Log.Debug && argErr.commandLine == nil {
+			argErr.pointAt(c.commandPath(), commandLine)
+		}
+		return err
+	}
+	// A nested super command records the invocation of its own
+	// subcommand, whose flags must come before its positional arguments.
+	if sc, ok := c.action.command.(*SuperCommand); ok && c.invocation != nil && sc.invocation != nil {
+		c.invocation = append(c.invocationArgs(c.action.name, nil), sc.invocation...)
+	}
+	return nil
+}
+
+// recordUsageError records a usage error for the selected subcommand.
+func (c *SuperCommand) recordUsageError(args []string, err error) {
+	if err == gnuflag.ErrHelp {
+		return
+	}
+	c.recordHelp(HelpEvent{
+		Kind:    HelpUsageError,
+		Command: c.commandPath(c.action.name),
+		Args:    args,
+		Err:     err,
+	})
+}
+
+// typoDistance returns the greatest number of edits between an
+// unrecognized command name and the name of a subcommand for it to be
+// considered a mistyping of the subcommand. Short names allow fewer edits,
+// so that they are not mistaken for unrelated commands.
+func typoDistance(name string) int {
+	if len(name) < 4 {
+		return 1
+	}
+	return 2
+}
+
+// nearSubCommand reports whether name is likely to be a mistyping of the
+// name of a visible subcommand.
+func (c *SuperCommand) nearSubCommand(name string) bool {
+	return len(c.suggestions(name)) > 0
+}
+
+// didYouMean returns a question suggesting the given names, or "" if there
+// are none.
+func didYouMean(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("did you mean %q?", names[0])
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return fmt.Sprintf("did you mean one of %s?", strings.Join(quoted, ", "))
+}
+
+// suggestions returns the names of the visible subcommands that are close
+// to the given unrecognized name, closest first. The same suggestions are
+// recorded and shown to the user.
+func (c *SuperCommand) suggestions(name string) []string {
+	var names []string
+	for _, match := range c.closestSubCommands(name, true) {
+		if match.distance > typoDistance(name) {
+			break
+		}
+		names = append(names, match.name)
+	}
+	return names
 }
 
 // Run executes the subcommand that was selected in Init.
-func (c *SuperCommand) Run(ctx *Context) error {
+func (c *SuperCommand) Run(ctx *Context) (err error) {
 	if c.showDescription {
 		if c.Purpose != "" {
 			fmt.Fprintf(ctx.Stdout, "%s\n", c.Purpose)
@@ -515,12 +1281,36 @@ func (c *SuperCommand) Run(ctx *Context) error {
 	// formatting directive. Set this early enough, so that everyone can take
 	// appropriate action further down stream.
 	ctx.serialisable = c.isSerialisableFormatDirective()
+	if c.noRemote {
+		ctx.noRemote = true
+	}
+	ctx.SuppressWarnings(c.suppressWarnings...)
+	if c.usageStats {
+		c.usageStatsDir = ""
+		if !c.telemetryDisabled(ctx) {
+			c.usageStatsDir = ctx.expandHome(c.dataDir)
+		}
+	}
+	if c.failedOutputLines > 0 && c.dataDir != "" {
+		c.failedOutputDir = filepath.Join(ctx.expandHome(c.dataDir), failedOutputDirname)
+	}
+	if !c.action.command.IsSuperCommand() {
+		// The output is saved after the error has been written.
+		failed := c.recordOutput(ctx)
+		defer func() {
+			failed.finish(ctx, c.commandPath(c.action.name), err)
+		}()
+	}
 
 	if c.Log != nil {
 		if err := c.Log.Start(ctx); err != nil {
 			return err
 		}
 	}
+	start := ctx.clock().Now()
+	if len(c.ignoredArgs) > 0 {
+		ctx.WarningWithCodef(WarningIgnoredArgs, "ignoring arguments given with --help: %s", strings.Join(c.ignoredArgs, " "))
+	}
 
 	if c.notifyRun != nil {
 		name := c.Name
@@ -529,36 +1319,186 @@ func (c *SuperCommand) Run(ctx *Context) error {
 		}
 		c.notifyRun(name)
 	}
-	if deprecated, replacement := c.action.Deprecated(); deprecated {
-		ctx.Warningf("%q is deprecated, please use %q", c.action.name, replacement)
+	if rename, ok := c.action.check.(*renameCheck); ok {
+		ctx.WarningWithCodef(WarningDeprecatedCommand, "%s", rename.notice(c.action.name))
+	} else if deprecated, replacement := c.action.Deprecated(); deprecated {
+		ctx.WarningWithCodef(WarningDeprecatedCommand, "%q is deprecated, please use %q", c.action.name, replacement)
+	}
+	if info := c.action.command.Info(); info != nil && info.Experimental && !c.action.command.IsSuperCommand() {
+		ctx.WarningWithCodef(WarningExperimentalCommand, "%q is experimental, and may change or be removed in a future release", c.commandPath(c.action.name))
+	}
+	c.trackVersion(ctx)
+	if c.invocationFile != "" {
+		if err := c.saveInvocation(ctx); err != nil {
+			return err
+		}
 	}
 
-	err := c.action.command.Run(ctx)
+	if sc, ok := c.action.command.(*SuperCommand); ok {
+		// Nested super commands wrap their own subcommands, and notify
+		// when they finish.
+		if sc.middleware == nil {
+			sc.middleware = c.middleware
+		}
+		if sc.notifyFinish == nil {
+			sc.notifyFinish = c.notifyFinish
+		}
+		if !sc.usageStats {
+			sc.usageStatsDir, sc.usageUploader = c.usageStatsDir, c.usageUploader
+		}
+		if sc.exitCodes == nil {
+			sc.exitCodes = c.exitCodes
+		}
+		sc.nestedHelp = sc.nestedHelp || c.nestedHelp
+		sc.nested = true
+		if sc.failedOutputLines <= 0 {
+			sc.failedOutputLines, sc.failedOutputDir = c.failedOutputLines, c.failedOutputDir
+		}
+	}
+	if sc, ok := c.action.command.(*SuperCommand); ok && sc.capabilities == nil {
+		// Nested super commands check the requirements of their own
+		// subcommands against the same server.
+		sc.capabilities, sc.serverKnownAs = c.capabilities, c.serverKnownAs
+	} else if c.capabilities != nil && !ctx.noRemote {
+		if err := checkRequirements(ctx, c.capabilities, c.serverKnownAs, c.action.command.Info()); err != nil {
+			return err
+		}
+	}
+
+	// Running the help command may change the selected subcommand.
+	action := c.action
+	if len(ctx.commandPath) == 0 {
+		ctx.startInvocation(c.Name)
+	}
+	ctx.addInvocation(action, c.viaUserAlias)
+	err = c.runAction(ctx, action)
+	ctx.flushPrintfWriters()
+	ctx.reportRepeatedWarnings()
+	if err != nil {
+		ctx.recordError(err)
+	}
+	if !action.command.IsSuperCommand() {
+		// Nested super commands log the completion of their subcommands.
+		// The completion is logged after the error is written, but with
+		// the error returned by the subcommand.
+		defer c.logCompletion(ctx, c.commandPath(action.name), start, err)
+		if notify := c.finishNotifier(ctx, action); notify != nil {
+			defer notify(c.commandPath(action.name), err, ctx.clock().Now().Sub(start))
+		}
+	}
 	if err != nil && !IsErrSilent(err) {
 		// Handle formatting when displaying errors.
-		handleErr := c.handleErrorForMachineFormats(ctx)
+		handleErr := c.handleErrorForMachineFormats(ctx, err)
 		if handleErr != nil {
 			// If there is a handle error when attempting to find the machine
 			// format, we should let the user know. In doing so, we dump the
 			// original error and return the handle error so that effective
 			// debugging is possible.
-			logger.Debugf("error stack: \n%v", errors.ErrorStack(err))
+			logger.Debugf("error stack: \n%v", Redact(errors.ErrorStack(err)))
 			return handleErr
 		}
 
-		WriteError(ctx.Stderr, err)
-		logger.Debugf("error stack: \n%v", errors.ErrorStack(err))
+		writeError(ctx, err, c.renderer)
+		logger.Debugf("error stack: \n%v", Redact(errors.ErrorStack(err)))
+		if output := ctx.CapturedOutput(); len(output) > 0 {
+			logger.Debugf("output before the error: \n%s", Redact(string(output)))
+		}
 
 		// Err has been logged above, we can make the err silent so it does not log again in cmd/main
-		if !utils.IsRcPassthroughError(err) {
+		if code := errorExitCode(err, c.exitCodes, c.partialExitCode, 1); code != 1 {
+			err = utils.NewRcPassthroughError(code)
+		} else {
 			err = ErrSilent
 		}
-	} else {
-		logger.Infof("command finished")
+	} else if err != nil && !utils.IsRcPassthroughError(err) {
+		// Silent errors exit with the code mapped from their category too.
+		if code := errorExitCode(UnwrapSilent(err), c.exitCodes, c.partialExitCode, 1); code != 1 {
+			err = utils.NewRcPassthroughError(code)
+		}
 	}
 	return err
 }
 
+// chainedArgs returns the arguments of each of the subcommands chained by
+// args, or nil if c does not chain subcommands or args has only one. Empty
+// commands are dropped.
+func chainedArgs(c Command, args []string) [][]string {
+	sc, ok := c.(*SuperCommand)
+	if !ok || sc.chainSeparator == "" {
+		return nil
+	}
+	var chain [][]string
+	start := 0
+	for i := 0; i <= len(args); i++ {
+		if i < len(args) && args[i] != sc.chainSeparator {
+			continue
+		}
+		if i > start {
+			chain = append(chain, args[start:i])
+		}
+		start = i + 1
+	}
+	if len(chain) < 2 {
+		return nil
+	}
+	return chain
+}
+
+// logCompletion logs the completion of the given subcommand, which started
+// at the given time and returned err.
+func (c *SuperCommand) logCompletion(ctx *Context, command string, start time.Time, err error) {
+	level := c.completionLogLevel
+	if level == loggo.UNSPECIFIED {
+		level = loggo.INFO
+	}
+	duration := formatDuration(ctx.clock().Now().Sub(start))
+	if err == nil {
+		logger.Logf(level, "command finished: command=%q duration=%s exit-code=0", command, duration)
+		return
+	}
+	code := errorExitCode(err, c.exitCodes, c.partialExitCode, 1)
+	logger.Logf(level, "command finished: command=%q duration=%s exit-code=%d error=%s",
+		command, duration, code, errorCategory(err))
+}
+
+// errorCategory returns the category of err, for logs that are searched
+// by the kind of failure rather than by the message.
+func errorCategory(err error) string {
+	err = UnwrapSilent(err)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errors.Timeout):
+		return "timeout"
+	case errors.Is(err, errors.NotFound):
+		return "not-found"
+	case errors.Is(err, errors.Unauthorized):
+		return "unauthorized"
+	case errors.Is(err, errors.Forbidden):
+		return "forbidden"
+	case errors.Is(err, errors.NotValid), errors.Is(err, errors.BadRequest):
+		return "not-valid"
+	case errors.Is(err, errors.AlreadyExists):
+		return "already-exists"
+	case errors.Is(err, errors.NotSupported), errors.Is(err, errors.NotImplemented):
+		return "not-supported"
+	}
+	var (
+		requirementErr *RequirementError
+		bulk           *BulkError
+		rc             *utils.RcPassthroughError
+	)
+	switch {
+	case errors.As(err, &requirementErr):
+		return "requirement"
+	case errors.As(err, &bulk):
+		return "bulk"
+	case errors.As(err, &rc):
+		return "exit-code"
+	}
+	return "error"
+}
+
 // isSerialisableFormatDirective checks to see if the output format for a given
 // super command common flag (global), is intended to be used by a machine or
 // not.
@@ -569,22 +1509,24 @@ func (c *SuperCommand) isSerialisableFormatDirective() bool {
 	if formatFlag == nil {
 		return false
 	}
-	formatName := formatFlag.Value.String()
-	if typeFormatter, ok := DefaultFormatters[formatName]; ok {
-		return typeFormatter.Serialisable
+	if v, ok := formatFlag.Value.(*formatterValue); ok {
+		return v.serialisable()
 	}
-	return false
+	typeFormatter, ok := DefaultFormatters[formatFlag.Value.String()]
+	return ok && typeFormatter.Serialisable
 }
 
 // handleErrorForMachineFormats attempts to handle fatal errors when using
 // formatting directives.
 // If the formatting directive is what we consider a machine format (yaml or
 // json), then we attempt to output nothing for that format. An example of this
-// would be; for json, that would be {}.
+// would be; for json, that would be {}. A BulkError is instead output as
+// structured entries for each failed target. Any warnings emitted with
+// WarningWithCodef are output in a "warnings" field.
 // No additional writes to stdout or stderr should be performed when a
 // successful format lookup is done, otherwise return errors from a unsuccessful
 // lookup.
-func (c *SuperCommand) handleErrorForMachineFormats(ctx *Context) error {
+func (c *SuperCommand) handleErrorForMachineFormats(ctx *Context, err error) error {
 	// If an output format was used on stdout already we can omit correction
 	// of the machine output.
 	if !ctx.IsSerial() || ctx.outputFormatUsed {
@@ -595,17 +1537,33 @@ func (c *SuperCommand) handleErrorForMachineFormats(ctx *Context) error {
 	if formatFlag == nil {
 		return nil
 	}
-	formatName := formatFlag.Value.String()
-	typeFormatter, ok := DefaultFormatters[formatName]
-	if !ok {
-		return errors.Errorf("missing formatter %q", formatName)
+	// The formatter is the one the command registered, falling back to
+	// DefaultFormatters for --format flags not added by Output.
+	var formatter Formatter
+	if v, ok := formatFlag.Value.(*formatterValue); ok {
+		formatter = v.formatter()
+	} else {
+		formatName := formatFlag.Value.String()
+		typeFormatter, ok := DefaultFormatters[formatName]
+		if !ok {
+			return errors.Errorf("missing formatter %q", formatName)
+		}
+		formatter = typeFormatter.Formatter
 	}
 	// Although this code handles errors for machine formats, the actual empty
 	// type should be written to stdout. This allows consumers of the output to
 	// correctly handle the resulting empty value.
 	// If we place it into stderr, it means that you can never add any more
 	// additional information to stderr, even if it helps the user.
-	return typeFormatter.Formatter(ctx.Stdout, struct{}{})
+	value := map[string]interface{}{}
+	var bulk *BulkError
+	if errors.As(err, &bulk) {
+		value = bulk.structured()
+	}
+	if warnings := ctx.Warnings(); len(warnings) > 0 {
+		value["warnings"] = warnings
+	}
+	return formatter(ctx.Stdout, value)
 }
 
 // FindClosestSubCommand attempts to find a sub command by a given name.
@@ -615,43 +1573,54 @@ func (c *SuperCommand) handleErrorForMachineFormats(ctx *Context) error {
 // far away from the size of the word, we disgard that and say a match isn't
 // relavent i.e. "foo" "barsomethingfoo" would not match
 func (c *SuperCommand) FindClosestSubCommand(name string) (string, Command, bool) {
+	matches := c.closestSubCommands(name, false)
 	// Exit early if there are no subcmds
-	if len(c.subcmds) == 0 {
+	if len(matches) == 0 {
 		return "", nil, false
 	}
+	matchedName := matches[0].name
+	matchedValue := matches[0].distance
 
-	// Attempt to find the closest match of a substring.
-	type Indexed = struct {
-		Name  string
-		Value int
+	// If the matched value is less than the length+1 of the string, fail the
+	// match.
+	if _, ok := c.subcmds[matchedName]; ok && matchedName != "" && matchedValue < len(matchedName)+1 {
+		return matchedName, c.subcmds[matchedName].command, true
 	}
-	matches := make([]Indexed, 0, len(c.subcmds))
-	for cmdName := range c.subcmds {
-		matches = append(matches, Indexed{
-			Name:  cmdName,
-			Value: levenshteinDistance(name, cmdName),
+	return "", nil, false
+}
+
+// subCommandMatch holds the name of a subcommand and its distance from
+// a requested name.
+type subCommandMatch struct {
+	name     string
+	distance int
+}
+
+// closestSubCommands returns the subcommands ordered by their levenshtein
+// distance from name, closest first. If visibleOnly is true, hidden and
+// deprecated subcommands are left out.
+func (c *SuperCommand) closestSubCommands(name string, visibleOnly bool) []subCommandMatch {
+	matches := make([]subCommandMatch, 0, len(c.subcmds))
+	for cmdName, ref := range c.subcmds {
+		if visibleOnly {
+			if deprecated, _ := ref.Deprecated(); deprecated || ref.hidden(cmdName) {
+				continue
+			}
+		}
+		matches = append(matches, subCommandMatch{
+			name:     cmdName,
+			distance: levenshteinDistance(name, cmdName),
 		})
 	}
 	// Find the smallest levenshtein distance. If two values are the same,
 	// fallback to sorting on the name, which should give predictable results.
 	sort.Slice(matches, func(i, j int) bool {
-		if matches[i].Value < matches[j].Value {
-			return true
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
 		}
-		if matches[i].Value > matches[j].Value {
-			return false
-		}
-		return matches[i].Name < matches[j].Name
+		return matches[i].name < matches[j].name
 	})
-	matchedName := matches[0].Name
-	matchedValue := matches[0].Value
-
-	// If the matched value is less than the length+1 of the string, fail the
-	// match.
-	if _, ok := c.subcmds[matchedName]; ok && matchedName != "" && matchedValue < len(matchedName)+1 {
-		return matchedName, c.subcmds[matchedName].command, true
-	}
-	return "", nil, false
+	return matches
 }
 
 // levenshteinDistance
@@ -693,10 +1662,12 @@ func levenshteinDistance(a, b string) int {
 
 type missingCommand struct {
 	CommandBase
-	callback  MissingCallback
-	superName string
-	name      string
-	args      []string
+	callback     MissingCallback
+	flagCallback MissingCallbackWithFlags
+	superName    string
+	name         string
+	flags        map[string]string
+	args         []string
 }
 
 // Missing commands only need to supply Info for the interface, but this is
@@ -706,7 +1677,12 @@ func (c *missingCommand) Info() *Info {
 }
 
 func (c *missingCommand) Run(ctx *Context) error {
-	err := c.callback(ctx, c.name, c.args)
+	var err error
+	if c.flagCallback != nil {
+		err = c.flagCallback(ctx, c.name, c.flags, c.args)
+	} else {
+		err = c.callback(ctx, c.name, c.args)
+	}
 	_, isUnrecognized := err.(*UnrecognizedCommand)
 	if !isUnrecognized {
 		return err
@@ -714,6 +1690,66 @@ func (c *missingCommand) Run(ctx *Context) error {
 	return DefaultUnrecognizedCommand(fmt.Sprintf("%s %s", c.superName, c.name))
 }
 
+// parseCommonFlags picks out any flags known to f from args, setting them on
+// f, and returns the names and values of the flags that were set along with
+// the remaining arguments. Unknown flags are left in place, as they are
+// expected to be handled by the missing command itself.
+func parseCommonFlags(f *gnuflag.FlagSet, args []string) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		var name string
+		switch {
+		case strings.HasPrefix(arg, "--") && len(arg) > 2:
+			name = arg[2:]
+		case strings.HasPrefix(arg, "-") && len(arg) == 2:
+			name = arg[1:]
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		value, hasValue := "", false
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+		}
+		flag := f.Lookup(name)
+		if flag == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if b, ok := flag.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, nil, fmt.Errorf("%s needs an argument: %s", f.FlagKnownAs, arg)
+			}
+		}
+		if err := f.Set(name, value); err != nil {
+			return nil, nil, fmt.Errorf("invalid value %q for %s %s: %v", value, f.FlagKnownAs, arg, err)
+		}
+		flags[name] = value
+	}
+	return flags, rest, nil
+}
+
+// hidden reports whether the command, registered with the given name,
+// should not be shown to users.
+func (r commandReference) hidden(name string) bool {
+	if isHiddenCommand(name) {
+		return true
+	}
+	info := r.command.Info()
+	return info != nil && info.Hidden
+}
+
 // Deprecated calls into the check interface if one was specified,
 // otherwise it says the command isn't deprecated.
 func (r commandReference) Deprecated() (bool, string) {
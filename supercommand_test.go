@@ -9,7 +9,10 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/juju/clock/testclock"
+	jujuerrors "github.com/juju/errors"
 	"github.com/juju/gnuflag"
 	"github.com/juju/loggo/v2"
 	gitjujutesting "github.com/juju/testing"
@@ -146,6 +149,46 @@ func (s *SuperCommandSuite) TestRegister(c *gc.C) {
 	c.Assert(badCall, gc.PanicMatches, `command already registered: "flap"`)
 }
 
+func (s *SuperCommandSuite) TestRegisterEnabledCommands(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:            "jujutest",
+		EnabledCommands: []string{"flip", "flap"},
+	})
+	jc.Register(&TestCommand{Name: "flip", Aliases: []string{"flop"}})
+	jc.Register(&TestCommand{Name: "flap"})
+	jc.Register(&TestCommand{Name: "flub"})
+	jc.RegisterAlias("flip-alias", "flip", nil)
+	// Aliases for disabled commands are skipped rather than panicking.
+	jc.RegisterAlias("flub-alias", "flub", nil)
+
+	info := jc.Info()
+	c.Assert(info.Subcommands, gc.DeepEquals, baseSubcommandsPlus(map[string]string{
+		"flap":       "flap the juju",
+		"flip":       "flip the juju",
+		"flip-alias": "Alias for 'flip'.",
+		"flop":       "Alias for 'flip'.",
+	}))
+}
+
+func (s *SuperCommandSuite) TestRegisterDisabledCommands(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:             "jujutest",
+		DisabledCommands: []string{"flap", "flip-alias"},
+	})
+	jc.Register(&TestCommand{Name: "flip", Aliases: []string{"flip-alias"}})
+	jc.Register(&TestCommand{Name: "flap", Aliases: []string{"flop"}})
+	jc.RegisterDeprecated(&TestCommand{Name: "flap"}, nil)
+
+	info := jc.Info()
+	c.Assert(info.Subcommands, gc.DeepEquals, baseSubcommandsPlus(map[string]string{
+		"flip": "flip the juju",
+	}))
+
+	code := cmd.Main(jc, s.ctx, []string{"flap"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "ERROR unrecognized command: jujutest flap\n")
+}
+
 func (s *SuperCommandSuite) TestAliasesRegistered(c *gc.C) {
 	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
 	jc.Register(&TestCommand{Name: "flip", Aliases: []string{"flap", "flop"}})
@@ -391,6 +434,70 @@ func (s *SuperCommandSuite) TestMissingCallbackContextWiredIn(c *gc.C) {
 	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "this is std err")
 }
 
+func (s *SuperCommandSuite) TestMissingCallbackWithFlags(c *gc.C) {
+	var calledName string
+	var calledFlags map[string]string
+	var calledArgs []string
+
+	log := &cmd.Log{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "jujutest",
+		Log:  log,
+		MissingCallbackWithFlags: func(ctx *cmd.Context, subcommand string, flags map[string]string, args []string) error {
+			calledName = subcommand
+			calledFlags = flags
+			calledArgs = args
+			return nil
+		},
+	})
+	code := cmd.Main(sc, s.ctx, []string{
+		"foo", "bar", "--show-log", "--plugin-flag", "-v", "--logging-config", "<root>=INFO", "baz", "--", "--quiet",
+	})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(calledName, gc.Equals, "foo")
+	c.Assert(calledFlags, gc.DeepEquals, map[string]string{
+		"show-log":       "true",
+		"v":              "true",
+		"logging-config": "<root>=INFO",
+	})
+	c.Assert(calledArgs, gc.DeepEquals, []string{"bar", "--plugin-flag", "baz", "--", "--quiet"})
+	c.Assert(log.ShowLog, gc.Equals, true)
+	c.Assert(log.Verbose, gc.Equals, true)
+	c.Assert(log.Quiet, gc.Equals, false)
+}
+
+func (s *SuperCommandSuite) TestMissingCallbackWithFlagsMissingValue(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "jujutest",
+		Log:  &cmd.Log{},
+		MissingCallbackWithFlags: func(ctx *cmd.Context, subcommand string, flags map[string]string, args []string) error {
+			c.Fatalf("callback should not be called")
+			return nil
+		},
+	})
+	code := cmd.Main(sc, s.ctx, []string{"foo", "--log-file"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "ERROR flag needs an argument: --log-file\n")
+}
+
+func (s *SuperCommandSuite) TestMissingCallbackWithFlagsHelp(c *gc.C) {
+	var calledFlags map[string]string
+	var calledArgs []string
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "jujutest",
+		Log:  &cmd.Log{},
+		MissingCallbackWithFlags: func(ctx *cmd.Context, subcommand string, flags map[string]string, args []string) error {
+			calledFlags = flags
+			calledArgs = args
+			return nil
+		},
+	})
+	code := cmd.Main(sc, s.ctx, []string{"help", "foo", "bar"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(calledFlags, gc.DeepEquals, map[string]string{"help": "true"})
+	c.Assert(calledArgs, gc.DeepEquals, []string{"bar"})
+}
+
 func (s *SuperCommandSuite) TestSupercommandAliases(c *gc.C) {
 	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
 		Name:        "jujutest",
@@ -734,6 +841,25 @@ func (s *SuperCommandSuite) TestErrInYaml(c *gc.C) {
 	s.assertFormattingErr(c, sc, "yaml")
 }
 
+func (s *SuperCommandSuite) TestErrInCSV(c *gc.C) {
+	output := cmd.Output{}
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		UsagePrefix: "juju",
+		Name:        "command",
+		Log:         &cmd.Log{},
+		GlobalFlags: flagAdderFunc(func(fset *gnuflag.FlagSet) {
+			output.AddFormatFlags(fset, "smart", cmd.DefaultFormatters)
+		}),
+	})
+	sc.Register(&TestCommand{Name: "blah", Option: "error"})
+	code := cmd.Main(sc, s.ctx, []string{"blah", "--format=csv", "--option=error"})
+	c.Assert(code, gc.Equals, 1)
+	c.Check(s.ctx.IsSerial(), gc.Equals, true)
+	c.Check(cmdtesting.Stderr(s.ctx), gc.Equals, "ERROR BAM!\n")
+	// An empty CSV table has an empty header row.
+	c.Check(cmdtesting.Stdout(s.ctx), gc.Equals, "\n")
+}
+
 func (s *SuperCommandSuite) TestErrInJsonWithOutput(c *gc.C) {
 	output := cmd.Output{}
 	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
@@ -874,3 +1000,439 @@ func (s *SuperCommandSuite) TestFindClosestSubCommandReturnsConsistentResults(c
 	c.Assert(ok, gc.Equals, true)
 	c.Assert(name, gc.Equals, "help")
 }
+
+func (s *SuperCommandSuite) TestRunSuperCommandNested(c *gc.C) {
+	inner := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "model",
+	})
+	ran := &TestCommand{Name: "config"}
+	inner.Register(ran)
+	outer := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+	})
+	outer.Register(inner)
+
+	result := cmdtesting.RunSuperCommand(c, outer, "model", "config", "--option", "hello")
+	c.Assert(result.Code, gc.Equals, 0)
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(result.Stdout, gc.Equals, "hello\n")
+	c.Assert(ran.Option, gc.Equals, "hello")
+
+	result = cmdtesting.RunSuperCommand(c, outer, "model", "config", "--option", "error")
+	c.Assert(result.Code, gc.Equals, 1)
+	c.Assert(result.Err, gc.ErrorMatches, "BAM!")
+	c.Assert(result.Stderr, gc.Equals, "ERROR BAM!\n")
+
+	result = cmdtesting.RunSuperCommand(c, outer, "model", "unknown")
+	c.Assert(result.Code, gc.Equals, 2)
+	c.Assert(result.Err, gc.ErrorMatches, `unrecognized command: model unknown`)
+}
+
+func (s *SuperCommandSuite) TestDynamicCommands(c *gc.C) {
+	var calls int
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "jujutest",
+		DynamicCommands: func(ctx *cmd.Context) []cmd.Command {
+			calls++
+			if ctx.Env["FEATURES"] == "" {
+				return nil
+			}
+			return []cmd.Command{
+				&TestCommand{Name: "blah", Aliases: []string{"bleh"}},
+				// The registered command is not replaced.
+				&TestCommand{Name: "static", Minimal: true},
+			}
+		},
+	})
+	sc.Register(&TestCommand{Name: "static"})
+
+	c.Assert(cmdtesting.InitCommand(sc, []string{"blah"}), gc.ErrorMatches, "unrecognized command: jujutest blah")
+	c.Assert(calls, gc.Equals, 0)
+
+	run := func(features string, args ...string) cmdtesting.SuperCommandResult {
+		ctx := cmdtesting.Context(c)
+		ctx.Env = map[string]string{"FEATURES": features}
+		return cmdtesting.RunSuperCommandWithContext(ctx, sc, args...)
+	}
+	result := run("blah", "bleh", "--option", "hello")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(result.Stdout, gc.Equals, "hello\n")
+	c.Assert(calls, gc.Equals, 1)
+
+	result = run("blah", "static", "--option", "hello")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(result.Stdout, gc.Equals, "hello\n")
+
+	result = run("blah", "help", "commands")
+	c.Assert(result.Stdout, gc.Matches, "(?s).*blah +blah the juju\n.*bleh +Alias for 'blah'.\n.*")
+
+	// The hook is evaluated each time the command line is dispatched.
+	result = run("", "blah")
+	c.Assert(result.Err, gc.ErrorMatches, "unrecognized command: jujutest blah")
+	result = run("", "help", "commands")
+	c.Assert(result.Stdout, gc.Not(gc.Matches), "(?s).*blah.*")
+	c.Assert(calls, gc.Equals, 5)
+}
+
+func (s *SuperCommandSuite) TestDynamicCommandsNested(c *gc.C) {
+	inner := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "model",
+		DynamicCommands: func(ctx *cmd.Context) []cmd.Command {
+			return []cmd.Command{&TestCommand{Name: "config"}}
+		},
+	})
+	outer := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+	})
+	outer.Register(inner)
+
+	result := cmdtesting.RunSuperCommand(c, outer, "model", "config", "--option", "hello")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(result.Stdout, gc.Equals, "hello\n")
+
+	result = cmdtesting.RunSuperCommand(c, outer, "help", "model", "config")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(result.Stdout, gc.Matches, "Usage: model config .*(.|\n)*")
+
+	result = cmdtesting.RunSuperCommand(c, outer, "documentation")
+	c.Assert(result.Err, gc.IsNil)
+	c.Assert(result.Stdout, gc.Matches, "(?s).*# MODEL CONFIG\n.*")
+}
+
+func (s *SuperCommandSuite) TestHelpFlagIgnoresArgs(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	jc.Register(&TestCommand{Name: "blah"})
+	for i, args := range [][]string{
+		{"blah", "--help", "foo", "bar"},
+		{"blah", "foo", "-h", "bar"},
+	} {
+		c.Logf("test %d: %v", i, args)
+		s.SetUpTest(c)
+		code := cmd.Main(jc, s.ctx, args)
+		c.Check(code, gc.Equals, 0)
+		c.Check(cmdtesting.Stdout(s.ctx), gc.Matches, "(?s)Usage: jujutest blah.*")
+		c.Check(s.ctx.Warnings(), gc.DeepEquals, []cmd.Warning{{
+			Code:    cmd.WarningIgnoredArgs,
+			Message: "ignoring arguments given with --help: foo bar",
+		}})
+		s.TearDownTest(c)
+	}
+
+	s.SetUpTest(c)
+	code := cmd.Main(jc, s.ctx, []string{"blah", "--help"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(s.ctx.Warnings(), gc.HasLen, 0)
+}
+
+func (s *SuperCommandSuite) TestNoArgsAction(c *gc.C) {
+	for i, test := range []struct {
+		action  cmd.NoArgsAction
+		command string
+		code    int
+		stdout  string
+		stderr  string
+	}{{
+		action: cmd.NoArgsShowHelp,
+		stdout: "(?s)Usage: jujutest \\[flags\\] <command> \\.\\.\\..*",
+	}, {
+		action: cmd.NoArgsShowUsage,
+		code:   2,
+		stderr: `ERROR no command specified
+Usage: jujutest <command> ...
+See "jujutest help" for a list of commands.
+`,
+	}, {
+		action:  cmd.NoArgsRunCommand,
+		command: "blah",
+		stdout:  "\n",
+	}, {
+		action:  cmd.NoArgsRunCommand,
+		command: "missing",
+		code:    2,
+		stderr:  "ERROR unrecognized command: jujutest missing\n",
+	}} {
+		c.Logf("test %d", i)
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:          "jujutest",
+			NoArgsAction:  test.action,
+			NoArgsCommand: test.command,
+		})
+		jc.Register(&TestCommand{Name: "blah"})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(jc, ctx, nil)
+		c.Check(code, gc.Equals, test.code)
+		c.Check(cmdtesting.Stdout(ctx), gc.Matches, test.stdout)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+	}
+}
+
+func (s *SuperCommandSuite) TestDefaultCommand(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		code   int
+		run    []string
+		stderr string
+	}{{
+		args: []string{"mysql/0", "mysql/1"},
+		run:  []string{"mysql/0", "mysql/1"},
+	}, {
+		args: []string{"remove-unit", "mysql/0"},
+		run:  []string{"mysql/0"},
+	}, {
+		args: nil,
+	}, {
+		args:   []string{"hepl", "mysql/0"},
+		code:   2,
+		stderr: "ERROR unrecognized command: jujutest hepl\n",
+	}, {
+		args:   []string{"remove-units", "mysql/0"},
+		code:   2,
+		stderr: "ERROR unrecognized command: jujutest remove-units\n",
+	}, {
+		args: []string{"xy", "mysql/0"},
+		run:  []string{"xy", "mysql/0"},
+	}, {
+		args: []string{"secrt", "mysql/0"},
+		run:  []string{"secrt", "mysql/0"},
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:           "jujutest",
+			NoArgsAction:   cmd.NoArgsRunCommand,
+			DefaultCommand: "remove-unit",
+		})
+		command := &argsCommand{}
+		jc.Register(command)
+		jc.Register(&TestCommand{Name: "do"})
+		jc.Register(&markedCommand{TestCommand: TestCommand{Name: "secret"}, hidden: true})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(jc, ctx, test.args)
+		c.Check(code, gc.Equals, test.code)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+		if test.code == 0 {
+			c.Check(command.args, gc.DeepEquals, test.run)
+		}
+	}
+}
+
+func (s *SuperCommandSuite) TestCompletionLogged(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		run    func(*cmd.Context) error
+		level  loggo.Level
+		code   int
+		logged string
+	}{{
+		args:   []string{"blah", "--debug"},
+		logged: `.* INFO  cmd .* command finished: command="juju command blah" duration=0s exit-code=0`,
+	}, {
+		args:   []string{"blah", "--debug", "--option", "error"},
+		code:   1,
+		logged: `.* INFO  cmd .* command finished: command="juju command blah" duration=0s exit-code=1 error=error`,
+	}, {
+		args:   []string{"blah", "--debug"},
+		run:    func(*cmd.Context) error { return jujuerrors.NotFoundf("model %q", "foo") },
+		code:   1,
+		logged: `.* INFO  cmd .* command finished: command="juju command blah" duration=0s exit-code=1 error=not-found`,
+	}, {
+		args:   []string{"blah", "--debug"},
+		level:  loggo.DEBUG,
+		logged: `.* DEBUG cmd .* command finished: command="juju command blah" duration=0s exit-code=0`,
+	}, {
+		args:  []string{"blah", "--show-log"},
+		level: loggo.DEBUG,
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			UsagePrefix:        "juju",
+			Name:               "command",
+			Log:                &cmd.Log{},
+			CompletionLogLevel: test.level,
+		})
+		sc.Register(&TestCommand{Name: "blah", CustomRun: test.run})
+		ctx := cmdtesting.Context(c)
+		ctx.Clock = testclock.NewClock(time.Now())
+		code := cmd.Main(sc, ctx, test.args)
+		c.Check(code, gc.Equals, test.code)
+		if test.logged == "" {
+			c.Check(cmdtesting.Stderr(ctx), gc.Not(gc.Matches), "(?s).*command finished.*")
+		} else {
+			c.Check(cmdtesting.Stderr(ctx), gc.Matches, "(?s)(.*\n)?"+test.logged+"\n.*")
+		}
+	}
+}
+
+func (s *SuperCommandSuite) TestNotifyFinish(c *gc.C) {
+	type finish struct {
+		name     string
+		err      string
+		duration time.Duration
+	}
+	var finished []finish
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name: "juju",
+		NotifyFinish: func(name string, err error, duration time.Duration) {
+			f := finish{name: name, duration: duration}
+			if err != nil {
+				f.err = err.Error()
+			}
+			finished = append(finished, f)
+		},
+	})
+	clock := testclock.NewClock(time.Now())
+	slow := func(*cmd.Context) error {
+		clock.Advance(3 * time.Second)
+		return jujuerrors.New("too slow")
+	}
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage", UsagePrefix: "juju"})
+	storage.Register(&TestCommand{Name: "list", CustomRun: slow})
+	jc.Register(storage)
+	jc.Register(&TestCommand{Name: "blah"})
+
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = clock
+	c.Assert(cmd.Main(jc, ctx, []string{"blah"}), gc.Equals, 0)
+	c.Assert(cmd.Main(jc, ctx, []string{"storage", "list"}), gc.Equals, 1)
+	c.Assert(finished, gc.DeepEquals, []finish{
+		{name: "juju blah"},
+		{name: "juju storage list", err: "too slow", duration: 3 * time.Second},
+	})
+}
+
+func (s *SuperCommandSuite) TestChainedCommands(c *gc.C) {
+	for i, test := range []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{{
+		args:   []string{"blah", "--option", "one", "--", "bleh", "--option", "two", "--"},
+		stdout: "one\ntwo\n",
+	}, {
+		args:   []string{"blah", "--option", "error", "--", "bleh", "--option", "two"},
+		code:   1,
+		stderr: "ERROR BAM!\n",
+	}, {
+		args:   []string{"blah", "--option", "one", "--", "bleh", "extra", "--", "blah"},
+		code:   2,
+		stdout: "one\n",
+		stderr: "ERROR unrecognized args: [\"extra\"]\n",
+	}, {
+		args:   []string{"blah", "--option", "one"},
+		stdout: "one\n",
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:           "jujutest",
+			Log:            &cmd.Log{},
+			ChainSeparator: "--",
+		})
+		jc.Register(&TestCommand{Name: "blah"})
+		jc.Register(&TestCommand{Name: "bleh"})
+		ctx := cmdtesting.Context(c)
+		code := cmd.Main(jc, ctx, test.args)
+		c.Check(code, gc.Equals, test.code)
+		c.Check(cmdtesting.Stdout(ctx), gc.Equals, test.stdout)
+		c.Check(cmdtesting.Stderr(ctx), gc.Equals, test.stderr)
+	}
+}
+
+// markedCommand is a TestCommand that may be hidden or experimental.
+type markedCommand struct {
+	TestCommand
+	hidden       bool
+	experimental bool
+}
+
+func (c *markedCommand) Info() *cmd.Info {
+	info := c.TestCommand.Info()
+	info.Hidden = c.hidden
+	info.Experimental = c.experimental
+	return info
+}
+
+func (s *SuperCommandSuite) newMarkedSuper(listCommands bool) *cmd.SuperCommand {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", ListCommands: listCommands})
+	jc.Register(&TestCommand{Name: "blah"})
+	jc.Register(&markedCommand{TestCommand: TestCommand{Name: "secret"}, hidden: true})
+	jc.Register(&markedCommand{TestCommand: TestCommand{Name: "shiny"}, experimental: true})
+	return jc
+}
+
+func (s *SuperCommandSuite) TestHiddenCommand(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newMarkedSuper(false), ctx, []string{"help", "commands"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Not(gc.Matches), "(?s).*secret.*")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, "(?s).*blah .*")
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(s.newMarkedSuper(true), ctx, []string{"commands", "--all"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `(?s).*secret +secret the juju \(hidden\)\n.*`)
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(s.newMarkedSuper(false), ctx, []string{"secret", "--option", "still runs"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "still runs\n")
+}
+
+func (s *SuperCommandSuite) TestExperimentalCommand(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newMarkedSuper(false), ctx, []string{"help", "commands"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `(?s).*shiny +shiny the juju \(experimental\)\n.*`)
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(s.newMarkedSuper(false), ctx, []string{"shiny", "--option", "new"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "new\n")
+	c.Assert(ctx.Warnings(), gc.DeepEquals, []cmd.Warning{{
+		Code:    cmd.WarningExperimentalCommand,
+		Message: `"jujutest shiny" is experimental, and may change or be removed in a future release`,
+	}})
+}
+
+func (s *SuperCommandSuite) TestSuggestCommands(c *gc.C) {
+	for i, test := range []struct {
+		name    string
+		suggest bool
+		err     string
+	}{{
+		name:    "statuses",
+		suggest: true,
+		err:     `unrecognized command: jujutest statuses — did you mean "status"\?`,
+	}, {
+		name:    "stat",
+		suggest: true,
+		err:     `unrecognized command: jujutest stat — did you mean one of "stats", "status"\?`,
+	}, {
+		name:    "discombobulate",
+		suggest: true,
+		err:     `unrecognized command: jujutest discombobulate`,
+	}, {
+		name:    "hiden",
+		suggest: true,
+		err:     `unrecognized command: jujutest hiden`,
+	}, {
+		name: "statuss",
+		err:  `unrecognized command: jujutest statuss`,
+	}} {
+		c.Logf("test %d: %s", i, test.name)
+		jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", SuggestCommands: test.suggest})
+		jc.Register(&TestCommand{Name: "status"})
+		jc.Register(&TestCommand{Name: "stats"})
+		jc.Register(&markedCommand{TestCommand: TestCommand{Name: "hidden"}, hidden: true})
+		err := cmdtesting.InitCommand(jc, []string{test.name})
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *SuperCommandSuite) TestNoArgsRunCommandWithoutTarget(c *gc.C) {
+	c.Assert(func() {
+		cmd.NewSuperCommand(cmd.SuperCommandParams{
+			Name:         "jujutest",
+			NoArgsAction: cmd.NoArgsRunCommand,
+		})
+	}, gc.PanicMatches, `"jujutest": NoArgsRunCommand requires NoArgsCommand or DefaultCommand`)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"runtime"
+	"strings"
+)
+
+// AccessibleEnvKey is the environment variable that, when set, turns on
+// the accessibility mode, in which output avoids symbols that screen
+// readers cannot read.
+const AccessibleEnvKey = "ACCESSIBLE"
+
+// goos is the operating system, which decides how Unicode support is
+// detected.
+var goos = runtime.GOOS
+
+// Symbols are the symbols commands use to mark the outcome of operations
+// in their output, e.g.
+//
+//	fmt.Fprintf(ctx.Stdout, "%s deployed %s\n", ctx.Symbols().Success, name)
+type Symbols struct {
+	Success string
+	Failure string
+	Warning string
+}
+
+// UnicodeSymbols are the symbols used by terminals that can display
+// Unicode.
+var UnicodeSymbols = Symbols{
+	Success: "✓",
+	Failure: "✗",
+	Warning: "!",
+}
+
+// ASCIISymbols are the symbols used when Unicode cannot be displayed, or
+// in the accessibility mode.
+var ASCIISymbols = Symbols{
+	Success: "OK",
+	Failure: "X",
+	Warning: "!",
+}
+
+// SetAccessible turns the accessibility mode on or off. It is also on
+// when the ACCESSIBLE environment variable is set.
+func (ctx *Context) SetAccessible(accessible bool) {
+	ctx.accessible = accessible
+}
+
+// Accessible reports whether the accessibility mode is on.
+func (ctx *Context) Accessible() bool {
+	return ctx.accessible || ctx.lookupEnv(AccessibleEnvKey) != ""
+}
+
+// Symbols returns the symbols commands should use, which are the ASCII
+// symbols in the accessibility mode or when the terminal is not known to
+// display Unicode, and the Unicode symbols otherwise.
+func (ctx *Context) Symbols() Symbols {
+	if ctx.Accessible() || !supportsUnicode(ctx.lookupEnv) {
+		return ASCIISymbols
+	}
+	return UnicodeSymbols
+}
+
+// supportsUnicode reports whether the terminal described by the
+// environment is able to display Unicode. On Windows only the newer
+// terminals are; elsewhere the locale decides.
+func supportsUnicode(lookupEnv func(string) string) bool {
+	if goos == "windows" {
+		return lookupEnv("WT_SESSION") != "" || lookupEnv("TERM_PROGRAM") == "vscode"
+	}
+	if lookupEnv("TERM") == "linux" {
+		// The Linux console lacks many symbols.
+		return false
+	}
+	locale := lookupEnv("LC_ALL")
+	if locale == "" {
+		locale = lookupEnv("LC_CTYPE")
+	}
+	if locale == "" {
+		locale = lookupEnv("LANG")
+	}
+	if locale == "" {
+		return true
+	}
+	locale = strings.ToLower(locale)
+	return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+}
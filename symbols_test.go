@@ -0,0 +1,52 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type SymbolsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&SymbolsSuite{})
+
+func (s *SymbolsSuite) TestSymbols(c *gc.C) {
+	for i, test := range []struct {
+		goos     string
+		env      map[string]string
+		expected cmd.Symbols
+	}{
+		{goos: "linux", env: map[string]string{}, expected: cmd.UnicodeSymbols},
+		{goos: "linux", env: map[string]string{"LANG": "en_GB.UTF-8"}, expected: cmd.UnicodeSymbols},
+		{goos: "linux", env: map[string]string{"LANG": "en_GB.utf8"}, expected: cmd.UnicodeSymbols},
+		{goos: "linux", env: map[string]string{"LANG": "C"}, expected: cmd.ASCIISymbols},
+		{goos: "linux", env: map[string]string{"LANG": "en_GB.UTF-8", "LC_ALL": "POSIX"}, expected: cmd.ASCIISymbols},
+		{goos: "linux", env: map[string]string{"TERM": "linux"}, expected: cmd.ASCIISymbols},
+		{goos: "linux", env: map[string]string{"ACCESSIBLE": "1"}, expected: cmd.ASCIISymbols},
+		{goos: "windows", env: map[string]string{}, expected: cmd.ASCIISymbols},
+		{goos: "windows", env: map[string]string{"WT_SESSION": "1"}, expected: cmd.UnicodeSymbols},
+		{goos: "windows", env: map[string]string{"WT_SESSION": "1", "ACCESSIBLE": "1"}, expected: cmd.ASCIISymbols},
+	} {
+		s.PatchValue(cmd.GOOS, test.goos)
+		ctx := cmdtesting.Context(c)
+		ctx.Env = test.env
+		c.Check(ctx.Symbols(), gc.Equals, test.expected, gc.Commentf("test %d", i))
+	}
+}
+
+func (s *SymbolsSuite) TestSetAccessible(c *gc.C) {
+	s.PatchValue(cmd.GOOS, "linux")
+	ctx := cmdtesting.Context(c)
+	ctx.Env = map[string]string{}
+	c.Assert(ctx.Accessible(), gc.Equals, false)
+	ctx.SetAccessible(true)
+	c.Assert(ctx.Accessible(), gc.Equals, true)
+	c.Assert(ctx.Symbols(), gc.Equals, cmd.ASCIISymbols)
+}
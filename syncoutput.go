@@ -0,0 +1,66 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// WithSynchronisedOutput returns a copy of ctx whose Stdout and Stderr
+// share a lock and only pass whole lines on to the original writers, so
+// that output, progress and warnings written concurrently never split
+// mid-line. Partial lines are held until they are completed; the returned
+// function writes any that remain and must be called when the command
+// finishes.
+func (ctx *Context) WithSynchronisedOutput() (*Context, func() error) {
+	var mu sync.Mutex
+	stdout := &lineWriter{mu: &mu, w: ctx.Stdout}
+	stderr := &lineWriter{mu: &mu, w: ctx.Stderr}
+	newCtx := *ctx
+	newCtx.Stdout = stdout
+	newCtx.Stderr = stderr
+	return &newCtx, func() error {
+		err := stdout.flush()
+		if err2 := stderr.flush(); err == nil {
+			err = err2
+		}
+		return err
+	}
+}
+
+// lineWriter buffers writes to w until a line is complete. The lock is
+// shared with the other writers of the same context.
+type lineWriter struct {
+	mu  *sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	_, err := w.w.Write(w.buf[:i+1])
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	return len(p), err
+}
+
+// flush writes any partial line that remains.
+func (w *lineWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+)
+
+type SyncOutputSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&SyncOutputSuite{})
+
+// recordingWriter records each call to Write.
+type recordingWriter struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func (s *SyncOutputSuite) TestWholeLines(c *gc.C) {
+	var out recordingWriter
+	ctx, flush := (&cmd.Context{Stdout: &out, Stderr: &out}).WithSynchronisedOutput()
+	fmt.Fprint(ctx.Stdout, "downloading")
+	fmt.Fprint(ctx.Stderr, "WARNING slow ")
+	fmt.Fprint(ctx.Stdout, " charm\nmachine 0 ")
+	fmt.Fprint(ctx.Stderr, "mirror\n")
+	c.Assert(out.writes, gc.DeepEquals, []string{"downloading charm\n", "WARNING slow mirror\n"})
+
+	c.Assert(flush(), gc.IsNil)
+	c.Assert(out.writes, gc.DeepEquals, []string{"downloading charm\n", "WARNING slow mirror\n", "machine 0 "})
+}
+
+func (s *SyncOutputSuite) TestConcurrentWrites(c *gc.C) {
+	var out recordingWriter
+	ctx, flush := (&cmd.Context{Stdout: &out, Stderr: &out}).WithSynchronisedOutput()
+	var wg sync.WaitGroup
+	for i, w := range []io.Writer{ctx.Stdout, ctx.Stderr} {
+		wg.Add(1)
+		go func(i int, w io.Writer) {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				fmt.Fprintf(w, "writer %d ", i)
+				fmt.Fprintf(w, "line %d\n", j)
+			}
+		}(i, w)
+	}
+	wg.Wait()
+	c.Assert(flush(), gc.IsNil)
+	c.Assert(out.writes, gc.HasLen, 500)
+	for _, write := range out.writes {
+		c.Assert(write, gc.Matches, `writer [01] line \d+\n`)
+		c.Assert(strings.Count(write, "\n"), gc.Equals, 1)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/juju/ansiterm"
+	"github.com/juju/errors"
+	"golang.org/x/term"
+)
+
+// WindowSize is the size of a terminal in character cells.
+type WindowSize struct {
+	Width  int
+	Height int
+}
+
+var (
+	rawMutex     sync.Mutex
+	rawTerminals = make(map[int]*term.State)
+)
+
+// MakeRaw puts the terminal connected to Stdin into raw mode, for commands
+// that pass input straight through to a remote process (ssh and console
+// style commands). Main restores the terminal when the command finishes,
+// even if it panics, but commands should call Restore as soon as raw mode
+// is no longer needed.
+func (ctx *Context) MakeRaw() error {
+	fd, ok := terminalFd(ctx.Stdin)
+	if !ok {
+		return errors.New("stdin is not a terminal")
+	}
+	rawMutex.Lock()
+	defer rawMutex.Unlock()
+	if _, ok := rawTerminals[fd]; ok {
+		return nil
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return errors.Annotate(err, "setting terminal to raw mode")
+	}
+	rawTerminals[fd] = state
+	return nil
+}
+
+// Restore returns the terminal connected to Stdin to the state it was in
+// before MakeRaw was called. It does nothing if the terminal is not in raw
+// mode.
+func (ctx *Context) Restore() error {
+	fd, ok := terminalFd(ctx.Stdin)
+	if !ok {
+		return nil
+	}
+	rawMutex.Lock()
+	defer rawMutex.Unlock()
+	return errors.Trace(restoreTerminal(fd))
+}
+
+// restoreTerminals restores every terminal put into raw mode.
+func restoreTerminals() {
+	rawMutex.Lock()
+	defer rawMutex.Unlock()
+	for fd := range rawTerminals {
+		if err := restoreTerminal(fd); err != nil {
+			logger.Warningf("cannot restore terminal: %v", err)
+		}
+	}
+}
+
+// restoreTerminal restores the terminal with the given file descriptor.
+// The caller must hold rawMutex.
+func restoreTerminal(fd int) error {
+	state, ok := rawTerminals[fd]
+	if !ok {
+		return nil
+	}
+	delete(rawTerminals, fd)
+	return term.Restore(fd, state)
+}
+
+// TerminalWriter is implemented by output streams that behave as a
+// terminal without being one, such as the fake terminals provided by the
+// cmdtesting package. The framework treats them as terminals of the size
+// and colour capability they report.
+type TerminalWriter interface {
+	io.Writer
+
+	// WindowSize returns the size of the terminal.
+	WindowSize() (WindowSize, error)
+
+	// ColorCapable reports whether the terminal displays colours.
+	ColorCapable() bool
+}
+
+// IsTerminal reports whether Stdout is a terminal, e.g. to decide whether
+// to render output for people or for scripts.
+func (ctx *Context) IsTerminal() bool {
+	return isTerminal(ctx.Stdout)
+}
+
+// IsStdinTerminal reports whether Stdin is a terminal, so that the user
+// can be prompted for input.
+func (ctx *Context) IsStdinTerminal() bool {
+	return isInputTerminal(ctx.Stdin)
+}
+
+// NewColorWriter returns an ansiterm.Writer that writes colours and styles
+// to w if it is a terminal capable of colour, and drops them otherwise.
+// Formatters should use it, so that the --color flag of Output is honoured.
+func NewColorWriter(w io.Writer) *ansiterm.Writer {
+	writer := ansiterm.NewWriter(w)
+	if t, ok := w.(colorCapable); ok {
+		writer.SetColorCapable(t.ColorCapable())
+	}
+	return writer
+}
+
+// WindowSize returns the size of the terminal connected to Stdout.
+func (ctx *Context) WindowSize() (WindowSize, error) {
+	if t, ok := ctx.Stdout.(TerminalWriter); ok {
+		return t.WindowSize()
+	}
+	fd, ok := terminalFd(ctx.Stdout)
+	if !ok {
+		return WindowSize{}, errors.New("stdout is not a terminal")
+	}
+	return windowSize(fd)
+}
+
+// windowSize returns the size of the terminal with the given file
+// descriptor.
+func windowSize(fd int) (WindowSize, error) {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return WindowSize{}, err
+	}
+	return WindowSize{Width: width, Height: height}, nil
+}
+
+// NotifyWindowSize sends the new size of the terminal connected to Stdout
+// on ch each time the window is resized (SIGWINCH), until the returned
+// function is called. It does nothing on platforms without window size
+// change notifications.
+func (ctx *Context) NotifyWindowSize(ch chan<- WindowSize) (stop func()) {
+	fd, ok := terminalFd(ctx.Stdout)
+	if !ok || resizeSignal == nil {
+		return func() {}
+	}
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, resizeSignal)
+	go func() {
+		for {
+			select {
+			case <-signals:
+			case <-done:
+				return
+			}
+			size, err := windowSize(fd)
+			if err != nil {
+				logger.Debugf("cannot get window size: %v", err)
+				continue
+			}
+			select {
+			case ch <- size:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// terminalFd returns the file descriptor of f, if it is a file.
+func terminalFd(f interface{}) (int, bool) {
+	file, ok := f.(interface{ Fd() uintptr })
+	if !ok {
+		return 0, false
+	}
+	return int(file.Fd()), true
+}
+
+// isTerminal reports whether w is connected to a terminal. It is a
+// variable so that tests can pretend that output goes to a terminal.
+var isTerminal = func(w io.Writer) bool {
+	if _, ok := w.(TerminalWriter); ok {
+		return true
+	}
+	fd, ok := terminalFd(w)
+	return ok && isTerminalFd(fd)
+}
+
+// supportsANSI reports whether the terminal w is connected to interprets
+// ANSI escape sequences, for colours and for moving the cursor. Terminals
+// that are not files, such as fake terminals, are assumed to.
+func supportsANSI(w io.Writer) bool {
+	fd, ok := terminalFd(w)
+	return !ok || supportsANSIFd(fd)
+}
+
+// setUpTerminals prepares the terminals that ctx writes to for the escape
+// sequences and UTF-8 text written by the framework, returning a function
+// that puts them back as they were.
+func setUpTerminals(ctx *Context) func() {
+	var fds []int
+	for _, w := range []io.Writer{ctx.Stdout, ctx.Stderr} {
+		if fd, ok := terminalFd(w); ok && isTerminalFd(fd) {
+			fds = append(fds, fd)
+		}
+	}
+	if len(fds) == 0 {
+		return func() {}
+	}
+	return setUpConsoles(fds...)
+}
+
+// isInputTerminal reports whether r is connected to a terminal. It is a
+// variable so that tests can pretend that input comes from a terminal.
+var isInputTerminal = func(r io.Reader) bool {
+	fd, ok := terminalFd(r)
+	return ok && isTerminalFd(fd)
+}
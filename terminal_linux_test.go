@@ -0,0 +1,147 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	"golang.org/x/sys/unix"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+// openPty returns the controlling and terminal sides of a new
+// pseudo-terminal.
+func openPty(c *gc.C) (*os.File, *os.File) {
+	ptmx, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		c.Skip(fmt.Sprintf("cannot open pseudo-terminal: %v", err))
+	}
+	c.Assert(unix.IoctlSetPointerInt(int(ptmx.Fd()), unix.TIOCSPTLCK, 0), gc.IsNil)
+	n, err := unix.IoctlGetInt(int(ptmx.Fd()), unix.TIOCGPTN)
+	c.Assert(err, gc.IsNil)
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptmx.Close()
+		c.Skip(fmt.Sprintf("cannot open pseudo-terminal: %v", err))
+	}
+	return ptmx, tty
+}
+
+func echoEnabled(c *gc.C, tty *os.File) bool {
+	termios, err := unix.IoctlGetTermios(int(tty.Fd()), unix.TCGETS)
+	c.Assert(err, gc.IsNil)
+	return termios.Lflag&unix.ECHO != 0
+}
+
+func (s *TerminalSuite) TestMakeRawAndRestore(c *gc.C) {
+	ptmx, tty := openPty(c)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	ctx := cmdtesting.Context(c)
+	ctx.Stdin = tty
+	c.Assert(echoEnabled(c, tty), gc.Equals, true)
+	c.Assert(ctx.MakeRaw(), gc.IsNil)
+	c.Assert(echoEnabled(c, tty), gc.Equals, false)
+	c.Assert(ctx.Restore(), gc.IsNil)
+	c.Assert(echoEnabled(c, tty), gc.Equals, true)
+}
+
+func (s *TerminalSuite) TestMainRestoresOnPanic(c *gc.C) {
+	ptmx, tty := openPty(c)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	ctx := cmdtesting.Context(c)
+	ctx.Stdin = tty
+	command := &TestCommand{
+		Name: "console",
+		CustomRun: func(ctx *cmd.Context) error {
+			c.Assert(ctx.MakeRaw(), gc.IsNil)
+			panic("connection lost")
+		},
+	}
+	c.Assert(func() { cmd.Main(command, ctx, nil) }, gc.PanicMatches, "connection lost")
+	c.Assert(echoEnabled(c, tty), gc.Equals, true)
+}
+
+func (s *TerminalSuite) TestWindowSize(c *gc.C) {
+	ptmx, tty := openPty(c)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	setSize := func(width, height int) {
+		err := unix.IoctlSetWinsize(int(ptmx.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Col: uint16(width), Row: uint16(height)})
+		c.Assert(err, gc.IsNil)
+	}
+	setSize(80, 24)
+
+	ctx := cmdtesting.Context(c)
+	ctx.Stdout = tty
+	size, err := ctx.WindowSize()
+	c.Assert(err, gc.IsNil)
+	c.Assert(size, gc.Equals, cmd.WindowSize{Width: 80, Height: 24})
+
+	sizes := make(chan cmd.WindowSize, 1)
+	stop := ctx.NotifyWindowSize(sizes)
+	defer stop()
+	setSize(120, 40)
+	c.Assert(syscall.Kill(os.Getpid(), syscall.SIGWINCH), gc.IsNil)
+	select {
+	case size := <-sizes:
+		c.Assert(size, gc.Equals, cmd.WindowSize{Width: 120, Height: 40})
+	case <-time.After(testing.LongWait):
+		c.Fatalf("no window size notification")
+	}
+}
+
+func (s *TerminalSuite) TestPromptPasswordDisablesEcho(c *gc.C) {
+	s.AddCleanup(func(*gc.C) { cmd.ResetRedactions() })
+	ptmx, tty := openPty(c)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	ctx := cmdtesting.Context(c)
+	ctx.Stdin = tty
+	go func() {
+		// Wait for the prompt to disable echo before typing.
+		for echoEnabled(c, tty) {
+			time.Sleep(time.Millisecond)
+		}
+		_, _ = ptmx.Write([]byte("s3cret\n"))
+	}()
+	password, err := ctx.PromptPassword("Password: ")
+	c.Assert(err, gc.IsNil)
+	c.Assert(password, gc.Equals, "s3cret")
+	c.Assert(echoEnabled(c, tty), gc.Equals, true)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "Password: \n")
+}
+
+func (s *TerminalSuite) TestPromptPasswordRestoresEchoOnCancel(c *gc.C) {
+	ptmx, tty := openPty(c)
+	defer ptmx.Close()
+	defer tty.Close()
+
+	ctx, cancel := cmdtesting.Context(c).WithCancel()
+	ctx.Stdin = tty
+	go func() {
+		for echoEnabled(c, tty) {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	_, err := ctx.PromptPassword("Password: ")
+	c.Assert(errors.Is(err, context.Canceled), jc.IsTrue)
+	c.Assert(echoEnabled(c, tty), gc.Equals, true)
+}
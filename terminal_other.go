@@ -0,0 +1,36 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/juju/errors"
+)
+
+var resizeSignal os.Signal
+
+type terminalState struct{}
+
+func disableEcho(fd int) (*terminalState, error) {
+	return nil, errors.NotSupportedf("disabling terminal echo on this platform")
+}
+
+func restore(fd int, state *terminalState) error {
+	return nil
+}
+
+func isTerminalFd(fd int) bool {
+	return false
+}
+
+func supportsANSIFd(fd int) bool {
+	return false
+}
+
+func setUpConsoles(fds ...int) func() {
+	return func() {}
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"errors"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type TerminalSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&TerminalSuite{})
+
+func (s *TerminalSuite) TestNotTerminal(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	c.Assert(ctx.MakeRaw(), gc.ErrorMatches, "stdin is not a terminal")
+	c.Assert(ctx.Restore(), gc.IsNil)
+	_, err := ctx.WindowSize()
+	c.Assert(err, gc.ErrorMatches, "stdout is not a terminal")
+	stop := ctx.NotifyWindowSize(make(chan cmd.WindowSize))
+	stop()
+}
+
+func (s *TerminalSuite) TestFakeTerminal(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	c.Assert(ctx.IsTerminal(), gc.Equals, false)
+
+	ctx = cmdtesting.TerminalContext(c, 80, 24, true)
+	c.Assert(ctx.IsTerminal(), gc.Equals, true)
+	size, err := ctx.WindowSize()
+	c.Assert(err, gc.IsNil)
+	c.Assert(size, gc.Equals, cmd.WindowSize{Width: 80, Height: 24})
+}
+
+func (s *TerminalSuite) TestColorWriter(c *gc.C) {
+	ctx := cmdtesting.TerminalContext(c, 80, 24, true)
+	cmd.WriteError(ctx.Stderr, errors.New("boom"))
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "\x1b[91mERROR\x1b[0m boom\n")
+
+	ctx = cmdtesting.TerminalContext(c, 80, 24, false)
+	cmd.WriteError(ctx.Stderr, errors.New("boom"))
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR boom\n")
+}
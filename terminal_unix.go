@@ -0,0 +1,56 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var resizeSignal os.Signal = syscall.SIGWINCH
+
+type terminalState struct {
+	termios unix.Termios
+}
+
+// disableEcho stops the terminal echoing what is typed, leaving line
+// editing enabled, e.g. while a password is entered.
+func disableEcho(fd int) (*terminalState, error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, err
+	}
+	state := &terminalState{termios: *termios}
+	termios.Lflag &^= unix.ECHO
+	termios.Lflag |= unix.ICANON | unix.ISIG
+	termios.Iflag |= unix.ICRNL
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, termios); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func restore(fd int, state *terminalState) error {
+	return unix.IoctlSetTermios(fd, ioctlWriteTermios, &state.termios)
+}
+
+func isTerminalFd(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	return err == nil
+}
+
+// supportsANSIFd reports whether the terminal interprets ANSI escape
+// sequences, which all Unix terminals do.
+func supportsANSIFd(fd int) bool {
+	return true
+}
+
+// setUpConsoles does nothing, as Unix terminals need no setting up.
+func setUpConsoles(fds ...int) func() {
+	return func() {}
+}
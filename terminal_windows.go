@@ -0,0 +1,101 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows consoles do not signal when they are resized.
+var resizeSignal os.Signal
+
+// utf8CodePage is the console code page for UTF-8.
+const utf8CodePage = 65001
+
+var (
+	kernel32               = windows.NewLazySystemDLL("kernel32.dll")
+	procGetConsoleOutputCP = kernel32.NewProc("GetConsoleOutputCP")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+)
+
+type terminalState struct {
+	mode uint32
+}
+
+// disableEcho stops the console echoing what is typed, leaving line
+// editing enabled, e.g. while a password is entered.
+func disableEcho(fd int) (*terminalState, error) {
+	var mode uint32
+	if err := windows.GetConsoleMode(windows.Handle(fd), &mode); err != nil {
+		return nil, err
+	}
+	state := &terminalState{mode: mode}
+	noEcho := mode&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err := windows.SetConsoleMode(windows.Handle(fd), noEcho); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func restore(fd int, state *terminalState) error {
+	return windows.SetConsoleMode(windows.Handle(fd), state.mode)
+}
+
+func isTerminalFd(fd int) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+// supportsANSIFd reports whether the console interprets ANSI escape
+// sequences, which legacy consoles do not.
+func supportsANSIFd(fd int) bool {
+	var mode uint32
+	if err := windows.GetConsoleMode(windows.Handle(fd), &mode); err != nil {
+		return false
+	}
+	return mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0
+}
+
+// setUpConsoles turns on the processing of ANSI escape sequences by the
+// consoles connected to the given file descriptors, which Windows 10 and
+// later support, and switches the console output to UTF-8, whether or not
+// escape sequences were already processed. It returns a function that puts
+// the consoles back as they were. Consoles that do not support escape
+// sequences are not written colours.
+func setUpConsoles(fds ...int) func() {
+	var restores []func()
+	consoles := false
+	for _, fd := range fds {
+		handle := windows.Handle(fd)
+		var mode uint32
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			continue
+		}
+		consoles = true
+		if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+			continue
+		}
+		if err := windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+			logger.Debugf("console does not support escape sequences: %v", err)
+			continue
+		}
+		restores = append(restores, func() { _ = windows.SetConsoleMode(handle, mode) })
+	}
+	if consoles {
+		if codePage, _, _ := procGetConsoleOutputCP.Call(); codePage != 0 && codePage != utf8CodePage {
+			if ok, _, _ := procSetConsoleOutputCP.Call(utf8CodePage); ok != 0 {
+				restores = append(restores, func() { _, _, _ = procSetConsoleOutputCP.Call(codePage) })
+			}
+		}
+	}
+	return func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+}
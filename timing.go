@@ -0,0 +1,72 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timedCommand is implemented by commands that can request that Main
+// reports how long they took to run (see the --time flag).
+type timedCommand interface {
+	showTiming() bool
+}
+
+// phaseTimer records the duration of each phase of running a command.
+type phaseTimer struct {
+	ctx    *Context
+	start  time.Time
+	last   time.Time
+	phases []phaseDuration
+}
+
+type phaseDuration struct {
+	name     string
+	duration time.Duration
+}
+
+func newPhaseTimer(ctx *Context) *phaseTimer {
+	now := ctx.clock().Now()
+	return &phaseTimer{ctx: ctx, start: now, last: now}
+}
+
+// done marks the end of the named phase.
+func (t *phaseTimer) done(phase string) {
+	now := t.ctx.clock().Now()
+	t.phases = append(t.phases, phaseDuration{name: phase, duration: now.Sub(t.last)})
+	t.last = now
+}
+
+// report writes the total and per phase durations to the context's
+// Stderr, if c requested it.
+func (t *phaseTimer) report(c Command) {
+	if tc, ok := c.(timedCommand); !ok || !tc.showTiming() {
+		return
+	}
+	phases := make([]string, len(t.phases))
+	for i, phase := range t.phases {
+		phases[i] = fmt.Sprintf("%s %s", phase.name, formatDuration(phase.duration))
+	}
+	total := t.ctx.clock().Now().Sub(t.start)
+	fmt.Fprintf(t.ctx.Stderr, "Time: %s (%s)\n", formatDuration(total), strings.Join(phases, ", "))
+}
+
+// formatDuration formats d to millisecond precision.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// showTiming implements timedCommand. The --time flag may be given to
+// the selected nested super command rather than to c.
+func (c *SuperCommand) showTiming() bool {
+	if c.showTime {
+		return true
+	}
+	if sc, ok := c.action.command.(*SuperCommand); ok {
+		return sc.showTiming()
+	}
+	return false
+}
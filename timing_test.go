@@ -0,0 +1,76 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type TimingSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&TimingSuite{})
+
+func (s *TimingSuite) TestTimeFlag(c *gc.C) {
+	clock := testclock.NewClock(time.Now())
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = clock
+
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", TimeFlag: true})
+	sc.Register(&TestCommand{Name: "blah", CustomRun: func(*cmd.Context) error {
+		clock.Advance(1234567 * time.Microsecond)
+		return nil
+	}})
+	code := cmd.Main(sc, ctx, []string{"--time", "blah"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "Time: 1.235s (init 0s, run 1.235s)\n")
+}
+
+func (s *TimingSuite) TestNoTimeFlag(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", TimeFlag: true})
+	sc.Register(&TestCommand{Name: "blah"})
+	code := cmd.Main(sc, ctx, []string{"blah"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *TimingSuite) TestTimeFlagOnError(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = testclock.NewClock(time.Now())
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", TimeFlag: true})
+	sc.Register(&TestCommand{Name: "blah"})
+	code := cmd.Main(sc, ctx, []string{"blah", "--time", "--option", "error"})
+	c.Assert(code, gc.Equals, 1)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR BAM!\nTime: 0s (init 0s, run 0s)\n")
+}
+
+func (s *TimingSuite) TestTimeFlagNested(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Clock = testclock.NewClock(time.Now())
+	storage := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "storage"})
+	storage.Register(&TestCommand{Name: "list"})
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest", TimeFlag: true})
+	sc.Register(storage)
+	code := cmd.Main(sc, ctx, []string{"storage", "list", "--time"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "Time: 0s (init 0s, run 0s)\n")
+}
+
+func (s *TimingSuite) TestTimeFlagNotAddedByDefault(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	sc.Register(&TestCommand{Name: "blah"})
+	code := cmd.Main(sc, ctx, []string{"blah", "--time"})
+	c.Assert(code, gc.Equals, 2)
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "ERROR flag provided but not defined: --time\n")
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// HelpTopic describes a help topic of a SuperCommand, for rendering
+// outside of the help command.
+type HelpTopic struct {
+	// Name is the name the topic was added with.
+	Name string
+
+	// Short is the description shown when topics are listed.
+	Short string
+
+	// Aliases are the other names the topic is found by.
+	Aliases []string
+
+	// Category groups the topic with related topics when topics are
+	// listed.
+	Category string
+
+	// Content is the full text of the topic, as it was written.
+	Content string
+
+	// Markdown is true when Content is written in Markdown.
+	Markdown bool
+}
+
+// HelpTopics returns the help topics of the SuperCommand, including the
+// built-in ones, sorted by name.
+func (c *SuperCommand) HelpTopics() []HelpTopic {
+	var names []string
+	for name, t := range c.help.topics {
+		if !t.alias {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	topics := make([]HelpTopic, len(names))
+	for i, name := range names {
+		topics[i], _ = c.HelpTopic(name)
+	}
+	return topics
+}
+
+// HelpTopic returns the help topic with the given name or alias, and
+// whether it was found.
+func (c *SuperCommand) HelpTopic(name string) (HelpTopic, bool) {
+	t, ok := c.help.topics[name]
+	if !ok {
+		return HelpTopic{}, false
+	}
+	if t.name != "" {
+		name = t.name
+	}
+	topic := HelpTopic{
+		Name:     name,
+		Short:    t.short,
+		Category: t.category,
+		Content:  t.long(),
+		Markdown: t.markdown,
+	}
+	for alias, other := range c.help.topics {
+		if other.alias && other.name == name {
+			topic.Aliases = append(topic.Aliases, alias)
+		}
+	}
+	sort.Strings(topic.Aliases)
+	return topic, true
+}
+
+// AddHelpTopicsFS adds a help topic for each file in fsys matching pattern,
+// e.g. "topics/*.md", so that topics may be kept in files embedded with
+// embed.FS. A topic is named after its file, without the extension. The
+// first line of the file is its description, and the rest its full text.
+// Files with a ".md" extension are added as with AddMarkdownHelpTopic, and
+// a heading on the first line is used as the description. Adding a topic
+// with a name already in use panics.
+func (c *SuperCommand) AddHelpTopicsFS(fsys fs.FS, pattern string) error {
+	paths, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return errors.Annotatef(err, "invalid pattern %q", pattern)
+	}
+	if len(paths) == 0 {
+		return errors.Errorf("no help topics match %q", pattern)
+	}
+	for _, p := range paths {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return errors.Annotatef(err, "reading help topic %q", p)
+		}
+		ext := path.Ext(p)
+		name := strings.TrimSuffix(path.Base(p), ext)
+		short, long, _ := strings.Cut(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		long = strings.Trim(long, "\n")
+		if ext == ".md" {
+			if match := mdHeading.FindStringSubmatch(short); match != nil {
+				short = match[1]
+			}
+			c.AddMarkdownHelpTopic(name, strings.TrimSpace(short), long)
+		} else {
+			c.AddHelpTopic(name, strings.TrimSpace(short), long)
+		}
+	}
+	return nil
+}
+
+// text returns the full text of the topic, with any Markdown rendered as
+// plain text.
+func (t topic) text() string {
+	if t.markdown {
+		return MarkdownToText(t.long())
+	}
+	return t.long()
+}
+
+var (
+	mdHeading  = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	mdListItem = regexp.MustCompile(`^(\s*)[*+]\s+`)
+	mdImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrong   = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmphasis = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdCode     = regexp.MustCompile("`([^`]+)`")
+)
+
+// MarkdownToText renders Markdown as plain text suitable for a terminal.
+// Headings, emphasis and code spans lose their markup, links are followed
+// by their target in parentheses, and fenced code blocks are indented.
+func MarkdownToText(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	result := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			if inFence {
+				line = "    " + line
+			}
+			result = append(result, line)
+			continue
+		}
+		if match := mdHeading.FindStringSubmatch(line); match != nil {
+			line = match[1]
+		}
+		line = mdListItem.ReplaceAllString(line, "$1- ")
+		line = mdImage.ReplaceAllString(line, "$1")
+		line = mdLink.ReplaceAllStringFunc(line, func(link string) string {
+			match := mdLink.FindStringSubmatch(link)
+			if match[1] == match[2] {
+				return match[1]
+			}
+			return match[1] + " (" + match[2] + ")"
+		})
+		line = replaceOutsideCode(line, func(s string) string {
+			s = mdStrong.ReplaceAllString(s, "$2")
+			return mdEmphasis.ReplaceAllString(s, "$1")
+		})
+		line = mdCode.ReplaceAllString(line, "$1")
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// replaceOutsideCode applies replace to the parts of line that are not in
+// code spans.
+func replaceOutsideCode(line string, replace func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range mdCode.FindAllStringIndex(line, -1) {
+		b.WriteString(replace(line[last:span[0]]))
+		b.WriteString(line[span[0]:span[1]])
+		last = span[1]
+	}
+	b.WriteString(replace(line[last:]))
+	return b.String()
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"testing/fstest"
+
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type TopicsSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&TopicsSuite{})
+
+const spacesTopic = "# Spaces\n\nA **space** is a `subnet` group, see [the docs](https://juju.is/docs).\n\n* one\n* two\n\n```\njuju spaces\n```"
+
+func (s *TopicsSuite) newSuper() *cmd.SuperCommand {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	super.AddHelpTopic("basics", "Basic commands", "juju help basics")
+	super.AddMarkdownHelpTopic("spaces", "About spaces", spacesTopic, "space", "network-spaces")
+	return super
+}
+
+func (s *TopicsSuite) TestHelpTopic(c *gc.C) {
+	super := s.newSuper()
+	expected := cmd.HelpTopic{
+		Name:     "spaces",
+		Short:    "About spaces",
+		Aliases:  []string{"network-spaces", "space"},
+		Content:  spacesTopic,
+		Markdown: true,
+	}
+	topic, ok := super.HelpTopic("spaces")
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(topic, gc.DeepEquals, expected)
+	topic, ok = super.HelpTopic("space")
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(topic, gc.DeepEquals, expected)
+	_, ok = super.HelpTopic("unknown")
+	c.Assert(ok, gc.Equals, false)
+}
+
+func (s *TopicsSuite) TestHelpTopics(c *gc.C) {
+	var names []string
+	for _, topic := range s.newSuper().HelpTopics() {
+		names = append(names, topic.Name)
+	}
+	c.Assert(names, gc.DeepEquals, []string{"basics", "commands", "global-flags", "spaces", "topics"})
+}
+
+func (s *TopicsSuite) TestHelpRendersMarkdown(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(s.newSuper(), ctx, []string{"help", "space"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+Spaces
+
+A space is a subnet group, see the docs (https://juju.is/docs).
+
+- one
+- two
+
+    juju spaces
+`[1:])
+}
+
+func (s *TopicsSuite) TestMarkdownToText(c *gc.C) {
+	for i, test := range []struct {
+		markdown string
+		text     string
+	}{
+		{"## Heading ##", "Heading"},
+		{"__strong__ and *emphasis*", "strong and emphasis"},
+		{"use `**not bold**` here", "use **not bold** here"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+		{"see <https://x>", "see <https://x>"},
+		{"[https://x](https://x)", "https://x"},
+		{"![logo](logo.png)", "logo"},
+		{"    *literal*", "    *literal*"},
+		{"  + nested", "  - nested"},
+	} {
+		c.Check(cmd.MarkdownToText(test.markdown), gc.Equals, test.text, gc.Commentf("test %d", i))
+	}
+}
+
+func (s *TopicsSuite) TestTopicCategories(c *gc.C) {
+	super := s.newSuper()
+	super.AddHelpTopicCallback("subnets", "About subnets", func() string { return "Subnets." }, "subnet")
+	super.AddHelpTopic("clouds", "About clouds", "Clouds.")
+	super.SetHelpTopicCategory("Networking", "space", "subnets")
+	super.SetHelpTopicCategory("Infrastructure", "clouds")
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"help", "topics"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+basics        Basic commands
+commands      Basic help for all commands
+global-flags  Flags common to all commands
+topics        Topic list
+
+Infrastructure:
+clouds        About clouds
+
+Networking:
+spaces        About spaces
+subnets       About subnets
+`[1:])
+
+	topic, ok := super.HelpTopic("subnet")
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(topic.Name, gc.Equals, "subnets")
+	c.Assert(topic.Aliases, gc.DeepEquals, []string{"subnet"})
+	c.Assert(topic.Category, gc.Equals, "Networking")
+	topic, _ = super.HelpTopic("network-spaces")
+	c.Assert(topic.Category, gc.Equals, "Networking")
+
+	ctx = cmdtesting.Context(c)
+	code = cmd.Main(super, ctx, []string{"help", "subnet"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "Subnets.\n")
+}
+
+func (s *TopicsSuite) TestTopicCategoryUnknown(c *gc.C) {
+	super := s.newSuper()
+	c.Assert(func() { super.SetHelpTopicCategory("Networking", "unknown") }, gc.PanicMatches, "unknown help topic: unknown")
+}
+
+func (s *TopicsSuite) TestAddHelpTopicsFS(c *gc.C) {
+	fsys := fstest.MapFS{
+		"topics/spaces.md":   {Data: []byte("# About spaces\n\nA **space** is a group of subnets.\n")},
+		"topics/basics.txt":  {Data: []byte("Basic commands\r\n\r\njuju help basics\r\n")},
+		"topics/README":      {Data: []byte("not a topic")},
+		"other/ignored.md":   {Data: []byte("# Ignored")},
+		"topics/no-body.txt": {Data: []byte("Just a description")},
+	}
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	err := super.AddHelpTopicsFS(fsys, "topics/*.*")
+	c.Assert(err, jc.ErrorIsNil)
+
+	topic, ok := super.HelpTopic("spaces")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(topic, jc.DeepEquals, cmd.HelpTopic{
+		Name:     "spaces",
+		Short:    "About spaces",
+		Content:  "A **space** is a group of subnets.",
+		Markdown: true,
+	})
+	topic, ok = super.HelpTopic("basics")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(topic, jc.DeepEquals, cmd.HelpTopic{
+		Name:    "basics",
+		Short:   "Basic commands",
+		Content: "juju help basics",
+	})
+	topic, ok = super.HelpTopic("no-body")
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(topic.Short, gc.Equals, "Just a description")
+	c.Assert(topic.Content, gc.Equals, "")
+
+	_, ok = super.HelpTopic("README")
+	c.Assert(ok, jc.IsFalse)
+	_, ok = super.HelpTopic("ignored")
+	c.Assert(ok, jc.IsFalse)
+
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(super, ctx, []string{"help", "spaces"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "A space is a group of subnets.\n")
+}
+
+func (s *TopicsSuite) TestAddHelpTopicsFSErrors(c *gc.C) {
+	super := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	err := super.AddHelpTopicsFS(fstest.MapFS{}, "topics/*.md")
+	c.Assert(err, gc.ErrorMatches, `no help topics match "topics/\*.md"`)
+
+	err = super.AddHelpTopicsFS(fstest.MapFS{}, "topics/[")
+	c.Assert(err, gc.ErrorMatches, `invalid pattern "topics/\[": .*`)
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/yaml.v2"
+)
+
+// usageStatsFilename is the name of the file in the data directory that
+// holds the usage counts of each command.
+const usageStatsFilename = "usage-stats.yaml"
+
+// noTelemetryFlag is the flag that prevents usage from being recorded.
+const noTelemetryFlag = "no-telemetry"
+
+// DoNotTrackEnvKey is the environment variable that, when set to anything
+// other than "" or "0", prevents usage from being recorded.
+const DoNotTrackEnvKey = "DO_NOT_TRACK"
+
+// UsageCount holds how often a command has been run. It holds no
+// arguments, so that nothing identifying is recorded.
+type UsageCount struct {
+	Command  string    `json:"command" yaml:"command"`
+	Runs     int       `json:"runs" yaml:"runs"`
+	Failures int       `json:"failures" yaml:"failures"`
+	LastUsed time.Time `json:"last-used" yaml:"last-used"`
+}
+
+// UsageUploader sends the usage counts recorded in the data directory
+// elsewhere. It is only called when the user has not opted out, and not
+// when --no-remote is given.
+type UsageUploader func(ctx *Context, counts []UsageCount) error
+
+// readUsageStats reads the usage counts from dir, sorted by the number of
+// runs. A missing file results in no counts.
+func readUsageStats(dir string) ([]UsageCount, error) {
+	var counts []UsageCount
+	content, err := ioutil.ReadFile(filepath.Join(dir, usageStatsFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := yaml.Unmarshal(content, &counts); err != nil {
+		return nil, errors.Trace(err)
+	}
+	sortUsageCounts(counts)
+	return counts, nil
+}
+
+// sortUsageCounts sorts counts by the number of runs, most first.
+func sortUsageCounts(counts []UsageCount) {
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Runs != counts[j].Runs {
+			return counts[i].Runs > counts[j].Runs
+		}
+		return counts[i].Command < counts[j].Command
+	})
+}
+
+// writeUsageStats writes the usage counts to dir.
+func writeUsageStats(dir string, counts []UsageCount) error {
+	content, err := yaml.Marshal(counts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(filepath.Join(dir, usageStatsFilename), content, 0600))
+}
+
+// telemetryDisabled reports whether the user has opted out of usage
+// statistics with --no-telemetry or the DO_NOT_TRACK environment variable.
+func (c *SuperCommand) telemetryDisabled(ctx *Context) bool {
+	if c.noTelemetry {
+		return true
+	}
+	value := ctx.lookupEnv(DoNotTrackEnvKey)
+	return value != "" && value != "0"
+}
+
+// usageStatsLock is the name of the lock held while the usage statistics
+// are updated, so that commands finishing at the same time do not lose
+// each other's counts.
+const usageStatsLock = "usage-stats"
+
+// finishNotifier returns the function called when the subcommand action
+// finishes: NotifyFinish, followed by the recording of usage statistics
+// when they are enabled. It returns nil if there is nothing to call.
+func (c *SuperCommand) finishNotifier(ctx *Context, action commandReference) func(string, error, time.Duration) {
+	notify := c.notifyFinish
+	if c.usageStatsDir == "" || !countsUsage(action) {
+		return notify
+	}
+	return func(command string, err error, duration time.Duration) {
+		if notify != nil {
+			notify(command, err, duration)
+		}
+		c.recordUsage(ctx, command, err)
+	}
+}
+
+// countsUsage reports whether runs of the subcommand action are counted in
+// the usage statistics. Hidden commands, such as the one run by shell
+// completion on each key press, and the framework's completion and stats
+// commands are not.
+func countsUsage(action commandReference) bool {
+	if isHiddenCommand(action.name) {
+		return false
+	}
+	switch action.command.(type) {
+	case *completionCommand, *statsCommand:
+		return false
+	}
+	return true
+}
+
+// recordUsage counts a run of the command, which failed if err is not nil,
+// in the usage statistics, and passes them to the uploader, if any.
+// Problems are logged rather than failing the command.
+func (c *SuperCommand) recordUsage(ctx *Context, command string, err error) {
+	// The wait for the lock is logged rather than shown to the user.
+	lockCtx := ctx.With(ctx.background())
+	lockCtx.quiet = true
+	release, lockErr := lockCtx.AcquireLock(c.usageStatsDir, usageStatsLock, true)
+	if lockErr != nil {
+		logger.Debugf("cannot lock usage statistics: %v", lockErr)
+		return
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil {
+			logger.Debugf("cannot unlock usage statistics: %v", releaseErr)
+		}
+	}()
+	counts, readErr := readUsageStats(c.usageStatsDir)
+	if readErr != nil {
+		logger.Debugf("cannot read usage statistics: %v", readErr)
+		return
+	}
+	i := 0
+	for i < len(counts) && counts[i].Command != command {
+		i++
+	}
+	if i == len(counts) {
+		counts = append(counts, UsageCount{Command: command})
+	}
+	counts[i].Runs++
+	if err != nil {
+		counts[i].Failures++
+	}
+	counts[i].LastUsed = ctx.clock().Now().UTC()
+	sortUsageCounts(counts)
+	if writeErr := writeUsageStats(c.usageStatsDir, counts); writeErr != nil {
+		logger.Debugf("cannot write usage statistics: %v", writeErr)
+		return
+	}
+	if c.usageUploader != nil && !ctx.noRemote {
+		if uploadErr := c.usageUploader(ctx, counts); uploadErr != nil {
+			logger.Debugf("cannot upload usage statistics: %v", uploadErr)
+		}
+	}
+}
+
+// statsCommand shows the usage statistics recorded in the data directory.
+type statsCommand struct {
+	CommandBase
+	super *SuperCommand
+	out   Output
+}
+
+func (c *statsCommand) Info() *Info {
+	return &Info{
+		Name:               "stats",
+		Purpose:            "Show how often each command has been run.",
+		NonInteractiveSafe: true,
+		Doc: fmt.Sprintf(`
+Show how often each command has been run on this machine, and how often it
+failed. Only the names of commands are recorded, and they are kept locally.
+Give --%s, or set %s=1, to stop recording them.`[1:], noTelemetryFlag, DoNotTrackEnvKey),
+	}
+}
+
+func (c *statsCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "tabular", map[string]Formatter{
+		"tabular": formatUsageCounts,
+		"json":    FormatJson,
+		"yaml":    FormatYaml,
+	})
+}
+
+func (c *statsCommand) Init(args []string) error {
+	return CheckEmpty(args)
+}
+
+func (c *statsCommand) Run(ctx *Context) error {
+	counts, err := readUsageStats(ctx.expandHome(c.super.dataDir))
+	if err != nil {
+		return errors.Annotate(err, "reading usage statistics")
+	}
+	if counts == nil {
+		counts = []UsageCount{}
+	}
+	return errors.Trace(c.out.Write(ctx, counts))
+}
+
+// formatUsageCounts writes usage counts as a table.
+func formatUsageCounts(writer io.Writer, value interface{}) error {
+	counts, ok := value.([]UsageCount)
+	if !ok {
+		return errors.Errorf("expected value of type %T, got %T", counts, value)
+	}
+	if len(counts) == 0 {
+		_, err := fmt.Fprintln(writer, "No commands have been recorded.")
+		return err
+	}
+	tw := tabwriter.NewWriter(writer, 0, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tRUNS\tFAILURES\tLAST USED")
+	for _, count := range counts {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", count.Command, count.Runs, count.Failures, count.LastUsed.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
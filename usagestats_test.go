@@ -0,0 +1,167 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/juju/clock/testclock"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type UsageStatsSuite struct {
+	testing.IsolationSuite
+
+	dataDir  string
+	now      time.Time
+	uploaded [][]cmd.UsageCount
+}
+
+var _ = gc.Suite(&UsageStatsSuite{})
+
+func (s *UsageStatsSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.dataDir = c.MkDir()
+	s.now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.uploaded = nil
+}
+
+func (s *UsageStatsSuite) run(c *gc.C, env map[string]string, args ...string) (*cmd.Context, int) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:         "juju",
+		DataDir:      s.dataDir,
+		UsageStats:   true,
+		NoRemoteFlag: true,
+		UsageUploader: func(ctx *cmd.Context, counts []cmd.UsageCount) error {
+			s.uploaded = append(s.uploaded, counts)
+			return nil
+		},
+	})
+	sc.Register(&TestCommand{Name: "blah"})
+	sc.Register(&TestCommand{Name: "bleh"})
+	ctx := cmdtesting.Context(c)
+	ctx.Env = env
+	ctx.Clock = testclock.NewClock(s.now)
+	return ctx, cmd.Main(sc, ctx, args)
+}
+
+func (s *UsageStatsSuite) TestRecordsUsage(c *gc.C) {
+	_, code := s.run(c, nil, "blah")
+	c.Assert(code, gc.Equals, 0)
+	_, code = s.run(c, nil, "bleh", "--option", "error")
+	c.Assert(code, gc.Equals, 1)
+	_, code = s.run(c, nil, "bleh")
+	c.Assert(code, gc.Equals, 0)
+
+	c.Assert(s.uploaded, gc.HasLen, 3)
+	c.Assert(s.uploaded[2], jc.DeepEquals, []cmd.UsageCount{
+		{Command: "juju bleh", Runs: 2, Failures: 1, LastUsed: s.now},
+		{Command: "juju blah", Runs: 1, LastUsed: s.now},
+	})
+
+	ctx, code := s.run(c, nil, "stats")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, ""+
+		"COMMAND    RUNS  FAILURES  LAST USED\n"+
+		"juju bleh  2     1         2024-05-01T12:00:00Z\n"+
+		"juju blah  1     0         2024-05-01T12:00:00Z\n")
+}
+
+func (s *UsageStatsSuite) TestFrameworkCommandsNotRecorded(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:            "juju",
+		DataDir:         s.dataDir,
+		UsageStats:      true,
+		ShellCompletion: true,
+		UsageUploader: func(ctx *cmd.Context, counts []cmd.UsageCount) error {
+			s.uploaded = append(s.uploaded, counts)
+			return nil
+		},
+	})
+	sc.Register(&TestCommand{Name: "blah"})
+	for _, args := range [][]string{
+		{"__complete", "2", "juju", "bl"},
+		{"completion", "bash"},
+		{"stats"},
+	} {
+		c.Assert(cmd.Main(sc, cmdtesting.Context(c), args), gc.Equals, 0, gc.Commentf("%v", args))
+	}
+	_, err := os.Stat(filepath.Join(s.dataDir, "usage-stats.yaml"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+	c.Assert(s.uploaded, gc.HasLen, 0)
+}
+
+func (s *UsageStatsSuite) TestConcurrentRuns(c *gc.C) {
+	const runs = 10
+	var finished []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+				Name:       "juju",
+				DataDir:    s.dataDir,
+				UsageStats: true,
+				NotifyFinish: func(name string, err error, _ time.Duration) {
+					mu.Lock()
+					defer mu.Unlock()
+					finished = append(finished, name)
+				},
+			})
+			sc.Register(&TestCommand{Name: "blah"})
+			c.Check(cmd.Main(sc, cmdtesting.Context(c), []string{"blah"}), gc.Equals, 0)
+		}()
+	}
+	wg.Wait()
+	c.Assert(finished, gc.HasLen, runs)
+
+	ctx, code := s.run(c, nil, "stats", "--format", "yaml")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `(?s)- command: juju blah\n  runs: 10\n.*`)
+}
+
+func (s *UsageStatsSuite) TestNoStats(c *gc.C) {
+	ctx, code := s.run(c, nil, "stats", "--no-telemetry")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "No commands have been recorded.\n")
+}
+
+func (s *UsageStatsSuite) TestOptOut(c *gc.C) {
+	_, code := s.run(c, nil, "blah", "--no-telemetry")
+	c.Assert(code, gc.Equals, 0)
+	_, code = s.run(c, map[string]string{"DO_NOT_TRACK": "1"}, "blah")
+	c.Assert(code, gc.Equals, 0)
+	_, err := os.Stat(filepath.Join(s.dataDir, "usage-stats.yaml"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+	c.Assert(s.uploaded, gc.HasLen, 0)
+
+	_, code = s.run(c, map[string]string{"DO_NOT_TRACK": "0"}, "blah")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(s.uploaded, gc.HasLen, 1)
+}
+
+func (s *UsageStatsSuite) TestNoRemoteSkipsUpload(c *gc.C) {
+	_, code := s.run(c, nil, "blah", "--no-remote")
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(s.uploaded, gc.HasLen, 0)
+	_, err := os.Stat(filepath.Join(s.dataDir, "usage-stats.yaml"))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UsageStatsSuite) TestDisabledWithoutDataDir(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju", UsageStats: true})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, []string{"stats"})
+	c.Assert(code, gc.Equals, 2)
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/yaml.v2"
+)
+
+// userConfig holds the persisted default flag values for the subcommands
+// of a SuperCommand, keyed by subcommand path and then by flag name.
+// Nested SuperCommands without a config file of their own share that of
+// their parent, under their own path.
+//
+// The file is YAML, for example:
+//
+//	status:
+//	  format: json
+//	  color: "true"
+//	storage list:
+//	  format: yaml
+type userConfig map[string]map[string]string
+
+// readUserConfig reads the user config file. A missing file is treated as
+// empty.
+func readUserConfig(filename string) (userConfig, error) {
+	cfg := make(userConfig)
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, errors.Annotate(err, "reading config file")
+	}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, errors.Annotatef(err, "parsing config file %q", filename)
+	}
+	return cfg, nil
+}
+
+// write writes the config to filename, creating its directory if needed.
+func (cfg userConfig) write(filename string) error {
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return errors.Annotate(err, "creating config directory")
+	}
+	return errors.Annotate(ioutil.WriteFile(filename, content, 0600), "writing config file")
+}
+
+// set records the default value of flag for the named command.
+func (cfg userConfig) set(command, flag, value string) {
+	if cfg[command] == nil {
+		cfg[command] = make(map[string]string)
+	}
+	cfg[command][flag] = value
+}
+
+// unset removes the default value of flag for the named command.
+func (cfg userConfig) unset(command, flag string) {
+	delete(cfg[command], flag)
+	if len(cfg[command]) == 0 {
+		delete(cfg, command)
+	}
+}
+
+// configKey returns the key of the subcommand action in the user config
+// file: its path below the SuperCommand the file belongs to. Aliases share
+// the key of the command they stand for.
+func (c *SuperCommand) configKey(action commandReference) string {
+	name := action.name
+	if action.alias != "" {
+		name = action.alias
+	} else if name == "" {
+		name = action.command.Info().Name
+	}
+	if c.configPrefix == "" {
+		return name
+	}
+	return c.configPrefix + " " + name
+}
+
+// applyFlagDefaults sets the flags in f of the subcommand named command
+// that were not given on the command line from the environment, if
+// FlagEnvPrefix is set, or else from the user config file. Flags given on
+// the command line take precedence over both. The common flags of the
+// SuperCommand, such as --help and --debug, are never defaulted.
+func (c *SuperCommand) applyFlagDefaults(f *gnuflag.FlagSet, command string) error {
+	c.defaulted = make(map[interface{}]bool)
+	if c.flagEnvPrefix == "" && c.userConfigFilename == "" {
+		return nil
+	}
+	getenv := os.Getenv
+	if c.dynamicContext != nil {
+		getenv = c.dynamicContext.lookupEnv
+	}
+	var defaults map[string]string
+	if c.userConfigFilename != "" {
+		filename := expandHome(c.userConfigFilename, getenv)
+		cfg, err := readUserConfig(filename)
+		if err != nil {
+			logger.Warningf("ignoring config file: %v", err)
+		}
+		defaults = cfg[command]
+	}
+
+	// Flags that set the same value are aliases of each other.
+	given := make(map[interface{}]bool)
+	f.Visit(func(flag *gnuflag.Flag) {
+		given[flag.Value] = true
+	})
+	if c.flags != nil {
+		c.flags.VisitAll(func(flag *gnuflag.Flag) {
+			given[flag.Value] = true
+		})
+	}
+	flags := make(map[interface{}][]*gnuflag.Flag)
+	var values []interface{}
+	f.VisitAll(func(flag *gnuflag.Flag) {
+		if given[flag.Value] {
+			return
+		}
+		if _, ok := flags[flag.Value]; !ok {
+			values = append(values, flag.Value)
+		}
+		flags[flag.Value] = append(flags[flag.Value], flag)
+	})
+	for _, value := range values {
+		if err := c.applyFlagDefault(flags[value], getenv, defaults, f.FlagKnownAs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyFlagDefault sets the flag with the given names from the environment
+// or from defaults.
+func (c *SuperCommand) applyFlagDefault(flags []*gnuflag.Flag, getenv func(string) string, defaults map[string]string, flagKnownAs string) error {
+	if c.flagEnvPrefix != "" {
+		for _, flag := range flags {
+			if len(flag.Name) == 1 {
+				continue
+			}
+			key := c.flagEnvPrefix + strings.ToUpper(strings.Replace(flag.Name, "-", "_", -1))
+			if value := getenv(key); value != "" {
+				if err := flag.Value.Set(value); err != nil {
+					return fmt.Errorf("invalid value %q for %s --%s from $%s: %v", value, flagKnownAs, flag.Name, key, err)
+				}
+				c.defaulted[flag.Value] = true
+				return nil
+			}
+		}
+	}
+	for _, flag := range flags {
+		if value, ok := defaults[flag.Name]; ok {
+			if err := flag.Value.Set(value); err != nil {
+				return fmt.Errorf("invalid value %q for %s %s from config file: %v", value, flagKnownAs, flag.Name, err)
+			}
+			c.defaulted[flag.Value] = true
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem describes an inconsistency in a tree of registered commands,
+// found by ValidateTree.
+type Problem struct {
+	// Command is the full name of the command with the problem.
+	Command string
+
+	// Message describes the problem.
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (p Problem) String() string {
+	return p.Command + ": " + p.Message
+}
+
+// ValidateTree checks the commands registered with super, and with any
+// SuperCommands registered beneath it, and returns the problems found:
+//   - names that differ from another registered name only by case;
+//   - commands that list their own name as an alias;
+//   - commands shown in help that have no purpose;
+//   - "see also" entries that do not name a registered command;
+//   - deprecated commands whose replacement is not a registered command.
+//
+// It is intended to be called from the tests of applications built with
+// this package, so that mistakes are caught before release.
+func ValidateTree(super *SuperCommand) []Problem {
+	var problems []Problem
+	validateTree(super, super.Name, &problems)
+	return problems
+}
+
+func validateTree(c *SuperCommand, path string, problems *[]Problem) {
+	c.init()
+	add := func(name, format string, args ...interface{}) {
+		*problems = append(*problems, Problem{
+			Command: strings.TrimSpace(path + " " + name),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	names := make([]string, 0, len(c.subcmds))
+	for name := range c.subcmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	folded := make(map[string]string)
+	for _, name := range names {
+		if other, found := folded[strings.ToLower(name)]; found {
+			add(name, "name differs only by case from %q", other)
+		} else {
+			folded[strings.ToLower(name)] = name
+		}
+	}
+
+	for _, name := range names {
+		ref := c.subcmds[name]
+		deprecated, replacement := ref.Deprecated()
+		if deprecated && replacement != "" && !c.resolves(replacement) {
+			add(name, "deprecated in favour of %q, which is not a registered command", replacement)
+		}
+		if ref.alias != "" {
+			continue
+		}
+		info := ref.command.Info()
+		for _, alias := range info.Aliases {
+			if alias == info.Name {
+				add(name, "lists its own name as an alias")
+			}
+		}
+		if !deprecated && !isDefaultCommand(name) && strings.TrimSpace(info.Purpose) == "" {
+			add(name, "has no purpose")
+		}
+		for _, seeAlso := range info.SeeAlso {
+			if !c.resolves(seeAlso) {
+				add(name, "see also %q is not a registered command", seeAlso)
+			}
+		}
+		if sub, ok := ref.command.(*SuperCommand); ok {
+			validateTree(sub, path+" "+name, problems)
+		}
+	}
+}
+
+// resolves reports whether path, a command name optionally followed by
+// the names of nested subcommands, names a registered command.
+func (c *SuperCommand) resolves(path string) bool {
+	fields := strings.Fields(path)
+	if len(fields) == 0 {
+		return false
+	}
+	ref, found := c.subcmds[fields[0]]
+	if !found {
+		return false
+	}
+	if len(fields) == 1 {
+		return true
+	}
+	sub, ok := ref.command.(*SuperCommand)
+	return ok && sub.resolves(strings.Join(fields[1:], " "))
+}
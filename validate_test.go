@@ -0,0 +1,108 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"strings"
+	"testing/quick"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type ValidateTreeSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ValidateTreeSuite{})
+
+// infoCommand is a command described by the Info it holds.
+type infoCommand struct {
+	cmd.CommandBase
+	info cmd.Info
+}
+
+func (c *infoCommand) Info() *cmd.Info {
+	return &c.info
+}
+
+func (c *infoCommand) Run(*cmd.Context) error {
+	return nil
+}
+
+func (s *ValidateTreeSuite) TestBuiltInCommands(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:               "juju",
+		Version:            "1.0.0",
+		Changelog:          changelog,
+		UserConfigFilename: "~/.config/juju/defaults.yaml",
+	})
+	sc.RegisterCheck("ok", check(cmd.CheckPass, "ok"))
+	c.Assert(cmd.ValidateTree(sc), gc.HasLen, 0)
+}
+
+func (s *ValidateTreeSuite) TestProblems(c *gc.C) {
+	model := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "model", Purpose: "Manage models."})
+	model.Register(&infoCommand{info: cmd.Info{Name: "config", Purpose: "Configure a model.", SeeAlso: []string{"status", "model-defaults"}}})
+	model.Register(&infoCommand{info: cmd.Info{Name: "undocumented"}})
+
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	sc.Register(model)
+	sc.Register(&infoCommand{info: cmd.Info{Name: "status", Purpose: "Show status.", Aliases: []string{"Status"}}})
+	sc.Register(&infoCommand{info: cmd.Info{Name: "deploy", Purpose: "Deploy.", SeeAlso: []string{"model config", "status"}}})
+	sc.RegisterAlias("old-deploy", "deploy", deprecate{replacement: "new-deploy"})
+	sc.RegisterAlias("older-deploy", "deploy", deprecate{replacement: "deploy"})
+
+	problems := cmd.ValidateTree(sc)
+	descriptions := make([]string, len(problems))
+	for i, problem := range problems {
+		descriptions[i] = problem.String()
+	}
+	c.Assert(descriptions, gc.DeepEquals, []string{
+		`juju status: name differs only by case from "Status"`,
+		`juju model config: see also "status" is not a registered command`,
+		`juju model config: see also "model-defaults" is not a registered command`,
+		`juju model undocumented: has no purpose`,
+		`juju old-deploy: deprecated in favour of "new-deploy", which is not a registered command`,
+	})
+}
+
+func (s *ValidateTreeSuite) TestRegistrationProperties(c *gc.C) {
+	// Whatever the names registered, the resulting tree is valid as long as
+	// no two names differ only by case, and each registered name selects
+	// its command.
+	property := func(names []string) bool {
+		sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+		registered := make(map[string]bool)
+		for _, name := range names {
+			name = strings.ToLower(strings.Map(func(r rune) rune {
+				if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+					return r
+				}
+				return -1
+			}, name))
+			if name == "" || registered[name] || name == "help" || name == "documentation" {
+				continue
+			}
+			registered[name] = true
+			sc.Register(&TestCommand{Name: name})
+		}
+		if len(cmd.ValidateTree(sc)) != 0 {
+			return false
+		}
+		for name := range registered {
+			if err := cmdtesting.InitCommand(sc, []string{name, "--option", "x"}); err != nil {
+				return false
+			}
+			if !strings.HasPrefix(sc.Info().Name, "juju "+name) {
+				return false
+			}
+		}
+		return true
+	}
+	c.Assert(quick.Check(property, nil), gc.IsNil)
+}
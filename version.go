@@ -26,17 +26,14 @@ func newVersionCommand(version string, versionDetail interface{}) *versionComman
 
 func (v *versionCommand) Info() *Info {
 	return &Info{
-		Name:    "version",
-		Purpose: "Print the current version.",
+		Name:               "version",
+		Purpose:            "Print the current version.",
+		NonInteractiveSafe: true,
 	}
 }
 
 func (v *versionCommand) SetFlags(f *gnuflag.FlagSet) {
-	formatters := make(map[string]Formatter, len(DefaultFormatters))
-	for k, v := range DefaultFormatters {
-		formatters[k] = v.Formatter
-	}
-	v.out.AddFlags(f, "smart", formatters)
+	v.out.AddFormatFlags(f, "smart", DefaultFormatters)
 	f.BoolVar(&v.showAll, "all", false, "Prints all version information")
 }
 
@@ -0,0 +1,149 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/juju/loggo/v2"
+)
+
+// WarningCode is a stable, machine readable identifier for a warning, so
+// that automation can suppress or alert on specific warnings rather than
+// matching on their text.
+type WarningCode string
+
+const (
+	// WarningDeprecatedCommand is emitted when a deprecated command or
+	// alias is run.
+	WarningDeprecatedCommand WarningCode = "deprecated-command"
+
+	// WarningIgnoredArgs is emitted when arguments given along with
+	// --help are ignored.
+	WarningIgnoredArgs WarningCode = "ignored-args"
+
+	// WarningNonInteractive is emitted when a command that may prompt
+	// for input is run in CI.
+	WarningNonInteractive WarningCode = "non-interactive"
+
+	// WarningExperimentalCommand is emitted when an experimental command
+	// is run.
+	WarningExperimentalCommand WarningCode = "experimental-command"
+)
+
+// Warning is a warning emitted with WarningWithCodef.
+type Warning struct {
+	Code    WarningCode `json:"code" yaml:"code"`
+	Message string      `json:"message" yaml:"message"`
+}
+
+// warningState holds the warnings emitted through a context, which may
+// be emitted concurrently, e.g. by a command working on several units at
+// once. It is shared by the copies of the context made once it exists.
+type warningState struct {
+	mu         sync.Mutex
+	warnings   []Warning
+	suppressed map[WarningCode]bool
+	counts     map[string]int
+	repeated   []string
+}
+
+// warningStateMu guards the creation of the warningState of each context.
+var warningStateMu sync.Mutex
+
+// warningState returns the warningState of ctx, creating it if needed.
+func (ctx *Context) warningState() *warningState {
+	warningStateMu.Lock()
+	defer warningStateMu.Unlock()
+	if ctx.warnings == nil {
+		ctx.warnings = &warningState{}
+	}
+	return ctx.warnings
+}
+
+// WarningWithCodef logs a warning in the same way as Warningf, and records
+// it along with its code so that it can be included in structured output
+// (see Warnings). Warnings whose code has been suppressed are neither
+// logged nor recorded.
+func (ctx *Context) WarningWithCodef(code WarningCode, format string, params ...interface{}) {
+	state := ctx.warningState()
+	state.mu.Lock()
+	if state.suppressed[code] {
+		state.mu.Unlock()
+		return
+	}
+	message := fmt.Sprintf(format, params...)
+	state.warnings = append(state.warnings, Warning{Code: code, Message: message})
+	repeated := state.repeat(message)
+	state.mu.Unlock()
+	if !repeated {
+		// See Warningf for why Logf is used here.
+		logger.Logf(loggo.WARNING, "%s", message)
+	}
+	ctx.emit(Event{Kind: EventWarning, Code: code, Message: message})
+}
+
+// repeatWarning counts a warning with the given message, and reports
+// whether it has already been logged.
+func (ctx *Context) repeatWarning(message string) bool {
+	state := ctx.warningState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.repeat(message)
+}
+
+// repeat counts a warning with the given message, and reports whether it
+// has already been logged. Warnings are only logged the first time they
+// are emitted while a command runs, so that warnings emitted in loops do
+// not flood the output. The repeats are reported when the command
+// finishes. The caller must hold s.mu.
+func (s *warningState) repeat(message string) bool {
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+	}
+	s.counts[message]++
+	switch s.counts[message] {
+	case 1:
+		return false
+	case 2:
+		s.repeated = append(s.repeated, message)
+	}
+	return true
+}
+
+// reportRepeatedWarnings logs each warning that was emitted more than
+// once, along with the number of times it was emitted, and forgets the
+// warnings that have been emitted.
+func (ctx *Context) reportRepeatedWarnings() {
+	state := ctx.warningState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for _, message := range state.repeated {
+		logger.Logf(loggo.WARNING, "%s (repeated %d times)", message, state.counts[message])
+	}
+	state.counts, state.repeated = nil, nil
+}
+
+// Warnings returns the warnings emitted with WarningWithCodef.
+func (ctx *Context) Warnings() []Warning {
+	state := ctx.warningState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return append([]Warning(nil), state.warnings...)
+}
+
+// SuppressWarnings prevents warnings with any of the given codes from
+// being emitted.
+func (ctx *Context) SuppressWarnings(codes ...WarningCode) {
+	state := ctx.warningState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.suppressed == nil {
+		state.suppressed = make(map[WarningCode]bool)
+	}
+	for _, code := range codes {
+		state.suppressed[code] = true
+	}
+}
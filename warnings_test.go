@@ -0,0 +1,129 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"sync"
+
+	"github.com/juju/loggo/v2"
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type WarningsSuite struct {
+	testing.LoggingCleanupSuite
+
+	ctx *cmd.Context
+}
+
+var _ = gc.Suite(&WarningsSuite{})
+
+func (s *WarningsSuite) SetUpTest(c *gc.C) {
+	s.LoggingCleanupSuite.SetUpTest(c)
+	s.ctx = cmdtesting.Context(c)
+	loggo.ReplaceDefaultWriter(cmd.NewWarningWriter(s.ctx.Stderr))
+}
+
+func (s *WarningsSuite) TestWarningWithCodef(c *gc.C) {
+	s.ctx.WarningWithCodef("test-warning", "something %s", "odd")
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "WARNING something odd\n")
+	c.Assert(s.ctx.Warnings(), gc.DeepEquals, []cmd.Warning{{
+		Code:    "test-warning",
+		Message: "something odd",
+	}})
+}
+
+func (s *WarningsSuite) TestSuppressWarnings(c *gc.C) {
+	s.ctx.SuppressWarnings("test-warning")
+	s.ctx.WarningWithCodef("test-warning", "something odd")
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "")
+	c.Assert(s.ctx.Warnings(), gc.HasLen, 0)
+}
+
+func (s *WarningsSuite) TestDeprecatedCommandWarning(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	jc.Register(&simple{name: "test"})
+	jc.RegisterAlias("old", "test", deprecate{replacement: "test"})
+
+	code := cmd.Main(jc, s.ctx, []string{"old"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "WARNING \"old\" is deprecated, please use \"test\"\n")
+	c.Assert(s.ctx.Warnings(), gc.DeepEquals, []cmd.Warning{{
+		Code:    cmd.WarningDeprecatedCommand,
+		Message: `"old" is deprecated, please use "test"`,
+	}})
+	c.Assert(cmdtesting.Events(s.ctx, cmd.EventWarning), gc.DeepEquals, []cmd.Event{{
+		Kind:    cmd.EventWarning,
+		Code:    cmd.WarningDeprecatedCommand,
+		Message: `"old" is deprecated, please use "test"`,
+	}})
+}
+
+func (s *WarningsSuite) TestWarningfEvent(c *gc.C) {
+	s.ctx.Warningf("something %s", "odd")
+	c.Assert(cmdtesting.Events(s.ctx), gc.DeepEquals, []cmd.Event{{
+		Kind:    cmd.EventWarning,
+		Message: "something odd",
+	}})
+	c.Assert(cmdtesting.Events(s.ctx, cmd.EventProgress, cmd.EventPrompt), gc.HasLen, 0)
+}
+
+func (s *WarningsSuite) TestSuppressDeprecatedCommandWarning(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:             "jujutest",
+		SuppressWarnings: []cmd.WarningCode{cmd.WarningDeprecatedCommand},
+	})
+	jc.Register(&simple{name: "test"})
+	jc.RegisterAlias("old", "test", deprecate{replacement: "test"})
+
+	code := cmd.Main(jc, s.ctx, []string{"old"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, "")
+}
+
+func (s *WarningsSuite) TestRepeatedWarnings(c *gc.C) {
+	jc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "jujutest"})
+	jc.Register(&TestCommand{
+		Name: "test",
+		CustomRun: func(ctx *cmd.Context) error {
+			for i := 0; i < 3; i++ {
+				ctx.Warningf("falling back to %s", "http")
+				ctx.WarningWithCodef("test-warning", "unit %d is old", i%2)
+			}
+			ctx.Warningf("only once")
+			return nil
+		},
+	})
+
+	code := cmd.Main(jc, s.ctx, []string{"test"})
+	c.Assert(code, gc.Equals, 0)
+	c.Assert(cmdtesting.Stderr(s.ctx), gc.Equals, ""+
+		"WARNING falling back to http\n"+
+		"WARNING unit 0 is old\n"+
+		"WARNING unit 1 is old\n"+
+		"WARNING only once\n"+
+		"WARNING falling back to http (repeated 3 times)\n"+
+		"WARNING unit 0 is old (repeated 2 times)\n")
+	c.Assert(s.ctx.Warnings(), gc.HasLen, 3)
+	c.Assert(cmdtesting.Events(s.ctx, cmd.EventWarning), gc.HasLen, 7)
+}
+
+func (s *WarningsSuite) TestConcurrentWarnings(c *gc.C) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				s.ctx.Warningf("falling back to %s", "http")
+				s.ctx.WarningWithCodef("test-warning", "unit %d is old", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+	c.Assert(s.ctx.Warnings(), gc.HasLen, 80)
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// WithStdoutWatchdog returns a copy of ctx whose context.Context is
+// cancelled when the consumer of Stdout goes away, for instance when the
+// output is piped into "head" and head has exited. Streaming commands (watch
+// and tail style) select on ctx.Done() so that they stop instead of running
+// on headless. The returned function stops the watchdog and must be called
+// when the command finishes.
+//
+// Writes to Stdout that fail because the pipe is closed cancel the context
+// and return the error, rather than killing the process with SIGPIPE. Where
+// the platform allows it, a closed pipe is also detected while the command
+// is not writing.
+func (ctx *Context) WithStdoutWatchdog() (*Context, func()) {
+	c, cancel := context.WithCancel(ctx.background())
+	newCtx := ctx.With(c)
+	newCtx.Stdout = &watchedWriter{Writer: ctx.Stdout, cancel: cancel}
+
+	stopSignals := ignoreBrokenPipe()
+	stopPoll := func() {}
+	if f, ok := ctx.Stdout.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+			stopPoll = watchPipeClosed(f, cancel)
+		}
+	}
+	var once sync.Once
+	return newCtx, func() {
+		once.Do(func() {
+			stopPoll()
+			stopSignals()
+			cancel()
+		})
+	}
+}
+
+// watchedWriter cancels the context when a write fails because the reader
+// has gone away.
+type watchedWriter struct {
+	io.Writer
+	cancel func()
+}
+
+// Write implements io.Writer.
+func (w *watchedWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		w.cancel()
+	}
+	return n, err
+}
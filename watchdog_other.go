@@ -0,0 +1,19 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package cmd
+
+import "os"
+
+// ignoreBrokenPipe does nothing on this platform.
+func ignoreBrokenPipe() func() {
+	return func() {}
+}
+
+// watchPipeClosed does nothing on this platform, so a closed pipe is only
+// detected when writing.
+func watchPipeClosed(f *os.File, cancel func()) func() {
+	return func() {}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type WatchdogSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&WatchdogSuite{})
+
+// brokenPipeWriter fails every write as a closed pipe would.
+type brokenPipeWriter struct{}
+
+func (brokenPipeWriter) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: "|1", Err: syscall.EPIPE}
+}
+
+func (s *WatchdogSuite) TestWriteCancels(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	ctx.Stdout = brokenPipeWriter{}
+	wctx, stop := ctx.WithStdoutWatchdog()
+	defer stop()
+	c.Assert(wctx.Err(), gc.IsNil)
+
+	_, err := wctx.Stdout.Write([]byte("hello\n"))
+	c.Assert(err, gc.ErrorMatches, ".*broken pipe")
+	c.Assert(wctx.Err(), gc.NotNil)
+	c.Assert(ctx.Err(), gc.IsNil)
+}
+
+func (s *WatchdogSuite) TestWritePassesThrough(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	wctx, stop := ctx.WithStdoutWatchdog()
+	defer stop()
+	_, err := wctx.Stdout.Write([]byte("hello\n"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(wctx.Err(), gc.IsNil)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "hello\n")
+}
+
+func (s *WatchdogSuite) TestStopCancels(c *gc.C) {
+	ctx := cmdtesting.Context(c)
+	wctx, stop := ctx.WithStdoutWatchdog()
+	stop()
+	stop()
+	c.Assert(wctx.Err(), gc.NotNil)
+}
+
+func (s *WatchdogSuite) TestReaderClosed(c *gc.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("closed pipes are only detected when writing on windows")
+	}
+	r, w, err := os.Pipe()
+	c.Assert(err, gc.IsNil)
+	defer w.Close()
+
+	ctx := cmdtesting.Context(c)
+	ctx.Stdout = w
+	wctx, stop := ctx.WithStdoutWatchdog()
+	defer stop()
+	c.Assert(wctx.Err(), gc.IsNil)
+
+	c.Assert(r.Close(), gc.IsNil)
+	select {
+	case <-wctx.Done():
+	case <-time.After(testing.LongWait):
+		c.Fatalf("watchdog did not notice the closed pipe")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// pipePollInterval is how often, in milliseconds, the watchdog wakes to
+// check whether it has been stopped.
+const pipePollInterval = 200
+
+// ignoreBrokenPipe arranges for writes to a closed stdout pipe to return
+// EPIPE instead of the runtime exiting on SIGPIPE.
+func ignoreBrokenPipe() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGPIPE)
+	return func() {
+		signal.Stop(signals)
+	}
+}
+
+// watchPipeClosed calls cancel when the reading end of the pipe f is
+// closed. The poll reports an error condition on the writing end of a pipe
+// once it has no readers left.
+func watchPipeClosed(f *os.File, cancel func()) func() {
+	done := make(chan struct{})
+	fd := int32(f.Fd())
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			fds := []unix.PollFd{{Fd: fd}}
+			n, err := unix.Poll(fds, pipePollInterval)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil || (n > 0 && fds[0].Revents&(unix.POLLERR|unix.POLLHUP|unix.POLLNVAL) != 0) {
+				cancel()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/yaml.v2"
+)
+
+// ChangelogEntry describes the user visible changes made in a version.
+type ChangelogEntry struct {
+	Version string
+	Changes []string
+}
+
+// versionStateFilename is the name of the file in the data directory that
+// tracks the version of the last run.
+const versionStateFilename = "version-state.yaml"
+
+// versionState records the version of the last run, and the version that
+// was run before the most recent upgrade.
+type versionState struct {
+	Version  string `yaml:"version"`
+	Previous string `yaml:"previous,omitempty"`
+}
+
+// readVersionState reads the version state from dataDir. A missing file
+// results in an empty state.
+func readVersionState(dataDir string) (versionState, error) {
+	var state versionState
+	content, err := ioutil.ReadFile(filepath.Join(dataDir, versionStateFilename))
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return state, errors.Trace(err)
+	}
+	err = yaml.Unmarshal(content, &state)
+	return state, errors.Trace(err)
+}
+
+// writeVersionState writes the version state to dataDir.
+func writeVersionState(dataDir string, state versionState) error {
+	content, err := yaml.Marshal(state)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(ioutil.WriteFile(filepath.Join(dataDir, versionStateFilename), content, 0600))
+}
+
+// trackVersion records the version being run in the data directory, and
+// writes a one line notice to stderr when it is newer than the version of
+// the last run. Failures are logged, as they must not stop the command.
+func (c *SuperCommand) trackVersion(ctx *Context) {
+	if c.changelog == nil || c.version == "" || c.dataDir == "" {
+		return
+	}
+	dataDir := ctx.expandHome(c.dataDir)
+	state, err := readVersionState(dataDir)
+	if err != nil {
+		logger.Debugf("cannot read version state: %v", err)
+		return
+	}
+	if state.Version == c.version {
+		return
+	}
+	if state.Version != "" && compareVersions(c.version, state.Version) <= 0 {
+		// After a downgrade there is nothing new to show.
+		state.Previous, state.Version = c.version, c.version
+	} else {
+		if state.Version != "" && c.action.name != "whatsnew" {
+			fmt.Fprintf(ctx.Stderr, "%s has been updated to %s; run %q to see what changed.\n",
+				c.Name, c.version, c.commandPath("whatsnew"))
+		}
+		state.Previous, state.Version = state.Version, c.version
+	}
+	if err := writeVersionState(dataDir, state); err != nil {
+		logger.Debugf("cannot write version state: %v", err)
+	}
+}
+
+// whatsNewCommand shows the changelog entries added since the version that
+// was run before the most recent upgrade.
+type whatsNewCommand struct {
+	CommandBase
+	super *SuperCommand
+	all   bool
+}
+
+func (c *whatsNewCommand) Info() *Info {
+	return &Info{
+		Name:               "whatsnew",
+		Purpose:            "Show what has changed since the last upgrade.",
+		NonInteractiveSafe: true,
+		Doc: `
+Show the changes made in the versions released since the version that was
+in use before the most recent upgrade.`[1:],
+	}
+}
+
+func (c *whatsNewCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&c.all, "all", false, "Show the changes made in every version")
+}
+
+func (c *whatsNewCommand) Init(args []string) error {
+	return CheckEmpty(args)
+}
+
+func (c *whatsNewCommand) Run(ctx *Context) error {
+	entries, err := c.super.changelog()
+	if err != nil {
+		return errors.Annotate(err, "reading changelog")
+	}
+	var since string
+	if !c.all && c.super.dataDir != "" {
+		state, err := readVersionState(ctx.expandHome(c.super.dataDir))
+		if err != nil {
+			return errors.Annotate(err, "reading version state")
+		}
+		since = state.Previous
+	}
+	shown := 0
+	// Entries are ordered newest first, so stop at the version run before
+	// the upgrade.
+	for _, entry := range entries {
+		if c.super.version != "" && compareVersions(entry.Version, c.super.version) > 0 {
+			continue
+		}
+		if since != "" && compareVersions(entry.Version, since) <= 0 {
+			break
+		}
+		if shown > 0 {
+			fmt.Fprintln(ctx.Stdout)
+		}
+		fmt.Fprintf(ctx.Stdout, "%s:\n", entry.Version)
+		for _, change := range entry.Changes {
+			fmt.Fprintf(ctx.Stdout, "  - %s\n", change)
+		}
+		shown++
+	}
+	if shown == 0 && since != "" {
+		ctx.Infof("No changes since %s.", since)
+	}
+	return nil
+}
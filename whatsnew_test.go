@@ -0,0 +1,151 @@
+// Copyright 2024 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENSE file for details.
+
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/juju/testing"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/cmd/v4"
+	"github.com/juju/cmd/v4/cmdtesting"
+)
+
+type WhatsNewSuite struct {
+	testing.IsolationSuite
+
+	dataDir string
+}
+
+var _ = gc.Suite(&WhatsNewSuite{})
+
+func (s *WhatsNewSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.dataDir = c.MkDir()
+}
+
+func changelog() ([]cmd.ChangelogEntry, error) {
+	return []cmd.ChangelogEntry{{
+		Version: "3.0.0",
+		Changes: []string{"--model-uuid renamed to --model", "status shows storage"},
+	}, {
+		Version: "2.9.1",
+		Changes: []string{"faster deploys"},
+	}, {
+		Version: "2.9.0",
+		Changes: []string{"initial release"},
+	}}, nil
+}
+
+func (s *WhatsNewSuite) run(c *gc.C, version string, args ...string) *cmd.Context {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:      "juju",
+		Version:   version,
+		Changelog: changelog,
+		DataDir:   s.dataDir,
+	})
+	sc.Register(&TestCommand{Name: "blah"})
+	ctx := cmdtesting.Context(c)
+	code := cmd.Main(sc, ctx, args)
+	c.Assert(code, gc.Equals, 0)
+	return ctx
+}
+
+func (s *WhatsNewSuite) TestUpgradeNotice(c *gc.C) {
+	// The first run records the version without a notice.
+	ctx := s.run(c, "2.9.0", "blah")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+
+	ctx = s.run(c, "3.0.0", "blah")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals,
+		"juju has been updated to 3.0.0; run \"juju whatsnew\" to see what changed.\n")
+
+	// The notice is only shown once.
+	ctx = s.run(c, "3.0.0", "blah")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *WhatsNewSuite) TestWhatsNew(c *gc.C) {
+	s.run(c, "2.9.0", "blah")
+	s.run(c, "3.0.0", "blah")
+	ctx := s.run(c, "3.0.0", "whatsnew")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+3.0.0:
+  - --model-uuid renamed to --model
+  - status shows storage
+
+2.9.1:
+  - faster deploys
+`[1:])
+}
+
+func (s *WhatsNewSuite) TestWhatsNewAll(c *gc.C) {
+	s.run(c, "2.9.0", "blah")
+	s.run(c, "3.0.0", "blah")
+	ctx := s.run(c, "3.0.0", "whatsnew", "--all")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Matches, `(?s)3\.0\.0:.*2\.9\.1:.*2\.9\.0:\n  - initial release\n`)
+}
+
+func (s *WhatsNewSuite) TestWhatsNewNoChanges(c *gc.C) {
+	s.run(c, "3.0.0", "blah")
+	s.run(c, "3.0.1", "blah")
+	ctx := s.run(c, "3.0.1", "whatsnew")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "No changes since 3.0.0.\n")
+
+	// Without a changelog the subcommand is not registered.
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{Name: "juju"})
+	ctx = cmdtesting.Context(c)
+	c.Assert(cmd.Main(sc, ctx, []string{"whatsnew"}), gc.Equals, 2)
+}
+
+func (s *WhatsNewSuite) TestWhatsNewWithoutPreviousVersion(c *gc.C) {
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:      "juju",
+		Version:   "3.0.0",
+		Changelog: func() ([]cmd.ChangelogEntry, error) { return nil, nil },
+		DataDir:   s.dataDir,
+	})
+	ctx := cmdtesting.Context(c)
+	c.Assert(cmd.Main(sc, ctx, []string{"whatsnew"}), gc.Equals, 0)
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+}
+
+func (s *WhatsNewSuite) TestDowngrade(c *gc.C) {
+	s.run(c, "3.0.0", "blah")
+	ctx := s.run(c, "2.9.1", "blah")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "")
+
+	ctx = s.run(c, "2.9.1", "whatsnew")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Equals, "No changes since 2.9.1.\n")
+
+	// Upgrading again shows the notice, and only the newer changes.
+	ctx = s.run(c, "3.0.0", "blah")
+	c.Assert(cmdtesting.Stderr(ctx), gc.Matches, "juju has been updated to 3.0.0; .*\n")
+	ctx = s.run(c, "3.0.0", "whatsnew")
+	c.Assert(cmdtesting.Stdout(ctx), gc.Equals, `
+3.0.0:
+  - --model-uuid renamed to --model
+  - status shows storage
+`[1:])
+}
+
+func (s *WhatsNewSuite) TestDataDirInContextHome(c *gc.C) {
+	fixture := cmdtesting.NewFixture(c, nil)
+	sc := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:      "juju",
+		Version:   "2.9.0",
+		Changelog: changelog,
+		DataDir:   "~/.local/share/juju",
+	})
+	sc.Register(&TestCommand{Name: "blah"})
+	code := cmd.Main(sc, fixture.Context(c), []string{"blah"})
+	c.Assert(code, gc.Equals, 0)
+	_, err := os.Stat(filepath.Join(fixture.DataDir, "juju", "version-state.yaml"))
+	c.Assert(err, gc.IsNil)
+}